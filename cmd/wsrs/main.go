@@ -4,15 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/internal/broker/pgbroker"
+	"github.com/lohanguedes/AMA-Backend/internal/config"
+	"github.com/lohanguedes/AMA-Backend/internal/contentfilter"
+	"github.com/lohanguedes/AMA-Backend/internal/ratelimit/redislimit"
+	"github.com/lohanguedes/AMA-Backend/internal/store/memstore"
 	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/internal/store/sqlitestore"
+	"github.com/lohanguedes/AMA-Backend/internal/tracing"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -20,28 +31,55 @@ func main() {
 		panic(err)
 	}
 
-	ctx := context.Background()
-	pool, err := pgxpool.New(ctx, fmt.Sprintf(
-		"user=%s password=%s host=%s port=%s dbname=%s",
-		os.Getenv("WSRS_DATABASE_USER"),
-		os.Getenv("WSRS_DATABASE_PASSWORD"),
-		os.Getenv("WSRS_DATABASE_HOST"),
-		os.Getenv("WSRS_DATABASE_PORT"),
-		os.Getenv("WSRS_DATABASE_NAME"),
-	))
+	cfg, err := config.Load()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	defer pool.Close()
 
-	if err := pool.Ping(ctx); err != nil {
-		panic(err)
+	ctx := context.Background()
+	store, pool := openStore(ctx, cfg)
+	if pool != nil {
+		defer pool.Close()
 	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
 
-	handler := api.NewHandler(pgstore.New(pool))
+	if cfg.Tracing {
+		shutdownTracing, err := tracing.Setup(ctx)
+		if err != nil {
+			panic(err)
+		}
+		defer shutdownTracing(context.Background())
+	}
+
+	opts := []api.Option{api.WithLogger(logger)}
+	if cfg.Broker == config.BrokerPostgres {
+		opts = append(opts, api.WithBroker(pgbroker.New(pool, "")))
+	}
+	if cfg.ContentFilter != config.ContentFilterOff {
+		filter := contentfilter.New(append(contentfilter.DefaultWords(), cfg.ContentFilterWords...))
+		opts = append(opts, api.WithContentFilter(filter, api.ContentFilterAction(cfg.ContentFilter)))
+	}
+	if cfg.RateLimiter == config.RateLimiterRedis {
+		redisOpts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			panic(err)
+		}
+		client := redis.NewClient(redisOpts)
+		defer client.Close()
+		opts = append(opts, api.WithRateLimiter(redislimit.New(client, "")))
+	}
+
+	handler := api.NewHandlerWithConfig(store, cfg.API, opts...)
+	server := &http.Server{Addr: cfg.Addr, Handler: handler}
 	go func() {
-		slog.Info("Server started on port :8080")
-		if err := http.ListenAndServe(":8080", handler); err != nil {
+		slog.Info("Server started on port " + cfg.Addr)
+		if err := server.ListenAndServe(); err != nil {
 			if !errors.Is(err, http.ErrServerClosed) {
 				panic(err)
 			}
@@ -49,7 +87,52 @@ func main() {
 	}()
 
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	slog.Info("server Quitted through signal")
+
+	// The handler closes the subscriptions first: the server doesn't track
+	// websockets and would wait for the event streams to end on their own.
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := handler.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("failed to shut down handler", "error", err)
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("failed to shut down server", "error", err)
+	}
+}
+
+// openStore connects to Postgres, or keeps everything in memory when
+// WSRS_STORE is "memory" so the server runs without a database, or in a
+// SQLite file when it is "sqlite". The pool is nil for the other stores.
+func openStore(ctx context.Context, cfg config.Config) (api.Store, *pgxpool.Pool) {
+	switch cfg.Store {
+	case config.StoreMemory:
+		slog.Warn("using the in-memory store, data is lost on restart")
+		return memstore.New(), nil
+	case config.StoreSQLite:
+		store, err := sqlitestore.Open(ctx, cfg.SQLitePath)
+		if err != nil {
+			panic(err)
+		}
+		return store, nil
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.Database.ConnString())
+	if err != nil {
+		panic(err)
+	}
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		panic(err)
+	}
+	return pgstore.New(pool), pool
 }
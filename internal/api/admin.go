@@ -0,0 +1,152 @@
+package api
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultWSTopWindow = 5 * time.Minute
+	defaultWSTopLimit  = 10
+	maxWSTopLimit      = 100
+)
+
+// requireAdmin only lets requests through that authenticate resolved to the
+// configured admin token. Without a configured token the admin API is
+// disabled altogether.
+func (api *Handler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.adminToken == "" {
+			writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+			return
+		}
+
+		if !authFrom(r.Context()).isAdmin() {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (api *Handler) handleGetWSStats(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(map[string]any{
+		"since":   api.wsStats.since,
+		"traffic": api.wsStats.totals(),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (api *Handler) handleGetWSTop(w http.ResponseWriter, r *http.Request) {
+	window := defaultWSTopWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 || d > wsStatsWindow*time.Minute {
+			writeError(w, http.StatusBadRequest, "invalid_window", "invalid window")
+			return
+		}
+		window = d
+	}
+
+	limit := defaultWSTopLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxWSTopLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"window":  window.String(),
+		"traffic": api.wsStats.top(window, limit, api.now()),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (api *Handler) handleGetRoomSubscribers(w http.ResponseWriter, r *http.Request) {
+	roomID := chi.URLParam(r, "room_id")
+
+	type subscriberInfo struct {
+		ID              string    `json:"id"`
+		RemoteAddr      string    `json:"remote_addr"`
+		ConnectedAt     time.Time `json:"connected_at"`
+		Transport       string    `json:"transport"`
+		Scope           string    `json:"scope"`
+		EventsDelivered uint64    `json:"events_delivered"`
+		EventsDropped   uint64    `json:"events_dropped"`
+	}
+
+	api.mu.Lock()
+	subscribers := make([]subscriberInfo, 0, len(api.subscribers[roomID]))
+	for sub := range api.subscribers[roomID] {
+		subscribers = append(subscribers, subscriberInfo{
+			ID:              sub.id,
+			RemoteAddr:      sub.remoteAddr,
+			ConnectedAt:     sub.connectedAt,
+			Transport:       sub.transport.Name(),
+			Scope:           cmp.Or(sub.scope, "public"),
+			EventsDelivered: sub.delivered.Load(),
+			EventsDropped:   sub.dropped.Load(),
+		})
+	}
+	api.mu.Unlock()
+
+	slices.SortFunc(subscribers, func(a, b subscriberInfo) int {
+		return a.ConnectedAt.Compare(b.ConnectedAt)
+	})
+
+	data, err := json.Marshal(subscribers)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleKickSubscriber closes a single subscription with the kicked reason.
+func (api *Handler) handleKickSubscriber(w http.ResponseWriter, r *http.Request) {
+	roomID := chi.URLParam(r, "room_id")
+	connID := chi.URLParam(r, "conn_id")
+
+	api.mu.Lock()
+	var found *subscriber
+	for sub := range api.subscribers[roomID] {
+		if sub.id == connID {
+			found = sub
+			break
+		}
+	}
+	api.mu.Unlock()
+
+	if found == nil {
+		writeError(w, http.StatusNotFound, "subscriber_not_found", "subscriber not found")
+		return
+	}
+
+	api.logger.Info("kicking subscriber", "room_id", roomID, "conn_id", connID, "client_ip", found.remoteAddr)
+	found.close("kicked")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// maxAnnouncementLength is the size of the body column of announcements.
+const maxAnnouncementLength = 255
+
+// announcement is the JSON representation of an announcement of a host.
+type announcement struct {
+	ID        string    `json:"id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handleCreateAnnouncement tells everyone in the room something, like that
+// the host is taking a break, without adding it to the questions.
+func (api *Handler) handleCreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	body := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+	var v validator
+	v.text("body", body.Body, maxAnnouncementLength)
+	if !v.valid(w) {
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	id := api.ids.NewID()
+	author := authFrom(r.Context()).actor()
+	createdAt := storedTime(api.now())
+	err = api.queries.InsertAnnouncement(r.Context(), pgstore.InsertAnnouncementParams{
+		ID:        id,
+		RoomID:    roomID,
+		Author:    author,
+		Body:      body.Body,
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindAnnouncementCreated,
+		RoomID: roomID.String(),
+		Value: events.AnnouncementCreated{
+			ID:     id.String(),
+			Author: author,
+			Body:   body.Body,
+		},
+	})
+
+	data, err := json.Marshal(map[string]any{
+		"id":         id.String(),
+		"author":     author,
+		"body":       body.Body,
+		"created_at": createdAt,
+		"seq":        seq,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	writeCreated(w, r, id, data)
+}
+
+// handleGetAnnouncement returns one of the announcements of a room.
+func (api *Handler) handleGetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+	announcementID, err := uuid.Parse(chi.URLParam(r, "announcement_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_announcement_id", "invalid announcement id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	stored, err := api.queries.GetAnnouncement(r.Context(), announcementID)
+	if err != nil {
+		api.writeStoreError(w, err, "announcement_not_found")
+		return
+	}
+	if stored.RoomID != roomID {
+		writeError(w, http.StatusNotFound, "announcement_not_found", "announcement not found")
+		return
+	}
+
+	data, err := json.Marshal(announcement{
+		ID:        stored.ID.String(),
+		Author:    stored.Author,
+		Body:      stored.Body,
+		CreatedAt: stored.CreatedAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleGetAnnouncements lists the announcements of a room, newest first.
+func (api *Handler) handleGetAnnouncements(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	stored, err := api.queries.GetRoomAnnouncements(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	announcements := make([]announcement, 0, len(stored))
+	for _, a := range stored {
+		announcements = append(announcements, announcement{
+			ID:        a.ID.String(),
+			Author:    a.Author,
+			Body:      a.Body,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+
+	data, err := json.Marshal(announcements)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
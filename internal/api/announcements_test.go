@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestAnnouncements(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	path := "/rooms/" + room.ID + "/announcements"
+	c := s.subscribe(t, room.ID, "")
+
+	var ids []string
+	for _, body := range []string{"Back in five minutes.", "We're back!"} {
+		resp := s.do(t, http.MethodPost, path, map[string]any{"body": body}, host...)
+		expectStatus(t, resp, http.StatusCreated)
+		created := resp.object(t)
+		ids = append(ids, created["id"].(string))
+
+		got := c.expect(events.KindAnnouncementCreated).Value.(events.AnnouncementCreated)
+		if want := (events.AnnouncementCreated{ID: created["id"].(string), Author: "host", Body: body}); got != want {
+			t.Errorf("got announcement_created %+v, want %+v", got, want)
+		}
+		s.clock.Advance(time.Minute)
+	}
+
+	resp := s.do(t, http.MethodGet, path, nil)
+	expectStatus(t, resp, http.StatusOK)
+	listed := resp.list(t)
+	if len(listed) != 2 || listed[0]["id"] != ids[1] || listed[1]["id"] != ids[0] {
+		t.Errorf("got announcements %v, want %v newest first", listed, ids)
+	}
+
+	resp = s.do(t, http.MethodGet, path+"/"+ids[0], nil)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["body"]; got != "Back in five minutes." {
+		t.Errorf("got body %v, want the first announcement", got)
+	}
+
+	// Announcements aren't questions.
+	if messages := s.messages(t, room.ID); len(messages) != 0 {
+		t.Errorf("got messages %v, want none", messages)
+	}
+}
+
+func TestAnnouncementsRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	path := "/rooms/" + room.ID + "/announcements"
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+other.ID+"/announcements", map[string]any{"body": "elsewhere"}, "Authorization", "Bearer "+other.HostToken)
+	expectStatus(t, resp, http.StatusCreated)
+	elsewhere := resp.object(t)["id"].(string)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		header []string
+		status int
+		code   string
+	}{
+		{"NotHost", http.MethodPost, path, map[string]any{"body": "hello"}, nil, http.StatusUnauthorized, "unauthorized"},
+		{"BlankBody", http.MethodPost, path, map[string]any{"body": ""}, host, http.StatusUnprocessableEntity, "validation_failed"},
+		{"LongBody", http.MethodPost, path, map[string]any{"body": strings.Repeat("a", 256)}, host, http.StatusUnprocessableEntity, "validation_failed"},
+		{"InvalidID", http.MethodGet, path + "/nope", nil, nil, http.StatusBadRequest, "invalid_announcement_id"},
+		{"OtherRoom", http.MethodGet, path + "/" + elsewhere, nil, nil, http.StatusNotFound, "announcement_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, tt.method, tt.path, tt.body, tt.header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
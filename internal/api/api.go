@@ -4,270 +4,1655 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"github.com/jackc/pgx/v5"
+	"github.com/lohanguedes/AMA-Backend/internal/langdetect"
 	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
 )
 
-type apiHandler struct {
-	queries     *pgstore.Queries
-	router      *chi.Mux
-	subscribers map[string]map[*websocket.Conn]context.CancelFunc
-	upgrader    websocket.Upgrader
-	mu          *sync.Mutex
+// Handler serves the REST API and room subscriptions. It holds the live
+// subscriber registry, so it must be used through the pointer returned by
+// NewHandler and never copied.
+//
+// The registry and the per-room event state are guarded by mu, which is only
+// held to update them and to queue events. Every subscriber is written to by
+// its own goroutine, so a slow client never holds it.
+type Handler struct {
+	queries        Store
+	router         *chi.Mux
+	subscribers    map[string]map[*subscriber]struct{}
+	upgrader       websocket.Upgrader
+	mu             sync.Mutex
+	clock          Clock
+	ids            IDGenerator
+	sendQueueSize  int
+	writeTimeout   time.Duration
+	logger         *slog.Logger
+	adminToken     string
+	wsStats        *wsStats
+	statsCache     *roomStatsCache
+	broadcasts     map[string]uint64
+	sequences      map[string]uint64
+	dbTimeout      time.Duration
+	detectLanguage bool
+	allowedOrigins []string
+	sweepInterval  time.Duration
+	stopBackground context.CancelFunc
+	background     sync.WaitGroup
+	requestTimeout time.Duration
+	webhookJobs    chan webhookJob
+	webhookClient  *http.Client
+	flagThreshold  int
+	// reactionFlushInterval is how often coalesced reaction counts and poll
+	// results are broadcast; pendingCounts holds them per room until then.
+	reactionFlushInterval time.Duration
+	pendingCounts         map[string]*roomCounts
+	// hostTokenSecret signs the host tokens handed out with new rooms.
+	hostTokenSecret []byte
+	// broker relays events to the other instances, which instanceID tells
+	// apart from this one. brokerJobs holds them until they are published.
+	broker     Broker
+	instanceID string
+	brokerJobs chan []byte
+	// pollEvents keeps the latest public events of every room for long
+	// polling clients. The channel of a room in pollWaiters is closed on its
+	// next event.
+	pollEvents  map[string][]events.Event
+	pollWaiters map[string]chan struct{}
+	// shuttingDown is closed by Shutdown, after which no subscription is
+	// accepted. subscriptions tracks the live ones until they end.
+	shuttingDown  chan struct{}
+	subscriptions sync.WaitGroup
+	// brokerDown is set while the broker listener waits to listen again.
+	brokerDown atomic.Bool
+	metrics    *metrics
+	pprof      bool
+	// wsPingInterval and the buffer sizes configure the websocket
+	// connections.
+	wsPingInterval    time.Duration
+	wsReadBufferSize  int
+	wsWriteBufferSize int
+	// rateLimiter keeps the buckets of the rateLimits, keyed by method and
+	// route.
+	rateLimiter RateLimiter
+	rateLimits  map[string]RateLimit
+	// trustedProxies are the proxies whose X-Forwarded-For tells which
+	// address requests come from.
+	trustedProxies []netip.Prefix
+	// contentFilter checks the questions asked, which are then handled as
+	// contentFilterAction says.
+	contentFilter       ContentFilter
+	contentFilterAction ContentFilterAction
+	// reactionKinds are the emoji clients may react to messages with.
+	reactionKinds []string
+	// viewerCountInterval is how often subscribers are told how many they
+	// are; viewerCounts holds the count last sent to each room.
+	viewerCountInterval time.Duration
+	viewerCounts        map[string]int
+	// composing holds when the clients composing a question in each room
+	// stop counting as such; composingCounts holds the count last sent to
+	// each room.
+	composing       map[string]map[string]time.Time
+	composingCounts map[string]int
+	// roomRetention is how long deleted rooms can be restored before the
+	// sweeper deletes them for good.
+	roomRetention time.Duration
 }
 
-func NewHandler(q *pgstore.Queries) http.Handler {
-	api := apiHandler{
-		queries: q,
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
-		},
-		subscribers: make(map[string]map[*websocket.Conn]context.CancelFunc),
-		mu:          &sync.Mutex{},
+func NewHandler(q Store, opts ...Option) *Handler {
+	api := &Handler{
+		subscribers:           make(map[string]map[*subscriber]struct{}),
+		clock:                 systemClock{},
+		ids:                   randomIDs{},
+		sendQueueSize:         defaultSendQueueSize,
+		writeTimeout:          defaultWriteTimeout,
+		logger:                slog.Default(),
+		statsCache:            newRoomStatsCache(),
+		broadcasts:            make(map[string]uint64),
+		sequences:             make(map[string]uint64),
+		dbTimeout:             defaultDBTimeout,
+		sweepInterval:         defaultSweepInterval,
+		requestTimeout:        defaultRequestTimeout,
+		webhookClient:         &http.Client{},
+		flagThreshold:         defaultFlagThreshold,
+		reactionFlushInterval: defaultReactionFlushInterval,
+		pendingCounts:         make(map[string]*roomCounts),
+		pollEvents:            make(map[string][]events.Event),
+		pollWaiters:           make(map[string]chan struct{}),
+		shuttingDown:          make(chan struct{}),
+		wsPingInterval:        defaultWSPingInterval,
+		wsReadBufferSize:      defaultWSBufferSize,
+		wsWriteBufferSize:     defaultWSBufferSize,
+		rateLimits:            maps.Clone(defaultRateLimits),
+		reactionKinds:         defaultReactionKinds,
+		viewerCountInterval:   defaultViewerCountInterval,
+		viewerCounts:          make(map[string]int),
+		composing:             make(map[string]map[string]time.Time),
+		composingCounts:       make(map[string]int),
+		roomRetention:         defaultRoomRetention,
+	}
+	for _, opt := range opts {
+		opt(api)
+	}
+	if api.rateLimiter == nil {
+		api.rateLimiter = newMemoryRateLimiter(api.clock)
+	}
+	api.queries = &dbStore{next: q, timeout: api.dbTimeout}
+	api.metrics = newMetrics(api)
+	if len(api.hostTokenSecret) == 0 {
+		api.logger.Warn("no host token secret configured, host tokens won't survive a restart")
+		api.hostTokenSecret = newHostTokenSecret()
+	}
+	api.wsStats = newWSStats(api.now())
+
+	if len(api.allowedOrigins) == 0 {
+		api.logger.Warn("no allowed origins configured, accepting requests from any origin")
+		api.allowedOrigins = defaultAllowedOrigins
+	}
+	api.upgrader = websocket.Upgrader{
+		ReadBufferSize:  api.wsReadBufferSize,
+		WriteBufferSize: api.wsWriteBufferSize,
+		CheckOrigin:     api.checkOrigin,
+		// permessage-deflate is used with clients that negotiate it;
+		// the others keep receiving uncompressed frames.
+		EnableCompression: true,
 	}
 
 	r := chi.NewRouter()
-	r.Use(middleware.RequestID, middleware.Recoverer, middleware.Logger)
+	r.Use(middleware.RequestID, exposeRequestID, recoverer(api.logger), requestLogger(api.logger), api.instrument, api.traceRequests)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
+		AllowedOrigins:   api.allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Client-Id", "Last-Event-ID", "If-Match"},
+		ExposedHeaders:   []string{"Link", "Location", "Deprecation", requestIDHeader, excludedMessagesHeader},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 
-	r.Get("/subscribe/{room_id}", api.handleSubscribe)
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+	})
+
+	r.Get("/healthz", api.handleHealthz)
+	r.Get("/readyz", api.handleReadyz)
+	r.With(api.authenticate, api.requireAdmin).Handle("/metrics", api.metrics.handler())
+	if api.pprof {
+		r.With(api.authenticate, api.requireAdmin).Mount("/debug", middleware.Profiler())
+	}
+	r.Get("/subscribe", api.handleSubscribeMux)
+	r.With(api.resolveRoomCode).Get("/subscribe/{room_id}", api.handleSubscribe)
+	r.With(api.resolveRoomCode).Get("/subscribe/{room_id}/sse", api.handleSubscribeSSE)
 
+	r.Route("/api/v1", api.routes)
+	// The unversioned API keeps serving the current shape to existing
+	// clients while they move to /api/v1.
 	r.Route("/api", func(r chi.Router) {
+		r.Use(deprecated)
+		api.routes(r)
+	})
+
+	api.router = r
+
+	ctx, cancel := context.WithCancel(context.Background())
+	api.stopBackground = cancel
+	api.goBackground(func() { api.runSweeper(ctx) })
+	api.goBackground(func() { api.runReactionFlusher(ctx) })
+	api.goBackground(func() { api.runViewerCounter(ctx) })
+	api.goBackground(func() { api.runComposingCounter(ctx) })
+	api.startWebhookWorkers(ctx)
+	if api.broker != nil {
+		api.instanceID = api.ids.NewID().String()
+		api.brokerJobs = make(chan []byte, brokerQueueSize)
+		api.goBackground(func() { api.runBrokerPublisher(ctx) })
+		api.goBackground(func() { api.runBrokerListener(ctx) })
+	}
+
+	return api
+}
+
+// routes registers the REST API on r, which is mounted at every version of
+// the API still served.
+func (api *Handler) routes(r chi.Router) {
+	r.Use(api.authenticate, api.rateLimit(r))
+
+	// Streamed and long polling responses can't go through the timeout
+	// middleware, which buffers the whole response and cuts it short.
+	r.With(api.resolveRoomCode).Get("/rooms/{room_id}/messages/export", api.handleExportRoomMessages)
+	r.With(api.resolveRoomCode).Get("/rooms/{room_id}/events", api.handlePollRoomEvents)
+
+	r.Group(func(r chi.Router) {
+		r.Use(timeout(api.requestTimeout))
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(api.requireAdmin)
+			r.Get("/ws/stats", api.handleGetWSStats)
+			r.Get("/ws/top", api.handleGetWSTop)
+		})
+
+		r.Post("/invites/redeem", api.handleRedeemInvite)
+
 		r.Route("/rooms", func(r chi.Router) {
 			r.Post("/", api.handleCreateRoom)
 			r.Get("/", api.handleGetRooms)
 
-			r.Route("/{room_id}/messages", func(r chi.Router) {
-				r.Get("/", api.handleGetRoomMessages)
-				r.Post("/", api.handleCreateRoomMessage)
+			r.Route("/{room_id}", func(r chi.Router) {
+				r.Use(api.resolveRoomCode)
+				r.Get("/", api.handleGetRoom)
+				r.Get("/stats", api.handleGetRoomStats)
+				r.Post("/reactions/batch", api.handleReactionBatch)
+				r.With(api.requireHost).Get("/audit", api.handleGetRoomAudit)
+				r.With(api.requireHost).Get("/flags", api.handleGetRoomFlags)
+				r.With(api.requireHost).Get("/subscribers", api.handleGetRoomSubscribers)
+				r.With(api.requireHost).Delete("/subscribers/{conn_id}", api.handleKickSubscriber)
+				r.With(api.requireOwner).Patch("/close", api.handleCloseRoom)
+				r.With(api.requireOwner).Patch("/", api.handleUpdateRoom)
+				r.With(api.requireOwner).Delete("/", api.handleDeleteRoom)
+				r.With(api.requireOwner).Post("/restore", api.handleRestoreRoom)
+				r.With(api.requireOwner).Post("/invites", api.handleCreateInvite)
+				r.With(api.requireOwner).Get("/webhooks", api.handleGetRoomWebhooks)
+				r.With(api.requireOwner).Patch("/webhooks", api.handleUpdateRoomWebhooks)
+
+				r.Route("/messages", func(r chi.Router) {
+					r.Get("/", api.handleGetRoomMessages)
+					r.Post("/", api.handleCreateRoomMessage)
+					r.Get("/search", api.handleSearchRoomMessages)
+					r.With(api.requireHost).Get("/pending", api.handleGetPendingMessages)
+
+					r.Route("/{message_id}", func(r chi.Router) {
+						r.Get("/", api.handleGetRoomMessage)
+						r.Put("/", api.handleUpdateRoomMessage)
+						r.Get("/edits", api.handleGetMessageEdits)
+						r.Get("/replies", api.handleGetMessageReplies)
+						r.Get("/replies/{reply_id}", api.handleGetMessageReply)
+						r.With(api.requireHost).Post("/replies", api.handleCreateMessageReply)
+						r.With(api.requireHost).Delete("/", api.handleDeleteRoomMessage)
+						r.With(api.requireHost).Post("/restore", api.handleRestoreRoomMessage)
+						r.With(api.requireHost).Post("/approve", api.handleApproveRoomMessage)
+						r.With(api.requireHost).Post("/reject", api.handleRejectRoomMessage)
+						r.Patch("/react", api.handleReactToMessage)
+						r.Delete("/react", api.handleRemoveReactionFromMessage)
+						r.Put("/reactions/{kind}", api.handleAddEmojiReaction)
+						r.Delete("/reactions/{kind}", api.handleRemoveEmojiReaction)
+						r.With(api.requireHost).Patch("/answer", api.handleMarkMessageAsAnswered)
+						r.Patch("/consent", api.handleUpdateMessageConsent)
+						r.With(api.requireHost).Patch("/language", api.handleUpdateMessageLanguage)
+						r.Post("/flag", api.handleFlagMessage)
+					})
+				})
+
+				r.Post("/composing", api.handleStartComposing)
+				r.Delete("/composing", api.handleStopComposing)
+
+				r.Route("/announcements", func(r chi.Router) {
+					r.Get("/", api.handleGetAnnouncements)
+					r.With(api.requireHost).Post("/", api.handleCreateAnnouncement)
+					r.Get("/{announcement_id}", api.handleGetAnnouncement)
+				})
+
+				r.Route("/polls", func(r chi.Router) {
+					r.Get("/", api.handleGetPolls)
+					r.With(api.requireHost).Post("/", api.handleCreatePoll)
 
-				r.Route("/{message_id}", func(r chi.Router) {
-					r.Get("/", api.handleGetRoomMessage)
-					r.Patch("/react", api.handleReactToMessage)
-					r.Delete("/react", api.handleRemoveReactionFromMessage)
-					r.Patch("/answer", api.handleMarkMessageAsAnswered)
+					r.Route("/{poll_id}", func(r chi.Router) {
+						r.Get("/", api.handleGetPoll)
+						r.Post("/votes", api.handleVotePoll)
+						r.With(api.requireHost).Post("/close", api.handleClosePoll)
+					})
 				})
 			})
 		})
 	})
-
-	api.router = r
-	return api
 }
 
-func (api apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+func (api *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	api.router.ServeHTTP(w, r)
 }
 
-const (
-	MessageKindMessageCreated = "message_created"
-)
+// Shutdown ends the live subscriptions and stops the background work started
+// by NewHandler, waiting for both until ctx is done. New subscriptions are
+// refused from then on. Websocket clients are told the server is going away,
+// which they can tell apart from a crash. In-flight HTTP requests are left to
+// http.Server.Shutdown, to be called after this.
+func (api *Handler) Shutdown(ctx context.Context) error {
+	api.mu.Lock()
+	select {
+	case <-api.shuttingDown:
+	default:
+		close(api.shuttingDown)
+	}
+	for _, subscribers := range api.subscribers {
+		for sub := range subscribers {
+			sub.close(closeReasonShutdown)
+		}
+	}
+	api.mu.Unlock()
+
+	if err := waitFor(ctx, &api.subscriptions); err != nil {
+		return err
+	}
+	api.stopBackground()
+	return waitFor(ctx, &api.background)
+}
+
+// isShuttingDown reports whether Shutdown was called.
+func (api *Handler) isShuttingDown() bool {
+	select {
+	case <-api.shuttingDown:
+		return true
+	default:
+		return false
+	}
+}
 
-type MessageMessageCreated struct {
-	ID      string `json:"id,omitempty"`
-	Message string `json:"message,omitempty"`
+// refuseWhileShuttingDown answers 503 and reports true once Shutdown was
+// called, so clients reconnect to another instance or after the restart.
+func (api *Handler) refuseWhileShuttingDown(w http.ResponseWriter) bool {
+	if !api.isShuttingDown() {
+		return false
+	}
+	writeError(w, http.StatusServiceUnavailable, closeReasonShutdown, "the server is shutting down, reconnect shortly")
+	return true
 }
 
-type Message struct {
-	Kind   string `json:"kind"`
-	Value  any    `json:"value"`
-	RoomID string `json:"-"`
+func waitFor(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (api apiHandler) notifyClients(msg Message) {
+func (api *Handler) goBackground(fn func()) {
+	api.background.Add(1)
+	go func() {
+		defer api.background.Done()
+		fn()
+	}()
+}
+
+// messageEditWindow is how long after creation the author may still edit a message.
+const messageEditWindow = 5 * time.Minute
+
+// notifyClients broadcasts msg to the subscribers of its room and returns the
+// sequence number it was given. Sequence numbers are assigned and events
+// queued under api.mu, so every connection receives them in order; callers
+// must not run it in a goroutine of its own, which would let two events of a
+// room overtake each other. Reaction counts still waiting to be coalesced are
+// sent first, so msg never overtakes them either. The webhook deliveries of
+// msg continue the trace of ctx.
+func (api *Handler) notifyClients(ctx context.Context, msg events.Event) uint64 {
 	api.mu.Lock()
 	defer api.mu.Unlock()
 
+	api.flushCountsLocked(msg.RoomID)
+	return api.broadcastLocked(ctx, msg)
+}
+
+// broadcastLocked is notifyClients for callers already holding api.mu.
+func (api *Handler) broadcastLocked(ctx context.Context, msg events.Event) uint64 {
+	api.queueWebhooks(ctx, msg)
+	api.publish(msg)
+	return api.fanOutLocked(msg)
+}
+
+// fanOutLocked numbers msg and queues it for the local subscribers of its
+// room. api.mu must be held.
+func (api *Handler) fanOutLocked(msg events.Event) uint64 {
+	if msg.Scope == events.ScopePublic {
+		api.sequences[msg.RoomID]++
+		msg.Seq = api.sequences[msg.RoomID]
+		api.recordPollEventLocked(msg)
+	}
+
+	api.broadcasts[msg.RoomID]++
 	subscribers, ok := api.subscribers[msg.RoomID]
 	if !ok || len(subscribers) == 0 {
-		slog.Warn("No subscribers on room id")
-		return
+		api.logger.Warn("No subscribers on room id")
+		api.metrics.fanOut.Observe(0)
+		return msg.Seq
 	}
 
-	for conn, cancel := range subscribers {
-		if err := conn.WriteJSON(msg); err != nil {
-			slog.Error("failed to send message to client", "error", err)
-			cancel()
+	// Serialized once here rather than per subscriber: busy rooms have
+	// thousands of them.
+	p, err := newPayload(msg)
+	if err != nil {
+		api.logger.Error("failed to marshal message", "kind", msg.Kind, "error", err)
+		return msg.Seq
+	}
+	delivered := 0
+	for sub := range subscribers {
+		if sub.receives(msg) {
+			sub.deliver(p)
+			delivered++
 		}
 	}
+	api.metrics.fanOut.Observe(float64(delivered))
+	return msg.Seq
 }
 
-// Websocket
-func (api apiHandler) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+// roomSequence returns the Seq of the last public event of roomID, which REST
+// snapshots of the room are at least as recent as.
+func (api *Handler) roomSequence(roomID string) uint64 {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.sequences[roomID]
+}
+
+// handleSubscribe streams room events over a websocket, or as server-sent
+// events when the client asks for text/event-stream.
+func (api *Handler) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	api.subscribe(w, r, wantsEventStream(r))
+}
+
+// handleSubscribeSSE streams room events as server-sent events whatever the
+// client accepts, for clients behind proxies that block websocket upgrades.
+func (api *Handler) handleSubscribeSSE(w http.ResponseWriter, r *http.Request) {
+	api.subscribe(w, r, true)
+}
+
+func (api *Handler) subscribe(w http.ResponseWriter, r *http.Request, sse bool) {
+	if !sse && !api.checkOrigin(r) {
+		writeError(w, http.StatusForbidden, "origin_not_allowed", "origin not allowed")
+		return
+	}
+	if api.refuseWhileShuttingDown(w) {
+		return
+	}
+
 	rawRoomID := chi.URLParam(r, "room_id")
 
 	roomID, err := uuid.Parse(rawRoomID)
 	if err != nil {
-		http.Error(w, "invalid room id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	scope, protocol, ok := api.subscriptionScope(w, r, rawRoomID)
+	if !ok {
 		return
 	}
 
 	ctx := context.Background()
-	_, err = api.queries.GetRoom(ctx, roomID)
+	_, err = api.getRoom(ctx, roomID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "invalid room id", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		api.writeStoreError(w, err, "room_not_found")
 		return
 	}
 
-	conn, err := api.upgrader.Upgrade(w, r, nil)
+	replay, resumeSeq, ok := api.resumePoint(w, r, roomID, rawRoomID)
+	if !ok {
+		return
+	}
+
+	if sse {
+		api.subscribeSSE(w, r, rawRoomID, scope, replay, resumeSeq)
+		return
+	}
+
+	var header http.Header
+	if protocol != "" {
+		header = http.Header{"Sec-WebSocket-Protocol": {protocol}}
+	}
+	conn, err := api.upgrader.Upgrade(w, r, header)
 	if err != nil {
-		slog.Warn("failed to upgrade conn", "error", err)
-		http.Error(w, "could not upgrade connection to websocket", http.StatusBadRequest)
+		api.logger.Warn("failed to upgrade conn", "error", err)
 		return
 	}
-	defer conn.Close()
+
+	conn.SetReadLimit(maxCommandFrameSize)
+	t := wsTransport{conn: conn, pingInterval: api.wsPingInterval}
+	clientID := commandClient(r)
 
 	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sub := api.newSubscriber(t, rawRoomID, api.clientIP(r), cancel)
+	sub.scope = scope
+	sub.resumeSeq = resumeSeq
 
-	api.mu.Lock()
-	if _, ok := api.subscribers[rawRoomID]; !ok {
-		api.subscribers[rawRoomID] = make(map[*websocket.Conn]context.CancelFunc)
+	go func() {
+		defer cancel()
+		t.readFrames(func(data []byte) {
+			api.handleCommand(ctx, sub, clientID, data)
+		})
+	}()
+	api.serveSubscriber(ctx, sub, replay)
+}
+
+func (api *Handler) subscribeSSE(w http.ResponseWriter, r *http.Request, rawRoomID, scope string, replay []events.Event, resumeSeq *uint64) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	t := newSSETransport(w)
+	if err := t.rc.Flush(); err != nil {
+		api.logger.Warn("streaming not supported", "error", err)
+		return
 	}
-	slog.Info("new client connected", "room_id", rawRoomID, "client_ip", r.RemoteAddr)
-	api.subscribers[rawRoomID][conn] = cancel
-	api.mu.Unlock()
-	<-ctx.Done()
 
-	api.mu.Lock()
-	slog.Info("new client disconnected", "room_id", rawRoomID, "client_ip", r.RemoteAddr)
-	delete(api.subscribers[rawRoomID], conn)
-	api.mu.Unlock()
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sub := api.newSubscriber(t, rawRoomID, api.clientIP(r), cancel)
+	sub.scope = scope
+	sub.resumeSeq = resumeSeq
+	api.serveSubscriber(ctx, sub, replay)
+}
+
+// writeCreated answers 201 with the JSON representation of a resource created
+// under the request's path, which Location points to.
+func writeCreated(w http.ResponseWriter, r *http.Request, id uuid.UUID, data []byte) {
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+id.String())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(data)
 }
 
-func (api apiHandler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+// storedTime rounds t to the microseconds Postgres keeps, so a timestamp
+// returned on creation matches the one later read back.
+func storedTime(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+func (api *Handler) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 	type _body struct {
-		Theme string `json:"theme"`
+		Theme       string `json:"theme"`
+		MaxMessages int32  `json:"max_messages"`
+		Prune       bool   `json:"prune"`
+		RequireName bool   `json:"require_name"`
+		// ExpiresInMinutes makes the room expire that long after creation.
+		ExpiresInMinutes *int `json:"expires_in_minutes"`
+		// ClosesInMinutes makes the room read-only that long after
+		// creation, as if its host closed it.
+		ClosesInMinutes *int `json:"closes_in_minutes"`
+		// Description, HostName and StartsAt tell listings what the AMA is
+		// about, who answers and when. They are informative only: the
+		// room is open as soon as it is created.
+		Description string     `json:"description"`
+		HostName    string     `json:"host_name"`
+		StartsAt    *time.Time `json:"starts_at"`
+		// DuplicateThreshold enables the near-duplicate check on new
+		// messages, as the trigram similarity (0-1) considered a duplicate.
+		DuplicateThreshold float32 `json:"duplicate_threshold"`
+		// MaxQuestionsPerParticipant is how many questions each participant
+		// may ask in the room, 0 for no limit.
+		MaxQuestionsPerParticipant int32 `json:"max_questions_per_participant"`
+		// RequireApproval holds new messages for a host to approve before
+		// anyone else sees them.
+		RequireApproval bool `json:"require_approval"`
+		// Webhooks are URLs notified of new and answered messages, of
+		// host replies and of the room closing.
+		Webhooks []string `json:"webhooks"`
 	}
 	var body _body
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid json", http.StatusUnprocessableEntity)
+		writeError(w, http.StatusUnprocessableEntity, "invalid_json", "invalid json")
+		return
+	}
+
+	var v validator
+	v.text("theme", body.Theme, maxThemeLength)
+	if body.Description != "" {
+		v.text("description", body.Description, maxDescriptionLength)
+	}
+	if body.HostName != "" {
+		v.text("host_name", body.HostName, maxHostNameLength)
+	}
+	if body.StartsAt != nil {
+		v.check(body.StartsAt.After(api.now()), "starts_at", "invalid_start", "starts_at must be in the future")
+		at := storedTime(*body.StartsAt)
+		body.StartsAt = &at
+	}
+	v.check(body.MaxMessages >= 0, "max_messages", "invalid_max_messages", "max_messages must not be negative")
+	v.check(body.DuplicateThreshold >= 0 && body.DuplicateThreshold <= 1, "duplicate_threshold", "invalid_duplicate_threshold", "duplicate_threshold must be between 0 and 1")
+	v.check(body.MaxQuestionsPerParticipant >= 0, "max_questions_per_participant", "invalid_max_questions_per_participant", "max_questions_per_participant must not be negative")
+	v.check(len(body.Webhooks) <= maxRoomWebhooks, "webhooks", "too_many_webhooks", "a room can have at most 5 webhooks")
+	for _, rawURL := range body.Webhooks {
+		if !validWebhookURL(rawURL) {
+			v.add("webhooks", "invalid_webhook_url", "webhooks must be absolute http or https URLs")
+			break
+		}
+	}
+	var lifetime time.Duration
+	if body.ExpiresInMinutes != nil {
+		lifetime = time.Duration(*body.ExpiresInMinutes) * time.Minute
+		v.check(lifetime >= minRoomLifetime && lifetime <= maxRoomLifetime, "expires_in_minutes", "invalid_expiry", "expires_in_minutes must be between 10 and 43200")
+	}
+	var openFor time.Duration
+	if body.ClosesInMinutes != nil {
+		openFor = time.Duration(*body.ClosesInMinutes) * time.Minute
+		v.check(openFor >= minRoomLifetime && openFor <= maxRoomLifetime, "closes_in_minutes", "invalid_closing", "closes_in_minutes must be between 10 and 43200")
+	}
+	if !v.valid(w) {
 		return
 	}
 
-	roomId, err := api.queries.InsertRoom(r.Context(), body.Theme)
+	webhooks := make([]pgstore.InsertWebhookParams, 0, len(body.Webhooks))
+	for _, rawURL := range body.Webhooks {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+			return
+		}
+		webhooks = append(webhooks, pgstore.InsertWebhookParams{
+			ID:        api.ids.NewID(),
+			Url:       rawURL,
+			Secret:    secret,
+			CreatedAt: api.now(),
+		})
+	}
+
+	var expiresAt *time.Time
+	if body.ExpiresInMinutes != nil {
+		at := api.now().Add(lifetime)
+		expiresAt = &at
+	}
+	var closesAt *time.Time
+	if body.ClosesInMinutes != nil {
+		at := api.now().Add(openFor)
+		closesAt = &at
+	}
+
+	createdAt := storedTime(api.now())
+	room := pgstore.InsertRoomParams{
+		ID:                         api.ids.NewID(),
+		Theme:                      body.Theme,
+		MaxMessages:                body.MaxMessages,
+		Prune:                      body.Prune,
+		RequireName:                body.RequireName,
+		ExpiresAt:                  expiresAt,
+		CreatedAt:                  createdAt,
+		DuplicateThreshold:         body.DuplicateThreshold,
+		MaxQuestionsPerParticipant: body.MaxQuestionsPerParticipant,
+		RequireApproval:            body.RequireApproval,
+		ClosesAt:                   closesAt,
+		Description:                body.Description,
+		HostName:                   body.HostName,
+		StartsAt:                   body.StartsAt,
+	}
+	// A conflict can only be the code being taken already: another one is
+	// tried.
+	var roomId uuid.UUID
+	var code string
+	var err error
+	for attempt := 1; ; attempt++ {
+		code, err = newRoomCode()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+			return
+		}
+		room.Code = code
+		roomId, err = api.queries.InsertRoomWithWebhooks(r.Context(), room, webhooks)
+		if !errors.Is(err, ErrConflict) || attempt == maxRoomCodeAttempts {
+			break
+		}
+	}
 	if err != nil {
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		api.writeStoreError(w, err, "room_not_found")
 		return
 	}
 
-	type response struct {
-		ID string `json:"id"`
+	// The room is answered as GET returns it, so clients need no follow-up
+	// request. The host token is only ever returned here: it is what lets
+	// the creator moderate the room.
+	resp := map[string]any{
+		"id":                            roomId.String(),
+		"code":                          code,
+		"theme":                         body.Theme,
+		"description":                   body.Description,
+		"host_name":                     body.HostName,
+		"starts_at":                     body.StartsAt,
+		"created_at":                    createdAt,
+		"message_count":                 0,
+		"answered_count":                0,
+		"subscriber_count":              0,
+		"max_messages":                  body.MaxMessages,
+		"require_name":                  body.RequireName,
+		"expires_at":                    expiresAt,
+		"duplicate_threshold":           body.DuplicateThreshold,
+		"max_questions_per_participant": body.MaxQuestionsPerParticipant,
+		"require_approval":              body.RequireApproval,
+		"closed_at":                     nil,
+		"closes_at":                     closesAt,
+		"reaction_kinds":                api.reactionKinds,
+		"seq":                           0,
+		"host_token":                    api.hostToken(roomId),
+	}
+	// Webhook secrets are only ever returned here, so receivers can verify
+	// the signature of deliveries.
+	if len(webhooks) > 0 {
+		created := make([]map[string]string, 0, len(webhooks))
+		for _, webhook := range webhooks {
+			created = append(created, map[string]string{
+				"id":     webhook.ID.String(),
+				"url":    webhook.Url,
+				"secret": webhook.Secret,
+			})
+		}
+		resp["webhooks"] = created
 	}
 
-	data, err := json.Marshal(map[string]any{
-		"id": roomId.String(),
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	writeCreated(w, r, roomId, data)
+}
+
+// handleGetRoomMessages lists the messages of a room oldest first, a page at
+// a time. The next page is asked for with the cursor returned as next_cursor
+// and in the Link header; it is absent on the last page. Hosts can include
+// deleted messages, and see whether askers consented to publishing.
+func (api *Handler) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	withDeleted, ok := includeDeleted(w, r, rawRoomID)
+	if !ok {
+		return
+	}
+
+	limit := defaultMessagesLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxMessagesLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	var after messageCursor
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		if after, err = parseMessageCursor(raw); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", "invalid cursor")
+			return
+		}
+	}
+
+	var language *string
+	if raw := r.URL.Query().Get("lang"); raw != "" {
+		tag := strings.ToLower(raw)
+		if !validLanguage(tag) {
+			writeError(w, http.StatusBadRequest, "invalid_lang", "lang must be a language code like en")
+			return
+		}
+		language = &tag
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	// One message more than asked for tells whether there is a next page.
+	page, err := api.queries.GetRoomMessagesPage(r.Context(), pgstore.GetRoomMessagesPageParams{
+		RoomID:         roomID,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		IncludeDeleted: withDeleted,
+		Language:       language,
+		MaxResults:     int32(limit + 1),
 	})
 	if err != nil {
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	var next string
+	if len(page) > limit {
+		page = page[:limit]
+		last := page[len(page)-1]
+		next = messageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	ids := make([]uuid.UUID, 0, len(page))
+	for _, m := range page {
+		ids = append(ids, m.ID)
+	}
+	reactions, err := api.emojiReactionCounts(r.Context(), ids)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	type message struct {
+		ID               string           `json:"id"`
+		Message          string           `json:"message"`
+		AuthorName       *string          `json:"author_name"`
+		ReactionCount    int64            `json:"reaction_count"`
+		Reactions        map[string]int64 `json:"reactions"`
+		Answered         bool             `json:"answered"`
+		Answer           *string          `json:"answer"`
+		Language         string           `json:"language"`
+		CreatedAt        time.Time        `json:"created_at"`
+		Version          int64            `json:"version"`
+		DeletedAt        *time.Time       `json:"deleted_at,omitempty"`
+		ConsentToPublish *bool            `json:"consent_to_publish,omitempty"`
+	}
+
+	canModerate := authFrom(r.Context()).canModerate(rawRoomID)
+	messages := make([]message, 0, len(page))
+	for _, m := range page {
+		var consent *bool
+		if canModerate {
+			consent = &m.ConsentToPublish
+		}
+		messages = append(messages, message{
+			ID:               m.ID.String(),
+			Message:          m.Message,
+			AuthorName:       m.AuthorName,
+			ReactionCount:    m.ReactionCount,
+			Reactions:        emojiReactionsOf(reactions, m.ID),
+			Answered:         m.Answered,
+			Answer:           m.Answer,
+			Language:         m.Language,
+			CreatedAt:        m.CreatedAt,
+			Version:          m.Version,
+			DeletedAt:        m.DeletedAt,
+			ConsentToPublish: consent,
+		})
+	}
+
+	resp := map[string]any{
+		"messages":     messages,
+		"limit":        limit,
+		"seq":          api.roomSequence(rawRoomID),
+		"can_moderate": canModerate,
+	}
+	if next != "" {
+		resp["next_cursor"] = next
+
+		nextURL := *r.URL
+		query := nextURL.Query()
+		query.Set("cursor", next)
+		nextURL.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextURL.RequestURI()))
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
-	w.Write(data)
 	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
 }
 
-func (api apiHandler) handleGetRooms(w http.ResponseWriter, r *http.Request) {
+// maxDuplicateCandidates is how many similar questions are returned when a
+// question looks like a duplicate.
+const maxDuplicateCandidates = 3
+
+// What to do with a question similar to unanswered ones, selected with the
+// on_duplicate parameter: refuse it with the candidates, or upvote the most
+// similar one instead.
+const (
+	onDuplicateReject = "reject"
+	onDuplicateUpvote = "upvote"
+)
+
+// handleCreateRoomMessage posts a question. In rooms created with a
+// duplicate_threshold, a question similar to unanswered ones is refused with
+// them unless force=true, or upvotes the most similar one with
+// on_duplicate=upvote. In rooms created with require_approval, the questions
+// of everyone but hosts wait for a host to approve them.
+func (api *Handler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	onDuplicate := r.URL.Query().Get("on_duplicate")
+	if onDuplicate != "" && onDuplicate != onDuplicateReject && onDuplicate != onDuplicateUpvote {
+		writeError(w, http.StatusBadRequest, "invalid_on_duplicate", "on_duplicate must be reject or upvote")
+		return
+	}
+
+	ctx := r.Context()
+	room, ok := api.openRoom(w, r, roomID)
+	if !ok {
+		return
+	}
+
+	body := struct {
+		Message          string `json:"message"`
+		ConsentToPublish bool   `json:"consent_to_publish"`
+		AuthorName       string `json:"author_name"`
+	}{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	authorName := sanitizeAuthorName(body.AuthorName)
+	var v validator
+	v.text("message", body.Message, maxMessageLength)
+	v.check(utf8.RuneCountInString(authorName) <= maxAuthorNameLength, "author_name", "name_too_long", "author_name must be at most 50 characters")
+	v.check(authorName != "" || !room.RequireName, "author_name", "name_required", "this room requires an author_name")
+	if !v.valid(w) {
+		return
+	}
+
+	text, flag, ok := api.filterContent(w, r, body.Message)
+	if !ok {
+		return
+	}
+	body.Message = text
+
+	if room.DuplicateThreshold > 0 && r.URL.Query().Get("force") != "true" {
+		similar, err := api.queries.FindSimilarUnansweredMessages(ctx, pgstore.FindSimilarUnansweredMessagesParams{
+			Message:    body.Message,
+			RoomID:     roomID,
+			Threshold:  room.DuplicateThreshold,
+			MaxResults: maxDuplicateCandidates,
+		})
+		if err != nil {
+			api.writeStoreError(w, err, "room_not_found")
+			return
+		}
+		if len(similar) > 0 {
+			if onDuplicate == onDuplicateUpvote {
+				api.upvoteDuplicate(w, r, roomID, similar[0])
+				return
+			}
+			writePossibleDuplicate(w, similar)
+			return
+		}
+	}
+
+	var authorNameParam *string
+	if authorName != "" {
+		authorNameParam = &authorName
+	}
+
+	language, confidence := langdetect.Undetermined, 0.0
+	if api.detectLanguage {
+		language, confidence = langdetect.Detect(body.Message)
+	}
+
+	createdAt := storedTime(api.now())
+	auth := authFrom(ctx)
+	// Hosts approve the questions of rooms requiring it, so theirs don't wait.
+	pending := room.RequireApproval && !auth.canModerate(rawRoomID)
+	messageID, prunedID, err := api.queries.InsertMessageWithinCapacity(r.Context(), pgstore.InsertMessageWithinCapacityParams{
+		InsertMessageParams: pgstore.InsertMessageParams{
+			ID:                 api.ids.NewID(),
+			RoomID:             roomID,
+			Message:            body.Message,
+			AuthorID:           auth.ClientID,
+			ConsentToPublish:   body.ConsentToPublish,
+			AuthorName:         authorNameParam,
+			Language:           language,
+			LanguageConfidence: float32(confidence),
+			CreatedAt:          createdAt,
+			Pending:            pending,
+		},
+		Participant: auth.participant(api.clientIP(r), rawRoomID),
+	})
+	if err != nil {
+		if errors.Is(err, pgstore.ErrRoomAtCapacity) {
+			writeError(w, http.StatusConflict, "room_at_capacity", "room reached its message limit")
+			return
+		}
+		if errors.Is(err, pgstore.ErrQuestionQuotaReached) {
+			writeQuestionQuotaReached(w, room.MaxQuestionsPerParticipant)
+			return
+		}
+		if errors.Is(err, pgstore.ErrRoomClosed) {
+			writeRoomClosed(w)
+			return
+		}
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+	if flag {
+		api.flagContent(ctx, messageID)
+	}
+	api.stopComposing(rawRoomID, auth.ClientID)
+
+	if prunedID != uuid.Nil {
+		api.notifyClients(r.Context(), events.Event{
+			Kind:   events.KindMessageDeleted,
+			RoomID: rawRoomID,
+			Value: events.MessageDeleted{
+				ID: prunedID.String(),
+			},
+		})
+	}
+
+	// A pending message is only announced to moderators, as
+	// message_pending; everyone else hears of it once it is approved.
+	created := events.Event{
+		Kind:   events.KindMessageCreated,
+		RoomID: rawRoomID,
+		Value: events.MessageCreated{
+			ID:         messageID.String(),
+			Message:    body.Message,
+			AuthorName: authorName,
+			Language:   language,
+			Version:    1,
+		},
+	}
+	if pending {
+		created.Kind, created.Scope = events.KindMessagePending, events.ScopeModerator
+	}
+	seq := api.notifyClients(r.Context(), created)
+
+	// The message is answered as GET returns it, with the seq of its
+	// message_created event.
+	resp := map[string]any{
+		"id":             messageID.String(),
+		"room_id":        rawRoomID,
+		"message":        body.Message,
+		"author_name":    authorNameParam,
+		"reaction_count": 0,
+		"answered":       false,
+		"answer":         nil,
+		"language":       language,
+		"created_at":     createdAt,
+		"version":        1,
+		"seq":            seq,
+	}
+	if pending {
+		resp["pending"] = true
+		delete(resp, "seq")
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	writeCreated(w, r, messageID, data)
 }
 
-func (api apiHandler) handleGetRoomMessages(w http.ResponseWriter, r *http.Request) {
-	panic("implement")
+// upvoteDuplicate adds the client's reaction to duplicate instead of posting
+// the question it duplicates, answering with the upvoted message.
+func (api *Handler) upvoteDuplicate(w http.ResponseWriter, r *http.Request, roomID uuid.UUID, duplicate pgstore.FindSimilarUnansweredMessagesRow) {
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	counts, err := api.queries.ApplyReactionBatch(r.Context(), pgstore.ApplyReactionBatchParams{
+		RoomID:   roomID,
+		ClientID: clientID,
+		Add:      []uuid.UUID{duplicate.ID},
+	})
+	if err != nil {
+		// The duplicate was pruned since it was found.
+		var unknownErr *pgstore.UnknownMessagesError
+		if errors.As(err, &unknownErr) {
+			writeError(w, http.StatusConflict, "conflict", "conflicting change, try again")
+			return
+		}
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+	api.coalesceReactionCounts(roomID.String(), events.ScopePublic, counts)
+
+	data, err := json.Marshal(map[string]any{
+		"upvoted": map[string]any{
+			"id":             duplicate.ID.String(),
+			"message":        duplicate.Message,
+			"reaction_count": counts[0].ReactionCount,
+			"version":        counts[0].Version,
+			"similarity":     duplicate.Similarity,
+		},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
 }
 
-func (api apiHandler) handleCreateRoomMessage(w http.ResponseWriter, r *http.Request) {
+func (api *Handler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request) {
 	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+
+	replies, err := api.messageReplies(r.Context(), message.ID)
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	reactions, err := api.emojiReactionCounts(r.Context(), []uuid.UUID{message.ID})
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	canModerate := authFrom(r.Context()).canModerate(rawRoomID)
+	resp := map[string]any{
+		"id":             message.ID.String(),
+		"room_id":        message.RoomID.String(),
+		"message":        message.Message,
+		"author_name":    message.AuthorName,
+		"reaction_count": message.ReactionCount,
+		"answered":       message.Answered,
+		"answer":         message.Answer,
+		"language":       message.Language,
+		"created_at":     message.CreatedAt,
+		"version":        message.Version,
+		"reactions":      emojiReactionsOf(reactions, message.ID),
+		"replies":        replies,
+		"can_moderate":   canModerate,
+	}
+	if canModerate {
+		resp["consent_to_publish"] = message.ConsentToPublish
+	}
 
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (api *Handler) handleUpdateRoomMessage(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
 	roomID, err := uuid.Parse(rawRoomID)
 	if err != nil {
-		http.Error(w, "invalid room id", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
 		return
 	}
 
-	ctx := context.Background()
-	_, err = api.queries.GetRoom(ctx, roomID)
+	messageID, err := uuid.Parse(chi.URLParam(r, "message_id"))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			http.Error(w, "invalid room id", http.StatusNotFound)
+		writeError(w, http.StatusBadRequest, "invalid_message_id", "invalid message id")
+		return
+	}
+
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	expectedVersion, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	body := struct {
+		Message string `json:"message"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+	var v validator
+	v.text("message", body.Message, maxMessageLength)
+	if !v.valid(w) {
+		return
+	}
+
+	text, flag, ok := api.filterContent(w, r, body.Message)
+	if !ok {
+		return
+	}
+	body.Message = text
+
+	if _, ok := api.openRoom(w, r, roomID); !ok {
+		return
+	}
+
+	message, err := api.queries.GetMessage(r.Context(), messageID)
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+	if message.RoomID != roomID || message.DeletedAt != nil {
+		writeError(w, http.StatusNotFound, "message_not_found", "message not found")
+		return
+	}
+
+	now := api.now()
+	if status, code, reason := editRejection(message, clientID, now); status != 0 {
+		writeError(w, status, code, reason)
+		return
+	}
+	if expectedVersion != nil && message.Version != *expectedVersion {
+		writeVersionConflict(w, message)
+		return
+	}
+
+	// The conditions are checked again in the UPDATE so that an answer or the
+	// window closing between the read above and the write can't be lost.
+	updated, err := api.queries.UpdateMessage(r.Context(), pgstore.UpdateMessageParams{
+		Message:         body.Message,
+		ID:              messageID,
+		AuthorID:        clientID,
+		EditWindowStart: now.Add(-messageEditWindow),
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			api.writeStoreError(w, err, "message_not_found")
+			return
+		}
+
+		current, err := api.queries.GetMessage(r.Context(), messageID)
+		if err != nil {
+			api.writeStoreError(w, err, "message_not_found")
+			return
+		}
+		if current.DeletedAt != nil {
+			writeError(w, http.StatusNotFound, "message_not_found", "message not found")
 			return
 		}
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		status, code, reason := editRejection(current, clientID, now)
+		if status == 0 && expectedVersion != nil && current.Version != *expectedVersion {
+			writeVersionConflict(w, current)
+			return
+		}
+		if status == 0 {
+			status, code, reason = http.StatusConflict, "message_changed", "message changed while editing"
+		}
+		writeError(w, status, code, reason)
+		return
+	}
+
+	api.recordModeration(r.Context(), updated, moderationEdit, &message.Message)
+	api.recordEdit(r.Context(), message, updated, now)
+	if flag {
+		api.flagContent(r.Context(), updated.ID)
+	}
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindMessageEdited,
+		RoomID: rawRoomID,
+		Scope:  messageScope(updated),
+		Value: events.MessageEdited{
+			ID:      updated.ID.String(),
+			Message: updated.Message,
+			Version: updated.Version,
+		},
+	})
+
+	data, err := json.Marshal(map[string]any{
+		"id":      updated.ID.String(),
+		"message": updated.Message,
+		"version": updated.Version,
+		"seq":     seq,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+const maxAuthorNameLength = 50
+
+// sanitizeAuthorName trims name and strips control characters from it.
+func sanitizeAuthorName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.TrimSpace(name)
+}
+
+// editRejection reports why clientID may not edit message at now, as the
+// status, error code and message of the response. The status is zero when the
+// edit is allowed.
+func editRejection(message pgstore.Message, clientID string, now time.Time) (int, string, string) {
+	// Authorship comes first so others don't learn the state of the message.
+	if message.AuthorID == "" || message.AuthorID != clientID {
+		return http.StatusForbidden, "not_author", "only the author can edit this message"
+	}
+	if message.Answered {
+		return http.StatusConflict, "already_answered", "message already answered"
+	}
+	if !now.Before(message.CreatedAt.Add(messageEditWindow)) {
+		return http.StatusForbidden, "edit_window_expired", "edit window has expired"
+	}
+	return 0, "", ""
+}
+
+// handleUpdateMessageConsent lets the author of a message change whether it may
+// be republished. Only the author's client id matches, so hosts can't flip it.
+// Deleted messages and, for everyone but the hosts, pending ones are not found
+// like everywhere else.
+func (api *Handler) handleUpdateMessageConsent(w http.ResponseWriter, r *http.Request) {
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
 		return
 	}
 
 	body := struct {
-		Message string `json:"message"`
+		ConsentToPublish *bool `json:"consent_to_publish"`
 	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ConsentToPublish == nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
 
+	consent, err := api.queries.UpdateMessageConsent(r.Context(), pgstore.UpdateMessageConsentParams{
+		ConsentToPublish: *body.ConsentToPublish,
+		ID:               message.ID,
+		AuthorID:         clientID,
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, http.StatusForbidden, "not_author", "only the author can change consent")
+			return
+		}
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"id":                 message.ID.String(),
+		"consent_to_publish": consent,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleUpdateMessageLanguage lets hosts correct the language a message was
+// tagged with, or tag it when detection is off. The tag of a host is certain,
+// so its confidence is 1.
+func (api *Handler) handleUpdateMessageLanguage(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Language string `json:"language"`
+	}{}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+	language := strings.ToLower(body.Language)
+	var v validator
+	v.check(validLanguage(language), "language", "invalid_language", "language must be a language code like en")
+	if !v.valid(w) {
+		return
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
 		return
 	}
 
-	messageID, err := api.queries.InsertMessage(r.Context(), pgstore.InsertMessageParams{
-		RoomID:  roomID,
-		Message: body.Message,
+	confidence := float32(1)
+	if language == langdetect.Undetermined {
+		confidence = 0
+	}
+	updated, err := api.queries.UpdateMessageLanguage(r.Context(), pgstore.UpdateMessageLanguageParams{
+		Language:           language,
+		LanguageConfidence: confidence,
+		ID:                 message.ID,
 	})
 	if err != nil {
-		slog.Error("failed to insert message", "error", err)
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		api.writeStoreError(w, err, "message_not_found")
 		return
 	}
 
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindMessageLanguage,
+		RoomID: updated.RoomID.String(),
+		Scope:  messageScope(updated),
+		Value: events.MessageLanguage{
+			ID:       updated.ID.String(),
+			Language: updated.Language,
+			Version:  updated.Version,
+		},
+	})
+
 	data, err := json.Marshal(map[string]any{
-		"id": messageID.String(),
+		"id":                  updated.ID.String(),
+		"language":            updated.Language,
+		"language_confidence": updated.LanguageConfidence,
+		"version":             updated.Version,
+		"seq":                 seq,
 	})
 	if err != nil {
-		http.Error(w, "something went wrong", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(data)
+}
 
-	go api.notifyClients(Message{
-		Kind:   MessageKindMessageCreated,
-		RoomID: rawRoomID,
-		Value: MessageMessageCreated{
-			ID:      messageID.String(),
-			Message: body.Message,
+// validLanguage reports whether tag is a lowercase ISO 639 language code, like
+// the "en" or "und" messages are tagged with.
+func validLanguage(tag string) bool {
+	if len(tag) < 2 || len(tag) > 3 {
+		return false
+	}
+	for i := range len(tag) {
+		if tag[i] < 'a' || tag[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+const maxAnswerLength = 5000
+
+func (api *Handler) handleMarkMessageAsAnswered(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	if message.Pending {
+		writeError(w, http.StatusConflict, "message_pending", "approve the message before answering it")
+		return
+	}
+	if expectedVersion != nil && message.Version != *expectedVersion {
+		writeVersionConflict(w, message)
+		return
+	}
+
+	// The body is optional: without one the message is only flagged as
+	// answered and any previously stored answer is kept.
+	body := struct {
+		Answer string `json:"answer"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	if utf8.RuneCountInString(body.Answer) > maxAnswerLength {
+		writeError(w, http.StatusBadRequest, "answer_too_long", "answer must be at most 5000 characters")
+		return
+	}
+
+	var answer *string
+	if body.Answer != "" {
+		answer = &body.Answer
+	}
+
+	answered, err := api.queries.MarkMessageAsAnswered(r.Context(), pgstore.MarkMessageAsAnsweredParams{
+		Answer:          answer,
+		ID:              message.ID,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		// With If-Match the update only misses when the version moved on.
+		if errors.Is(err, ErrNotFound) && expectedVersion != nil {
+			if current, err := api.queries.GetMessage(r.Context(), message.ID); err == nil {
+				writeVersionConflict(w, current)
+				return
+			}
+		}
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	api.recordModeration(r.Context(), answered, moderationAnswer, message.Answer)
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindMessageAnswered,
+		RoomID: answered.RoomID.String(),
+		Value: events.MessageAnswered{
+			ID:      answered.ID.String(),
+			Answer:  derefString(answered.Answer),
+			Version: answered.Version,
 		},
 	})
+
+	data, err := json.Marshal(map[string]any{
+		"id":       answered.ID.String(),
+		"answered": answered.Answered,
+		"answer":   answered.Answer,
+		"version":  answered.Version,
+		"seq":      seq,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
 }
 
-func (api apiHandler) handleGetRoomMessage(w http.ResponseWriter, r *http.Request) {
-	panic("implement")
+// roomMessage loads the message addressed by the room_id and message_id URL
+// params. When it doesn't exist in that room an error response is written and
+// false returned.
+func (api *Handler) roomMessage(w http.ResponseWriter, r *http.Request) (pgstore.Message, bool) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return pgstore.Message{}, false
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "message_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_message_id", "invalid message id")
+		return pgstore.Message{}, false
+	}
+
+	message, err := api.queries.GetMessage(r.Context(), messageID)
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return pgstore.Message{}, false
+	}
+	if message.RoomID != roomID || message.DeletedAt != nil {
+		writeError(w, http.StatusNotFound, "message_not_found", "message not found")
+		return pgstore.Message{}, false
+	}
+	// Messages waiting for approval only exist for the hosts of the room.
+	if message.Pending && !authFrom(r.Context()).canModerate(roomID.String()) {
+		writeError(w, http.StatusNotFound, "message_not_found", "message not found")
+		return pgstore.Message{}, false
+	}
+
+	return message, true
 }
 
-func (api apiHandler) handleReactToMessage(w http.ResponseWriter, r *http.Request) {
-	panic("implement")
+// ifMatchVersion parses the optional If-Match header of a mutating request as
+// the message version the client expects to change. Without it the change
+// applies to whatever version is stored.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (*int64, bool) {
+	raw := r.Header.Get("If-Match")
+	if raw == "" {
+		return nil, true
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(raw, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_if_match", "If-Match must be a message version")
+		return nil, false
+	}
+	return &version, true
 }
 
-func (api apiHandler) handleRemoveReactionFromMessage(w http.ResponseWriter, r *http.Request) {
-	panic("implement")
+// messageScope is the scope of the events about message: those of a message
+// waiting for approval are for moderators only.
+func messageScope(message pgstore.Message) string {
+	if message.Pending {
+		return events.ScopeModerator
+	}
+	return events.ScopePublic
 }
 
-func (api apiHandler) handleMarkMessageAsAnswered(w http.ResponseWriter, r *http.Request) {
-	panic("implement")
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
@@ -0,0 +1,315 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/internal/api/testutil"
+	"github.com/lohanguedes/AMA-Backend/internal/store/memstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// testStart is when the clock of every test server starts.
+var testStart = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// waitTimeout bounds every wait for an event or a subscription.
+const waitTimeout = 5 * time.Second
+
+// testServer serves a handler backed by the in-memory store, with a fake
+// clock and sequential ids.
+type testServer struct {
+	*httptest.Server
+	handler *api.Handler
+	clock   *testutil.FakeClock
+	store   *memstore.Store
+}
+
+// unlimited is a RateLimiter that never limits, since the fake clock never
+// refills the buckets. Tests of the rate limits set their own.
+type unlimited struct{}
+
+func (unlimited) Take(context.Context, string, api.RateLimit) (bool, time.Duration, error) {
+	return true, 0, nil
+}
+
+// newTestServer starts a test server configured by opts, which are applied
+// after the test defaults.
+func newTestServer(t *testing.T, opts ...api.Option) *testServer {
+	t.Helper()
+	s := &testServer{
+		clock: testutil.NewFakeClock(testStart),
+		store: memstore.New(),
+	}
+	opts = append([]api.Option{
+		api.WithClock(s.clock),
+		api.WithIDGenerator(&testutil.SequentialIDs{}),
+		api.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		api.WithHostTokenSecret("test secret"),
+		api.WithRateLimiter(unlimited{}),
+	}, opts...)
+	s.handler = api.NewHandler(s.store, opts...)
+	s.Server = httptest.NewServer(s.handler)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+		defer cancel()
+		if err := s.handler.Shutdown(ctx); err != nil {
+			t.Errorf("shutting down handler: %v", err)
+		}
+		s.Close()
+	})
+	return s
+}
+
+// response is a response with its body read.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// object decodes the body as a JSON object.
+func (r response) object(t *testing.T) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal(r.body, &v); err != nil {
+		t.Fatalf("decoding %s: %v", r.body, err)
+	}
+	return v
+}
+
+// list decodes the body as a JSON array of objects.
+func (r response) list(t *testing.T) []map[string]any {
+	t.Helper()
+	var v []map[string]any
+	if err := json.Unmarshal(r.body, &v); err != nil {
+		t.Fatalf("decoding %s: %v", r.body, err)
+	}
+	return v
+}
+
+// code returns the error code of a problem response.
+func (r response) code(t *testing.T) string {
+	t.Helper()
+	code, _ := r.object(t)["code"].(string)
+	return code
+}
+
+// do sends a request to path, relative to the API root, with body encoded as
+// JSON unless it is nil or a string. header holds header names and values in
+// turn.
+func (s *testServer) do(t *testing.T, method, path string, body any, header ...string) response {
+	t.Helper()
+	var reader io.Reader
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(body)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL+"/api/v1"+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", method, path, err)
+	}
+	return response{status: resp.StatusCode, header: resp.Header, body: data}
+}
+
+// expectStatus fails the test unless resp has status.
+func expectStatus(t *testing.T, resp response, status int) {
+	t.Helper()
+	if resp.status != status {
+		t.Fatalf("got status %d, want %d: %s", resp.status, status, resp.body)
+	}
+}
+
+type testRoom struct {
+	ID        string
+	HostToken string
+	Code      string
+}
+
+// createRoom creates a room with the fields of body, which may be nil.
+func (s *testServer) createRoom(t *testing.T, body map[string]any) testRoom {
+	t.Helper()
+	if body == nil {
+		body = map[string]any{}
+	}
+	if _, ok := body["theme"]; !ok {
+		body["theme"] = "test room"
+	}
+	resp := s.do(t, http.MethodPost, "/rooms", body)
+	expectStatus(t, resp, http.StatusCreated)
+	room := resp.object(t)
+	return testRoom{
+		ID:        room["id"].(string),
+		HostToken: room["host_token"].(string),
+		Code:      room["code"].(string),
+	}
+}
+
+// postMessage asks text in roomID and returns the id of the message.
+func (s *testServer) postMessage(t *testing.T, roomID, text string, header ...string) string {
+	t.Helper()
+	resp := s.do(t, http.MethodPost, "/rooms/"+roomID+"/messages", map[string]any{"message": text}, header...)
+	expectStatus(t, resp, http.StatusCreated)
+	return resp.object(t)["id"].(string)
+}
+
+// waitSubscribers waits until roomID has n subscribers.
+func (s *testServer) waitSubscribers(t *testing.T, roomID string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		resp := s.do(t, http.MethodGet, "/rooms/"+roomID, nil)
+		expectStatus(t, resp, http.StatusOK)
+		count := int(resp.object(t)["subscriber_count"].(float64))
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("room has %d subscribers, want %d", count, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// wsClient is a websocket subscription of a test.
+type wsClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// dial opens a websocket to path, relative to the server root, such as
+// "/subscribe/{room_id}". header holds header names and values in turn.
+func (s *testServer) dial(t *testing.T, path string, header ...string) (*wsClient, *http.Response, error) {
+	t.Helper()
+	h := http.Header{}
+	for i := 0; i+1 < len(header); i += 2 {
+		h.Set(header[i], header[i+1])
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+path, h)
+	if err != nil {
+		return nil, resp, err
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &wsClient{t: t, conn: conn}, resp, nil
+}
+
+// subscribe subscribes to roomID over a websocket, with the query string
+// query, and waits until the subscription is registered so it receives every
+// event broadcast from then on.
+func (s *testServer) subscribe(t *testing.T, roomID, query string, header ...string) *wsClient {
+	t.Helper()
+	before := s.do(t, http.MethodGet, "/rooms/"+roomID, nil).object(t)["subscriber_count"].(float64)
+	path := "/subscribe/" + roomID
+	if query != "" {
+		path += "?" + query
+	}
+	c, _, err := s.dial(t, path, header...)
+	if err != nil {
+		t.Fatalf("subscribing to %s: %v", roomID, err)
+	}
+	s.waitSubscribers(t, roomID, int(before)+1)
+	return c
+}
+
+// next returns the next event received, failing the test when none comes.
+func (c *wsClient) next() events.Event {
+	c.t.Helper()
+	if err := c.conn.SetReadDeadline(time.Now().Add(waitTimeout)); err != nil {
+		c.t.Fatal(err)
+	}
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		c.t.Fatalf("reading event: %v", err)
+	}
+	event, err := events.UnmarshalEvent(data)
+	if err != nil {
+		c.t.Fatalf("decoding event %s: %v", data, err)
+	}
+	return event
+}
+
+// until returns the events received up to the first one of kind, which is
+// the last one returned.
+func (c *wsClient) until(kind string) []events.Event {
+	c.t.Helper()
+	var received []events.Event
+	for {
+		event := c.next()
+		received = append(received, event)
+		if event.Kind == kind {
+			return received
+		}
+	}
+}
+
+// expect returns the next event of kind, skipping the others.
+func (c *wsClient) expect(kind string) events.Event {
+	c.t.Helper()
+	received := c.until(kind)
+	return received[len(received)-1]
+}
+
+// messages returns the first page of the messages of roomID.
+func (s *testServer) messages(t *testing.T, roomID string, header ...string) []map[string]any {
+	t.Helper()
+	resp := s.do(t, http.MethodGet, "/rooms/"+roomID+"/messages", nil, header...)
+	expectStatus(t, resp, http.StatusOK)
+	var page struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	if err := json.Unmarshal(resp.body, &page); err != nil {
+		t.Fatalf("decoding %s: %v", resp.body, err)
+	}
+	return page.Messages
+}
+
+// fieldErrors returns the code of every invalid field of a validation_failed
+// problem, by field.
+func (r response) fieldErrors(t *testing.T) map[string]string {
+	t.Helper()
+	var problem struct {
+		Errors []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(r.body, &problem); err != nil {
+		t.Fatalf("decoding %s: %v", r.body, err)
+	}
+	codes := make(map[string]string, len(problem.Errors))
+	for _, e := range problem.Errors {
+		codes[e.Field] = e.Code
+	}
+	return codes
+}
@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// pendingIDs returns the ids of the messages of room waiting for approval.
+func pendingIDs(t *testing.T, s *testServer, room testRoom) []string {
+	t.Helper()
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/pending", nil, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusOK)
+	var ids []string
+	for _, m := range resp.list(t) {
+		ids = append(ids, m["id"].(string))
+	}
+	return ids
+}
+
+func TestApproveMessage(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"require_approval": true})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	public := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "pending question"})
+	expectStatus(t, resp, http.StatusCreated)
+	created := resp.object(t)
+	id := created["id"].(string)
+	if created["pending"] != true {
+		t.Errorf("got %v, want the question pending", created)
+	}
+	if messages := s.messages(t, room.ID); len(messages) != 0 {
+		t.Errorf("got messages %v before approval, want none", messages)
+	}
+	if ids := pendingIDs(t, s, room); len(ids) != 1 || ids[0] != id {
+		t.Errorf("got pending messages %v, want %s", ids, id)
+	}
+
+	path := "/rooms/" + room.ID + "/messages/" + id
+	expectStatus(t, s.do(t, http.MethodPost, path+"/approve", nil), http.StatusUnauthorized)
+	resp = s.do(t, http.MethodPost, path+"/approve", nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t); got["message"] != "pending question" {
+		t.Errorf("got %v, want the approved message", got)
+	}
+
+	// Approving is the first the public hears of the question.
+	if e := public.next(); e.Kind != events.KindMessageCreated || e.Value.(events.MessageCreated).ID != id {
+		t.Errorf("public got %s %+v, want message_created of %s", e.Kind, e.Value, id)
+	}
+	if messages := s.messages(t, room.ID); len(messages) != 1 || messages[0]["id"] != id {
+		t.Errorf("got messages %v after approval, want %s", messages, id)
+	}
+	if ids := pendingIDs(t, s, room); len(ids) != 0 {
+		t.Errorf("got pending messages %v after approval, want none", ids)
+	}
+
+	resp = s.do(t, http.MethodPost, path+"/approve", nil, host...)
+	expectStatus(t, resp, http.StatusConflict)
+	if code := resp.code(t); code != "not_pending" {
+		t.Errorf("approving twice got code %q, want not_pending", code)
+	}
+}
+
+func TestRejectMessage(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"require_approval": true})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	public := s.subscribe(t, room.ID, "")
+	id := s.postMessage(t, room.ID, "rejected question")
+	path := "/rooms/" + room.ID + "/messages/" + id
+
+	expectStatus(t, s.do(t, http.MethodPost, path+"/reject", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do(t, http.MethodPost, path+"/reject", nil, host...), http.StatusNoContent)
+	if ids := pendingIDs(t, s, room); len(ids) != 0 {
+		t.Errorf("got pending messages %v after rejecting, want none", ids)
+	}
+	if messages := s.messages(t, room.ID); len(messages) != 0 {
+		t.Errorf("got messages %v after rejecting, want none", messages)
+	}
+
+	// The public never hears of a rejected question: the next event it gets is
+	// the one sent now.
+	visible := s.postMessage(t, room.ID, "host question", host...)
+	if e := public.next(); e.Kind != events.KindMessageCreated || e.Value.(events.MessageCreated).ID != visible {
+		t.Errorf("public got %s %+v, want message_created of %s", e.Kind, e.Value, visible)
+	}
+
+	// Restoring puts the question back in the queue.
+	expectStatus(t, s.do(t, http.MethodPost, path+"/restore", nil, host...), http.StatusOK)
+	if ids := pendingIDs(t, s, room); len(ids) != 1 || ids[0] != id {
+		t.Errorf("got pending messages %v after restoring, want %s", ids, id)
+	}
+}
+
+func TestApprovalNotPending(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "visible question")
+	for _, action := range []string{"approve", "reject"} {
+		resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages/"+id+"/"+action, nil, "Authorization", "Bearer "+room.HostToken)
+		expectStatus(t, resp, http.StatusConflict)
+		if code := resp.code(t); code != "not_pending" {
+			t.Errorf("%s got code %q, want not_pending", action, code)
+		}
+	}
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/pending", nil), http.StatusUnauthorized)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// authInfo is who a request comes from, resolved once by authenticate.
+type authInfo struct {
+	// ClientID is the anonymous client id sent as X-Client-Id.
+	ClientID string
+	// IsHost is set when the request carries a valid token, which lets it
+	// moderate RoomID.
+	IsHost bool
+	// RoomID is the room the token is a host token of. The admin token isn't
+	// bound to a room and leaves it empty, hosting every room.
+	RoomID string
+	// Moderator is set for the tokens of co-hosts, who moderate RoomID but
+	// can't act as its host otherwise, e.g. to invite more co-hosts.
+	Moderator bool
+}
+
+// canModerate reports whether the requester hosts roomID.
+func (a authInfo) canModerate(roomID string) bool {
+	return a.IsHost && (a.RoomID == "" || a.RoomID == roomID)
+}
+
+// ownsRoom reports whether the requester is the host of roomID rather than
+// one of its co-hosts.
+func (a authInfo) ownsRoom(roomID string) bool {
+	return a.canModerate(roomID) && !a.Moderator
+}
+
+// isAdmin reports whether the request carries the admin token.
+func (a authInfo) isAdmin() bool {
+	return a.IsHost && a.RoomID == ""
+}
+
+// actor identifies the requester in the moderation audit.
+func (a authInfo) actor() string {
+	switch {
+	case a.isAdmin():
+		return "admin"
+	case a.Moderator:
+		return "moderator"
+	case a.IsHost:
+		return "host"
+	case a.ClientID != "":
+		return "client:" + a.ClientID
+	default:
+		return "anonymous"
+	}
+}
+
+// participant identifies the requester for the question quota of roomID by a
+// hash of ip. Client ids are chosen by the clients, which could ask under a
+// new one every time, so they don't tell participants apart. Hosts aren't
+// held to the quota and get an empty participant.
+func (a authInfo) participant(ip, roomID string) string {
+	if a.canModerate(roomID) {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(ip))
+	return "ip:" + hex.EncodeToString(sum[:16])
+}
+
+type authContextKey struct{}
+
+func withAuth(ctx context.Context, auth authInfo) context.Context {
+	return context.WithValue(ctx, authContextKey{}, auth)
+}
+
+// authFrom returns the authInfo stored by authenticate, or that of an
+// anonymous client without id when there is none.
+func authFrom(ctx context.Context) authInfo {
+	auth, _ := ctx.Value(authContextKey{}).(authInfo)
+	return auth
+}
+
+// authenticate resolves the Authorization bearer token and the client id of a
+// request and stores them in its context for the handlers. Requests without
+// a token go through as anonymous clients; a malformed header or an unknown
+// token is rejected with 401.
+func (api *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := authInfo{ClientID: r.Header.Get("X-Client-Id")}
+
+		if header := r.Header.Get("Authorization"); header != "" {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || strings.TrimSpace(token) == "" {
+				writeError(w, http.StatusUnauthorized, "invalid_authorization", "authorization must be a bearer token")
+				return
+			}
+			host, ok := api.resolveToken(token)
+			if !ok {
+				writeError(w, http.StatusUnauthorized, "invalid_token", "unknown token")
+				return
+			}
+			auth.IsHost, auth.RoomID, auth.Moderator = host.IsHost, host.RoomID, host.Moderator
+		}
+
+		next.ServeHTTP(w, r.WithContext(withAuth(r.Context(), auth)))
+	})
+}
+
+// requireHost only lets requests through from hosts and co-hosts of the room
+// in the room_id URL param.
+func (api *Handler) requireHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authFrom(r.Context()).canModerate(chi.URLParam(r, "room_id")) {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "a host token is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// resolveToken returns who token makes its bearer: the admin, hosting every
+// room, or the host or a co-host of a single room. It reports false for
+// unknown tokens.
+func (api *Handler) resolveToken(token string) (authInfo, bool) {
+	if api.isAdminToken(token) {
+		return authInfo{IsHost: true}, true
+	}
+	if roomID, ok := api.parseRoomToken(token, hostTokenPrefix); ok {
+		return authInfo{IsHost: true, RoomID: roomID.String()}, true
+	}
+	if roomID, ok := api.parseRoomToken(token, moderatorTokenPrefix); ok {
+		return authInfo{IsHost: true, RoomID: roomID.String(), Moderator: true}, true
+	}
+	return authInfo{}, false
+}
+
+// requireOwner is requireHost without co-hosts.
+func (api *Handler) requireOwner(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authFrom(r.Context()).ownsRoom(chi.URLParam(r, "room_id")) {
+			writeError(w, http.StatusForbidden, "forbidden", "only the room host can do this")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (api *Handler) isAdminToken(token string) bool {
+	return api.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(api.adminToken)) == 1
+}
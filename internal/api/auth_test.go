@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAuthenticate(t *testing.T) {
+	api := newTestHandler(t, WithHostTokenSecret("secret"), WithAdminToken("admin token"))
+	roomID := uuid.New()
+	other := newTestHandler(t, WithHostTokenSecret("other secret"))
+	hostToken := api.hostToken(roomID)
+
+	tests := []struct {
+		name          string
+		authorization string
+		clientID      string
+		status        int
+		code          string
+		auth          authInfo
+	}{
+		{"Anonymous", "", "", http.StatusOK, "", authInfo{}},
+		{"AnonymousClient", "", "client", http.StatusOK, "", authInfo{ClientID: "client"}},
+		{"Host", "Bearer " + hostToken, "client", http.StatusOK, "", authInfo{ClientID: "client", IsHost: true, RoomID: roomID.String()}},
+		{"Moderator", "Bearer " + api.moderatorToken(roomID), "", http.StatusOK, "", authInfo{IsHost: true, RoomID: roomID.String(), Moderator: true}},
+		{"Admin", "Bearer admin token", "", http.StatusOK, "", authInfo{IsHost: true}},
+		{"NotBearer", "Basic " + hostToken, "", http.StatusUnauthorized, "invalid_authorization", authInfo{}},
+		{"LowercaseScheme", "bearer " + hostToken, "", http.StatusUnauthorized, "invalid_authorization", authInfo{}},
+		{"NoToken", "Bearer ", "", http.StatusUnauthorized, "invalid_authorization", authInfo{}},
+		{"BlankToken", "Bearer    ", "", http.StatusUnauthorized, "invalid_authorization", authInfo{}},
+		{"BareToken", hostToken, "", http.StatusUnauthorized, "invalid_authorization", authInfo{}},
+		{"UnknownToken", "Bearer nope", "", http.StatusUnauthorized, "invalid_token", authInfo{}},
+		{"TamperedToken", "Bearer " + hostToken[:len(hostToken)-2] + "xx", "", http.StatusUnauthorized, "invalid_token", authInfo{}},
+		{"OtherSecret", "Bearer " + other.hostToken(roomID), "", http.StatusUnauthorized, "invalid_token", authInfo{}},
+		{"HostTokenAsModerator", "Bearer " + strings.Replace(hostToken, hostTokenPrefix, moderatorTokenPrefix, 1), "", http.StatusUnauthorized, "invalid_token", authInfo{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *authInfo
+			handler := api.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := authFrom(r.Context())
+				got = &auth
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.clientID != "" {
+				req.Header.Set("X-Client-Id", tt.clientID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("got status %d, want %d", w.Code, tt.status)
+			}
+			if tt.code != "" {
+				if got != nil {
+					t.Error("rejected request reached the handler")
+				}
+				if code := problemCode(t, w.Body.Bytes()); code != tt.code {
+					t.Errorf("got code %q, want %q", code, tt.code)
+				}
+				return
+			}
+			if got == nil || *got != tt.auth {
+				t.Errorf("got auth %+v, want %+v", got, tt.auth)
+			}
+		})
+	}
+}
+
+func TestAuthInfo(t *testing.T) {
+	room, other := uuid.NewString(), uuid.NewString()
+	tests := []struct {
+		name        string
+		auth        authInfo
+		canModerate bool
+		ownsRoom    bool
+		actor       string
+	}{
+		{"Anonymous", authInfo{}, false, false, "anonymous"},
+		{"Client", authInfo{ClientID: "c"}, false, false, "client:c"},
+		{"Host", authInfo{IsHost: true, RoomID: room}, true, true, "host"},
+		{"HostOfOtherRoom", authInfo{IsHost: true, RoomID: other}, false, false, "host"},
+		{"Moderator", authInfo{IsHost: true, RoomID: room, Moderator: true}, true, false, "moderator"},
+		{"Admin", authInfo{IsHost: true}, true, true, "admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.auth.canModerate(room); got != tt.canModerate {
+				t.Errorf("canModerate = %v, want %v", got, tt.canModerate)
+			}
+			if got := tt.auth.ownsRoom(room); got != tt.ownsRoom {
+				t.Errorf("ownsRoom = %v, want %v", got, tt.ownsRoom)
+			}
+			if got := tt.auth.actor(); got != tt.actor {
+				t.Errorf("actor = %q, want %q", got, tt.actor)
+			}
+		})
+	}
+}
+
+func TestRequireHost(t *testing.T) {
+	api := newTestHandler(t, WithHostTokenSecret("secret"))
+	room, other := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"Anonymous", "", http.StatusUnauthorized},
+		{"Host", api.hostToken(room), http.StatusOK},
+		{"Moderator", api.moderatorToken(room), http.StatusOK},
+		{"HostOfOtherRoom", api.hostToken(other), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/rooms/"+room.String()+"/flags", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, req)
+			// The room doesn't exist, so hosts get through to an empty list.
+			if w.Code != tt.status {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
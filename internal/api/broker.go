@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	// brokerQueueSize is how many events may wait to be published before
+	// new ones are dropped.
+	brokerQueueSize = 1024
+	brokerTimeout   = 5 * time.Second
+	// brokerRetryInterval is how long the listener waits before listening
+	// again after the broker failed.
+	brokerRetryInterval = time.Second
+)
+
+// Broker relays room events between the instances of the server, so that
+// subscribers receive every event of their room whichever instance they are
+// connected to. Without one, events only reach the subscribers of the
+// instance they happened on.
+type Broker interface {
+	// Publish sends data to every instance. It may be delivered back to the
+	// publishing instance as well.
+	Publish(ctx context.Context, data []byte) error
+	// Listen calls handle with everything published, in order, until ctx is
+	// done or the broker fails.
+	Listen(ctx context.Context, handle func(data []byte)) error
+}
+
+// brokerMessage is how an event travels through the broker. Room and scope
+// aren't part of the event's wire format, so they are carried next to it.
+type brokerMessage struct {
+	// Origin is the instance the event happened on, which already
+	// delivered it.
+	Origin string          `json:"origin"`
+	RoomID string          `json:"room_id"`
+	Scope  string          `json:"scope,omitempty"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// publish queues msg for the other instances without blocking.
+func (api *Handler) publish(msg events.Event) {
+	if api.broker == nil {
+		return
+	}
+
+	event, err := json.Marshal(msg)
+	if err != nil {
+		api.logger.Error("failed to marshal message", "kind", msg.Kind, "error", err)
+		return
+	}
+	data, err := json.Marshal(brokerMessage{
+		Origin: api.instanceID,
+		RoomID: msg.RoomID,
+		Scope:  msg.Scope,
+		Event:  event,
+	})
+	if err != nil {
+		api.logger.Error("failed to marshal broker message", "kind", msg.Kind, "error", err)
+		return
+	}
+
+	select {
+	case api.brokerJobs <- data:
+	default:
+		api.logger.Warn("broker queue full, dropping event", "room_id", msg.RoomID, "kind", msg.Kind)
+	}
+}
+
+// runBrokerPublisher publishes queued events one at a time, keeping their
+// order, until ctx is done.
+func (api *Handler) runBrokerPublisher(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-api.brokerJobs:
+			publishCtx, cancel := context.WithTimeout(ctx, brokerTimeout)
+			if err := api.broker.Publish(publishCtx, data); err != nil {
+				api.logger.Warn("failed to publish event", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// runBrokerListener delivers the events published by other instances to
+// local subscribers until ctx is done, listening again whenever the broker
+// fails.
+func (api *Handler) runBrokerListener(ctx context.Context) {
+	for {
+		api.brokerDown.Store(false)
+		err := api.broker.Listen(ctx, api.handleBrokerMessage)
+		if ctx.Err() != nil {
+			return
+		}
+		api.brokerDown.Store(true)
+		api.logger.Warn("broker listener stopped, retrying", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(brokerRetryInterval):
+		}
+	}
+}
+
+func (api *Handler) handleBrokerMessage(data []byte) {
+	var bm brokerMessage
+	if err := json.Unmarshal(data, &bm); err != nil {
+		api.logger.Warn("invalid broker message", "error", err)
+		return
+	}
+	if bm.Origin == api.instanceID {
+		return
+	}
+
+	msg, err := events.UnmarshalEvent(bm.Event)
+	if err != nil {
+		api.logger.Warn("invalid broker message", "room_id", bm.RoomID, "error", err)
+		return
+	}
+	msg.RoomID, msg.Scope = bm.RoomID, bm.Scope
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if endsRoom(msg.Kind) {
+		api.closeRoomLocked(msg.RoomID, msg.Kind)
+		return
+	}
+	// Sequence numbers are per instance: the event is numbered here like
+	// the ones that happen locally.
+	api.flushCountsLocked(msg.RoomID)
+	api.fanOutLocked(msg)
+}
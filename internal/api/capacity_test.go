@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestRoomCapacity(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"max_messages": 2})
+	s.postMessage(t, room.ID, "first")
+	s.postMessage(t, room.ID, "second")
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "third"})
+	expectStatus(t, resp, http.StatusConflict)
+	if code := resp.code(t); code != "room_at_capacity" {
+		t.Errorf("got code %q, want room_at_capacity", code)
+	}
+	if n := len(s.messages(t, room.ID)); n != 2 {
+		t.Errorf("room has %d messages, want 2", n)
+	}
+}
+
+func TestRoomCapacityPrune(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"max_messages": 2, "prune": true})
+	popular := s.postMessage(t, room.ID, "popular")
+	unreacted := s.postMessage(t, room.ID, "unreacted")
+	expectStatus(t, s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+popular+"/react", nil, "X-Client-Id", "fan"), http.StatusOK)
+	c := s.subscribe(t, room.ID, "")
+
+	// The oldest message nobody reacted to makes space.
+	latest := s.postMessage(t, room.ID, "latest")
+	deleted := c.expect(events.KindMessageDeleted)
+	if got := deleted.Value.(events.MessageDeleted).ID; got != unreacted {
+		t.Errorf("got message_deleted for %s, want %s", got, unreacted)
+	}
+	if got := c.expect(events.KindMessageCreated).Value.(events.MessageCreated).ID; got != latest {
+		t.Errorf("got message_created for %s, want %s", got, latest)
+	}
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+unreacted, nil), http.StatusNotFound)
+
+	messages := s.messages(t, room.ID)
+	if len(messages) != 2 || messages[0]["id"] != popular || messages[1]["id"] != latest {
+		t.Errorf("got messages %v, want %s and %s", messages, popular, latest)
+	}
+}
+
+func TestRoomCapacityPruneNothingPrunable(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"max_messages": 1, "prune": true})
+	id := s.postMessage(t, room.ID, "answered")
+	expectStatus(t, s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/answer", nil, "Authorization", "Bearer "+room.HostToken), http.StatusOK)
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "more"})
+	expectStatus(t, resp, http.StatusConflict)
+	if code := resp.code(t); code != "room_at_capacity" {
+		t.Errorf("got code %q, want room_at_capacity", code)
+	}
+}
+
+func TestRoomCapacityValidation(t *testing.T) {
+	s := newTestServer(t)
+	resp := s.do(t, http.MethodPost, "/rooms", map[string]any{"theme": "room", "max_messages": -1})
+	expectStatus(t, resp, http.StatusUnprocessableEntity)
+}
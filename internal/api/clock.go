@@ -0,0 +1,35 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Clock tells the handler the current time. Everything time-dependent, like
+// created_at timestamps, edit windows and room expiry, goes through it so it
+// can be faked in tests. Network deadlines keep using the system clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator generates the ids of new rooms and messages.
+type IDGenerator interface {
+	NewID() uuid.UUID
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type randomIDs struct{}
+
+func (randomIDs) NewID() uuid.UUID {
+	return uuid.New()
+}
+
+func (api *Handler) now() time.Time {
+	return api.clock.Now()
+}
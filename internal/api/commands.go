@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// maxCommandFrameSize bounds the frames read from the websocket of a single
+// room, which carry the questions submitted through it.
+const maxCommandFrameSize = 4096
+
+// Commands a client can send over the websocket of a room instead of calling
+// the REST API.
+const (
+	commandSubmitQuestion = "submit_question"
+	commandReact          = "react"
+	commandRemoveReaction = "remove_reaction"
+	commandComposing      = "composing"
+	commandStopComposing  = "stop_composing"
+)
+
+// commandFrame is a command sent by a client over the websocket of a room. ID
+// is echoed in the result so the client can match them. Body, Force and
+// OnDuplicate are the body and parameters of submit_question, MessageID the
+// message the reactions apply to.
+type commandFrame struct {
+	ID          string          `json:"id"`
+	Command     string          `json:"command"`
+	MessageID   string          `json:"message_id"`
+	Body        json.RawMessage `json:"body"`
+	Force       bool            `json:"force"`
+	OnDuplicate string          `json:"on_duplicate"`
+}
+
+// commandClient is who sends the commands of a subscription: the client id
+// it was opened with, from the X-Client-Id header or, for browsers that
+// can't set it, the client_id parameter.
+func commandClient(r *http.Request) string {
+	if clientID := r.Header.Get("X-Client-Id"); clientID != "" {
+		return clientID
+	}
+	return r.URL.Query().Get("client_id")
+}
+
+// handleCommand runs a command of sub by serving the REST call it stands for,
+// so it goes through the same validation, rate limits and broadcasts, and
+// sends the response back to sub as a command_result event.
+func (api *Handler) handleCommand(ctx context.Context, sub *subscriber, clientID string, data []byte) {
+	var frame commandFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		api.sendCommandResult(sub, frame, http.StatusBadRequest, []byte("commands must be JSON objects"))
+		return
+	}
+
+	room := "/api/v1/rooms/" + sub.roomID
+	messages := room + "/messages"
+	var method, target string
+	var body []byte
+	switch frame.Command {
+	case commandSubmitQuestion:
+		method, target, body = http.MethodPost, messages, frame.Body
+		query := url.Values{}
+		if frame.Force {
+			query.Set("force", "true")
+		}
+		if frame.OnDuplicate != "" {
+			query.Set("on_duplicate", frame.OnDuplicate)
+		}
+		if len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+	case commandReact, commandRemoveReaction:
+		if _, err := uuid.Parse(frame.MessageID); err != nil {
+			api.sendCommandResult(sub, frame, http.StatusBadRequest, []byte("invalid message id"))
+			return
+		}
+		method, target = http.MethodPatch, messages+"/"+frame.MessageID+"/react"
+		if frame.Command == commandRemoveReaction {
+			method = http.MethodDelete
+		}
+	case commandComposing:
+		method, target = http.MethodPost, room+"/composing"
+	case commandStopComposing:
+		method, target = http.MethodDelete, room+"/composing"
+	default:
+		api.sendCommandResult(sub, frame, http.StatusBadRequest, []byte("command must be submit_question, react, remove_reaction, composing or stop_composing"))
+		return
+	}
+
+	// The routing state of the subscription request would make the router
+	// skip routing the command.
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, (*chi.Context)(nil))
+	r, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		api.sendCommandResult(sub, frame, http.StatusBadRequest, []byte("invalid command"))
+		return
+	}
+	r.RemoteAddr = sub.remoteAddr
+	r.Header.Set("Content-Type", "application/json")
+	if clientID != "" {
+		r.Header.Set("X-Client-Id", clientID)
+	}
+
+	rec := newCommandRecorder()
+	api.router.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	api.sendCommandResult(sub, frame, rec.status, rec.body.Bytes())
+}
+
+func (api *Handler) sendCommandResult(sub *subscriber, frame commandFrame, status int, body []byte) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.sendLocked(sub, events.Event{
+		Kind: events.KindCommandResult,
+		Value: events.CommandResult{
+			ID:      frame.ID,
+			Command: frame.Command,
+			Status:  status,
+			Body:    body,
+		},
+	})
+}
+
+// commandRecorder is the http.ResponseWriter commands are served with.
+type commandRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newCommandRecorder() *commandRecorder {
+	return &commandRecorder{header: make(http.Header)}
+}
+
+func (rec *commandRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *commandRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *commandRecorder) Write(b []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(b)
+}
@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialCompressed subscribes to roomID, negotiating permessage-deflate when
+// compress is set.
+func (s *testServer) dialCompressed(t *testing.T, roomID string, compress bool) (*websocket.Conn, *http.Response) {
+	t.Helper()
+	dialer := websocket.Dialer{EnableCompression: compress}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/subscribe/"+roomID, nil)
+	if err != nil {
+		t.Fatalf("subscribing to %s: %v", roomID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, resp
+}
+
+// readRaw returns the value of the next event of conn as it was sent.
+func readRaw(t *testing.T, conn *websocket.Conn) (string, map[string]json.RawMessage) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(waitTimeout))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading event: %v", err)
+	}
+	var event struct {
+		Kind  string                     `json:"kind"`
+		Value map[string]json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return event.Kind, event.Value
+}
+
+func TestCompressionNegotiation(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	compressed, resp := s.dialCompressed(t, room.ID, true)
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Errorf("got extensions %q, want permessage-deflate negotiated", ext)
+	}
+	plain, resp := s.dialCompressed(t, room.ID, false)
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Errorf("got extensions %q, want none", ext)
+	}
+	s.waitSubscribers(t, room.ID, 2)
+
+	// Both receive the same events, whatever the framing.
+	text := strings.Repeat("a repetitive question ", 10)
+	s.postMessage(t, room.ID, text)
+	for name, conn := range map[string]*websocket.Conn{"compressed": compressed, "plain": plain} {
+		kind, value := readRaw(t, conn)
+		var message string
+		json.Unmarshal(value["message"], &message)
+		if kind != "message_created" || message != text {
+			t.Errorf("%s client got %s %v, want the message", name, kind, value)
+		}
+	}
+}
+
+func TestSlimEvents(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, strings.Repeat("long question ", 15))
+	conn, _ := s.dialCompressed(t, room.ID, false)
+	s.waitSubscribers(t, room.ID, 1)
+	path := "/rooms/" + room.ID + "/messages/" + id
+
+	// Only the id and the changed fields ride along, never the text.
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "short"}, "Authorization", "Bearer "+room.HostToken), http.StatusOK)
+	if kind, value := readRaw(t, conn); kind != "message_answered" || len(value) != 3 || value["id"] == nil || value["answer"] == nil || value["version"] == nil {
+		t.Errorf("got %s with %v, want id, answer and version only", kind, keys(value))
+	}
+
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/react", nil, "X-Client-Id", "fan"), http.StatusOK)
+	if kind, value := readRaw(t, conn); kind != "reaction_counts_updated" || len(value) != 1 || value["counts"] == nil {
+		t.Errorf("got %s with %v, want the counts only", kind, keys(value))
+	}
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
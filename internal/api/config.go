@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Config gathers the settings of a Handler in one value, for callers that
+// load them from configuration rather than choosing options one by one. Zero
+// fields keep their default, see DefaultConfig.
+type Config struct {
+	// AdminToken enables the admin API and moderator subscriptions.
+	AdminToken string
+	// HostTokenSecret signs host tokens, see WithHostTokenSecret.
+	HostTokenSecret string
+	// AllowedOrigins restricts CORS and websocket handshakes, see
+	// WithAllowedOrigins. Empty accepts every origin.
+	AllowedOrigins []string
+	// DetectLanguage tags new messages with their language.
+	DetectLanguage bool
+	// SendQueueSize is how many events may wait for a slow subscriber.
+	SendQueueSize int
+	// WriteTimeout bounds every write to a subscriber.
+	WriteTimeout time.Duration
+	// DBTimeout bounds every database call.
+	DBTimeout time.Duration
+	// RequestTimeout bounds REST requests.
+	RequestTimeout time.Duration
+	// SweepInterval is how often expired rooms are deleted.
+	SweepInterval time.Duration
+	// FlagThreshold is how many flags notify moderators about a message.
+	FlagThreshold int
+	// ReactionFlushInterval is how often coalesced reaction counts are
+	// broadcast.
+	ReactionFlushInterval time.Duration
+	// ViewerCountInterval is how often subscribers are told how many they
+	// are.
+	ViewerCountInterval time.Duration
+	// RoomRetention is how long deleted rooms can be restored.
+	RoomRetention time.Duration
+	// Pprof serves the runtime profiles to admins, see WithPprof.
+	Pprof bool
+	// WSPingInterval is how often websocket clients are pinged.
+	WSPingInterval time.Duration
+	// WSReadBufferSize and WSWriteBufferSize size the buffers of every
+	// websocket connection.
+	WSReadBufferSize  int
+	WSWriteBufferSize int
+	// RateLimits override the limits of POST routes, see WithRateLimits.
+	RateLimits map[string]RateLimit
+	// TrustedProxies are the proxies whose X-Forwarded-For is trusted, see
+	// WithTrustedProxies.
+	TrustedProxies []netip.Prefix
+	// ReactionKinds are the emoji clients may react to messages with, see
+	// WithReactionKinds.
+	ReactionKinds []string
+}
+
+// DefaultConfig returns the settings NewHandler uses when given no options.
+func DefaultConfig() Config {
+	return Config{
+		SendQueueSize:         defaultSendQueueSize,
+		WriteTimeout:          defaultWriteTimeout,
+		DBTimeout:             defaultDBTimeout,
+		RequestTimeout:        defaultRequestTimeout,
+		SweepInterval:         defaultSweepInterval,
+		FlagThreshold:         defaultFlagThreshold,
+		ReactionFlushInterval: defaultReactionFlushInterval,
+		ViewerCountInterval:   defaultViewerCountInterval,
+		RoomRetention:         defaultRoomRetention,
+		WSPingInterval:        defaultWSPingInterval,
+		WSReadBufferSize:      defaultWSBufferSize,
+		WSWriteBufferSize:     defaultWSBufferSize,
+		ReactionKinds:         defaultReactionKinds,
+	}
+}
+
+// Options returns the options applying c.
+func (c Config) Options() []Option {
+	return []Option{
+		WithAdminToken(c.AdminToken),
+		WithHostTokenSecret(c.HostTokenSecret),
+		WithAllowedOrigins(c.AllowedOrigins...),
+		WithLanguageDetection(c.DetectLanguage),
+		WithSendQueueSize(c.SendQueueSize),
+		WithWriteTimeout(c.WriteTimeout),
+		WithDBTimeout(c.DBTimeout),
+		WithRequestTimeout(c.RequestTimeout),
+		WithSweepInterval(c.SweepInterval),
+		WithFlagThreshold(c.FlagThreshold),
+		WithReactionFlushInterval(c.ReactionFlushInterval),
+		WithViewerCountInterval(c.ViewerCountInterval),
+		WithRoomRetention(c.RoomRetention),
+		WithPprof(c.Pprof),
+		WithWSPingInterval(c.WSPingInterval),
+		WithWSBufferSizes(c.WSReadBufferSize, c.WSWriteBufferSize),
+		WithRateLimits(c.RateLimits),
+		WithTrustedProxies(c.TrustedProxies...),
+		WithReactionKinds(c.ReactionKinds...),
+	}
+}
+
+// NewHandlerWithConfig is NewHandler configured by cfg. Options are applied
+// after cfg, for what it doesn't cover such as the logger or clock.
+func NewHandlerWithConfig(q Store, cfg Config, opts ...Option) *Handler {
+	return NewHandler(q, append(cfg.Options(), opts...)...)
+}
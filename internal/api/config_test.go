@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/store/memstore"
+)
+
+// newConfiguredHandler returns a handler configured by cfg then opts, shut
+// down when the test ends.
+func newConfiguredHandler(t *testing.T, cfg Config, opts ...Option) *Handler {
+	t.Helper()
+	opts = append([]Option{WithLogger(discardLogger())}, opts...)
+	api := NewHandlerWithConfig(memstore.New(), cfg, opts...)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := api.Shutdown(ctx); err != nil {
+			t.Errorf("shutting down handler: %v", err)
+		}
+	})
+	return api
+}
+
+// settings reads back the Config fields api was configured with.
+func settings(api *Handler) Config {
+	return Config{
+		SendQueueSize:         api.sendQueueSize,
+		WriteTimeout:          api.writeTimeout,
+		DBTimeout:             api.dbTimeout,
+		RequestTimeout:        api.requestTimeout,
+		SweepInterval:         api.sweepInterval,
+		FlagThreshold:         api.flagThreshold,
+		ReactionFlushInterval: api.reactionFlushInterval,
+		ViewerCountInterval:   api.viewerCountInterval,
+		RoomRetention:         api.roomRetention,
+		WSPingInterval:        api.wsPingInterval,
+		WSReadBufferSize:      api.wsReadBufferSize,
+		WSWriteBufferSize:     api.wsWriteBufferSize,
+		ReactionKinds:         api.reactionKinds,
+	}
+}
+
+func equalSettings(a, b Config) bool {
+	kinds := slices.Equal(a.ReactionKinds, b.ReactionKinds)
+	a.ReactionKinds, b.ReactionKinds = nil, nil
+	return kinds && a.SendQueueSize == b.SendQueueSize &&
+		a.WriteTimeout == b.WriteTimeout &&
+		a.DBTimeout == b.DBTimeout &&
+		a.RequestTimeout == b.RequestTimeout &&
+		a.SweepInterval == b.SweepInterval &&
+		a.FlagThreshold == b.FlagThreshold &&
+		a.ReactionFlushInterval == b.ReactionFlushInterval &&
+		a.ViewerCountInterval == b.ViewerCountInterval &&
+		a.RoomRetention == b.RoomRetention &&
+		a.WSPingInterval == b.WSPingInterval &&
+		a.WSReadBufferSize == b.WSReadBufferSize &&
+		a.WSWriteBufferSize == b.WSWriteBufferSize
+}
+
+func TestConfigDefaults(t *testing.T) {
+	want := DefaultConfig()
+	if got := settings(newTestHandler(t)); !equalSettings(got, want) {
+		t.Errorf("NewHandler: got %+v, want the defaults %+v", got, want)
+	}
+	// Zero fields keep their default.
+	if got := settings(newConfiguredHandler(t, Config{})); !equalSettings(got, want) {
+		t.Errorf("NewHandlerWithConfig with a zero Config: got %+v, want the defaults %+v", got, want)
+	}
+}
+
+func TestConfigOverrides(t *testing.T) {
+	want := Config{
+		SendQueueSize:         3,
+		WriteTimeout:          time.Second,
+		DBTimeout:             2 * time.Second,
+		RequestTimeout:        3 * time.Second,
+		SweepInterval:         time.Hour,
+		FlagThreshold:         7,
+		ReactionFlushInterval: 250 * time.Millisecond,
+		ViewerCountInterval:   10 * time.Second,
+		RoomRetention:         48 * time.Hour,
+		WSPingInterval:        15 * time.Second,
+		WSReadBufferSize:      512,
+		WSWriteBufferSize:     2048,
+		ReactionKinds:         []string{"🚀", "🎉"},
+	}
+	if got := settings(newConfiguredHandler(t, want)); !equalSettings(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Only the zero fields keep their default.
+	partial := Config{SendQueueSize: 3, RoomRetention: 48 * time.Hour}
+	want = DefaultConfig()
+	want.SendQueueSize, want.RoomRetention = 3, 48*time.Hour
+	if got := settings(newConfiguredHandler(t, partial)); !equalSettings(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigOptionsOverride(t *testing.T) {
+	api := newConfiguredHandler(t, Config{SendQueueSize: 3}, WithSendQueueSize(5))
+	if api.sendQueueSize != 5 {
+		t.Errorf("got send queue size %d, want the option's 5 applied after the config", api.sendQueueSize)
+	}
+}
@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUpdateMessageConsent(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question", "X-Client-Id", "author")
+	path := "/rooms/" + room.ID + "/messages/" + id
+
+	for _, consent := range []bool{true, false} {
+		resp := s.do(t, http.MethodPatch, path+"/consent", map[string]any{"consent_to_publish": consent}, "X-Client-Id", "author")
+		expectStatus(t, resp, http.StatusOK)
+		if got := resp.object(t); got["id"] != id || got["consent_to_publish"] != consent {
+			t.Errorf("got %v, want consent_to_publish %v", got, consent)
+		}
+	}
+}
+
+func TestUpdateMessageConsentRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		pending bool
+		deleted bool
+		host    bool
+		header  []string
+		body    any
+		status  int
+		code    string
+	}{
+		{"NoClientID", false, false, false, nil, map[string]any{"consent_to_publish": true}, http.StatusForbidden, "missing_client_id"},
+		{"OtherClient", false, false, false, []string{"X-Client-Id", "someone else"}, map[string]any{"consent_to_publish": true}, http.StatusForbidden, "not_author"},
+		{"Host", false, false, true, []string{"X-Client-Id", "host"}, map[string]any{"consent_to_publish": true}, http.StatusForbidden, "not_author"},
+		{"MissingConsent", false, false, false, []string{"X-Client-Id", "author"}, map[string]any{}, http.StatusBadRequest, "invalid_json"},
+		{"InvalidJSON", false, false, false, []string{"X-Client-Id", "author"}, "{", http.StatusBadRequest, "invalid_json"},
+		{"Deleted", false, true, false, []string{"X-Client-Id", "author"}, map[string]any{"consent_to_publish": true}, http.StatusNotFound, "message_not_found"},
+		{"Pending", true, false, false, []string{"X-Client-Id", "author"}, map[string]any{"consent_to_publish": true}, http.StatusNotFound, "message_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, map[string]any{"require_approval": tt.pending})
+			id := s.postMessage(t, room.ID, "question", "X-Client-Id", "author")
+			path := "/rooms/" + room.ID + "/messages/" + id
+			host := []string{"Authorization", "Bearer " + room.HostToken}
+			if tt.deleted {
+				expectStatus(t, s.do(t, http.MethodDelete, path, nil, host...), http.StatusNoContent)
+			}
+			header := tt.header
+			if tt.host {
+				header = append(header, host...)
+			}
+
+			resp := s.do(t, http.MethodPatch, path+"/consent", tt.body, header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+			if tt.deleted {
+				return
+			}
+			resp = s.do(t, http.MethodGet, path, nil, host...)
+			expectStatus(t, resp, http.StatusOK)
+			if got := resp.object(t)["consent_to_publish"]; got != false {
+				t.Errorf("got consent_to_publish %v, want it unchanged", got)
+			}
+		})
+	}
+}
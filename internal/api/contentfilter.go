@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// ContentFilter finds the offending parts of the questions asked, such as
+// profanity.
+type ContentFilter interface {
+	// Match returns the byte ranges of text that are offending, in order and
+	// not overlapping, or none when text is acceptable.
+	Match(ctx context.Context, text string) ([][2]int, error)
+}
+
+// ContentFilterAction is what is done with the questions a ContentFilter
+// matches.
+type ContentFilterAction string
+
+const (
+	// ContentFilterReject refuses the question with 422.
+	ContentFilterReject ContentFilterAction = "reject"
+	// ContentFilterMask replaces the offending parts with asterisks.
+	ContentFilterMask ContentFilterAction = "mask"
+	// ContentFilterFlag keeps the question as asked and flags it as abuse
+	// for the hosts to review.
+	ContentFilterFlag ContentFilterAction = "flag"
+)
+
+// contentFilterClientID is who the flags of the content filter are from.
+const contentFilterClientID = "content_filter"
+
+// filterContent runs the content filter on the text of a question. It returns
+// the text to store, masked if need be, and whether to flag the question.
+// When it reports false the question was refused and the response written.
+func (api *Handler) filterContent(w http.ResponseWriter, r *http.Request, text string) (string, bool, bool) {
+	if api.contentFilter == nil {
+		return text, false, true
+	}
+
+	matches, err := api.contentFilter.Match(r.Context(), text)
+	if err != nil {
+		// Questions aren't let through unchecked.
+		api.logger.Warn("content filter failed", "error", err)
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "service unavailable")
+		return "", false, false
+	}
+	if len(matches) == 0 {
+		return text, false, true
+	}
+
+	switch api.contentFilterAction {
+	case ContentFilterMask:
+		return maskMatches(text, matches), false, true
+	case ContentFilterFlag:
+		return text, true, true
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "content_rejected", "the message contains words that aren't allowed")
+		return "", false, false
+	}
+}
+
+// maskMatches replaces every character of the matches in text with an
+// asterisk.
+func maskMatches(text string, matches [][2]int) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m[0]])
+		for range text[m[0]:m[1]] {
+			b.WriteByte('*')
+		}
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// flagContent flags messageID on behalf of the content filter. Failing to do
+// so only loses the flag, the question is already stored.
+func (api *Handler) flagContent(ctx context.Context, messageID uuid.UUID) {
+	reason := "abuse"
+	_, err := api.queries.InsertMessageFlag(ctx, pgstore.InsertMessageFlagParams{
+		MessageID: messageID,
+		ClientID:  contentFilterClientID,
+		Reason:    &reason,
+		CreatedAt: api.now(),
+	})
+	if err != nil {
+		api.logger.Warn("failed to flag message", "message_id", messageID, "error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/internal/contentfilter"
+)
+
+// failingFilter is a ContentFilter that can't check anything.
+type failingFilter struct{}
+
+func (failingFilter) Match(context.Context, string) ([][2]int, error) {
+	return nil, errors.New("filter unavailable")
+}
+
+// flaggedIDs returns the ids of the flagged messages of room.
+func flaggedIDs(t *testing.T, s *testServer, room testRoom) []string {
+	t.Helper()
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/flags", nil, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusOK)
+	var ids []string
+	for _, m := range resp.list(t) {
+		ids = append(ids, m["id"].(string))
+	}
+	return ids
+}
+
+func TestContentFilter(t *testing.T) {
+	words := contentfilter.New([]string{"darn"})
+
+	t.Run("Reject", func(t *testing.T) {
+		s := newTestServer(t, api.WithContentFilter(words, api.ContentFilterReject))
+		room := s.createRoom(t, nil)
+		resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "Why is the darn build red?"})
+		expectStatus(t, resp, http.StatusUnprocessableEntity)
+		if code := resp.code(t); code != "content_rejected" {
+			t.Errorf("got code %q, want content_rejected", code)
+		}
+		if messages := s.messages(t, room.ID); len(messages) != 0 {
+			t.Errorf("got messages %v, want none", messages)
+		}
+	})
+
+	t.Run("Mask", func(t *testing.T) {
+		s := newTestServer(t, api.WithContentFilter(words, api.ContentFilterMask))
+		room := s.createRoom(t, nil)
+		id := s.postMessage(t, room.ID, "Why is the DARN build red?", "X-Client-Id", "author")
+		if got := s.messages(t, room.ID)[0]["message"]; got != "Why is the **** build red?" {
+			t.Errorf("got %q, want the word masked", got)
+		}
+
+		resp := s.do(t, http.MethodPut, "/rooms/"+room.ID+"/messages/"+id, map[string]any{"message": "darn, it is green now"}, "X-Client-Id", "author")
+		expectStatus(t, resp, http.StatusOK)
+		if got := s.messages(t, room.ID)[0]["message"]; got != "****, it is green now" {
+			t.Errorf("got %q after editing, want the word masked", got)
+		}
+	})
+
+	t.Run("Flag", func(t *testing.T) {
+		s := newTestServer(t, api.WithContentFilter(words, api.ContentFilterFlag))
+		room := s.createRoom(t, nil)
+		flagged := s.postMessage(t, room.ID, "Why is the darn build red?")
+		s.postMessage(t, room.ID, "Why is the build red?")
+		if got := s.messages(t, room.ID)[0]["message"]; got != "Why is the darn build red?" {
+			t.Errorf("got %q, want the question as asked", got)
+		}
+		if ids := flaggedIDs(t, s, room); len(ids) != 1 || ids[0] != flagged {
+			t.Errorf("got flagged messages %v, want only %s", ids, flagged)
+		}
+	})
+
+	t.Run("Failing", func(t *testing.T) {
+		s := newTestServer(t, api.WithContentFilter(failingFilter{}, api.ContentFilterMask))
+		room := s.createRoom(t, nil)
+		resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "Why is the build red?"})
+		expectStatus(t, resp, http.StatusServiceUnavailable)
+		if messages := s.messages(t, room.ID); len(messages) != 0 {
+			t.Errorf("got messages %v, want none", messages)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestPendingReactionCountsScope(t *testing.T) {
+	api := newTestHandler(t, WithReactionFlushInterval(time.Hour))
+	public, moderator := newFakeTransport(false), newFakeTransport(false)
+	serve(t, api, "room", public)
+	sub, _ := serve(t, api, "room", moderator)
+	api.mu.Lock()
+	sub.scope = events.ScopeModerator
+	api.mu.Unlock()
+
+	visible, pending := uuid.New(), uuid.New()
+	api.coalesceReactionCounts("room", events.ScopePublic, []pgstore.GetReactionCountsRow{{ID: visible, ReactionCount: 1}})
+	api.coalesceReactionCounts("room", events.ScopeModerator, []pgstore.GetReactionCountsRow{{ID: pending, ReactionCount: 2}})
+	api.mu.Lock()
+	api.flushCountsLocked("room")
+	api.mu.Unlock()
+
+	counts := func(tr *fakeTransport) map[string]int64 {
+		t.Helper()
+		select {
+		case e := <-tr.written:
+			return e.Value.(events.ReactionCountsUpdated).Counts
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reaction_counts_updated written")
+			return nil
+		}
+	}
+	if got := counts(public); len(got) != 1 || got[visible.String()] != 1 {
+		t.Errorf("public got counts %v, want only %s", got, visible)
+	}
+	first, second := counts(moderator), counts(moderator)
+	if first[visible.String()] != 1 || second[pending.String()] != 2 || len(second) != 1 {
+		t.Errorf("moderator got counts %v then %v, want %s then %s", first, second, visible, pending)
+	}
+	select {
+	case e := <-public.written:
+		t.Errorf("public got %s %+v, want nothing about the pending message", e.Kind, e.Value)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 200
+)
+
+// messageCursor is the position of the last message of a page, by the
+// (created_at, id) order messages are listed in. The zero cursor is before
+// every message.
+type messageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// String encodes c as the opaque cursor handed to clients.
+func (c messageCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseMessageCursor(s string) (messageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return messageCursor{}, err
+	}
+	rawTime, rawID, found := strings.Cut(string(raw), "|")
+	if !found {
+		return messageCursor{}, errors.New("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, rawTime)
+	if err != nil {
+		return messageCursor{}, err
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return messageCursor{}, err
+	}
+	return messageCursor{CreatedAt: createdAt, ID: id}, nil
+}
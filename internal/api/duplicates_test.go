@@ -0,0 +1,112 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDuplicateQuestions(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		duplicate bool
+	}{
+		{"Identical", "How do you test websocket servers?", true},
+		{"NearIdentical", "how do you test websocket servers", true},
+		{"Typo", "How do you tset websocket servers?", true},
+		{"Different", "What is your favourite editor?", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, map[string]any{"duplicate_threshold": 0.5})
+			existing := s.postMessage(t, room.ID, "How do you test websocket servers?")
+
+			resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": tt.message})
+			if !tt.duplicate {
+				expectStatus(t, resp, http.StatusCreated)
+				return
+			}
+			expectStatus(t, resp, http.StatusConflict)
+			body := resp.object(t)
+			if body["code"] != "possible_duplicate" {
+				t.Errorf("got code %v, want possible_duplicate", body["code"])
+			}
+			match, _ := body["existing_message"].(map[string]any)
+			if match["id"] != existing || match["message"] != "How do you test websocket servers?" {
+				t.Errorf("got existing_message %v, want message %s", match, existing)
+			}
+			if len(s.messages(t, room.ID)) != 1 {
+				t.Error("duplicate was posted")
+			}
+		})
+	}
+}
+
+func TestDuplicateQuestionsForce(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"duplicate_threshold": 0.5})
+	s.postMessage(t, room.ID, "How do you test websocket servers?")
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages?force=true", map[string]any{"message": "How do you test websocket servers?"})
+	expectStatus(t, resp, http.StatusCreated)
+	if len(s.messages(t, room.ID)) != 2 {
+		t.Error("forced question was not posted")
+	}
+}
+
+func TestDuplicateQuestionsUpvote(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"duplicate_threshold": 0.5})
+	existing := s.postMessage(t, room.ID, "How do you test websocket servers?")
+	path := "/rooms/" + room.ID + "/messages?on_duplicate=upvote"
+
+	resp := s.do(t, http.MethodPost, path, map[string]any{"message": "how do you test websocket servers"}, "X-Client-Id", "asker")
+	expectStatus(t, resp, http.StatusOK)
+	upvoted, _ := resp.object(t)["upvoted"].(map[string]any)
+	if upvoted["id"] != existing || upvoted["reaction_count"] != float64(1) {
+		t.Errorf("got upvoted %v, want %s at 1 reaction", upvoted, existing)
+	}
+	if len(s.messages(t, room.ID)) != 1 {
+		t.Error("duplicate was posted")
+	}
+
+	resp = s.do(t, http.MethodPost, path, map[string]any{"message": "how do you test websocket servers"})
+	expectStatus(t, resp, http.StatusForbidden)
+	if code := resp.code(t); code != "missing_client_id" {
+		t.Errorf("got code %q, want missing_client_id", code)
+	}
+
+	resp = s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages?on_duplicate=merge", map[string]any{"message": "other"})
+	expectStatus(t, resp, http.StatusBadRequest)
+	if code := resp.code(t); code != "invalid_on_duplicate" {
+		t.Errorf("got code %q, want invalid_on_duplicate", code)
+	}
+}
+
+func TestDuplicateQuestionsIgnoreAnswered(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"duplicate_threshold": 0.5})
+	existing := s.postMessage(t, room.ID, "How do you test websocket servers?")
+	expectStatus(t, s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+existing+"/answer", nil, "Authorization", "Bearer "+room.HostToken), http.StatusOK)
+
+	s.postMessage(t, room.ID, "How do you test websocket servers?")
+}
+
+func TestDuplicateQuestionsDisabled(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	s.postMessage(t, room.ID, "How do you test websocket servers?")
+	s.postMessage(t, room.ID, "How do you test websocket servers?")
+}
+
+func TestDuplicateThresholdValidation(t *testing.T) {
+	for _, threshold := range []float64{-0.1, 1.5} {
+		s := newTestServer(t)
+		resp := s.do(t, http.MethodPost, "/rooms", map[string]any{"theme": "dups", "duplicate_threshold": threshold})
+		expectStatus(t, resp, http.StatusUnprocessableEntity)
+		if got := resp.fieldErrors(t)["duplicate_threshold"]; got != "invalid_duplicate_threshold" {
+			t.Errorf("threshold %v: got error %q, want invalid_duplicate_threshold", threshold, got)
+		}
+	}
+}
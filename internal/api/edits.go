@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// recordEdit keeps the text previous had before it was edited into updated,
+// for the message's edit history. The edit already happened, so a failure is
+// only logged.
+func (api *Handler) recordEdit(ctx context.Context, previous, updated pgstore.Message, editedAt time.Time) {
+	err := api.queries.InsertMessageEdit(ctx, pgstore.InsertMessageEditParams{
+		MessageID: updated.ID,
+		Version:   updated.Version - 1,
+		Message:   previous.Message,
+		EditedAt:  editedAt,
+	})
+	if err != nil {
+		api.logger.Warn("failed to record message edit",
+			"room_id", updated.RoomID,
+			"message_id", updated.ID,
+			"version", updated.Version,
+			"error", err,
+		)
+	}
+}
+
+// handleGetMessageEdits lists the previous versions of a message, oldest
+// first, each with the time it was edited away. The current text is the
+// message itself.
+func (api *Handler) handleGetMessageEdits(w http.ResponseWriter, r *http.Request) {
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+
+	edits, err := api.queries.GetMessageEdits(r.Context(), message.ID)
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	type edit struct {
+		Version  int64     `json:"version"`
+		Message  string    `json:"message"`
+		EditedAt time.Time `json:"edited_at"`
+	}
+
+	history := make([]edit, 0, len(edits))
+	for _, e := range edits {
+		history = append(history, edit{
+			Version:  e.Version,
+			Message:  e.Message,
+			EditedAt: e.EditedAt,
+		})
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"id":      message.ID.String(),
+		"message": message.Message,
+		"version": message.Version,
+		"edits":   history,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// defaultReactionKinds are the emoji clients may react to messages with,
+// unless WithReactionKinds changes them. Upvotes are not among them: they
+// stay the reactions of /react, which rank messages.
+var defaultReactionKinds = []string{"❤️", "😂", "🎉", "😮", "🤔"}
+
+// emojiReactionCounts returns the counts of every kind of emoji reaction to
+// ids, by message and kind. Messages without any are left out.
+func (api *Handler) emojiReactionCounts(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]map[string]int64, error) {
+	rows, err := api.queries.GetEmojiReactionCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]map[string]int64)
+	for _, row := range rows {
+		if counts[row.MessageID] == nil {
+			counts[row.MessageID] = make(map[string]int64)
+		}
+		counts[row.MessageID][row.Kind] = row.Count
+	}
+	return counts, nil
+}
+
+// emojiReactionsOf returns the emoji reaction counts of id in counts, empty
+// rather than nil so it is sent as an object.
+func emojiReactionsOf(counts map[uuid.UUID]map[string]int64, id uuid.UUID) map[string]int64 {
+	if c, ok := counts[id]; ok {
+		return c
+	}
+	return map[string]int64{}
+}
+
+// handleAddEmojiReaction adds the client's reaction of the kind in the URL to
+// a message. Reacting twice with the same kind counts once.
+func (api *Handler) handleAddEmojiReaction(w http.ResponseWriter, r *http.Request) {
+	api.setEmojiReaction(w, r, true)
+}
+
+// handleRemoveEmojiReaction takes the client's reaction of the kind in the
+// URL back.
+func (api *Handler) handleRemoveEmojiReaction(w http.ResponseWriter, r *http.Request) {
+	api.setEmojiReaction(w, r, false)
+}
+
+// setEmojiReaction adds or removes the client's emoji reaction and answers
+// with the new counts of the message. Only actual changes are broadcast,
+// coalesced with the reaction counts.
+func (api *Handler) setEmojiReaction(w http.ResponseWriter, r *http.Request, react bool) {
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	kind := chi.URLParam(r, "kind")
+	if unescaped, err := url.PathUnescape(kind); err == nil {
+		kind = unescaped
+	}
+	if !slices.Contains(api.reactionKinds, kind) {
+		writeProblem(w, http.StatusBadRequest, "invalid_reaction_kind", "kind must be one of the reaction kinds", map[string]any{
+			"reaction_kinds": api.reactionKinds,
+		})
+		return
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := api.openRoom(w, r, message.RoomID); !ok {
+		return
+	}
+
+	var changed int64
+	var err error
+	if react {
+		changed, err = api.queries.InsertEmojiReaction(r.Context(), pgstore.InsertEmojiReactionParams{
+			MessageID: message.ID,
+			ClientID:  clientID,
+			Kind:      kind,
+			CreatedAt: api.now(),
+		})
+	} else {
+		changed, err = api.queries.DeleteEmojiReaction(r.Context(), pgstore.DeleteEmojiReactionParams{
+			MessageID: message.ID,
+			ClientID:  clientID,
+			Kind:      kind,
+		})
+	}
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	counts, err := api.emojiReactionCounts(r.Context(), []uuid.UUID{message.ID})
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+	reactions := emojiReactionsOf(counts, message.ID)
+
+	if changed > 0 {
+		api.coalesceEmojiReactions(message, reactions)
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"id":        message.ID.String(),
+		"kind":      kind,
+		"count":     reactions[kind],
+		"reactions": reactions,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
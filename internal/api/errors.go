@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+const (
+	requestIDHeader = "X-Request-Id"
+	// problemTypePrefix prefixes the error codes to make the type URIs of
+	// problem details. They identify the problem and don't resolve to a
+	// document.
+	problemTypePrefix = "urn:wsrs:problem:"
+)
+
+// writeError responds with RFC 7807 problem details, which clients tell apart
+// by their type. code is the last part of the type. It is also sent as
+// "code", and message as "message" besides "detail", so the clients of the
+// former error bodies keep working. The request id is included when
+// exposeRequestID set it on the response.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeProblem(w, status, code, message, nil)
+}
+
+// writeProblem is writeError with extension members added to the problem
+// details.
+func writeProblem(w http.ResponseWriter, status int, code, message string, extensions map[string]any) {
+	body := map[string]any{
+		"type":    problemTypePrefix + code,
+		"title":   http.StatusText(status),
+		"status":  status,
+		"detail":  message,
+		"code":    code,
+		"message": message,
+	}
+	if requestID := w.Header().Get(requestIDHeader); requestID != "" {
+		body["request_id"] = requestID
+	}
+	for k, v := range extensions {
+		body[k] = v
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writePossibleDuplicate rejects a message that closely matches unanswered
+// ones, most similar first, so the client can offer to upvote one of them
+// instead. existing_message is the most similar one.
+func writePossibleDuplicate(w http.ResponseWriter, similar []pgstore.FindSimilarUnansweredMessagesRow) {
+	candidates := make([]map[string]any, 0, len(similar))
+	for _, m := range similar {
+		candidates = append(candidates, map[string]any{
+			"id":             m.ID.String(),
+			"message":        m.Message,
+			"reaction_count": m.ReactionCount,
+			"similarity":     m.Similarity,
+		})
+	}
+	writeProblem(w, http.StatusConflict, "possible_duplicate", "a similar question was already asked, upvote it with on_duplicate=upvote or use force=true to post anyway", map[string]any{
+		"existing_message": candidates[0],
+		"candidates":       candidates,
+	})
+}
+
+// writeQuestionQuotaReached rejects a message of a participant who already
+// asked the most questions the room allows.
+func writeQuestionQuotaReached(w http.ResponseWriter, quota int32) {
+	writeProblem(w, http.StatusTooManyRequests, "question_quota_reached", fmt.Sprintf("you can ask at most %d questions in this room", quota), map[string]any{
+		"max_questions_per_participant": quota,
+	})
+}
+
+// writeVersionConflict rejects a change made against an outdated version of
+// message, sending its current state so the client can reapply the change.
+func writeVersionConflict(w http.ResponseWriter, message pgstore.Message) {
+	writeProblem(w, http.StatusPreconditionFailed, "version_conflict", "the message was changed by someone else", map[string]any{
+		"current": map[string]any{
+			"id":             message.ID.String(),
+			"message":        message.Message,
+			"reaction_count": message.ReactionCount,
+			"answered":       message.Answered,
+			"answer":         message.Answer,
+			"author_name":    message.AuthorName,
+			"created_at":     message.CreatedAt,
+			"version":        message.Version,
+		},
+	})
+}
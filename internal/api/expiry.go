@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	minRoomLifetime      = 10 * time.Minute
+	maxRoomLifetime      = 30 * 24 * time.Hour
+	defaultSweepInterval = 5 * time.Minute
+	defaultRoomRetention = 7 * 24 * time.Hour
+)
+
+var (
+	errRoomExpired = errors.New("room expired")
+	errRoomDeleted = errors.New("room deleted")
+)
+
+func roomExpired(room pgstore.Room, now time.Time) bool {
+	return room.ExpiresAt != nil && !room.ExpiresAt.After(now)
+}
+
+// getRoom is GetRoom for the handlers: rooms past their expiry are reported as
+// not found even before the sweeper got to delete them, and so are deleted
+// rooms until they are restored.
+func (api *Handler) getRoom(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	room, err := api.queries.GetRoom(ctx, id)
+	if err != nil {
+		return room, err
+	}
+	if roomExpired(room, api.now()) {
+		return pgstore.Room{}, &storeError{class: ErrNotFound, err: errRoomExpired}
+	}
+	if room.DeletedAt != nil {
+		return pgstore.Room{}, &storeError{class: ErrNotFound, err: errRoomDeleted}
+	}
+	return room, nil
+}
+
+// runSweeper closes the rooms due to close, deletes expired rooms and purges
+// the deleted ones past their retention every sweepInterval until ctx is
+// done.
+func (api *Handler) runSweeper(ctx context.Context) {
+	ticker := time.NewTicker(api.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			api.closeDueRooms(ctx)
+			api.sweepExpiredRooms(ctx)
+			api.purgeDeletedRooms(ctx)
+		}
+	}
+}
+
+// closeDueRooms closes the rooms past the time they were scheduled to close
+// and tells their clients with room_closed. Closing them is atomic, so only
+// one instance broadcasts each.
+func (api *Handler) closeDueRooms(ctx context.Context) {
+	now := api.now()
+	ids, err := api.queries.CloseDueRooms(ctx, &now)
+	if err != nil {
+		api.logger.Warn("failed to close due rooms", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		api.notifyClients(ctx, events.Event{
+			Kind:   events.KindRoomClosed,
+			RoomID: id.String(),
+			Value:  events.RoomClosed{ID: id.String()},
+		})
+		api.logger.Info("closed room", "room_id", id)
+	}
+}
+
+// sweepExpiredRooms closes the subscriptions of every expired room and deletes
+// it along with its messages.
+func (api *Handler) sweepExpiredRooms(ctx context.Context) {
+	now := api.now()
+	ids, err := api.queries.GetExpiredRoomIDs(ctx, &now)
+	if err != nil {
+		api.logger.Warn("failed to list expired rooms", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		api.closeRoom(id.String(), events.KindRoomExpired)
+		if err := api.queries.DeleteRoomWithMessages(ctx, id); err != nil {
+			api.logger.Warn("failed to delete expired room", "room_id", id, "error", err)
+			continue
+		}
+		api.logger.Info("deleted expired room", "room_id", id)
+	}
+}
+
+// purgeDeletedRooms deletes for good the rooms deleted longer than
+// roomRetention ago, along with their messages.
+func (api *Handler) purgeDeletedRooms(ctx context.Context) {
+	deletedBefore := api.now().Add(-api.roomRetention)
+	ids, err := api.queries.GetDeletedRoomIDs(ctx, &deletedBefore)
+	if err != nil {
+		api.logger.Warn("failed to list deleted rooms", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		if err := api.queries.DeleteRoomWithMessages(ctx, id); err != nil {
+			api.logger.Warn("failed to purge deleted room", "room_id", id, "error", err)
+			continue
+		}
+		api.logger.Info("purged deleted room", "room_id", id)
+	}
+}
+
+// endsRoom reports whether events of kind end the subscriptions of their
+// room.
+func endsRoom(kind string) bool {
+	return kind == events.KindRoomExpired || kind == events.KindRoomDeleted
+}
+
+// roomEnded is the event of kind, room_expired or room_deleted, ending the
+// subscriptions of a room.
+func roomEnded(roomID, kind string) events.Event {
+	var value any = events.RoomExpired{ID: roomID}
+	if kind == events.KindRoomDeleted {
+		value = events.RoomDeleted{ID: roomID}
+	}
+	return events.Event{Kind: kind, RoomID: roomID, Value: value}
+}
+
+// closeRoom sends kind, room_expired or room_deleted, to every subscriber of
+// the room, after which their subscriptions end. Only the instance deleting
+// the room knows, so the others are told to close it through the broker.
+func (api *Handler) closeRoom(roomID, kind string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.closeRoomLocked(roomID, kind)
+	api.publish(roomEnded(roomID, kind))
+}
+
+// closeRoomLocked closes the room's local subscriptions. api.mu must be held.
+func (api *Handler) closeRoomLocked(roomID, kind string) {
+	// Counts of messages about to be hidden are of no use anymore.
+	delete(api.pendingCounts, roomID)
+	api.sequences[roomID]++
+	msg := roomEnded(roomID, kind)
+	msg.Seq = api.sequences[roomID]
+	// Long polling clients only need to learn the room is gone.
+	api.pollEvents[roomID] = nil
+	api.recordPollEventLocked(msg)
+
+	p, err := newPayload(msg)
+	if err != nil {
+		api.logger.Error("failed to marshal message", "kind", kind, "error", err)
+		return
+	}
+	for sub := range api.subscribers[roomID] {
+		if !sub.enqueue(p) {
+			sub.close(kind)
+		}
+		if sub.roomID == "" {
+			api.leaveLocked(sub, roomID)
+		}
+	}
+}
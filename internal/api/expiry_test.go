@@ -0,0 +1,169 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// sweepInterval is the sweep interval of the tests waiting on the sweeper.
+const sweepInterval = 10 * time.Millisecond
+
+func TestSequentialIDs(t *testing.T) {
+	s := newTestServer(t)
+	first := s.createRoom(t, nil)
+	second := s.createRoom(t, nil)
+	if first.ID != "00000000-0000-0000-0000-000000000001" || second.ID != "00000000-0000-0000-0000-000000000002" {
+		t.Errorf("got room ids %s and %s, want the first two sequential ids", first.ID, second.ID)
+	}
+}
+
+func TestRoomExpiry(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"expires_in_minutes": 10})
+
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID, nil)
+	expectStatus(t, resp, http.StatusOK)
+	want := testStart.Add(10 * time.Minute).Format(time.RFC3339Nano)
+	if got := resp.object(t)["expires_at"]; got != want {
+		t.Errorf("got expires_at %v, want %s", got, want)
+	}
+
+	s.clock.Advance(10*time.Minute - time.Nanosecond)
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.ID, nil), http.StatusOK)
+
+	// Expired rooms are gone even before the sweeper deletes them.
+	s.clock.Advance(time.Nanosecond)
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.ID, nil)
+	expectStatus(t, resp, http.StatusNotFound)
+	if code := resp.code(t); code != "room_not_found" {
+		t.Errorf("got code %q, want room_not_found", code)
+	}
+}
+
+func TestRoomExpiryValidation(t *testing.T) {
+	s := newTestServer(t)
+	for _, minutes := range []int{9, 43201} {
+		resp := s.do(t, http.MethodPost, "/rooms", map[string]any{"theme": "room", "expires_in_minutes": minutes})
+		expectStatus(t, resp, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestSweeperDeletesExpiredRooms(t *testing.T) {
+	s := newTestServer(t, api.WithSweepInterval(sweepInterval))
+	room := s.createRoom(t, map[string]any{"expires_in_minutes": 10})
+	kept := s.createRoom(t, map[string]any{"expires_in_minutes": 20})
+	c := s.subscribe(t, room.ID, "")
+
+	s.clock.Advance(15 * time.Minute)
+	expired := c.expect(events.KindRoomExpired)
+	if got := expired.Value.(events.RoomExpired).ID; got != room.ID {
+		t.Errorf("got room_expired for %s, want %s", got, room.ID)
+	}
+	if _, err := s.store.GetRoom(context.Background(), uuid.MustParse(room.ID)); err == nil {
+		t.Error("expired room was not deleted")
+	}
+	if _, err := s.store.GetRoom(context.Background(), uuid.MustParse(kept.ID)); err != nil {
+		t.Errorf("room not yet expired was deleted: %v", err)
+	}
+}
+
+func TestRoomClosesAt(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"closes_in_minutes": 10})
+	s.postMessage(t, room.ID, "before closing")
+
+	// The room is read-only once it is due, even before the sweeper closes
+	// it.
+	s.clock.Advance(10 * time.Minute)
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "after closing"})
+	expectStatus(t, resp, http.StatusConflict)
+	if code := resp.code(t); code != "room_closed" {
+		t.Errorf("got code %q, want room_closed", code)
+	}
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages", nil), http.StatusOK)
+}
+
+func TestSweeperClosesDueRooms(t *testing.T) {
+	s := newTestServer(t, api.WithSweepInterval(sweepInterval))
+	room := s.createRoom(t, map[string]any{"closes_in_minutes": 10})
+	c := s.subscribe(t, room.ID, "")
+
+	s.clock.Advance(10 * time.Minute)
+	closed := c.expect(events.KindRoomClosed)
+	if got := closed.Value.(events.RoomClosed).ID; got != room.ID {
+		t.Errorf("got room_closed for %s, want %s", got, room.ID)
+	}
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID, nil)
+	expectStatus(t, resp, http.StatusOK)
+	want := testStart.Add(10 * time.Minute).Format(time.RFC3339Nano)
+	if got := resp.object(t)["closed_at"]; got != want {
+		t.Errorf("got closed_at %v, want %s", got, want)
+	}
+}
+
+func TestExpiredRoomRoutes(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"expires_in_minutes": 10})
+	id := s.postMessage(t, room.ID, "question")
+	s.clock.Advance(10 * time.Minute)
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/rooms/" + room.ID + "/messages"},
+		{http.MethodGet, "/rooms/" + room.ID + "/messages/" + id},
+		{http.MethodPost, "/rooms/" + room.ID + "/messages"},
+		{http.MethodPatch, "/rooms/" + room.ID + "/messages/" + id + "/react"},
+	} {
+		var body any
+		if req.method == http.MethodPost {
+			body = map[string]any{"message": "late question"}
+		}
+		resp := s.do(t, req.method, req.path, body, "X-Client-Id", "client")
+		if resp.status != http.StatusNotFound {
+			t.Errorf("%s %s: got status %d, want 404", req.method, req.path, resp.status)
+		}
+	}
+	if _, resp, err := s.dial(t, "/subscribe/"+room.ID); err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("subscribing to an expired room: got %v, want 404", resp)
+	}
+}
+
+func TestListRoomsExpired(t *testing.T) {
+	s := newTestServer(t)
+	expiring := s.createRoom(t, map[string]any{"expires_in_minutes": 10})
+	lasting := s.createRoom(t, nil)
+	s.clock.Advance(10 * time.Minute)
+
+	resp := s.do(t, http.MethodGet, "/rooms", nil)
+	expectStatus(t, resp, http.StatusOK)
+	rooms := resp.list(t)
+	if len(rooms) != 1 || rooms[0]["id"] != lasting.ID {
+		t.Errorf("got rooms %v, want only %s", rooms, lasting.ID)
+	}
+
+	resp = s.do(t, http.MethodGet, "/rooms?include_expired=true&sort=oldest", nil)
+	expectStatus(t, resp, http.StatusOK)
+	rooms = resp.list(t)
+	if len(rooms) != 2 || rooms[0]["id"] != expiring.ID || rooms[0]["expired"] != true || rooms[1]["expired"] != nil {
+		t.Errorf("got rooms %v, want %s flagged expired then %s", rooms, expiring.ID, lasting.ID)
+	}
+}
+
+func TestSweeperEndsSubscriptions(t *testing.T) {
+	s := newTestServer(t, api.WithSweepInterval(sweepInterval))
+	room := s.createRoom(t, map[string]any{"expires_in_minutes": 10})
+	s.postMessage(t, room.ID, "question")
+	c := s.subscribe(t, room.ID, "")
+
+	s.clock.Advance(10 * time.Minute)
+	c.expect(events.KindRoomExpired)
+	c.conn.SetReadDeadline(time.Now().Add(waitTimeout))
+	if _, _, err := c.conn.ReadMessage(); err == nil {
+		t.Fatal("subscription still open after room_expired")
+	}
+}
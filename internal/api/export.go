@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// exportPageSize is how many messages are read and written at a time while
+// exporting, so large rooms are never held in memory at once.
+const exportPageSize = 500
+
+// excludedMessagesHeader tells how many messages consented_only left out of
+// an export.
+const excludedMessagesHeader = "X-Excluded-Messages"
+
+var exportColumns = []string{"id", "message", "author_name", "reaction_count", "answered", "answer", "created_at"}
+
+// handleExportRoomMessages streams every message of a room as CSV, oldest
+// first. Hosts can include deleted messages, which adds a deleted_at column,
+// and see whether askers consented to publishing in a consent_to_publish
+// column. With consented_only=true the messages without consent are left out
+// and counted in the X-Excluded-Messages header.
+func (api *Handler) handleExportRoomMessages(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	withDeleted, ok := includeDeleted(w, r, rawRoomID)
+	if !ok {
+		return
+	}
+
+	consentedOnly := r.URL.Query().Get("consented_only") == "true"
+	withConsent := authFrom(r.Context()).canModerate(rawRoomID)
+
+	room, err := api.getRoom(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	var excluded int64
+	if consentedOnly {
+		excluded, err = api.queries.CountRoomMessagesWithoutConsent(r.Context(), pgstore.CountRoomMessagesWithoutConsentParams{
+			RoomID:         roomID,
+			IncludeDeleted: withDeleted,
+		})
+		if err != nil {
+			api.writeStoreError(w, err, "room_not_found")
+			return
+		}
+	}
+
+	// The first page is read before answering so that a failing store still
+	// gets a proper error response.
+	page, err := api.queries.GetRoomMessagesPage(r.Context(), pgstore.GetRoomMessagesPageParams{
+		RoomID:         roomID,
+		IncludeDeleted: withDeleted,
+		MaxResults:     exportPageSize,
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, exportFilename(room)))
+	if consentedOnly {
+		w.Header().Set(excludedMessagesHeader, strconv.FormatInt(excluded, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	cw := csv.NewWriter(w)
+	columns := slices.Clone(exportColumns)
+	if withConsent {
+		columns = append(columns, "consent_to_publish")
+	}
+	if withDeleted {
+		columns = append(columns, "deleted_at")
+	}
+	cw.Write(columns)
+
+	for {
+		for _, m := range page {
+			if consentedOnly && !m.ConsentToPublish {
+				continue
+			}
+			record := []string{
+				m.ID.String(),
+				m.Message,
+				derefString(m.AuthorName),
+				strconv.FormatInt(m.ReactionCount, 10),
+				strconv.FormatBool(m.Answered),
+				derefString(m.Answer),
+				m.CreatedAt.UTC().Format(time.RFC3339),
+			}
+			if withConsent {
+				record = append(record, strconv.FormatBool(m.ConsentToPublish))
+			}
+			if withDeleted {
+				var deletedAt string
+				if m.DeletedAt != nil {
+					deletedAt = m.DeletedAt.UTC().Format(time.RFC3339)
+				}
+				record = append(record, deletedAt)
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			api.logger.Warn("failed to write export", "room_id", roomID, "error", err)
+			return
+		}
+		rc.Flush()
+
+		if len(page) < exportPageSize {
+			return
+		}
+
+		last := page[len(page)-1]
+		page, err = api.queries.GetRoomMessagesPage(r.Context(), pgstore.GetRoomMessagesPageParams{
+			RoomID:         roomID,
+			AfterCreatedAt: last.CreatedAt,
+			AfterID:        last.ID,
+			IncludeDeleted: withDeleted,
+			MaxResults:     exportPageSize,
+		})
+		if err != nil {
+			// Headers are gone already, all that's left is cutting the
+			// export short.
+			api.logger.Warn("failed to read export page", "room_id", roomID, "error", err)
+			return
+		}
+	}
+}
+
+// exportFilename derives a file name from the room theme, falling back to the
+// room id when the theme has nothing usable.
+func exportFilename(room pgstore.Room) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(room.Theme) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= 60 {
+			break
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "room-" + room.ID.String()
+	}
+	return slug
+}
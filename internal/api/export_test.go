@@ -0,0 +1,175 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// export downloads the CSV export of roomID with the query string query and
+// returns the response with its parsed records, header row first.
+func (s *testServer) export(t *testing.T, roomID, query string, header ...string) (response, [][]string) {
+	t.Helper()
+	resp := s.do(t, http.MethodGet, "/rooms/"+roomID+"/messages/export?"+query, nil, header...)
+	expectStatus(t, resp, http.StatusOK)
+	if ct := resp.header.Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Fatalf("got content type %q, want CSV", ct)
+	}
+	records, err := csv.NewReader(bytes.NewReader(resp.body)).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing %s: %v", resp.body, err)
+	}
+	return resp, records
+}
+
+func TestExportMatchesJSON(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"theme": "Go, Websockets & You!"})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	texts := []string{
+		"plain question",
+		"with, commas",
+		`with "quotes"`,
+		"with\nnew lines",
+		" leading and trailing spaces ",
+	}
+	for i, text := range texts {
+		resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": text, "author_name": fmt.Sprintf("Asker, %d", i)}, "X-Client-Id", "asker")
+		expectStatus(t, resp, http.StatusCreated)
+		id := resp.object(t)["id"].(string)
+		if i%2 == 0 {
+			expectStatus(t, s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/answer", map[string]any{"answer": "yes, \"really\"\nreally"}, host...), http.StatusOK)
+		}
+		expectStatus(t, s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/react", nil, "X-Client-Id", "fan"), http.StatusOK)
+		s.clock.Advance(time.Second)
+	}
+
+	resp, records := s.export(t, room.ID, "")
+	if got := resp.header.Get("Content-Disposition"); got != `attachment; filename="go-websockets-you.csv"` {
+		t.Errorf("got Content-Disposition %q", got)
+	}
+	if got := fmt.Sprint(records[0]); got != "[id message author_name reaction_count answered answer created_at]" {
+		t.Fatalf("got columns %s", got)
+	}
+
+	messages := s.messages(t, room.ID)
+	if len(records)-1 != len(messages) {
+		t.Fatalf("got %d rows, want %d", len(records)-1, len(messages))
+	}
+	byID := make(map[string]map[string]any, len(messages))
+	for _, m := range messages {
+		byID[m["id"].(string)] = m
+	}
+	for _, record := range records[1:] {
+		m, ok := byID[record[0]]
+		if !ok {
+			t.Errorf("exported unknown message %s", record[0])
+			continue
+		}
+		answer, _ := m["answer"].(string)
+		want := []string{
+			m["id"].(string),
+			m["message"].(string),
+			m["author_name"].(string),
+			strconv.FormatFloat(m["reaction_count"].(float64), 'f', -1, 64),
+			strconv.FormatBool(m["answered"].(bool)),
+			answer,
+		}
+		if got := record[:6]; fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+			t.Errorf("got row %q, want %q", got, want)
+		}
+		createdAt, err := time.Parse(time.RFC3339, m["created_at"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := record[6]; got != createdAt.UTC().Format(time.RFC3339) {
+			t.Errorf("got created_at %s, want %s", got, createdAt)
+		}
+	}
+}
+
+func TestExportPages(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	roomID := uuid.MustParse(room.ID)
+
+	// More than a page of messages, inserted directly to skip the capacity
+	// and rate limits.
+	const n = 1234
+	for i := range n {
+		if _, err := s.store.InsertMessage(context.Background(), pgstore.InsertMessageParams{
+			ID:        uuid.New(),
+			RoomID:    roomID,
+			Message:   fmt.Sprintf("question %d", i),
+			CreatedAt: testStart.Add(time.Duration(i) * time.Second),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, records := s.export(t, room.ID, "")
+	if len(records)-1 != n {
+		t.Fatalf("got %d rows, want %d", len(records)-1, n)
+	}
+	for i, record := range records[1:] {
+		if want := fmt.Sprintf("question %d", i); record[1] != want {
+			t.Fatalf("row %d is %q, want %q oldest first", i, record[1], want)
+		}
+	}
+}
+
+func TestExportHostColumns(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	consented := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "publish me", "consent_to_publish": true})
+	expectStatus(t, consented, http.StatusCreated)
+	s.postMessage(t, room.ID, "keep me private")
+	deleted := s.postMessage(t, room.ID, "deleted")
+	expectStatus(t, s.do(t, http.MethodDelete, "/rooms/"+room.ID+"/messages/"+deleted, nil, host...), http.StatusNoContent)
+
+	tests := []struct {
+		name     string
+		query    string
+		header   []string
+		columns  string
+		rows     int
+		excluded string
+	}{
+		{"Public", "", nil, "[id message author_name reaction_count answered answer created_at]", 2, ""},
+		{"Host", "", host, "[id message author_name reaction_count answered answer created_at consent_to_publish]", 2, ""},
+		{"HostWithDeleted", "include_deleted=true", host, "[id message author_name reaction_count answered answer created_at consent_to_publish deleted_at]", 3, ""},
+		{"ConsentedOnly", "consented_only=true", host, "[id message author_name reaction_count answered answer created_at consent_to_publish]", 1, "1"},
+		{"ConsentedOnlyWithDeleted", "consented_only=true&include_deleted=true", host, "[id message author_name reaction_count answered answer created_at consent_to_publish deleted_at]", 1, "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, records := s.export(t, room.ID, tt.query, tt.header...)
+			if got := fmt.Sprint(records[0]); got != tt.columns {
+				t.Errorf("got columns %s, want %s", got, tt.columns)
+			}
+			if len(records)-1 != tt.rows {
+				t.Errorf("got %d rows, want %d", len(records)-1, tt.rows)
+			}
+			if got := resp.header.Get("X-Excluded-Messages"); got != tt.excluded {
+				t.Errorf("got X-Excluded-Messages %q, want %q", got, tt.excluded)
+			}
+		})
+	}
+
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/export?include_deleted=true", nil), http.StatusForbidden)
+}
+
+func TestExportUnknownRoom(t *testing.T) {
+	s := newTestServer(t)
+	resp := s.do(t, http.MethodGet, "/rooms/"+uuid.NewString()+"/messages/export", nil)
+	expectStatus(t, resp, http.StatusNotFound)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const fanOutSubscribers = 1000
+
+// countingConn counts the bytes written to the connection.
+type countingConn struct {
+	net.Conn
+	written *atomic.Int64
+}
+
+func (c countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+type countingListener struct {
+	net.Listener
+	written *atomic.Int64
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{Conn: conn, written: l.written}, nil
+}
+
+// fanOut connects n websocket clients draining their connections and returns
+// the server side of the connections, with the counter of the bytes written
+// to them.
+func fanOut(b *testing.B, n int, compress bool) ([]*websocket.Conn, *atomic.Int64) {
+	b.Helper()
+	written := &atomic.Int64{}
+	conns := make(chan *websocket.Conn, n)
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}))
+	srv.Listener = countingListener{Listener: srv.Listener, written: written}
+	srv.Start()
+	b.Cleanup(srv.Close)
+
+	dialer := websocket.Dialer{EnableCompression: compress}
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	server := make([]*websocket.Conn, 0, n)
+	for range n {
+		client, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { client.Close() })
+		go func() {
+			for {
+				if _, _, err := client.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+		conn := <-conns
+		b.Cleanup(func() { conn.Close() })
+		server = append(server, conn)
+	}
+	return server, written
+}
+
+// BenchmarkFanOut broadcasts an answer to 1000 subscribers, marshalling the
+// event for every connection as broadcasts used to, or once as they do now,
+// and reports the bytes written per broadcast.
+func BenchmarkFanOut(b *testing.B) {
+	// The event as it was before it was slimmed down, with the text of the
+	// message.
+	full := struct {
+		Kind  string `json:"kind"`
+		Value any    `json:"value"`
+	}{events.KindMessageAnswered, map[string]any{
+		"id":             "0e7d1a54-8d7c-4c43-a9a4-3f1a7c0f7e2b",
+		"message":        strings.Repeat("what's the best way to test a websocket server? ", 5),
+		"reaction_count": 42,
+		"answered":       true,
+		"answer":         "with a fake transport",
+	}}
+	slim := events.Event{Kind: events.KindMessageAnswered, Value: events.MessageAnswered{
+		ID:      "0e7d1a54-8d7c-4c43-a9a4-3f1a7c0f7e2b",
+		Answer:  "with a fake transport",
+		Version: 3,
+	}}
+
+	for _, compress := range []bool{false, true} {
+		name := "Plain"
+		if compress {
+			name = "Compressed"
+		}
+		b.Run(name+"/PerConnection", func(b *testing.B) {
+			conns, written := fanOut(b, fanOutSubscribers, compress)
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := written.Load()
+			for range b.N {
+				for _, conn := range conns {
+					conn.SetWriteDeadline(time.Now().Add(time.Second))
+					if err := conn.WriteJSON(full); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(written.Load()-start)/float64(b.N), "wire-B/op")
+		})
+		b.Run(name+"/Prepared", func(b *testing.B) {
+			conns, written := fanOut(b, fanOutSubscribers, compress)
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := written.Load()
+			for range b.N {
+				p, err := newPayload(slim)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, conn := range conns {
+					if err := (wsTransport{conn: conn}).WriteEvent(p, time.Now().Add(time.Second)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(written.Load()-start)/float64(b.N), "wire-B/op")
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const defaultFlagThreshold = 3
+
+var flagReasons = map[string]bool{
+	"spam":      true,
+	"abuse":     true,
+	"off_topic": true,
+}
+
+// handleFlagMessage records a client's report of a message. Reporting the same
+// message twice is accepted but only counted once. Moderators are notified
+// when the message reaches the flag threshold.
+func (api *Handler) handleFlagMessage(w http.ResponseWriter, r *http.Request) {
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	body := struct {
+		Reason string `json:"reason"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	var reason *string
+	if body.Reason != "" {
+		if !flagReasons[body.Reason] {
+			writeError(w, http.StatusBadRequest, "invalid_reason", "reason must be spam, abuse or off_topic")
+			return
+		}
+		reason = &body.Reason
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+
+	inserted, err := api.queries.InsertMessageFlag(r.Context(), pgstore.InsertMessageFlagParams{
+		MessageID: message.ID,
+		ClientID:  clientID,
+		Reason:    reason,
+		CreatedAt: api.now(),
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	count, err := api.queries.CountMessageFlags(r.Context(), message.ID)
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	// Only the flag that makes the count reach the threshold notifies, so
+	// moderators hear about each message once.
+	if inserted > 0 && count == int64(api.flagThreshold) {
+		api.notifyClients(r.Context(), events.Event{
+			Kind:   events.KindMessageFlagThreshold,
+			RoomID: message.RoomID.String(),
+			Scope:  events.ScopeModerator,
+			Value: events.MessageFlagThreshold{
+				ID:      message.ID.String(),
+				Message: message.Message,
+				Flags:   count,
+			},
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetRoomFlags lists the flagged messages of a room, most flagged first.
+func (api *Handler) handleGetRoomFlags(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	flagged, err := api.queries.GetRoomFlaggedMessages(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	type flaggedMessage struct {
+		ID        string           `json:"id"`
+		Message   string           `json:"message"`
+		Answered  bool             `json:"answered"`
+		FlagCount int64            `json:"flag_count"`
+		Reasons   map[string]int64 `json:"reasons"`
+	}
+
+	resp := make([]flaggedMessage, 0, len(flagged))
+	for _, f := range flagged {
+		resp = append(resp, flaggedMessage{
+			ID:        f.ID.String(),
+			Message:   f.Message,
+			Answered:  f.Answered,
+			FlagCount: f.FlagCount,
+			Reasons: map[string]int64{
+				"spam":        f.SpamCount,
+				"abuse":       f.AbuseCount,
+				"off_topic":   f.OffTopicCount,
+				"unspecified": f.UnspecifiedCount,
+			},
+		})
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,109 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestFlagThreshold(t *testing.T) {
+	s := newTestServer(t, api.WithFlagThreshold(2))
+	room := s.createRoom(t, nil)
+	public := s.subscribe(t, room.ID, "")
+	moderator := s.subscribe(t, room.ID, "scope=moderator&token="+room.HostToken)
+	id := s.postMessage(t, room.ID, "flag me")
+	public.expect(events.KindMessageCreated)
+	moderator.expect(events.KindMessageCreated)
+	path := "/rooms/" + room.ID + "/messages/" + id + "/flag"
+
+	expectStatus(t, s.do(t, http.MethodPost, path, map[string]any{"reason": "spam"}, "X-Client-Id", "first"), http.StatusNoContent)
+	// Flagging twice counts once, so this doesn't reach the threshold.
+	expectStatus(t, s.do(t, http.MethodPost, path, map[string]any{"reason": "spam"}, "X-Client-Id", "first"), http.StatusNoContent)
+	expectStatus(t, s.do(t, http.MethodPost, path, nil, "X-Client-Id", "second"), http.StatusNoContent)
+	// Flags past the threshold don't notify again.
+	expectStatus(t, s.do(t, http.MethodPost, path, map[string]any{"reason": "abuse"}, "X-Client-Id", "third"), http.StatusNoContent)
+
+	e := moderator.next()
+	if e.Kind != events.KindMessageFlagThreshold {
+		t.Fatalf("moderator got %s, want %s", e.Kind, events.KindMessageFlagThreshold)
+	}
+	if got := e.Value.(events.MessageFlagThreshold); got.ID != id || got.Message != "flag me" || got.Flags != 2 {
+		t.Errorf("got %+v, want message %s at 2 flags", got, id)
+	}
+
+	// The next events of both connections are the public one sent now.
+	next := s.postMessage(t, room.ID, "next")
+	for name, c := range map[string]*wsClient{"public": public, "moderator": moderator} {
+		if e := c.next(); e.Kind != events.KindMessageCreated || e.Value.(events.MessageCreated).ID != next {
+			t.Errorf("%s got %s %+v, want message_created of %s", name, e.Kind, e.Value, next)
+		}
+	}
+}
+
+func TestRoomFlags(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	once := s.postMessage(t, room.ID, "flagged once")
+	s.postMessage(t, room.ID, "not flagged")
+	thrice := s.postMessage(t, room.ID, "flagged thrice")
+	flag := func(id, client, reason string) {
+		t.Helper()
+		var body any
+		if reason != "" {
+			body = map[string]any{"reason": reason}
+		}
+		expectStatus(t, s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages/"+id+"/flag", body, "X-Client-Id", client), http.StatusNoContent)
+	}
+	flag(once, "a", "off_topic")
+	flag(thrice, "a", "spam")
+	flag(thrice, "b", "spam")
+	flag(thrice, "c", "")
+
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/flags", nil, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusOK)
+	flagged := resp.list(t)
+	if len(flagged) != 2 {
+		t.Fatalf("got %d flagged messages, want 2", len(flagged))
+	}
+	if flagged[0]["id"] != thrice || flagged[0]["flag_count"] != float64(3) || flagged[1]["id"] != once {
+		t.Errorf("got %v, want %s then %s", flagged, thrice, once)
+	}
+	want := map[string]any{"spam": float64(2), "abuse": float64(0), "off_topic": float64(0), "unspecified": float64(1)}
+	if got := flagged[0]["reasons"]; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got reasons %v, want %v", got, want)
+	}
+
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.ID+"/flags", nil), http.StatusUnauthorized)
+}
+
+func TestFlagRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		header []string
+		status int
+		code   string
+	}{
+		{"NoClientID", "/rooms/" + room.ID + "/messages/" + id + "/flag", nil, nil, http.StatusForbidden, "missing_client_id"},
+		{"InvalidReason", "/rooms/" + room.ID + "/messages/" + id + "/flag", map[string]any{"reason": "boring"}, []string{"X-Client-Id", "c"}, http.StatusBadRequest, "invalid_reason"},
+		{"OtherRoom", "/rooms/" + other.ID + "/messages/" + id + "/flag", nil, []string{"X-Client-Id", "c"}, http.StatusNotFound, "message_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodPost, tt.path, tt.body, tt.header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// pinger is implemented by the stores and brokers that can tell whether the
+// database behind them is reachable. The others are always considered ready.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// handleHealthz is the liveness probe: it answers as long as the process
+// serves requests.
+func (api *Handler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// handleReadyz is the readiness probe: it answers 503 while the store or the
+// broker can't be reached, or once the server is shutting down, so traffic
+// is only routed to instances able to serve it. The response lists the
+// result of every check.
+func (api *Handler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"store": api.checkStore(r.Context())}
+	if api.broker != nil {
+		checks["broker"] = api.checkBroker(r.Context())
+	}
+	if api.isShuttingDown() {
+		checks["server"] = closeReasonShutdown
+	}
+
+	status, ready := http.StatusOK, "ready"
+	for _, result := range checks {
+		if result != "ok" {
+			status, ready = http.StatusServiceUnavailable, "not_ready"
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"status": ready, "checks": checks})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func (api *Handler) checkStore(ctx context.Context) string {
+	p, ok := api.queries.(*dbStore).next.(pinger)
+	if !ok {
+		return "ok"
+	}
+	return ping(ctx, p, api.dbTimeout)
+}
+
+// checkBroker fails while the listener is down, as the events of the other
+// instances don't reach this one's subscribers until it listens again.
+func (api *Handler) checkBroker(ctx context.Context) string {
+	if api.brokerDown.Load() {
+		return "listener down"
+	}
+	p, ok := api.broker.(pinger)
+	if !ok {
+		return "ok"
+	}
+	return ping(ctx, p, brokerTimeout)
+}
+
+func ping(ctx context.Context, p pinger, timeout time.Duration) string {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := p.Ping(ctx); err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,78 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+)
+
+// downBroker is a broker that can't be reached.
+type downBroker struct{}
+
+func (downBroker) Publish(context.Context, []byte) error { return errors.New("broker down") }
+
+func (downBroker) Listen(ctx context.Context, _ func([]byte)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (downBroker) Ping(context.Context) error { return errors.New("broker down") }
+
+// probe gets path, which is outside the API root.
+func (s *testServer) probe(t *testing.T, path string) response {
+	t.Helper()
+	resp, err := s.Client().Get(s.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET %s: reading body: %v", path, err)
+	}
+	return response{status: resp.StatusCode, header: resp.Header, body: data}
+}
+
+func TestHealthz(t *testing.T) {
+	s := newTestServer(t, api.WithBroker(downBroker{}))
+	resp := s.probe(t, "/healthz")
+	expectStatus(t, resp, http.StatusOK)
+	if status := resp.object(t)["status"]; status != "ok" {
+		t.Errorf("got status %v, want ok", status)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	s := newTestServer(t)
+	resp := s.probe(t, "/readyz")
+	expectStatus(t, resp, http.StatusOK)
+	got := resp.object(t)
+	if checks, _ := got["checks"].(map[string]any); got["status"] != "ready" || checks["store"] != "ok" {
+		t.Errorf("got %s, want ready", resp.body)
+	}
+
+	// Shutting down takes the instance out of rotation.
+	if err := s.handler.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resp = s.probe(t, "/readyz")
+	expectStatus(t, resp, http.StatusServiceUnavailable)
+	got = resp.object(t)
+	if checks, _ := got["checks"].(map[string]any); got["status"] != "not_ready" || checks["server"] != "server_shutdown" {
+		t.Errorf("got %s, want not ready while shutting down", resp.body)
+	}
+}
+
+func TestReadyzBrokerDown(t *testing.T) {
+	s := newTestServer(t, api.WithBroker(downBroker{}))
+	resp := s.probe(t, "/readyz")
+	expectStatus(t, resp, http.StatusServiceUnavailable)
+	got := resp.object(t)
+	if checks, _ := got["checks"].(map[string]any); got["status"] != "not_ready" || checks["broker"] != "broker down" || checks["store"] != "ok" {
+		t.Errorf("got %s, want the broker check failed", resp.body)
+	}
+}
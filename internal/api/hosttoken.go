@@ -0,0 +1,116 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prefixes of the tokens signed with the host token secret, telling them
+// apart from each other and from the admin token, and leaving room for other
+// formats later on.
+const (
+	hostTokenPrefix      = "h1."
+	moderatorTokenPrefix = "m1."
+	inviteCodePrefix     = "i1."
+)
+
+// hostToken returns the token that makes its bearer a host of roomID. It is
+// the HMAC of the room id, so it needs no storage and stays valid for as long
+// as the room exists and the secret is unchanged.
+func (api *Handler) hostToken(roomID uuid.UUID) string {
+	return hostTokenPrefix + roomID.String() + "." + encodeMAC(api.signToken("host", roomID[:]))
+}
+
+// moderatorToken returns the token of a co-host of roomID, handed out when
+// an invite is redeemed.
+func (api *Handler) moderatorToken(roomID uuid.UUID) string {
+	return moderatorTokenPrefix + roomID.String() + "." + encodeMAC(api.signToken("moderator", roomID[:]))
+}
+
+// inviteCode returns a code redeemable for a moderator token of roomID until
+// expiresAt.
+func (api *Handler) inviteCode(roomID uuid.UUID, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	mac := api.signToken("invite", roomID[:], []byte(expiry))
+	return inviteCodePrefix + roomID.String() + "." + expiry + "." + encodeMAC(mac)
+}
+
+// parseRoomToken returns the room a host or moderator token was issued for,
+// reporting false when token isn't a valid one of the kind given by prefix.
+func (api *Handler) parseRoomToken(token, prefix string) (uuid.UUID, bool) {
+	purpose := "host"
+	if prefix == moderatorTokenPrefix {
+		purpose = "moderator"
+	}
+
+	rest, ok := strings.CutPrefix(token, prefix)
+	if !ok {
+		return uuid.Nil, false
+	}
+	rawRoomID, rawMAC, ok := strings.Cut(rest, ".")
+	if !ok {
+		return uuid.Nil, false
+	}
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil || !api.validMAC(rawMAC, purpose, roomID[:]) {
+		return uuid.Nil, false
+	}
+	return roomID, true
+}
+
+// parseInviteCode returns the room an invite code was issued for and when it
+// expires, reporting false when the code wasn't issued by this server.
+func (api *Handler) parseInviteCode(code string) (uuid.UUID, time.Time, bool) {
+	rest, ok := strings.CutPrefix(code, inviteCodePrefix)
+	if !ok {
+		return uuid.Nil, time.Time{}, false
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return uuid.Nil, time.Time{}, false
+	}
+	roomID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return uuid.Nil, time.Time{}, false
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || !api.validMAC(parts[2], "invite", roomID[:], []byte(parts[1])) {
+		return uuid.Nil, time.Time{}, false
+	}
+	return roomID, time.Unix(expiry, 0), true
+}
+
+// signToken returns the HMAC of data for purpose, which keeps a token of one
+// kind from being passed off as another.
+func (api *Handler) signToken(purpose string, data ...[]byte) []byte {
+	mac := hmac.New(sha256.New, api.hostTokenSecret)
+	mac.Write([]byte(purpose + ":"))
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+func (api *Handler) validMAC(raw, purpose string, data ...[]byte) bool {
+	mac, err := base64.RawURLEncoding.DecodeString(raw)
+	return err == nil && hmac.Equal(mac, api.signToken(purpose, data...))
+}
+
+func encodeMAC(mac []byte) string {
+	return base64.RawURLEncoding.EncodeToString(mac)
+}
+
+func newHostTokenSecret() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("api: reading random host token secret: " + err.Error())
+	}
+	return b
+}
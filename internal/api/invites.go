@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultInviteLifetime = 24 * time.Hour
+	maxInviteLifetime     = 7 * 24 * time.Hour
+)
+
+// handleCreateInvite issues a code the host can share with co-hosts. Anyone
+// redeeming it before it expires gets a moderator token for the room.
+func (api *Handler) handleCreateInvite(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	// The body is optional, invites last a day by default.
+	body := struct {
+		ExpiresInMinutes int `json:"expires_in_minutes"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	lifetime := defaultInviteLifetime
+	if body.ExpiresInMinutes != 0 {
+		lifetime = time.Duration(body.ExpiresInMinutes) * time.Minute
+		if lifetime <= 0 || lifetime > maxInviteLifetime {
+			writeError(w, http.StatusBadRequest, "invalid_expiry", "expires_in_minutes must be between 1 and 10080")
+			return
+		}
+	}
+
+	room, err := api.getRoom(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	// An invite is of no use once the room is gone.
+	expiresAt := api.now().Add(lifetime).Truncate(time.Second)
+	if room.ExpiresAt != nil && room.ExpiresAt.Before(expiresAt) {
+		expiresAt = room.ExpiresAt.Truncate(time.Second)
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"code":       api.inviteCode(roomID, expiresAt),
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleRedeemInvite exchanges an invite code for a moderator token of its
+// room. Moderators can answer, delete and restore messages but can't invite
+// others.
+func (api *Handler) handleRedeemInvite(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Code string `json:"code"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	roomID, expiresAt, ok := api.parseInviteCode(body.Code)
+	if !ok {
+		writeError(w, http.StatusNotFound, "invite_not_found", "invalid invite code")
+		return
+	}
+	if !api.now().Before(expiresAt) {
+		writeError(w, http.StatusGone, "invite_expired", "the invite expired")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"room_id":         roomID.String(),
+		"moderator_token": api.moderatorToken(roomID),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
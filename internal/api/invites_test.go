@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// redeemInvite has the host of room invite a co-host and returns the
+// moderator token the invite is redeemed for.
+func (s *testServer) redeemInvite(t *testing.T, room testRoom) string {
+	t.Helper()
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/invites", nil, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusOK)
+	code := resp.object(t)["code"].(string)
+
+	resp = s.do(t, http.MethodPost, "/invites/redeem", map[string]any{"code": code})
+	expectStatus(t, resp, http.StatusOK)
+	redeemed := resp.object(t)
+	if redeemed["room_id"] != room.ID {
+		t.Fatalf("redeemed an invite of room %v, want %s", redeemed["room_id"], room.ID)
+	}
+	return redeemed["moderator_token"].(string)
+}
+
+func TestInvites(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	moderator := []string{"Authorization", "Bearer " + s.redeemInvite(t, room)}
+	answered := s.postMessage(t, room.ID, "answer me")
+	deleted := s.postMessage(t, room.ID, "delete me")
+	path := "/rooms/" + room.ID
+
+	// Co-hosts moderate the messages.
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/messages/"+answered+"/answer", nil, moderator...), http.StatusOK)
+	expectStatus(t, s.do(t, http.MethodDelete, path+"/messages/"+deleted, nil, moderator...), http.StatusNoContent)
+	expectStatus(t, s.do(t, http.MethodPost, path+"/messages/"+deleted+"/restore", nil, moderator...), http.StatusOK)
+
+	// The room stays the host's.
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPatch, path + "/close"},
+		{http.MethodPatch, path},
+		{http.MethodDelete, path},
+		{http.MethodPost, path + "/invites"},
+	}
+	for _, tt := range tests {
+		resp := s.do(t, tt.method, tt.path, map[string]any{}, moderator...)
+		expectStatus(t, resp, http.StatusForbidden)
+		if code := resp.code(t); code != "forbidden" {
+			t.Errorf("%s %s: got code %q, want forbidden", tt.method, tt.path, code)
+		}
+	}
+	if got := s.do(t, http.MethodGet, path, nil).object(t); got["closed_at"] != nil {
+		t.Errorf("a co-host closed the room: %v", got)
+	}
+}
+
+func TestInviteOtherRoom(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	moderator := []string{"Authorization", "Bearer " + s.redeemInvite(t, other)}
+	id := s.postMessage(t, room.ID, "question")
+
+	resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/answer", nil, moderator...)
+	expectStatus(t, resp, http.StatusUnauthorized)
+}
+
+func TestInviteExpiry(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/invites", map[string]any{"expires_in_minutes": 5}, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusOK)
+	invite := resp.object(t)
+	if want := testStart.Add(5 * time.Minute).Format(time.RFC3339); invite["expires_at"] != want {
+		t.Errorf("got expires_at %v, want %s", invite["expires_at"], want)
+	}
+
+	s.clock.Advance(5*time.Minute - time.Second)
+	expectStatus(t, s.do(t, http.MethodPost, "/invites/redeem", map[string]any{"code": invite["code"]}), http.StatusOK)
+
+	s.clock.Advance(time.Second)
+	resp = s.do(t, http.MethodPost, "/invites/redeem", map[string]any{"code": invite["code"]})
+	expectStatus(t, resp, http.StatusGone)
+	if code := resp.code(t); code != "invite_expired" {
+		t.Errorf("got code %q, want invite_expired", code)
+	}
+}
+
+func TestInviteRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		header []string
+		status int
+		code   string
+	}{
+		{"NotHost", "/rooms/" + room.ID + "/invites", nil, nil, http.StatusForbidden, "forbidden"},
+		{"ExpiryTooShort", "/rooms/" + room.ID + "/invites", map[string]any{"expires_in_minutes": -1}, host, http.StatusBadRequest, "invalid_expiry"},
+		{"ExpiryTooLong", "/rooms/" + room.ID + "/invites", map[string]any{"expires_in_minutes": 7*24*60 + 1}, host, http.StatusBadRequest, "invalid_expiry"},
+		{"UnknownCode", "/invites/redeem", map[string]any{"code": "i1.nope"}, nil, http.StatusNotFound, "invite_not_found"},
+		{"HostTokenAsCode", "/invites/redeem", map[string]any{"code": room.HostToken}, nil, http.StatusNotFound, "invite_not_found"},
+		{"InvalidJSON", "/invites/redeem", "{", nil, http.StatusBadRequest, "invalid_json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodPost, tt.path, tt.body, tt.header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	englishQuestion    = "What are the plans for hiring new engineers next year?"
+	portugueseQuestion = "Quais são os planos para contratar novos engenheiros no próximo ano?"
+)
+
+// languages returns the language of every message listed at path, by id.
+func languages(t *testing.T, s *testServer, path string) map[string]any {
+	t.Helper()
+	resp := s.do(t, http.MethodGet, path, nil)
+	expectStatus(t, resp, http.StatusOK)
+	got := map[string]any{}
+	for _, m := range resp.object(t)["messages"].([]any) {
+		m := m.(map[string]any)
+		got[m["id"].(string)] = m["language"]
+	}
+	return got
+}
+
+func TestMessageLanguageFilter(t *testing.T) {
+	s := newTestServer(t, api.WithLanguageDetection(true))
+	room := s.createRoom(t, nil)
+	english := s.postMessage(t, room.ID, englishQuestion)
+	portuguese := s.postMessage(t, room.ID, portugueseQuestion)
+	short := s.postMessage(t, room.ID, "Why?")
+	path := "/rooms/" + room.ID + "/messages"
+
+	tests := []struct {
+		query string
+		want  map[string]any
+	}{
+		{"", map[string]any{english: "en", portuguese: "pt", short: "und"}},
+		{"?lang=en", map[string]any{english: "en"}},
+		{"?lang=PT", map[string]any{portuguese: "pt"}},
+		{"?lang=und", map[string]any{short: "und"}},
+		{"?lang=de", map[string]any{}},
+	}
+	for _, tt := range tests {
+		got := languages(t, s, path+tt.query)
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for id, language := range tt.want {
+			if got[id] != language {
+				t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+
+	for _, lang := range []string{"e", "english", "e1"} {
+		resp := s.do(t, http.MethodGet, path+"?lang="+lang, nil)
+		expectStatus(t, resp, http.StatusBadRequest)
+		if code := resp.code(t); code != "invalid_lang" {
+			t.Errorf("lang %q: got code %q, want invalid_lang", lang, code)
+		}
+	}
+}
+
+func TestUpdateMessageLanguage(t *testing.T) {
+	s := newTestServer(t, api.WithLanguageDetection(true))
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, englishQuestion)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	c := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/language", map[string]any{"language": "ES"}, host...)
+	expectStatus(t, resp, http.StatusOK)
+	got := resp.object(t)
+	if got["language"] != "es" || got["language_confidence"] != 1.0 || got["version"] != 2.0 {
+		t.Errorf("got %v, want language es with confidence 1 at version 2", got)
+	}
+
+	event := c.expect(events.KindMessageLanguage)
+	if value := event.Value.(events.MessageLanguage); value != (events.MessageLanguage{ID: id, Language: "es", Version: 2}) {
+		t.Errorf("got event %+v", value)
+	}
+	if got := languages(t, s, "/rooms/"+room.ID+"/messages?lang=es"); got[id] != "es" {
+		t.Errorf("listing es got %v, want the message", got)
+	}
+	if got := languages(t, s, "/rooms/"+room.ID+"/messages?lang=en"); len(got) != 0 {
+		t.Errorf("listing en got %v, want none", got)
+	}
+}
+
+func TestUpdateMessageLanguageRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    bool
+		deleted bool
+		body    any
+		status  int
+	}{
+		{"NotHost", false, false, map[string]any{"language": "es"}, http.StatusUnauthorized},
+		{"InvalidJSON", true, false, "{", http.StatusBadRequest},
+		{"MissingLanguage", true, false, map[string]any{}, http.StatusUnprocessableEntity},
+		{"InvalidLanguage", true, false, map[string]any{"language": "spanish"}, http.StatusUnprocessableEntity},
+		{"Deleted", true, true, map[string]any{"language": "es"}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, api.WithLanguageDetection(true))
+			room := s.createRoom(t, nil)
+			id := s.postMessage(t, room.ID, englishQuestion)
+			path := "/rooms/" + room.ID + "/messages/" + id
+			host := []string{"Authorization", "Bearer " + room.HostToken}
+			if tt.deleted {
+				expectStatus(t, s.do(t, http.MethodDelete, path, nil, host...), http.StatusNoContent)
+			}
+			var header []string
+			if tt.host {
+				header = host
+			}
+
+			resp := s.do(t, http.MethodPatch, path+"/language", tt.body, header...)
+			expectStatus(t, resp, tt.status)
+			if tt.status == http.StatusUnprocessableEntity {
+				if code := resp.fieldErrors(t)["language"]; code != "invalid_language" {
+					t.Errorf("got error %q, want invalid_language", code)
+				}
+			}
+			if tt.deleted {
+				return
+			}
+			if got := languages(t, s, "/rooms/"+room.ID+"/messages"); got[id] != "en" {
+				t.Errorf("got languages %v, want the message still en", got)
+			}
+		})
+	}
+}
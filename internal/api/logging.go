@@ -0,0 +1,60 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// requestLogger logs every request as structured slog attributes once the
+// handler returns. For websocket subscriptions that happens on disconnect, so
+// the logged duration is the lifetime of the subscription.
+func requestLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			upgrade := isWebsocketUpgrade(r)
+			if status == 0 {
+				status = http.StatusOK
+				if upgrade {
+					status = http.StatusSwitchingProtocols
+				}
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			msg := "request completed"
+			if upgrade && status == http.StatusSwitchingProtocols {
+				msg = "websocket subscription closed"
+			}
+
+			logger.LogAttrs(r.Context(), level, msg,
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.Int("bytes", ww.BytesWritten()),
+			)
+		})
+	}
+}
+
+func isWebsocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// recordingHandler is a slog.Handler keeping the records it handles.
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler      { return h }
+
+// attrs returns the attributes of r by key.
+func attrs(r slog.Record) map[string]slog.Value {
+	m := make(map[string]slog.Value)
+	r.Attrs(func(a slog.Attr) bool {
+		m[a.Key] = a.Value
+		return true
+	})
+	return m
+}
+
+func TestRequestLogger(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		upgrade bool
+		level   slog.Level
+		msg     string
+		logged  int
+	}{
+		{"ImplicitOK", 0, "hello", false, slog.LevelInfo, "request completed", http.StatusOK},
+		{"Created", http.StatusCreated, "", false, slog.LevelInfo, "request completed", http.StatusCreated},
+		{"ClientError", http.StatusNotFound, "", false, slog.LevelWarn, "request completed", http.StatusNotFound},
+		{"ServerError", http.StatusServiceUnavailable, "", false, slog.LevelError, "request completed", http.StatusServiceUnavailable},
+		{"Subscription", 0, "", true, slog.LevelInfo, "websocket subscription closed", http.StatusSwitchingProtocols},
+		{"RefusedSubscription", http.StatusForbidden, "", true, slog.LevelWarn, "request completed", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &recordingHandler{}
+			// Upgraded connections are hijacked, so nothing is written
+			// through the ResponseWriter.
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				if tt.body != "" {
+					w.Write([]byte(tt.body))
+				}
+			})
+			handler := middleware.RequestID(requestLogger(slog.New(h))(next))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/rooms?limit=1", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.upgrade {
+				req.Header.Set("Upgrade", "websocket")
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(h.records) != 1 {
+				t.Fatalf("got %d records, want 1", len(h.records))
+			}
+			r := h.records[0]
+			if r.Level != tt.level || r.Message != tt.msg {
+				t.Errorf("got %s %q, want %s %q", r.Level, r.Message, tt.level, tt.msg)
+			}
+			a := attrs(r)
+			if got := a["status"].Int64(); got != int64(tt.logged) {
+				t.Errorf("got status %d, want %d", got, tt.logged)
+			}
+			if got := a["method"].String(); got != http.MethodGet {
+				t.Errorf("got method %q, want GET", got)
+			}
+			if got := a["path"].String(); got != "/api/v1/rooms" {
+				t.Errorf("got path %q, want it without the query", got)
+			}
+			if got := a["remote_addr"].String(); got != req.RemoteAddr {
+				t.Errorf("got remote_addr %q, want %q", got, req.RemoteAddr)
+			}
+			if got := a["request_id"].String(); got == "" {
+				t.Error("request_id not logged")
+			}
+			if got := a["bytes"].Int64(); got != int64(len(tt.body)) {
+				t.Errorf("got bytes %d, want %d", got, len(tt.body))
+			}
+			if _, ok := a["duration"]; !ok {
+				t.Error("duration not logged")
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	// maxPollEvents is how many of the latest events of a room are kept for
+	// long polling clients. Clients further behind must reload the room.
+	maxPollEvents      = 256
+	defaultPollTimeout = 25
+	maxPollTimeout     = 60
+)
+
+// recordPollEventLocked keeps msg for long polling clients and wakes the ones
+// waiting on its room. api.mu must be held.
+func (api *Handler) recordPollEventLocked(msg events.Event) {
+	recent := append(api.pollEvents[msg.RoomID], msg)
+	if len(recent) > maxPollEvents {
+		recent = recent[len(recent)-maxPollEvents:]
+	}
+	api.pollEvents[msg.RoomID] = recent
+
+	if wake, ok := api.pollWaiters[msg.RoomID]; ok {
+		close(wake)
+		delete(api.pollWaiters, msg.RoomID)
+	}
+}
+
+// recentEventsLocked returns the public events of roomID after since. It
+// reports false when they aren't kept anymore, or never happened on this
+// instance. api.mu must be held.
+func (api *Handler) recentEventsLocked(roomID string, since uint64) ([]events.Event, bool) {
+	seq := api.sequences[roomID]
+	switch {
+	case since > seq:
+		return nil, false
+	case since == seq:
+		return nil, true
+	}
+
+	recent := api.pollEvents[roomID]
+	if len(recent) == 0 || recent[0].Seq > since+1 {
+		return nil, false
+	}
+	for i, msg := range recent {
+		if msg.Seq > since {
+			return append([]events.Event(nil), recent[i:]...), true
+		}
+	}
+	return nil, false
+}
+
+// pollEventsLocked is recentEventsLocked, except that when there are no
+// events yet it returns a channel closed on the next one. api.mu must be
+// held.
+func (api *Handler) pollEventsLocked(roomID string, since uint64) ([]events.Event, <-chan struct{}, bool) {
+	evts, ok := api.recentEventsLocked(roomID, since)
+	if !ok || len(evts) > 0 {
+		return evts, nil, ok
+	}
+
+	wake, ok := api.pollWaiters[roomID]
+	if !ok {
+		wake = make(chan struct{})
+		api.pollWaiters[roomID] = wake
+	}
+	return nil, wake, true
+}
+
+// handlePollRoomEvents is the long polling fallback for clients that can
+// neither open a websocket nor read server-sent events. It answers with the
+// public events of the room after the since sequence number as a JSON array,
+// waiting up to timeout seconds for one to happen. Without since it waits for
+// the next event. The seq of the last event returned is the since of the next
+// poll; a 410 tells the client it fell behind and must reload the room.
+func (api *Handler) handlePollRoomEvents(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	var since uint64
+	hasSince := false
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		since, err = strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_since", "invalid since")
+			return
+		}
+		hasSince = true
+	}
+
+	timeout := defaultPollTimeout
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > maxPollTimeout {
+			writeError(w, http.StatusBadRequest, "invalid_timeout", "invalid timeout")
+			return
+		}
+		timeout = n
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	if !hasSince {
+		since = api.roomSequence(rawRoomID)
+	}
+
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+
+	var found []events.Event
+wait:
+	for {
+		api.mu.Lock()
+		evts, wake, ok := api.pollEventsLocked(rawRoomID, since)
+		api.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusGone, eventsExpired, "the events after since are gone, reload the room")
+			return
+		}
+		if wake == nil {
+			found = evts
+			break
+		}
+
+		select {
+		case <-wake:
+		case <-timer.C:
+			break wait
+		case <-api.shuttingDown:
+			break wait
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if found == nil {
+		found = []events.Event{}
+	}
+	data, err := json.Marshal(found)
+	if err != nil {
+		api.logger.Error("failed to marshal events", "room_id", rawRoomID, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
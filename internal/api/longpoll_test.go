@@ -0,0 +1,106 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// pollEvents decodes the events of a long polling response.
+func pollEvents(t *testing.T, resp response) []events.Event {
+	t.Helper()
+	var raw []json.RawMessage
+	if err := json.Unmarshal(resp.body, &raw); err != nil {
+		t.Fatalf("decoding %s: %v", resp.body, err)
+	}
+	evts := make([]events.Event, 0, len(raw))
+	for _, data := range raw {
+		e, err := events.UnmarshalEvent(data)
+		if err != nil {
+			t.Fatalf("decoding %s: %v", data, err)
+		}
+		evts = append(evts, e)
+	}
+	return evts
+}
+
+func TestPollRoomEvents(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	path := "/rooms/" + room.ID + "/events"
+	first := s.postMessage(t, room.ID, "first question")
+	second := s.postMessage(t, room.ID, "second question")
+
+	resp := s.do(t, http.MethodGet, path+"?since=0&timeout=0", nil)
+	expectStatus(t, resp, http.StatusOK)
+	evts := pollEvents(t, resp)
+	if len(evts) != 2 || evts[0].Value.(events.MessageCreated).ID != first || evts[1].Value.(events.MessageCreated).ID != second {
+		t.Fatalf("got events %s, want the two questions", resp.body)
+	}
+	since := evts[1].Seq
+
+	resp = s.do(t, http.MethodGet, path+"?since="+strconv.FormatUint(since-1, 10)+"&timeout=0", nil)
+	expectStatus(t, resp, http.StatusOK)
+	if evts := pollEvents(t, resp); len(evts) != 1 || evts[0].Seq != since {
+		t.Errorf("got events %s, want the second question only", resp.body)
+	}
+
+	// Caught up, the poll times out empty.
+	resp = s.do(t, http.MethodGet, path+"?since="+strconv.FormatUint(since, 10)+"&timeout=0", nil)
+	expectStatus(t, resp, http.StatusOK)
+	if string(resp.body) != "[]" {
+		t.Errorf("got %s, want no events", resp.body)
+	}
+
+	// A waiting poll answers with the next event.
+	done := make(chan response)
+	go func() {
+		done <- s.do(t, http.MethodGet, path+"?since="+strconv.FormatUint(since, 10)+"&timeout=10", nil)
+	}()
+	third := s.postMessage(t, room.ID, "third question")
+	resp = <-done
+	expectStatus(t, resp, http.StatusOK)
+	if evts := pollEvents(t, resp); len(evts) != 1 || evts[0].Seq != since+1 || evts[0].Value.(events.MessageCreated).ID != third {
+		t.Errorf("got events %s, want the third question", resp.body)
+	}
+
+	// Polling by room code works too.
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.Code+"/events?since=0&timeout=0", nil)
+	expectStatus(t, resp, http.StatusOK)
+	if evts := pollEvents(t, resp); len(evts) != 3 {
+		t.Errorf("got events %s, want the three questions", resp.body)
+	}
+}
+
+func TestPollRoomEventsRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	s.postMessage(t, room.ID, "question")
+	path := "/rooms/" + room.ID + "/events"
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+	}{
+		{"InvalidSince", path + "?since=-1", http.StatusBadRequest, "invalid_since"},
+		{"InvalidTimeout", path + "?timeout=soon", http.StatusBadRequest, "invalid_timeout"},
+		{"NegativeTimeout", path + "?timeout=-1", http.StatusBadRequest, "invalid_timeout"},
+		{"LongTimeout", path + "?timeout=61", http.StatusBadRequest, "invalid_timeout"},
+		{"SinceAhead", path + "?since=5&timeout=0", http.StatusGone, "events_expired"},
+		{"InvalidRoomID", "/rooms/nope/events", http.StatusBadRequest, "invalid_room_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodGet, tt.path, nil)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestMessageEditWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		status  int
+		code    string
+	}{
+		{"JustCreated", 0, http.StatusOK, ""},
+		{"BeforeWindowEnds", 5*time.Minute - time.Nanosecond, http.StatusOK, ""},
+		{"WindowEnds", 5 * time.Minute, http.StatusForbidden, "edit_window_expired"},
+		{"AfterWindow", time.Hour, http.StatusForbidden, "edit_window_expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, nil)
+			id := s.postMessage(t, room.ID, "original question", "X-Client-Id", "author")
+
+			s.clock.Advance(tt.elapsed)
+			resp := s.do(t, http.MethodPut, "/rooms/"+room.ID+"/messages/"+id, map[string]any{"message": "edited question"}, "X-Client-Id", "author")
+			expectStatus(t, resp, tt.status)
+			if tt.code != "" {
+				if code := resp.code(t); code != tt.code {
+					t.Errorf("got code %q, want %q", code, tt.code)
+				}
+				return
+			}
+			if got := resp.object(t)["message"]; got != "edited question" {
+				t.Errorf("got message %v, want the edited one", got)
+			}
+		})
+	}
+}
+
+func TestUpdateMessage(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "original question", "X-Client-Id", "author")
+	path := "/rooms/" + room.ID + "/messages/" + id
+	c := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPut, path, map[string]any{"message": "edited question"}, "X-Client-Id", "author")
+	expectStatus(t, resp, http.StatusOK)
+	edited := c.expect(events.KindMessageEdited).Value.(events.MessageEdited)
+	if edited.ID != id || edited.Message != "edited question" {
+		t.Errorf("got message_edited %+v, want the edited message %s", edited, id)
+	}
+
+	resp = s.do(t, http.MethodGet, path, nil)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["message"]; got != "edited question" {
+		t.Errorf("got message %v, want the edited one", got)
+	}
+}
+
+func TestUpdateMessageRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		body   any
+		answer bool
+		status int
+		code   string
+	}{
+		{"NoClientID", nil, map[string]any{"message": "edited"}, false, http.StatusForbidden, "missing_client_id"},
+		{"OtherClient", []string{"X-Client-Id", "someone else"}, map[string]any{"message": "edited"}, false, http.StatusForbidden, "not_author"},
+		{"Answered", []string{"X-Client-Id", "author"}, map[string]any{"message": "edited"}, true, http.StatusConflict, "already_answered"},
+		{"OtherClientAnswered", []string{"X-Client-Id", "someone else"}, map[string]any{"message": "edited"}, true, http.StatusForbidden, "not_author"},
+		{"Empty", []string{"X-Client-Id", "author"}, map[string]any{"message": "  "}, false, http.StatusUnprocessableEntity, ""},
+		{"InvalidJSON", []string{"X-Client-Id", "author"}, "{", false, http.StatusBadRequest, "invalid_json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, nil)
+			id := s.postMessage(t, room.ID, "original question", "X-Client-Id", "author")
+			path := "/rooms/" + room.ID + "/messages/" + id
+			if tt.answer {
+				expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", nil, "Authorization", "Bearer "+room.HostToken), http.StatusOK)
+			}
+
+			resp := s.do(t, http.MethodPut, path, tt.body, tt.header...)
+			expectStatus(t, resp, tt.status)
+			if tt.code != "" {
+				if code := resp.code(t); code != tt.code {
+					t.Errorf("got code %q, want %q", code, tt.code)
+				}
+			}
+			resp = s.do(t, http.MethodGet, path, nil)
+			if got := resp.object(t)["message"]; got != "original question" {
+				t.Errorf("got message %v, want it unchanged", got)
+			}
+		})
+	}
+}
+
+func TestMessageAuthorName(t *testing.T) {
+	tests := []struct {
+		name        string
+		requireName bool
+		authorName  any
+		want        any
+		code        string
+	}{
+		{"Given", false, "Ada", "Ada", ""},
+		{"Trimmed", false, "  Ada\x00 Lovelace\t ", "Ada Lovelace", ""},
+		{"Omitted", false, nil, nil, ""},
+		{"Blank", false, "   ", nil, ""},
+		{"AtMaxLength", false, strings.Repeat("é", 50), strings.Repeat("é", 50), ""},
+		{"TooLong", false, strings.Repeat("é", 51), nil, "name_too_long"},
+		{"Required", true, "Ada", "Ada", ""},
+		{"RequiredOmitted", true, nil, nil, "name_required"},
+		{"RequiredBlank", true, "\t", nil, "name_required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, map[string]any{"require_name": tt.requireName})
+			c := s.subscribe(t, room.ID, "")
+
+			body := map[string]any{"message": "question"}
+			if tt.authorName != nil {
+				body["author_name"] = tt.authorName
+			}
+			resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", body)
+			if tt.code != "" {
+				expectStatus(t, resp, http.StatusUnprocessableEntity)
+				if code := resp.fieldErrors(t)["author_name"]; code != tt.code {
+					t.Errorf("got author_name error %q, want %q", code, tt.code)
+				}
+				return
+			}
+			expectStatus(t, resp, http.StatusCreated)
+			created := resp.object(t)
+			if got := created["author_name"]; got != tt.want {
+				t.Errorf("got author_name %q, want %q", got, tt.want)
+			}
+
+			want, _ := tt.want.(string)
+			if got := c.expect(events.KindMessageCreated).Value.(events.MessageCreated).AuthorName; got != want {
+				t.Errorf("broadcast author_name %q, want %q", got, want)
+			}
+			resp = s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+created["id"].(string), nil)
+			if got := resp.object(t)["author_name"]; got != tt.want {
+				t.Errorf("read author_name %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnswerMessage(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+	path := "/rooms/" + room.ID + "/messages/" + id
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	c := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "the answer"}, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t); got["answered"] != true || got["answer"] != "the answer" {
+		t.Errorf("got %v, want the message answered with its answer", got)
+	}
+	if got := c.expect(events.KindMessageAnswered).Value.(events.MessageAnswered); got.ID != id || got.Answer != "the answer" {
+		t.Errorf("got message_answered %+v, want the answer of %s", got, id)
+	}
+
+	// Answering again without a body keeps the answer.
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", nil, host...), http.StatusOK)
+	resp = s.do(t, http.MethodGet, path, nil)
+	if got := resp.object(t); got["answered"] != true || got["answer"] != "the answer" {
+		t.Errorf("got %v, want the answer kept", got)
+	}
+}
+
+func TestAnswerMessageWithoutText(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+
+	resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/answer", nil, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t); got["answered"] != true || got["answer"] != nil {
+		t.Errorf("got %v, want the message answered without an answer", got)
+	}
+}
+
+func TestAnswerMessageRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   any
+		host   bool
+		status int
+		code   string
+	}{
+		{"NotHost", map[string]any{"answer": "yes"}, false, http.StatusUnauthorized, "unauthorized"},
+		{"InvalidJSON", "{", true, http.StatusBadRequest, "invalid_json"},
+		{"TooLong", map[string]any{"answer": strings.Repeat("é", 5001)}, true, http.StatusBadRequest, "answer_too_long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, nil)
+			id := s.postMessage(t, room.ID, "question")
+			var header []string
+			if tt.host {
+				header = []string{"Authorization", "Bearer " + room.HostToken}
+			}
+
+			resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/answer", tt.body, header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+			if got := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+id, nil).object(t)["answered"]; got != false {
+				t.Errorf("got answered %v, want false", got)
+			}
+		})
+	}
+}
+
+func TestAnswerMaxLength(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+	answer := strings.Repeat("é", 5000)
+
+	resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/answer", map[string]any{"answer": answer}, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusOK)
+	if resp.object(t)["answer"] != answer {
+		t.Error("answer of the maximum length was not stored")
+	}
+}
+
+func TestCanModerate(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+
+	tests := []struct {
+		name        string
+		header      []string
+		canModerate bool
+	}{
+		{"Anonymous", nil, false},
+		{"Client", []string{"X-Client-Id", "c"}, false},
+		{"Host", []string{"Authorization", "Bearer " + room.HostToken}, true},
+		{"HostOfOtherRoom", []string{"Authorization", "Bearer " + other.HostToken}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range []string{"/rooms/" + room.ID + "/messages", "/rooms/" + room.ID + "/messages/" + id} {
+				resp := s.do(t, http.MethodGet, path, nil, tt.header...)
+				expectStatus(t, resp, http.StatusOK)
+				if got := resp.object(t)["can_moderate"]; got != tt.canModerate {
+					t.Errorf("GET %s: got can_moderate %v, want %v", path, got, tt.canModerate)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are the Prometheus metrics of a handler. They are kept in a
+// registry of its own, so that several handlers in one process don't clash.
+type metrics struct {
+	registry        *prometheus.Registry
+	requestDuration *prometheus.HistogramVec
+	fanOut          prometheus.Histogram
+	droppedClients  prometheus.Counter
+}
+
+func newMetrics(api *Handler) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "wsrs_http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by route, subscriptions excluded.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		fanOut: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "wsrs_broadcast_fanout_size",
+			Help:    "Number of subscribers each broadcast event was delivered to.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+		}),
+		droppedClients: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "wsrs_dropped_clients_total",
+			Help: "Subscribers disconnected because their send queue overflowed.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.fanOut,
+		m.droppedClients,
+		subscriberCollector{api: api},
+	)
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// instrument records the latency of every request by route pattern, so
+// that room ids don't end up in the labels. Subscriptions are left out: they
+// last as long as the client stays connected.
+func (api *Handler) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWebsocketUpgrade(r) || strings.HasPrefix(r.URL.Path, "/subscribe") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		api.metrics.requestDuration.
+			WithLabelValues(r.Method, route, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+var subscribersDesc = prometheus.NewDesc(
+	"wsrs_subscribers",
+	"Active subscriptions by room and transport.",
+	[]string{"room_id", "transport"}, nil,
+)
+
+// subscriberCollector reports the live subscriptions when scraped, so rooms
+// that have none left don't linger in the output.
+type subscriberCollector struct {
+	api *Handler
+}
+
+func (c subscriberCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- subscribersDesc
+}
+
+func (c subscriberCollector) Collect(ch chan<- prometheus.Metric) {
+	type key struct{ roomID, transport string }
+	counts := make(map[key]int)
+
+	c.api.mu.Lock()
+	for roomID, subscribers := range c.api.subscribers {
+		for sub := range subscribers {
+			counts[key{roomID, sub.transport.Name()}]++
+		}
+	}
+	c.api.mu.Unlock()
+
+	for k, n := range counts {
+		ch <- prometheus.MustNewConstMetric(subscribersDesc, prometheus.GaugeValue, float64(n), k.roomID, k.transport)
+	}
+}
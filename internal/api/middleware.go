@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const defaultRequestTimeout = 10 * time.Second
+
+// exposeRequestID sends the id middleware.RequestID gave the request in the
+// X-Request-Id header, where error responses also read it from.
+func exposeRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+			w.Header().Set(requestIDHeader, requestID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deprecated marks the responses of a deprecated version of the API with the
+// Deprecation header, so clients can tell they should move to the current
+// one.
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverer turns panics into a logged stack trace and a JSON 500 carrying the
+// request id, so users can quote it when reporting the failure.
+func recoverer(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+
+				requestID := middleware.GetReqID(r.Context())
+				logger.ErrorContext(r.Context(), "panic serving request",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"request_id", requestID,
+					"panic", rvr,
+					"stack", string(debug.Stack()),
+				)
+
+				// The connection was hijacked, there is no response to write.
+				if isWebsocketUpgrade(r) {
+					return
+				}
+
+				writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// timeout cancels the request context after d and answers 503 with code
+// timeout if the handler hasn't responded by then. Responses are buffered
+// until the handler returns, so it must not wrap streaming routes.
+func timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{header: w.Header().Clone()}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if rvr := recover(); rvr != nil {
+						panicked <- rvr
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case rvr := <-panicked:
+				panic(rvr)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for k, v := range tw.header {
+					dst[k] = v
+				}
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					writeError(w, http.StatusServiceUnavailable, "timeout", "request timed out")
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler returns, discarding it
+// when the request timed out in the meantime.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestRecoverer(t *testing.T) {
+	h := &recordingHandler{}
+	handler := middleware.RequestID(exposeRequestID(recoverer(slog.New(h))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/rooms", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want 500", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "json") {
+		t.Errorf("got content type %q, want JSON", ct)
+	}
+	if code := problemCode(t, w.Body.Bytes()); code != "internal_error" {
+		t.Errorf("got code %q, want internal_error", code)
+	}
+	requestID := w.Header().Get(requestIDHeader)
+	if requestID == "" || !strings.Contains(w.Body.String(), requestID) {
+		t.Errorf("response %s doesn't carry the request id %q", w.Body, requestID)
+	}
+
+	if len(h.records) != 1 {
+		t.Fatalf("got %d records, want 1", len(h.records))
+	}
+	a := attrs(h.records[0])
+	if h.records[0].Level != slog.LevelError || a["panic"].String() != "boom" || a["request_id"].String() != requestID {
+		t.Errorf("got record %v, want the panic logged as an error with the request id", h.records[0])
+	}
+	if !strings.Contains(a["stack"].String(), "TestRecoverer") {
+		t.Error("stack trace not logged")
+	}
+}
+
+func TestRecovererAbortHandler(t *testing.T) {
+	handler := recoverer(discardLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rvr := recover(); rvr != http.ErrAbortHandler {
+			t.Errorf("got panic %v, want http.ErrAbortHandler passed on", rvr)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecovererWebsocket(t *testing.T) {
+	handler := recoverer(discardLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	// The connection of an upgraded request is hijacked, so nothing is
+	// written to it.
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/subscribe/room", nil)
+	req.Header.Set("Upgrade", "websocket")
+	handler.ServeHTTP(w, req)
+	if w.Body.Len() != 0 {
+		t.Errorf("got body %s, want none", w.Body)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	written := make(chan error, 1)
+	handler := timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		// Writes once timed out are discarded.
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("X-Late", "true")
+		_, err := w.Write([]byte("late"))
+		written <- err
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want 503", w.Code)
+	}
+	if code := problemCode(t, w.Body.Bytes()); code != "timeout" {
+		t.Errorf("got code %q, want timeout", code)
+	}
+	if err := <-written; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("late write returned %v, want http.ErrHandlerTimeout", err)
+	}
+	if strings.Contains(w.Body.String(), "late") || w.Header().Get("X-Late") != "" {
+		t.Error("late response reached the client")
+	}
+}
+
+func TestTimeoutInTime(t *testing.T) {
+	handler := timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/rooms/1")
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/rooms/1" || w.Body.String() != `{"id":"1"}` {
+		t.Errorf("got %d %v %s, want the handler's response", w.Code, w.Header(), w.Body)
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	// Panics of the handler goroutine reach the recoverer of the request.
+	handler := recoverer(discardLogger())(timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want 500", w.Code)
+	}
+}
@@ -0,0 +1,384 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// Actions recorded in the moderation audit.
+const (
+	moderationDelete  = "delete"
+	moderationRestore = "restore"
+	moderationAnswer  = "answer"
+	moderationEdit    = "edit"
+	moderationApprove = "approve"
+	moderationReject  = "reject"
+)
+
+// recordModeration adds action on message to the audit of its room, with the
+// value it replaced. The action already happened, so a failure is only logged.
+func (api *Handler) recordModeration(ctx context.Context, message pgstore.Message, action string, previous *string) {
+	err := api.queries.InsertModerationAudit(ctx, pgstore.InsertModerationAuditParams{
+		ID:            api.ids.NewID(),
+		RoomID:        message.RoomID,
+		MessageID:     message.ID,
+		Actor:         authFrom(ctx).actor(),
+		Action:        action,
+		PreviousValue: previous,
+		CreatedAt:     api.now(),
+	})
+	if err != nil {
+		api.logger.Warn("failed to record moderation action",
+			"room_id", message.RoomID,
+			"message_id", message.ID,
+			"action", action,
+			"error", err,
+		)
+	}
+}
+
+// handleDeleteRoomMessage hides a message from everyone but hosts. It stays in
+// the database and can be brought back with handleRestoreRoomMessage.
+func (api *Handler) handleDeleteRoomMessage(w http.ResponseWriter, r *http.Request) {
+	expectedVersion, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	if expectedVersion != nil && message.Version != *expectedVersion {
+		writeVersionConflict(w, message)
+		return
+	}
+
+	now := api.now()
+	actor := authFrom(r.Context()).actor()
+	deleted, err := api.queries.SoftDeleteMessage(r.Context(), pgstore.SoftDeleteMessageParams{
+		DeletedAt:       &now,
+		DeletedBy:       &actor,
+		ID:              message.ID,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		// With If-Match the update also misses when the version moved on.
+		if errors.Is(err, ErrNotFound) && expectedVersion != nil {
+			if current, err := api.queries.GetMessage(r.Context(), message.ID); err == nil && current.DeletedAt == nil {
+				writeVersionConflict(w, current)
+				return
+			}
+		}
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	api.recordModeration(r.Context(), deleted, moderationDelete, &message.Message)
+
+	api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindMessageDeleted,
+		RoomID: deleted.RoomID.String(),
+		Scope:  messageScope(deleted),
+		Value: events.MessageDeleted{
+			ID: deleted.ID.String(),
+		},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRestoreRoomMessage undoes the deletion of a message. Restoring a
+// message that isn't deleted is a conflict.
+func (api *Handler) handleRestoreRoomMessage(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "message_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_message_id", "invalid message id")
+		return
+	}
+
+	message, err := api.queries.GetMessage(r.Context(), messageID)
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+	if message.RoomID != roomID {
+		writeError(w, http.StatusNotFound, "message_not_found", "message not found")
+		return
+	}
+	if message.DeletedAt == nil {
+		writeError(w, http.StatusConflict, "not_deleted", "message is not deleted")
+		return
+	}
+
+	restored, err := api.queries.RestoreMessage(r.Context(), messageID)
+	if err != nil {
+		// Another host restored it in the meantime.
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, http.StatusConflict, "not_deleted", "message is not deleted")
+			return
+		}
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	api.recordModeration(r.Context(), restored, moderationRestore, message.DeletedBy)
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindMessageRestored,
+		RoomID: restored.RoomID.String(),
+		Scope:  messageScope(restored),
+		Value: events.MessageRestored{
+			ID:            restored.ID.String(),
+			Message:       restored.Message,
+			AuthorName:    derefString(restored.AuthorName),
+			ReactionCount: restored.ReactionCount,
+			Answered:      restored.Answered,
+			Answer:        derefString(restored.Answer),
+			Version:       restored.Version,
+		},
+	})
+
+	data, err := json.Marshal(map[string]any{
+		"id":      restored.ID.String(),
+		"version": restored.Version,
+		"seq":     seq,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleGetPendingMessages lists the messages of a room waiting for approval,
+// oldest first.
+func (api *Handler) handleGetPendingMessages(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	pending, err := api.queries.GetRoomPendingMessages(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	type message struct {
+		ID         string    `json:"id"`
+		Message    string    `json:"message"`
+		AuthorName *string   `json:"author_name"`
+		Language   string    `json:"language"`
+		CreatedAt  time.Time `json:"created_at"`
+		Version    int64     `json:"version"`
+	}
+
+	resp := make([]message, 0, len(pending))
+	for _, m := range pending {
+		resp = append(resp, message{
+			ID:         m.ID.String(),
+			Message:    m.Message,
+			AuthorName: m.AuthorName,
+			Language:   m.Language,
+			CreatedAt:  m.CreatedAt,
+			Version:    m.Version,
+		})
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleApproveRoomMessage makes a message waiting for approval visible to
+// everyone, who receive it as message_created. Approving a message that isn't
+// pending is a conflict.
+func (api *Handler) handleApproveRoomMessage(w http.ResponseWriter, r *http.Request) {
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	if !message.Pending {
+		writeError(w, http.StatusConflict, "not_pending", "message is not waiting for approval")
+		return
+	}
+
+	approved, err := api.queries.ApproveMessage(r.Context(), message.ID)
+	if err != nil {
+		// Another host approved or rejected it in the meantime.
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, http.StatusConflict, "not_pending", "message is not waiting for approval")
+			return
+		}
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	api.recordModeration(r.Context(), approved, moderationApprove, nil)
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindMessageCreated,
+		RoomID: approved.RoomID.String(),
+		Value: events.MessageCreated{
+			ID:         approved.ID.String(),
+			Message:    approved.Message,
+			AuthorName: derefString(approved.AuthorName),
+			Language:   approved.Language,
+			Version:    approved.Version,
+		},
+	})
+
+	data, err := json.Marshal(map[string]any{
+		"id":             approved.ID.String(),
+		"room_id":        approved.RoomID.String(),
+		"message":        approved.Message,
+		"author_name":    approved.AuthorName,
+		"reaction_count": approved.ReactionCount,
+		"answered":       approved.Answered,
+		"answer":         approved.Answer,
+		"language":       approved.Language,
+		"created_at":     approved.CreatedAt,
+		"version":        approved.Version,
+		"seq":            seq,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleRejectRoomMessage deletes a message waiting for approval, so it is
+// never shown. Like any deletion, it can be undone with
+// handleRestoreRoomMessage, which puts it back in the queue.
+func (api *Handler) handleRejectRoomMessage(w http.ResponseWriter, r *http.Request) {
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	if !message.Pending {
+		writeError(w, http.StatusConflict, "not_pending", "message is not waiting for approval")
+		return
+	}
+
+	now := api.now()
+	actor := authFrom(r.Context()).actor()
+	rejected, err := api.queries.SoftDeleteMessage(r.Context(), pgstore.SoftDeleteMessageParams{
+		DeletedAt: &now,
+		DeletedBy: &actor,
+		ID:        message.ID,
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	api.recordModeration(r.Context(), rejected, moderationReject, nil)
+
+	// Approved in the meantime, the message is deleted for everyone.
+	api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindMessageDeleted,
+		RoomID: rejected.RoomID.String(),
+		Scope:  messageScope(rejected),
+		Value: events.MessageDeleted{
+			ID: rejected.ID.String(),
+		},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetRoomAudit lists the moderation actions taken in a room, newest
+// first.
+func (api *Handler) handleGetRoomAudit(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	entries, err := api.queries.GetRoomModerationAudit(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	type auditEntry struct {
+		ID            string    `json:"id"`
+		MessageID     string    `json:"message_id"`
+		Actor         string    `json:"actor"`
+		Action        string    `json:"action"`
+		PreviousValue *string   `json:"previous_value"`
+		CreatedAt     time.Time `json:"created_at"`
+	}
+
+	resp := make([]auditEntry, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, auditEntry{
+			ID:            e.ID.String(),
+			MessageID:     e.MessageID.String(),
+			Actor:         e.Actor,
+			Action:        e.Action,
+			PreviousValue: e.PreviousValue,
+			CreatedAt:     e.CreatedAt,
+		})
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// includeDeleted reports whether deleted messages were asked for through
+// ?include_deleted=true, which only hosts of roomID may do. Otherwise an
+// error response is written and ok is false.
+func includeDeleted(w http.ResponseWriter, r *http.Request, roomID string) (include, ok bool) {
+	if r.URL.Query().Get("include_deleted") != "true" {
+		return false, true
+	}
+	if !authFrom(r.Context()).canModerate(roomID) {
+		writeError(w, http.StatusForbidden, "forbidden", "only hosts can see deleted messages")
+		return false, false
+	}
+	return true, true
+}
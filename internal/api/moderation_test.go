@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func messageIDs(messages []map[string]any) []string {
+	ids := make([]string, 0, len(messages))
+	for _, m := range messages {
+		ids = append(ids, m["id"].(string))
+	}
+	return ids
+}
+
+func TestDeleteHidesMessage(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	kept := s.postMessage(t, room.ID, "kept")
+	deleted := s.postMessage(t, room.ID, "deleted")
+	c := s.subscribe(t, room.ID, "")
+
+	expectStatus(t, s.do(t, http.MethodDelete, "/rooms/"+room.ID+"/messages/"+deleted, nil, host...), http.StatusNoContent)
+	if e := c.expect(events.KindMessageDeleted).Value.(events.MessageDeleted); e.ID != deleted {
+		t.Errorf("got message_deleted of %s, want %s", e.ID, deleted)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		header []string
+		ids    []string
+	}{
+		{"Public", "", nil, []string{kept}},
+		{"Host", "", host, []string{kept}},
+		{"HostWithDeleted", "?include_deleted=true", host, []string{kept, deleted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages"+tt.query, nil, tt.header...)
+			expectStatus(t, resp, http.StatusOK)
+			var page struct {
+				Messages []map[string]any `json:"messages"`
+			}
+			if err := json.Unmarshal(resp.body, &page); err != nil {
+				t.Fatal(err)
+			}
+			messages := page.Messages
+			if got := messageIDs(messages); fmt.Sprint(got) != fmt.Sprint(tt.ids) {
+				t.Fatalf("got messages %v, want %v", got, tt.ids)
+			}
+			for _, m := range messages {
+				_, hasDeletedAt := m["deleted_at"]
+				if want := m["id"] == deleted; hasDeletedAt != want {
+					t.Errorf("message %s: got deleted_at %v, want it set only on the deleted message", m["id"], m["deleted_at"])
+				}
+			}
+		})
+	}
+
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages?include_deleted=true", nil, "X-Client-Id", "c")
+	expectStatus(t, resp, http.StatusForbidden)
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+deleted, nil, host...)
+	expectStatus(t, resp, http.StatusNotFound)
+	// Deleting again finds nothing to delete.
+	expectStatus(t, s.do(t, http.MethodDelete, "/rooms/"+room.ID+"/messages/"+deleted, nil, host...), http.StatusNotFound)
+}
+
+func TestReactToDeletedMessage(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	deleted := s.postMessage(t, room.ID, "deleted")
+	expectStatus(t, s.do(t, http.MethodDelete, "/rooms/"+room.ID+"/messages/"+deleted, nil, host...), http.StatusNoContent)
+
+	resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+deleted+"/react", nil, "X-Client-Id", "c")
+	expectStatus(t, resp, http.StatusNotFound)
+	// The batch endpoint behaves like the single-message one.
+	resp = s.do(t, http.MethodPost, "/rooms/"+room.ID+"/reactions/batch", map[string]any{"add": []string{deleted}}, "X-Client-Id", "c")
+	expectStatus(t, resp, http.StatusUnprocessableEntity)
+	if code := resp.code(t); code != "unknown_messages" {
+		t.Errorf("got code %q, want unknown_messages", code)
+	}
+
+	expectStatus(t, s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages/"+deleted+"/restore", nil, host...), http.StatusOK)
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+deleted, nil)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t); got["reaction_count"] != 0.0 || got["version"] != 3.0 {
+		t.Errorf("got reaction_count %v and version %v, want 0 and 3: deleted and restored only", got["reaction_count"], got["version"])
+	}
+}
+
+func TestRestoreMessage(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "deleted by mistake")
+	path := "/rooms/" + room.ID + "/messages/" + id
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/react", nil, "X-Client-Id", "fan"), http.StatusOK)
+	expectStatus(t, s.do(t, http.MethodDelete, path, nil, host...), http.StatusNoContent)
+	c := s.subscribe(t, room.ID, "")
+
+	expectStatus(t, s.do(t, http.MethodPost, path+"/restore", nil), http.StatusUnauthorized)
+
+	resp := s.do(t, http.MethodPost, path+"/restore", nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["version"]; got != float64(4) {
+		t.Errorf("got version %v, want 4", got)
+	}
+	restored := c.expect(events.KindMessageRestored).Value.(events.MessageRestored)
+	if restored.ID != id || restored.Message != "deleted by mistake" || restored.ReactionCount != 1 {
+		t.Errorf("got message_restored %+v, want the message with its reaction", restored)
+	}
+	if got := messageIDs(s.messages(t, room.ID)); len(got) != 1 || got[0] != id {
+		t.Errorf("got messages %v, want %s back", got, id)
+	}
+
+	for _, target := range []string{id, s.postMessage(t, room.ID, "never deleted")} {
+		resp = s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages/"+target+"/restore", nil, host...)
+		expectStatus(t, resp, http.StatusConflict)
+		if code := resp.code(t); code != "not_deleted" {
+			t.Errorf("got code %q, want not_deleted", code)
+		}
+	}
+}
+
+func TestModerationAudit(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "original", "X-Client-Id", "author")
+	path := "/rooms/" + room.ID + "/messages/" + id
+
+	steps := []func(){
+		func() {
+			expectStatus(t, s.do(t, http.MethodPut, path, map[string]any{"message": "edited"}, "X-Client-Id", "author"), http.StatusOK)
+		},
+		func() {
+			expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "first answer"}, host...), http.StatusOK)
+		},
+		func() {
+			expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "second answer"}, host...), http.StatusOK)
+		},
+		func() { expectStatus(t, s.do(t, http.MethodDelete, path, nil, host...), http.StatusNoContent) },
+		func() { expectStatus(t, s.do(t, http.MethodPost, path+"/restore", nil, host...), http.StatusOK) },
+	}
+	for _, step := range steps {
+		step()
+		s.clock.Advance(time.Second)
+	}
+
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/audit", nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	entries := resp.list(t)
+	want := []struct {
+		action, actor string
+		previous      any
+	}{
+		{"restore", "host", "host"},
+		{"delete", "host", "edited"},
+		{"answer", "host", "first answer"},
+		{"answer", "host", nil},
+		{"edit", "client:author", "original"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d audit entries, want %d: %v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e["action"] != w.action || e["actor"] != w.actor || e["previous_value"] != w.previous || e["message_id"] != id {
+			t.Errorf("entry %d: got %v, want %s by %s replacing %v", i, e, w.action, w.actor, w.previous)
+		}
+	}
+
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.ID+"/audit", nil), http.StatusUnauthorized)
+}
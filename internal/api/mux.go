@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	// maxMuxRooms is how many rooms a single multi-room connection may join.
+	maxMuxRooms = 20
+	// maxControlFrameSize bounds the frames read from multi-room clients.
+	maxControlFrameSize = 1024
+)
+
+// Actions of the control frames sent by multi-room clients.
+const (
+	muxActionJoin  = "join"
+	muxActionLeave = "leave"
+)
+
+// controlFrame is what multi-room clients send to change their rooms.
+type controlFrame struct {
+	Action string `json:"action"`
+	RoomID string `json:"room_id"`
+}
+
+// muxTransport is a websocket carrying the events of several rooms, each
+// tagged with the room it belongs to.
+type muxTransport struct {
+	wsTransport
+}
+
+func (t muxTransport) Name() string {
+	return "ws-mux"
+}
+
+func (t muxTransport) WriteEvent(p *payload, deadline time.Time) error {
+	tagged, err := p.withRoomID()
+	if err != nil {
+		return err
+	}
+	if err := t.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return t.conn.WritePreparedMessage(tagged)
+}
+
+// handleSubscribeMux opens a websocket that isn't tied to a room. The client
+// joins and leaves rooms with control frames and receives the events of all
+// of them on the one connection, tagged with their room_id.
+func (api *Handler) handleSubscribeMux(w http.ResponseWriter, r *http.Request) {
+	if !api.checkOrigin(r) {
+		writeError(w, http.StatusForbidden, "origin_not_allowed", "origin not allowed")
+		return
+	}
+	if api.refuseWhileShuttingDown(w) {
+		return
+	}
+
+	scope, protocol, ok := api.subscriptionScope(w, r, "")
+	if !ok {
+		return
+	}
+
+	var header http.Header
+	if protocol != "" {
+		header = http.Header{"Sec-WebSocket-Protocol": {protocol}}
+	}
+	conn, err := api.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		api.logger.Warn("failed to upgrade conn", "error", err)
+		return
+	}
+	conn.SetReadLimit(maxControlFrameSize)
+
+	t := muxTransport{wsTransport{conn: conn, pingInterval: api.wsPingInterval}}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sub := api.newSubscriber(t, "", api.clientIP(r), cancel)
+	sub.scope = scope
+
+	go api.readControlFrames(ctx, sub, t.wsTransport)
+	api.serveSubscriber(ctx, sub, nil)
+}
+
+// readControlFrames applies the control frames of a multi-room client until
+// it goes away, which ends the subscription.
+func (api *Handler) readControlFrames(ctx context.Context, sub *subscriber, t wsTransport) {
+	defer sub.cancel()
+
+	t.readFrames(func(data []byte) {
+		var frame controlFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			api.sendSubscriptionError(sub, frame, "invalid_frame", "control frames must be JSON objects")
+			return
+		}
+
+		switch frame.Action {
+		case muxActionJoin:
+			api.joinRoom(ctx, sub, frame)
+		case muxActionLeave:
+			api.leaveRoom(ctx, sub, frame)
+		default:
+			api.sendSubscriptionError(sub, frame, "invalid_action", "action must be join or leave")
+		}
+	})
+}
+
+func (api *Handler) joinRoom(ctx context.Context, sub *subscriber, frame controlFrame) {
+	roomID, err := api.resolveRoomID(ctx, frame.RoomID)
+	if errors.Is(err, errInvalidRoomID) {
+		api.sendSubscriptionError(sub, frame, "invalid_room_id", "invalid room id")
+		return
+	}
+	if err == nil {
+		_, err = api.getRoom(ctx, roomID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			api.sendSubscriptionError(sub, frame, "room_not_found", "room not found")
+			return
+		}
+		api.logger.Warn("failed to join room", "room_id", frame.RoomID, "error", err)
+		api.sendSubscriptionError(sub, frame, "unavailable", "the room could not be joined, try again")
+		return
+	}
+	// Rooms joined by their code are known by their id from now on.
+	frame.RoomID = roomID.String()
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	// Joining twice only acknowledges the join again.
+	if _, joined := sub.rooms[frame.RoomID]; !joined {
+		if len(sub.rooms) >= maxMuxRooms {
+			api.sendSubscriptionErrorLocked(sub, frame, "too_many_rooms", "too many rooms joined on this connection")
+			return
+		}
+		api.joinLocked(sub, frame.RoomID)
+	}
+
+	// The acknowledgement is queued under the lock, so it comes before any
+	// event of the room and its seq is the one those events continue from.
+	api.sendLocked(sub, events.Event{
+		Kind:   events.KindRoomJoined,
+		RoomID: frame.RoomID,
+		Value: events.RoomJoined{
+			RoomID: frame.RoomID,
+			Seq:    api.sequences[frame.RoomID],
+		},
+	})
+}
+
+func (api *Handler) leaveRoom(ctx context.Context, sub *subscriber, frame controlFrame) {
+	if roomID, err := api.resolveRoomID(ctx, frame.RoomID); err == nil {
+		frame.RoomID = roomID.String()
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if _, joined := sub.rooms[frame.RoomID]; !joined {
+		return
+	}
+	api.leaveLocked(sub, frame.RoomID)
+	api.sendLocked(sub, events.Event{
+		Kind:   events.KindRoomLeft,
+		RoomID: frame.RoomID,
+		Value:  events.RoomLeft{RoomID: frame.RoomID},
+	})
+}
+
+func (api *Handler) sendSubscriptionError(sub *subscriber, frame controlFrame, code, message string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.sendSubscriptionErrorLocked(sub, frame, code, message)
+}
+
+func (api *Handler) sendSubscriptionErrorLocked(sub *subscriber, frame controlFrame, code, message string) {
+	api.sendLocked(sub, events.Event{
+		Kind: events.KindSubscriptionError,
+		Value: events.SubscriptionError{
+			Action:  frame.Action,
+			RoomID:  frame.RoomID,
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+// sendLocked queues msg for sub alone. api.mu must be held, which orders it
+// with the broadcasts of the rooms sub is in.
+func (api *Handler) sendLocked(sub *subscriber, msg events.Event) {
+	p, err := newPayload(msg)
+	if err != nil {
+		api.logger.Error("failed to marshal message", "kind", msg.Kind, "error", err)
+		return
+	}
+	sub.deliver(p)
+}
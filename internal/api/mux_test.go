@@ -0,0 +1,177 @@
+package api_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// dialMux opens a multi-room websocket.
+func (s *testServer) dialMux(t *testing.T, header ...string) *wsClient {
+	t.Helper()
+	c, _, err := s.dial(t, "/subscribe", header...)
+	if err != nil {
+		t.Fatalf("dialing the multi-room endpoint: %v", err)
+	}
+	return c
+}
+
+// send writes a control frame.
+func (c *wsClient) send(action, roomID string) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(map[string]string{"action": action, "room_id": roomID}); err != nil {
+		c.t.Fatalf("sending %s %s: %v", action, roomID, err)
+	}
+}
+
+// join joins roomID, returning its acknowledgement.
+func (c *wsClient) join(roomID string) events.RoomJoined {
+	c.t.Helper()
+	c.send("join", roomID)
+	event := c.next()
+	joined, ok := event.Value.(events.RoomJoined)
+	if !ok {
+		c.t.Fatalf("got %s event %+v joining %s, want room_joined", event.Kind, event.Value, roomID)
+	}
+	return joined
+}
+
+// expectSubscriptionError fails the test unless the next event is a
+// subscription_error with code.
+func (c *wsClient) expectSubscriptionError(code string) {
+	c.t.Helper()
+	event := c.next()
+	e, ok := event.Value.(events.SubscriptionError)
+	if !ok || e.Code != code {
+		c.t.Fatalf("got %s event %+v, want a subscription_error %s", event.Kind, event.Value, code)
+	}
+}
+
+func TestMultiRoomSubscribe(t *testing.T) {
+	s := newTestServer(t)
+	first := s.createRoom(t, nil)
+	second := s.createRoom(t, nil)
+	s.postMessage(t, second.ID, "asked before joining")
+
+	c := s.dialMux(t)
+	if joined := c.join(first.ID); joined.RoomID != first.ID || joined.Seq != 0 {
+		t.Errorf("got %+v, want %s joined at seq 0", joined, first.ID)
+	}
+	if joined := c.join(second.ID); joined.RoomID != second.ID || joined.Seq != 1 {
+		t.Errorf("got %+v, want %s joined at seq 1", joined, second.ID)
+	}
+	s.waitSubscribers(t, first.ID, 1)
+	s.waitSubscribers(t, second.ID, 1)
+
+	single := s.subscribe(t, first.ID, "")
+	s.postMessage(t, first.ID, "to the first room")
+	s.postMessage(t, second.ID, "to the second room")
+
+	for _, want := range []struct {
+		roomID, message string
+		seq             uint64
+	}{
+		{first.ID, "to the first room", 1},
+		{second.ID, "to the second room", 2},
+	} {
+		event := c.expect(events.KindMessageCreated)
+		created := event.Value.(events.MessageCreated)
+		if event.RoomID != want.roomID || created.Message != want.message || event.Seq != want.seq {
+			t.Errorf("got %q tagged %q at seq %d, want %q tagged %q at seq %d", created.Message, event.RoomID, event.Seq, want.message, want.roomID, want.seq)
+		}
+	}
+	// Single-room subscriptions know their room: events aren't tagged.
+	if event := single.expect(events.KindMessageCreated); event.RoomID != "" {
+		t.Errorf("single-room event tagged %q, want no room_id", event.RoomID)
+	}
+
+	c.send("leave", first.ID)
+	if left := c.next(); left.Kind != events.KindRoomLeft || left.RoomID != first.ID {
+		t.Fatalf("got %s event of %q, want room_left of %s", left.Kind, left.RoomID, first.ID)
+	}
+	s.waitSubscribers(t, first.ID, 1)
+	s.postMessage(t, first.ID, "after leaving")
+	s.postMessage(t, second.ID, "still joined")
+	if event := c.expect(events.KindMessageCreated); event.RoomID != second.ID {
+		t.Errorf("got an event of %q after leaving it", event.RoomID)
+	}
+
+	// Disconnecting leaves every room.
+	c.conn.Close()
+	s.waitSubscribers(t, second.ID, 0)
+	s.waitSubscribers(t, first.ID, 1)
+}
+
+func TestMultiRoomJoinByCode(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	c := s.dialMux(t)
+
+	if joined := c.join(room.Code); joined.RoomID != room.ID {
+		t.Errorf("got %q joined, want the room id %s", joined.RoomID, room.ID)
+	}
+	// The room is known by its id once joined.
+	c.send("leave", room.ID)
+	if left := c.next(); left.Kind != events.KindRoomLeft || left.RoomID != room.ID {
+		t.Errorf("got %s event of %q, want room_left of %s", left.Kind, left.RoomID, room.ID)
+	}
+}
+
+func TestMultiRoomJoinTwice(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	c := s.dialMux(t)
+
+	c.join(room.ID)
+	c.join(room.ID)
+	s.waitSubscribers(t, room.ID, 1)
+
+	s.postMessage(t, room.ID, "question")
+	c.expect(events.KindMessageCreated)
+	c.send("leave", room.ID)
+	if event := c.next(); event.Kind != events.KindRoomLeft {
+		t.Errorf("got %s event, want the message delivered once then room_left", event.Kind)
+	}
+}
+
+func TestMultiRoomErrors(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	c := s.dialMux(t)
+
+	c.send("join", "00000000-0000-0000-0000-000000000000")
+	c.expectSubscriptionError("room_not_found")
+	c.send("join", "not a room")
+	c.expectSubscriptionError("invalid_room_id")
+	c.send("subscribe", room.ID)
+	c.expectSubscriptionError("invalid_action")
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte("{")); err != nil {
+		t.Fatal(err)
+	}
+	c.expectSubscriptionError("invalid_frame")
+
+	// Leaving a room that wasn't joined is a no-op, and the connection is
+	// still open after every error.
+	c.send("leave", room.ID)
+	if joined := c.join(room.ID); joined.RoomID != room.ID {
+		t.Errorf("got %q joined, want %s", joined.RoomID, room.ID)
+	}
+}
+
+func TestMultiRoomLimit(t *testing.T) {
+	s := newTestServer(t)
+	c := s.dialMux(t)
+
+	for i := range 20 {
+		c.join(s.createRoom(t, map[string]any{"theme": "room " + strconv.Itoa(i)}).ID)
+	}
+	extra := s.createRoom(t, nil)
+	c.send("join", extra.ID)
+	c.expectSubscriptionError("too_many_rooms")
+	if resp := s.do(t, http.MethodGet, "/rooms/"+extra.ID, nil); resp.object(t)["subscriber_count"] != 0.0 {
+		t.Errorf("got subscriber_count %v, want the join refused", resp.object(t)["subscriber_count"])
+	}
+}
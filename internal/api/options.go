@@ -0,0 +1,248 @@
+package api
+
+import (
+	"log/slog"
+	"net/netip"
+	"time"
+)
+
+// Option configures optional behaviour of the handler returned by NewHandler.
+type Option func(*Handler)
+
+// WithSendQueueSize sets how many events may be queued per subscriber before
+// the subscriber is considered too slow and disconnected.
+func WithSendQueueSize(n int) Option {
+	return func(api *Handler) {
+		if n > 0 {
+			api.sendQueueSize = n
+		}
+	}
+}
+
+// WithWriteTimeout sets the deadline applied to every websocket write.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.writeTimeout = d
+		}
+	}
+}
+
+// WithLogger sets the logger used for request and subscription logs.
+func WithLogger(logger *slog.Logger) Option {
+	return func(api *Handler) {
+		if logger != nil {
+			api.logger = logger
+		}
+	}
+}
+
+// WithAdminToken enables the /api/admin routes, guarded by the given bearer token.
+func WithAdminToken(token string) Option {
+	return func(api *Handler) {
+		api.adminToken = token
+	}
+}
+
+// WithHostTokenSecret sets the key host tokens are signed with. Without one
+// a random key is generated, invalidating host tokens on every restart and
+// across instances.
+func WithHostTokenSecret(secret string) Option {
+	return func(api *Handler) {
+		if secret != "" {
+			api.hostTokenSecret = []byte(secret)
+		}
+	}
+}
+
+// WithAllowedOrigins restricts CORS and websocket handshakes to the given
+// origins. Patterns may contain a "*" wildcard, e.g. "https://*.example.com".
+func WithAllowedOrigins(origins ...string) Option {
+	return func(api *Handler) {
+		api.allowedOrigins = origins
+	}
+}
+
+// WithDBTimeout bounds every database call made by a handler.
+func WithDBTimeout(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.dbTimeout = d
+		}
+	}
+}
+
+// WithLanguageDetection tags new messages with their detected language. It is
+// off by default since it costs CPU on every post.
+func WithLanguageDetection(enabled bool) Option {
+	return func(api *Handler) {
+		api.detectLanguage = enabled
+	}
+}
+
+// WithSweepInterval sets how often expired rooms are looked for and deleted.
+func WithSweepInterval(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.sweepInterval = d
+		}
+	}
+}
+
+// WithRequestTimeout sets how long REST requests may take before they are
+// cancelled and answered with 503. Subscriptions are not affected.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.requestTimeout = d
+		}
+	}
+}
+
+// WithBroker relays events through b so that subscribers connected to other
+// instances of the server receive them too.
+func WithBroker(b Broker) Option {
+	return func(api *Handler) {
+		api.broker = b
+	}
+}
+
+// WithClock sets the clock the handler reads the current time from.
+func WithClock(clock Clock) Option {
+	return func(api *Handler) {
+		if clock != nil {
+			api.clock = clock
+		}
+	}
+}
+
+// WithIDGenerator sets how the ids of new rooms and messages are generated.
+func WithIDGenerator(ids IDGenerator) Option {
+	return func(api *Handler) {
+		if ids != nil {
+			api.ids = ids
+		}
+	}
+}
+
+// WithFlagThreshold sets how many clients must flag a message before
+// moderators are notified about it.
+func WithFlagThreshold(n int) Option {
+	return func(api *Handler) {
+		if n > 0 {
+			api.flagThreshold = n
+		}
+	}
+}
+
+// WithReactionFlushInterval sets how often coalesced reaction counts are
+// broadcast to the subscribers of a room.
+func WithReactionFlushInterval(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.reactionFlushInterval = d
+		}
+	}
+}
+
+// WithViewerCountInterval sets how often the subscribers of a room are told
+// how many they are, when that changed.
+func WithViewerCountInterval(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.viewerCountInterval = d
+		}
+	}
+}
+
+// WithRoomRetention sets how long deleted rooms can be restored before they
+// are deleted for good.
+func WithRoomRetention(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.roomRetention = d
+		}
+	}
+}
+
+// WithWSPingInterval sets how often websocket clients are pinged. Clients
+// silent for two intervals are dropped.
+func WithWSPingInterval(d time.Duration) Option {
+	return func(api *Handler) {
+		if d > 0 {
+			api.wsPingInterval = d
+		}
+	}
+}
+
+// WithWSBufferSizes sets the size of the read and write buffers allocated
+// for every websocket connection. Larger buffers mean fewer system calls
+// for large events, at the cost of memory per connection.
+func WithWSBufferSizes(read, write int) Option {
+	return func(api *Handler) {
+		if read > 0 {
+			api.wsReadBufferSize = read
+		}
+		if write > 0 {
+			api.wsWriteBufferSize = write
+		}
+	}
+}
+
+// WithRateLimiter keeps the rate limits in l, such as a store shared by every
+// instance. They are kept in memory by default.
+func WithRateLimiter(l RateLimiter) Option {
+	return func(api *Handler) {
+		if l != nil {
+			api.rateLimiter = l
+		}
+	}
+}
+
+// WithRateLimits overrides the rate limits of POST routes, keyed by method and
+// route relative to the API root, such as "POST /rooms". The "POST" key is the
+// limit of the routes not listed; a zero RateLimit lifts the limit.
+func WithRateLimits(limits map[string]RateLimit) Option {
+	return func(api *Handler) {
+		for route, limit := range limits {
+			api.rateLimits[route] = limit
+		}
+	}
+}
+
+// WithTrustedProxies trusts the X-Forwarded-For header of the requests coming
+// from prefixes, such as the load balancers in front of the server, to tell
+// clients apart. It is ignored by default, every client behind a proxy then
+// sharing its rate limits.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(api *Handler) {
+		api.trustedProxies = prefixes
+	}
+}
+
+// WithContentFilter checks the questions asked and edited with f, refusing,
+// masking or flagging the ones it matches as action says.
+func WithContentFilter(f ContentFilter, action ContentFilterAction) Option {
+	return func(api *Handler) {
+		api.contentFilter, api.contentFilterAction = f, action
+	}
+}
+
+// WithPprof serves the net/http/pprof profiles under /debug/pprof. They are
+// only given to requests carrying the admin token, so nothing is served
+// without one. It is off by default.
+func WithPprof(enabled bool) Option {
+	return func(api *Handler) {
+		api.pprof = enabled
+	}
+}
+
+// WithReactionKinds sets the emoji clients may react to messages with,
+// replacing the default set. Without any the default set is kept.
+func WithReactionKinds(kinds ...string) Option {
+	return func(api *Handler) {
+		if len(kinds) > 0 {
+			api.reactionKinds = kinds
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// defaultAllowedOrigins is used when no origins are configured and keeps the
+// historical allow-everything behaviour.
+var defaultAllowedOrigins = []string{"https://*", "http://*"}
+
+// originAllowed reports whether origin matches one of the allowed patterns. A
+// pattern may contain a single "*" wildcard, e.g. "https://*.example.com".
+func originAllowed(origin string, allowed []string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+
+		prefix, suffix, ok := strings.Cut(pattern, "*")
+		if !ok {
+			continue
+		}
+		if len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) &&
+			strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin validates the Origin header of a websocket handshake. Requests
+// without an Origin header don't come from a browser and are let through.
+func (api *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	return originAllowed(origin, api.allowedOrigins)
+}
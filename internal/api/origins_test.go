@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+)
+
+func TestAllowedOrigins(t *testing.T) {
+	s := newTestServer(t, api.WithAllowedOrigins("https://app.example.com", "https://*.example.org"))
+	room := s.createRoom(t, nil)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"https://live.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.test", false},
+		{"https://evil.test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			resp := s.do(t, http.MethodOptions, "/rooms", nil,
+				"Origin", tt.origin,
+				"Access-Control-Request-Method", http.MethodPost,
+				"Access-Control-Request-Headers", "Content-Type, X-Client-Id",
+			)
+			got := resp.header.Get("Access-Control-Allow-Origin")
+			if tt.allowed && !strings.EqualFold(got, tt.origin) {
+				t.Errorf("preflight allowed origin %q, want %q", got, tt.origin)
+			}
+			if !tt.allowed && got != "" {
+				t.Errorf("preflight allowed origin %q, want none", got)
+			}
+
+			// Both the room subscriptions and the multiplexed ones
+			// check the origin.
+			for _, path := range []string{"/subscribe/" + room.ID, "/subscribe"} {
+				c, wsResp, err := s.dial(t, path, "Origin", tt.origin)
+				if tt.allowed {
+					if err != nil {
+						t.Fatalf("subscribing to %s: %v", path, err)
+					}
+					c.conn.Close()
+					continue
+				}
+				if err == nil {
+					t.Fatalf("subscription to %s from a disallowed origin was accepted", path)
+				}
+				if wsResp == nil || wsResp.StatusCode != http.StatusForbidden {
+					t.Fatalf("got response %v from %s, want 403", wsResp, path)
+				}
+				var problem struct{ Code string }
+				if err := json.NewDecoder(wsResp.Body).Decode(&problem); err != nil || problem.Code != "origin_not_allowed" {
+					t.Errorf("got code %q (error %v) from %s, want origin_not_allowed", problem.Code, err, path)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscribeWithoutOrigin(t *testing.T) {
+	s := newTestServer(t, api.WithAllowedOrigins("https://app.example.com"))
+	room := s.createRoom(t, nil)
+
+	// Clients other than browsers send no Origin and are let through.
+	if _, _, err := s.dial(t, "/subscribe/"+room.ID); err != nil {
+		t.Fatalf("subscribing without an origin: %v", err)
+	}
+}
+
+func TestDefaultOrigins(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+
+	if _, _, err := s.dial(t, "/subscribe/"+room.ID, "Origin", "https://anywhere.test"); err != nil {
+		t.Fatalf("subscribing with the default origins: %v", err)
+	}
+	resp := s.do(t, http.MethodOptions, "/rooms", nil, "Origin", "https://anywhere.test", "Access-Control-Request-Method", http.MethodPost)
+	if got := resp.header.Get("Access-Control-Allow-Origin"); got != "https://anywhere.test" {
+		t.Errorf("preflight allowed origin %q, want https://anywhere.test", got)
+	}
+}
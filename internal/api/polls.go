@@ -0,0 +1,419 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	// maxPollQuestionLength and maxPollOptionLength are the sizes of the
+	// question and label columns.
+	maxPollQuestionLength = 255
+	maxPollOptionLength   = 100
+	minPollOptions        = 2
+	maxPollOptions        = 10
+)
+
+// pollOption is the JSON representation of an option of a poll with its
+// votes.
+type pollOption struct {
+	Label string `json:"label"`
+	Votes int64  `json:"votes"`
+}
+
+// poll is the JSON representation of a poll and its results. Votes pick an
+// option by its index in Options.
+type poll struct {
+	ID         string       `json:"id"`
+	Question   string       `json:"question"`
+	Options    []pollOption `json:"options"`
+	TotalVotes int64        `json:"total_votes"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ClosedAt   *time.Time   `json:"closed_at"`
+}
+
+// votes returns the vote count of every option of p, in order.
+func (p poll) votes() []int64 {
+	votes := make([]int64, len(p.Options))
+	for i, option := range p.Options {
+		votes[i] = option.Votes
+	}
+	return votes
+}
+
+// pollResults loads the options of stored and counts their votes.
+func (api *Handler) pollResults(ctx context.Context, stored pgstore.Poll) (poll, error) {
+	options, err := api.queries.GetPollOptions(ctx, stored.ID)
+	if err != nil {
+		return poll{}, err
+	}
+	counts, err := api.queries.GetPollVoteCounts(ctx, stored.ID)
+	if err != nil {
+		return poll{}, err
+	}
+
+	p := poll{
+		ID:        stored.ID.String(),
+		Question:  stored.Question,
+		Options:   make([]pollOption, len(options)),
+		CreatedAt: stored.CreatedAt,
+		ClosedAt:  stored.ClosedAt,
+	}
+	for i, option := range options {
+		p.Options[i].Label = option.Label
+	}
+	for _, count := range counts {
+		if int(count.Choice) < len(p.Options) {
+			p.Options[count.Choice].Votes = count.Votes
+		}
+		p.TotalVotes += count.Votes
+	}
+	return p, nil
+}
+
+// roomPoll loads the poll in the URL, answering with an error when it doesn't
+// exist in the room in the URL, or the room itself is gone.
+func (api *Handler) roomPoll(w http.ResponseWriter, r *http.Request) (pgstore.Poll, bool) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return pgstore.Poll{}, false
+	}
+
+	pollID, err := uuid.Parse(chi.URLParam(r, "poll_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_poll_id", "invalid poll id")
+		return pgstore.Poll{}, false
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return pgstore.Poll{}, false
+	}
+
+	stored, err := api.queries.GetPoll(r.Context(), pollID)
+	if err != nil {
+		api.writeStoreError(w, err, "poll_not_found")
+		return pgstore.Poll{}, false
+	}
+	if stored.RoomID != roomID {
+		writeError(w, http.StatusNotFound, "poll_not_found", "poll not found")
+		return pgstore.Poll{}, false
+	}
+
+	return stored, true
+}
+
+// handleCreatePoll opens a poll in a room, with between 2 and 10 options
+// everyone in the room can vote for until a host closes it.
+func (api *Handler) handleCreatePoll(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	body := struct {
+		Question string   `json:"question"`
+		Options  []string `json:"options"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+	var v validator
+	v.text("question", body.Question, maxPollQuestionLength)
+	v.check(len(body.Options) >= minPollOptions && len(body.Options) <= maxPollOptions, "options", "invalid_options", "a poll must have between 2 and 10 options")
+	for i, label := range body.Options {
+		v.text("options["+strconv.Itoa(i)+"]", label, maxPollOptionLength)
+	}
+	if !v.valid(w) {
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	id := api.ids.NewID()
+	createdAt := storedTime(api.now())
+	err = api.queries.InsertPollWithOptions(r.Context(), pgstore.InsertPollParams{
+		ID:        id,
+		RoomID:    roomID,
+		Question:  body.Question,
+		CreatedAt: createdAt,
+	}, body.Options)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindPollCreated,
+		RoomID: roomID.String(),
+		Value: events.PollCreated{
+			ID:       id.String(),
+			Question: body.Question,
+			Options:  body.Options,
+		},
+	})
+
+	p := poll{
+		ID:        id.String(),
+		Question:  body.Question,
+		Options:   make([]pollOption, len(body.Options)),
+		CreatedAt: createdAt,
+	}
+	for i, label := range body.Options {
+		p.Options[i].Label = label
+	}
+	data, err := json.Marshal(struct {
+		poll
+		Seq uint64 `json:"seq"`
+	}{p, seq})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	writeCreated(w, r, id, data)
+}
+
+// handleGetPolls lists the polls of a room with their results, newest first.
+func (api *Handler) handleGetPolls(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	stored, err := api.queries.GetRoomPolls(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	// The options of every poll of the room and their votes come at once.
+	options, err := api.queries.GetRoomPollOptions(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+	optionsOf := make(map[uuid.UUID][]pollOption, len(stored))
+	for _, option := range options {
+		optionsOf[option.PollID] = append(optionsOf[option.PollID], pollOption{
+			Label: option.Label,
+			Votes: option.Votes,
+		})
+	}
+
+	polls := make([]poll, 0, len(stored))
+	for _, s := range stored {
+		p := poll{
+			ID:        s.ID.String(),
+			Question:  s.Question,
+			Options:   optionsOf[s.ID],
+			CreatedAt: s.CreatedAt,
+			ClosedAt:  s.ClosedAt,
+		}
+		for _, option := range p.Options {
+			p.TotalVotes += option.Votes
+		}
+		polls = append(polls, p)
+	}
+
+	data, err := json.Marshal(polls)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleGetPoll answers with a poll and its results.
+func (api *Handler) handleGetPoll(w http.ResponseWriter, r *http.Request) {
+	stored, ok := api.roomPoll(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := api.pollResults(r.Context(), stored)
+	if err != nil {
+		api.writeStoreError(w, err, "poll_not_found")
+		return
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleVotePoll casts the client's vote for the option at the choice index
+// of the body. Each client has a single vote per poll: voting again moves it
+// to the new choice. Votes are counted until the poll is closed. The new
+// results are broadcast as poll_vote with the next flush of the reaction
+// counts, so a busy poll sends one event per interval rather than per vote.
+func (api *Handler) handleVotePoll(w http.ResponseWriter, r *http.Request) {
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	stored, ok := api.roomPoll(w, r)
+	if !ok {
+		return
+	}
+	if stored.ClosedAt != nil {
+		writeError(w, http.StatusConflict, "poll_closed", "the poll is closed")
+		return
+	}
+
+	body := struct {
+		Choice *int `json:"choice"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	options, err := api.queries.GetPollOptions(r.Context(), stored.ID)
+	if err != nil {
+		api.writeStoreError(w, err, "poll_not_found")
+		return
+	}
+	var v validator
+	v.check(body.Choice != nil && *body.Choice >= 0 && *body.Choice < len(options), "choice", "invalid_choice", "choice must be the index of an option of the poll")
+	if !v.valid(w) {
+		return
+	}
+
+	changed, err := api.queries.CastPollVote(r.Context(), pgstore.CastPollVoteParams{
+		PollID:    stored.ID,
+		ClientID:  clientID,
+		Choice:    int32(*body.Choice),
+		CreatedAt: api.now(),
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "poll_not_found")
+		return
+	}
+
+	// Nothing changes when the client votes for the same option again, or
+	// when the poll was closed in the meantime.
+	if changed == 0 {
+		stored, err = api.queries.GetPoll(r.Context(), stored.ID)
+		if err != nil {
+			api.writeStoreError(w, err, "poll_not_found")
+			return
+		}
+		if stored.ClosedAt != nil {
+			writeError(w, http.StatusConflict, "poll_closed", "the poll is closed")
+			return
+		}
+	}
+
+	p, err := api.pollResults(r.Context(), stored)
+	if err != nil {
+		api.writeStoreError(w, err, "poll_not_found")
+		return
+	}
+
+	if changed > 0 {
+		api.coalescePollResults(stored.RoomID.String(), events.PollResults{
+			ID:         p.ID,
+			Votes:      p.votes(),
+			TotalVotes: p.TotalVotes,
+		})
+	}
+
+	data, err := json.Marshal(struct {
+		poll
+		Choice int `json:"choice"`
+	}{p, *body.Choice})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleClosePoll stops a poll from counting votes and broadcasts its final
+// results.
+func (api *Handler) handleClosePoll(w http.ResponseWriter, r *http.Request) {
+	stored, ok := api.roomPoll(w, r)
+	if !ok {
+		return
+	}
+	if stored.ClosedAt != nil {
+		writeError(w, http.StatusConflict, "poll_closed", "the poll is closed")
+		return
+	}
+
+	closedAt := storedTime(api.now())
+	closed, err := api.queries.ClosePoll(r.Context(), pgstore.ClosePollParams{
+		ID:       stored.ID,
+		ClosedAt: &closedAt,
+	})
+	if errors.Is(err, ErrNotFound) {
+		// Closed by someone else in the meantime.
+		writeError(w, http.StatusConflict, "poll_closed", "the poll is closed")
+		return
+	}
+	if err != nil {
+		api.writeStoreError(w, err, "poll_not_found")
+		return
+	}
+
+	p, err := api.pollResults(r.Context(), closed)
+	if err != nil {
+		api.writeStoreError(w, err, "poll_not_found")
+		return
+	}
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindPollClosed,
+		RoomID: closed.RoomID.String(),
+		Value: events.PollResults{
+			ID:         p.ID,
+			Votes:      p.votes(),
+			TotalVotes: p.TotalVotes,
+		},
+	})
+
+	data, err := json.Marshal(struct {
+		poll
+		Seq uint64 `json:"seq"`
+	}{p, seq})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,149 @@
+package api_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// createPoll has the host of room open a poll with options and returns its
+// id.
+func (s *testServer) createPoll(t *testing.T, room testRoom, options ...string) string {
+	t.Helper()
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/polls", map[string]any{"question": "which one?", "options": options}, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, resp, http.StatusCreated)
+	return resp.object(t)["id"].(string)
+}
+
+// pollVotes returns the vote count of every option of a poll response.
+func pollVotes(t *testing.T, resp response) []int64 {
+	t.Helper()
+	var votes []int64
+	for _, option := range resp.object(t)["options"].([]any) {
+		votes = append(votes, int64(option.(map[string]any)["votes"].(float64)))
+	}
+	return votes
+}
+
+func TestPolls(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	c := s.subscribe(t, room.ID, "")
+
+	id := s.createPoll(t, room, "red", "green", "blue")
+	created := c.expect(events.KindPollCreated).Value.(events.PollCreated)
+	if created.ID != id || !slices.Equal(created.Options, []string{"red", "green", "blue"}) {
+		t.Errorf("got poll_created %+v, want poll %s", created, id)
+	}
+
+	path := "/rooms/" + room.ID + "/polls/" + id
+	vote := func(client string, choice int) response {
+		t.Helper()
+		resp := s.do(t, http.MethodPost, path+"/votes", map[string]any{"choice": choice}, "X-Client-Id", client)
+		expectStatus(t, resp, http.StatusOK)
+		return resp
+	}
+	vote("a", 0)
+	vote("b", 1)
+	vote("c", 1)
+	// Voting again moves the vote.
+	resp := vote("a", 1)
+	if got := pollVotes(t, resp); !slices.Equal(got, []int64{0, 3, 0}) {
+		t.Errorf("got votes %v, want [0 3 0]", got)
+	}
+	if choice := resp.object(t)["choice"]; choice != 1.0 {
+		t.Errorf("got choice %v, want 1", choice)
+	}
+
+	resp = s.do(t, http.MethodGet, path, nil)
+	expectStatus(t, resp, http.StatusOK)
+	if got := pollVotes(t, resp); !slices.Equal(got, []int64{0, 3, 0}) || resp.object(t)["total_votes"] != 3.0 {
+		t.Errorf("got %s, want 3 votes for green", resp.body)
+	}
+
+	expectStatus(t, s.do(t, http.MethodPost, path+"/close", nil), http.StatusUnauthorized)
+	resp = s.do(t, http.MethodPost, path+"/close", nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if resp.object(t)["closed_at"] == nil {
+		t.Errorf("got %s, want the poll closed", resp.body)
+	}
+
+	// The votes are coalesced, but their last results come before the
+	// final ones.
+	received := c.until(events.KindPollClosed)
+	var last events.PollResults
+	for _, e := range received {
+		if e.Kind == events.KindPollVote {
+			last = e.Value.(events.PollResults)
+		}
+	}
+	if last.ID != id || !slices.Equal(last.Votes, []int64{0, 3, 0}) {
+		t.Errorf("got last poll_vote %+v, want [0 3 0]", last)
+	}
+	final := received[len(received)-1].Value.(events.PollResults)
+	if final.ID != id || !slices.Equal(final.Votes, []int64{0, 3, 0}) || final.TotalVotes != 3 {
+		t.Errorf("got poll_closed %+v, want [0 3 0]", final)
+	}
+
+	for _, resp := range []response{
+		s.do(t, http.MethodPost, path+"/votes", map[string]any{"choice": 2}, "X-Client-Id", "d"),
+		s.do(t, http.MethodPost, path+"/close", nil, host...),
+	} {
+		expectStatus(t, resp, http.StatusConflict)
+		if code := resp.code(t); code != "poll_closed" {
+			t.Errorf("got code %q, want poll_closed", code)
+		}
+	}
+
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.ID+"/polls", nil)
+	expectStatus(t, resp, http.StatusOK)
+	if polls := resp.list(t); len(polls) != 1 || polls[0]["id"] != id || polls[0]["total_votes"] != 3.0 {
+		t.Errorf("got polls %s, want %s with 3 votes", resp.body, id)
+	}
+}
+
+func TestPollRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.createPoll(t, room, "yes", "no")
+	otherPoll := s.createPoll(t, other, "yes", "no")
+	client := []string{"X-Client-Id", "voter"}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		header []string
+		status int
+		code   string
+	}{
+		{"CreateNotHost", "/polls", map[string]any{"question": "which?", "options": []string{"a", "b"}}, nil, http.StatusUnauthorized, "unauthorized"},
+		{"CreateOneOption", "/polls", map[string]any{"question": "which?", "options": []string{"a"}}, host, http.StatusUnprocessableEntity, "validation_failed"},
+		{"CreateBlankQuestion", "/polls", map[string]any{"question": " ", "options": []string{"a", "b"}}, host, http.StatusUnprocessableEntity, "validation_failed"},
+		{"VoteWithoutClientID", "/polls/" + id + "/votes", map[string]any{"choice": 0}, nil, http.StatusForbidden, "missing_client_id"},
+		{"VoteOutOfRange", "/polls/" + id + "/votes", map[string]any{"choice": 2}, client, http.StatusUnprocessableEntity, "validation_failed"},
+		{"VoteWithoutChoice", "/polls/" + id + "/votes", map[string]any{}, client, http.StatusUnprocessableEntity, "validation_failed"},
+		{"VoteInvalidPollID", "/polls/nope/votes", map[string]any{"choice": 0}, client, http.StatusBadRequest, "invalid_poll_id"},
+		{"VoteOtherRoom", "/polls/" + otherPoll + "/votes", map[string]any{"choice": 0}, client, http.StatusNotFound, "poll_not_found"},
+		{"CloseOtherRoom", "/polls/" + otherPoll + "/close", nil, host, http.StatusNotFound, "poll_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+tt.path, tt.body, tt.header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/polls/"+id, nil)
+	if got := pollVotes(t, resp); !slices.Equal(got, []int64{0, 0}) {
+		t.Errorf("got votes %v, want none counted", got)
+	}
+}
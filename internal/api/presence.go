@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const defaultViewerCountInterval = 5 * time.Second
+
+// runViewerCounter tells the subscribers of every room how many they are each
+// viewerCountInterval until ctx is done.
+func (api *Handler) runViewerCounter(ctx context.Context) {
+	ticker := time.NewTicker(api.viewerCountInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			api.mu.Lock()
+			api.sendViewerCountsLocked()
+			api.mu.Unlock()
+		}
+	}
+}
+
+// sendViewerCountsLocked sends a viewer_count event to the subscribers of the
+// rooms whose count changed since the last one, so a busy room gets at most
+// one per interval. The count is of this instance's subscribers only, and is
+// not numbered: it replaces the previous one rather than adding to the
+// room's history. api.mu must be held.
+func (api *Handler) sendViewerCountsLocked() {
+	for roomID := range api.viewerCounts {
+		if _, ok := api.subscribers[roomID]; !ok {
+			delete(api.viewerCounts, roomID)
+		}
+	}
+
+	for roomID, subscribers := range api.subscribers {
+		count := len(subscribers)
+		if last, ok := api.viewerCounts[roomID]; ok && last == count {
+			continue
+		}
+		api.viewerCounts[roomID] = count
+
+		msg := events.Event{
+			Kind:   events.KindViewerCount,
+			RoomID: roomID,
+			Value:  events.ViewerCount{Count: count},
+		}
+		for sub := range subscribers {
+			api.sendLocked(sub, msg)
+		}
+	}
+}
+
+const (
+	// composingTimeout is how long a client counts as composing after its
+	// last signal; clients signal again every few seconds while they type.
+	composingTimeout = 5 * time.Second
+	// composingInterval debounces the composing events of a room.
+	composingInterval = time.Second
+)
+
+// handleStartComposing marks the client as composing a question in the room
+// until it sends one, stops with handleStopComposing or goes composingTimeout
+// without signalling again.
+func (api *Handler) handleStartComposing(w http.ResponseWriter, r *http.Request) {
+	api.setComposing(w, r, true)
+}
+
+// handleStopComposing marks the client as no longer composing.
+func (api *Handler) handleStopComposing(w http.ResponseWriter, r *http.Request) {
+	api.setComposing(w, r, false)
+}
+
+func (api *Handler) setComposing(w http.ResponseWriter, r *http.Request, composing bool) {
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	if composing {
+		api.startComposing(rawRoomID, clientID)
+	} else {
+		api.stopComposing(rawRoomID, clientID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *Handler) startComposing(roomID, clientID string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	clients, ok := api.composing[roomID]
+	if !ok {
+		clients = make(map[string]time.Time)
+		api.composing[roomID] = clients
+	}
+	clients[clientID] = api.now().Add(composingTimeout)
+}
+
+func (api *Handler) stopComposing(roomID, clientID string) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	delete(api.composing[roomID], clientID)
+}
+
+// runComposingCounter tells the subscribers of every room how many clients
+// are composing a question each composingInterval until ctx is done.
+func (api *Handler) runComposingCounter(ctx context.Context) {
+	ticker := time.NewTicker(composingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			api.mu.Lock()
+			api.sendComposingCountsLocked()
+			api.mu.Unlock()
+		}
+	}
+}
+
+// sendComposingCountsLocked forgets the clients that stopped signalling and
+// sends a composing event to the subscribers of the rooms whose count changed
+// since the last one. Like viewer_count, it is not numbered. api.mu must be
+// held.
+func (api *Handler) sendComposingCountsLocked() {
+	now := api.now()
+	for roomID, clients := range api.composing {
+		for clientID, until := range clients {
+			if !now.Before(until) {
+				delete(clients, clientID)
+			}
+		}
+		if len(clients) == 0 {
+			delete(api.composing, roomID)
+		}
+	}
+
+	rooms := make(map[string]struct{}, len(api.composing)+len(api.composingCounts))
+	for roomID := range api.composing {
+		rooms[roomID] = struct{}{}
+	}
+	for roomID := range api.composingCounts {
+		rooms[roomID] = struct{}{}
+	}
+
+	for roomID := range rooms {
+		count := len(api.composing[roomID])
+		if count == api.composingCounts[roomID] {
+			continue
+		}
+		if count == 0 {
+			delete(api.composingCounts, roomID)
+		} else {
+			api.composingCounts[roomID] = count
+		}
+
+		msg := events.Event{
+			Kind:   events.KindComposing,
+			RoomID: roomID,
+			Value:  events.Composing{Count: count},
+		}
+		for sub := range api.subscribers[roomID] {
+			api.sendLocked(sub, msg)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// expectCount skips events until one of kind carries count. The counts are
+// debounced, so intermediate ones may or may not be sent.
+func (c *wsClient) expectCount(kind string, count int) {
+	c.t.Helper()
+	for {
+		var got int
+		switch v := c.expect(kind).Value.(type) {
+		case events.ViewerCount:
+			got = v.Count
+		case events.Composing:
+			got = v.Count
+		default:
+			c.t.Fatalf("got %s %+v, want a count", kind, v)
+		}
+		if got == count {
+			return
+		}
+	}
+}
+
+func TestViewerCount(t *testing.T) {
+	s := newTestServer(t, api.WithViewerCountInterval(10*time.Millisecond))
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+
+	first := s.subscribe(t, room.ID, "")
+	first.expectCount(events.KindViewerCount, 1)
+
+	second := s.subscribe(t, room.ID, "")
+	s.subscribe(t, other.ID, "")
+	first.expectCount(events.KindViewerCount, 2)
+	second.expectCount(events.KindViewerCount, 2)
+
+	second.conn.Close()
+	s.waitSubscribers(t, room.ID, 1)
+	first.expectCount(events.KindViewerCount, 1)
+}
+
+func TestComposing(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	path := "/rooms/" + room.ID + "/composing"
+	c := s.subscribe(t, room.ID, "")
+
+	for _, client := range []string{"a", "b"} {
+		expectStatus(t, s.do(t, http.MethodPost, path, nil, "X-Client-Id", client), http.StatusNoContent)
+	}
+	c.expectCount(events.KindComposing, 2)
+
+	expectStatus(t, s.do(t, http.MethodDelete, path, nil, "X-Client-Id", "a"), http.StatusNoContent)
+	c.expectCount(events.KindComposing, 1)
+
+	// Sending the question stops composing it.
+	s.postMessage(t, room.ID, "question", "X-Client-Id", "b")
+	c.expectCount(events.KindComposing, 0)
+
+	// So does going quiet.
+	expectStatus(t, s.do(t, http.MethodPost, path, nil, "X-Client-Id", "a"), http.StatusNoContent)
+	c.expectCount(events.KindComposing, 1)
+	s.clock.Advance(5 * time.Second)
+	c.expectCount(events.KindComposing, 0)
+}
+
+func TestComposingRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	client := []string{"X-Client-Id", "a"}
+
+	tests := []struct {
+		name   string
+		path   string
+		header []string
+		status int
+		code   string
+	}{
+		{"WithoutClientID", "/rooms/" + room.ID + "/composing", nil, http.StatusForbidden, "missing_client_id"},
+		{"InvalidRoomID", "/rooms/nope/composing", client, http.StatusBadRequest, "invalid_room_id"},
+		{"UnknownRoom", "/rooms/" + uuid.NewString() + "/composing", client, http.StatusNotFound, "room_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodPost, tt.path, nil, tt.header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+)
+
+func TestQuestionQuota(t *testing.T) {
+	s := newTestServer(t, api.WithTrustedProxies(netip.MustParsePrefix("127.0.0.1/32")))
+	room := s.createRoom(t, map[string]any{"max_questions_per_participant": 1})
+	path := "/rooms/" + room.ID + "/messages"
+	ask := func(header ...string) response {
+		t.Helper()
+		return s.do(t, http.MethodPost, path, map[string]any{"message": "question"}, header...)
+	}
+
+	expectStatus(t, ask("X-Client-Id", "a", "X-Forwarded-For", "192.0.2.1"), http.StatusCreated)
+	// New client ids don't make a new participant.
+	for _, header := range [][]string{
+		{"X-Client-Id", "a", "X-Forwarded-For", "192.0.2.1"},
+		{"X-Client-Id", "b", "X-Forwarded-For", "192.0.2.1"},
+		{"X-Client-Id", "c", "X-Forwarded-For", "192.0.2.1"},
+		{"X-Forwarded-For", "192.0.2.1"},
+	} {
+		resp := ask(header...)
+		expectStatus(t, resp, http.StatusTooManyRequests)
+		if code := resp.code(t); code != "question_quota_reached" {
+			t.Errorf("got code %q, want question_quota_reached", code)
+		}
+		if got := resp.object(t)["max_questions_per_participant"]; got != 1.0 {
+			t.Errorf("got max_questions_per_participant %v, want 1", got)
+		}
+	}
+
+	expectStatus(t, ask("X-Forwarded-For", "192.0.2.2"), http.StatusCreated)
+	// Hosts aren't held to the quota.
+	for range 2 {
+		expectStatus(t, ask("Authorization", "Bearer "+room.HostToken, "X-Forwarded-For", "192.0.2.1"), http.StatusCreated)
+	}
+	if got := len(s.messages(t, room.ID)); got != 4 {
+		t.Errorf("got %d messages, want 4", got)
+	}
+}
+
+func TestQuestionQuotaPerRoom(t *testing.T) {
+	s := newTestServer(t)
+	first := s.createRoom(t, map[string]any{"max_questions_per_participant": 1})
+	second := s.createRoom(t, map[string]any{"max_questions_per_participant": 1})
+	unlimited := s.createRoom(t, nil)
+
+	s.postMessage(t, first.ID, "question")
+	s.postMessage(t, second.ID, "question")
+	for range 3 {
+		s.postMessage(t, unlimited.ID, "question")
+	}
+	expectStatus(t, s.do(t, http.MethodPost, "/rooms/"+first.ID+"/messages", map[string]any{"message": "again"}), http.StatusTooManyRequests)
+}
@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// TestConcurrentRoomActivity subscribes, broadcasts, creates messages and
+// unsubscribes concurrently against one room. It is meant to be run with
+// -race, which checks the subscriber registry and the broadcast path.
+func TestConcurrentRoomActivity(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	messageID := s.postMessage(t, room.ID, "first question")
+
+	const (
+		subscribers = 16
+		posters     = 16
+		rounds      = 5
+	)
+	send := func(method, path string, body any, header ...string) error {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(method, s.URL+"/api/v1"+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := s.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("%s %s: status %d", method, path, resp.StatusCode)
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, (subscribers+posters)*rounds)
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + "/subscribe/" + room.ID
+	for range subscribers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						break
+					}
+				}
+				conn.Close()
+			}
+		}()
+	}
+	for i := range posters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clientID := fmt.Sprintf("client-%d", i)
+			for j := range rounds {
+				text := fmt.Sprintf("question %d from %s", j, clientID)
+				if err := send(http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": text}); err != nil {
+					errs <- err
+				}
+				if err := send(http.MethodPatch, "/rooms/"+room.ID+"/messages/"+messageID+"/react", nil, "X-Client-Id", clientID); err != nil {
+					errs <- err
+				}
+				announcement := map[string]any{"body": text}
+				if err := send(http.MethodPost, "/rooms/"+room.ID+"/announcements", announcement, "Authorization", "Bearer "+room.HostToken); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// Every subscription that ended left the room, and the room still
+	// delivers to new ones.
+	s.waitSubscribers(t, room.ID, 0)
+	c := s.subscribe(t, room.ID, "")
+	lastID := s.postMessage(t, room.ID, "last question")
+	created := c.expect(events.KindMessageCreated)
+	if got := created.Value.(events.MessageCreated).ID; got != lastID {
+		t.Errorf("got message_created for %s, want %s", got, lastID)
+	}
+
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+messageID, nil)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["reaction_count"]; got != float64(posters) {
+		t.Errorf("got reaction_count %v, want %d", got, posters)
+	}
+}
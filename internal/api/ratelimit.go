@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxRateLimiterKeys is how many buckets are kept in memory before the full
+// ones are dropped.
+const maxRateLimiterKeys = 10_000
+
+// RateLimit lets a client make Requests requests every Per, in bursts of up to
+// Requests. A zero RateLimit doesn't limit anything.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// RateLimiter keeps the token buckets limiting how often clients may call the
+// API. The default keeps them in memory, which limits each instance on its
+// own; redislimit shares them between instances.
+type RateLimiter interface {
+	// Take takes a token from the bucket of key, which holds up to
+	// limit.Requests tokens and refills over limit.Per. When the bucket is
+	// empty it reports false and how long until a token is available.
+	Take(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error)
+}
+
+// defaultRateLimits are the limits of the POST endpoints, by method and route
+// relative to the API root. The routes not listed share the one of "POST".
+var defaultRateLimits = map[string]RateLimit{
+	"POST":                           {Requests: 60, Per: time.Minute},
+	"POST /rooms":                    {Requests: 10, Per: 10 * time.Minute},
+	"POST /rooms/{room_id}/messages": {Requests: 20, Per: time.Minute},
+	// Clients signal every few seconds while composing a question.
+	"POST /rooms/{room_id}/composing":                  {Requests: 60, Per: time.Minute},
+	"POST /rooms/{room_id}/messages/{message_id}/flag": {Requests: 10, Per: time.Minute},
+}
+
+// rateLimit rejects the POST requests of clients that exhausted the limit of
+// the route with 429, telling them when to retry. routes is the router the
+// middleware is used on, which tells which route a request is for before it
+// is routed. Clients are told apart by IP address, see clientIP; the store
+// being unreachable lets requests through rather than taking the API down
+// with it.
+func (api *Handler) rateLimit(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rctx := chi.NewRouteContext()
+			if !routes.Match(rctx, r.Method, chi.RouteContext(r.Context()).RoutePath) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			route := r.Method + " " + strings.TrimSuffix(rctx.RoutePattern(), "/")
+			limit, ok := api.rateLimits[route]
+			if !ok {
+				limit = api.rateLimits[r.Method]
+			}
+			if limit.Requests <= 0 || limit.Per <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, retryAfter, err := api.rateLimiter.Take(r.Context(), api.clientIP(r)+" "+route, limit)
+			if err != nil {
+				api.logger.Warn("rate limiter unavailable", "error", err)
+				allowed = true
+			}
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+				writeError(w, http.StatusTooManyRequests, "rate_limited", "too many requests, try again later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the IP address r comes from. Requests of the trusted
+// proxies are from the last address they forwarded for, read from the right
+// of X-Forwarded-For: clients can put anything on its left.
+func (api *Handler) clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if !api.trustedProxy(ip) {
+		return ip
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !api.trustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// trustedProxy reports whether ip is the address of a trusted proxy.
+func (api *Handler) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range api.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryRateLimiter is the RateLimiter keeping the buckets in memory.
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	clock   Clock
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	// at is when tokens was last updated.
+	at time.Time
+	// full is when the bucket will be full again, after which it can be
+	// dropped.
+	full time.Time
+}
+
+func newMemoryRateLimiter(clock Clock) *memoryRateLimiter {
+	return &memoryRateLimiter{clock: clock, buckets: make(map[string]*tokenBucket)}
+}
+
+func (l *memoryRateLimiter) Take(_ context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	capacity := float64(limit.Requests)
+	perToken := limit.Per / time.Duration(limit.Requests)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimiterKeys {
+			l.dropFull(now)
+		}
+		b = &tokenBucket{tokens: capacity, at: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+float64(now.Sub(b.at))/float64(perToken))
+	b.at = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(perToken)), nil
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((capacity - b.tokens) * float64(perToken)))
+	return true, 0, nil
+}
+
+func (l *memoryRateLimiter) dropFull(now time.Time) {
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	maxReactionBatchSize         = 100
+	defaultReactionFlushInterval = 500 * time.Millisecond
+)
+
+// handleReactionBatch applies the reactions a client queued while offline.
+// The whole batch fails when any of its ids is not a message of the room. The
+// new counts are broadcast with the coalesced reaction_counts_updated rather
+// than in an event of their own: many clients replaying their batches as they
+// reconnect at once would flood the room like single reactions do.
+func (api *Handler) handleReactionBatch(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	var body struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+
+	if len(body.Add)+len(body.Remove) == 0 {
+		writeError(w, http.StatusBadRequest, "empty_batch", "add or remove at least one reaction")
+		return
+	}
+	if len(body.Add)+len(body.Remove) > maxReactionBatchSize {
+		writeError(w, http.StatusBadRequest, "batch_too_large", "a batch holds at most 100 reactions")
+		return
+	}
+
+	var invalid []string
+	parseIDs := func(raw []string) []uuid.UUID {
+		ids := make([]uuid.UUID, 0, len(raw))
+		for _, s := range raw {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				invalid = append(invalid, s)
+				continue
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	}
+	add, remove := parseIDs(body.Add), parseIDs(body.Remove)
+	if len(invalid) > 0 {
+		writeUnknownMessages(w, invalid)
+		return
+	}
+
+	if _, ok := api.openRoom(w, r, roomID); !ok {
+		return
+	}
+
+	counts, err := api.queries.ApplyReactionBatch(r.Context(), pgstore.ApplyReactionBatchParams{
+		RoomID:   roomID,
+		ClientID: clientID,
+		Add:      add,
+		Remove:   remove,
+	})
+	if err != nil {
+		var unknownErr *pgstore.UnknownMessagesError
+		if errors.As(err, &unknownErr) {
+			unknown := make([]string, 0, len(unknownErr.IDs))
+			for _, id := range unknownErr.IDs {
+				unknown = append(unknown, id.String())
+			}
+			writeUnknownMessages(w, unknown)
+			return
+		}
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	reactions := make([]events.MessageReaction, 0, len(counts))
+	for _, c := range counts {
+		reactions = append(reactions, events.MessageReaction{ID: c.ID.String(), Count: c.ReactionCount, Version: c.Version})
+	}
+
+	// The store only takes reactions to messages everyone can see.
+	api.coalesceReactionCounts(rawRoomID, events.ScopePublic, counts)
+
+	data, err := json.Marshal(map[string]any{
+		"messages": reactions,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleReactToMessage adds the client's reaction to a message. Reacting
+// twice counts once.
+func (api *Handler) handleReactToMessage(w http.ResponseWriter, r *http.Request) {
+	api.setReaction(w, r, true)
+}
+
+// handleRemoveReactionFromMessage takes the client's reaction back. Removing
+// a reaction the client didn't make leaves the count alone.
+func (api *Handler) handleRemoveReactionFromMessage(w http.ResponseWriter, r *http.Request) {
+	api.setReaction(w, r, false)
+}
+
+// setReaction adds or removes the client's reaction to the message in the URL
+// and answers with its new count. Counts are only ever changed by increments
+// in the store, paired with the client's reaction row, so concurrent
+// reactions can't be lost and a count can't go negative.
+func (api *Handler) setReaction(w http.ResponseWriter, r *http.Request, react bool) {
+	clientID := authFrom(r.Context()).ClientID
+	if clientID == "" {
+		writeError(w, http.StatusForbidden, "missing_client_id", "missing client id")
+		return
+	}
+
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := api.openRoom(w, r, message.RoomID); !ok {
+		return
+	}
+
+	batch := pgstore.ApplyReactionBatchParams{
+		RoomID:   message.RoomID,
+		ClientID: clientID,
+	}
+	if react {
+		batch.Add = []uuid.UUID{message.ID}
+	} else {
+		batch.Remove = []uuid.UUID{message.ID}
+	}
+
+	counts, err := api.queries.ApplyReactionBatch(r.Context(), batch)
+	if err != nil {
+		// The message was pruned since it was read, or waits for approval.
+		var unknownErr *pgstore.UnknownMessagesError
+		if errors.As(err, &unknownErr) {
+			writeError(w, http.StatusNotFound, "message_not_found", "message not found")
+			return
+		}
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+	if len(counts) != 1 {
+		writeError(w, http.StatusNotFound, "message_not_found", "message not found")
+		return
+	}
+
+	api.coalesceReactionCounts(message.RoomID.String(), messageScope(message), counts)
+
+	data, err := json.Marshal(events.MessageReaction{
+		ID:      counts[0].ID.String(),
+		Count:   counts[0].ReactionCount,
+		Version: counts[0].Version,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// writeUnknownMessages rejects a reaction batch naming messages of other
+// rooms, or none, listing their ids as message_ids.
+func writeUnknownMessages(w http.ResponseWriter, ids []string) {
+	writeProblem(w, http.StatusUnprocessableEntity, "unknown_messages", "some messages don't exist in this room, no reaction was applied", map[string]any{
+		"message_ids": ids,
+	})
+}
+
+// roomCounts are the counts of a room that changed since they were last
+// broadcast, kept until the next flush.
+type roomCounts struct {
+	// reactions are upvote counts by message id. Those of messages waiting
+	// for approval are in moderatorReactions, since only moderators may hear
+	// about them.
+	reactions          map[string]int64
+	moderatorReactions map[string]int64
+	// emoji are emoji reaction counts by message id and kind. Those of
+	// messages waiting for approval are in moderatorEmoji, since only
+	// moderators may hear about them.
+	emoji          map[string]map[string]int64
+	moderatorEmoji map[string]map[string]int64
+	// polls are the results of the polls voted in, by poll id.
+	polls map[string]events.PollResults
+}
+
+// pendingCountsLocked returns the counts of roomID waiting for the next flush.
+// api.mu must be held.
+func (api *Handler) pendingCountsLocked(roomID string) *roomCounts {
+	pending, ok := api.pendingCounts[roomID]
+	if !ok {
+		pending = &roomCounts{
+			reactions:          make(map[string]int64),
+			moderatorReactions: make(map[string]int64),
+			emoji:              make(map[string]map[string]int64),
+			moderatorEmoji:     make(map[string]map[string]int64),
+			polls:              make(map[string]events.PollResults),
+		}
+		api.pendingCounts[roomID] = pending
+	}
+	return pending
+}
+
+// coalesceReactionCounts records new reaction counts to be broadcast with the
+// next flush instead of right away: in busy rooms reactions change many times
+// a second and clients only need the latest count. The counts of messages in
+// the moderator scope are only broadcast to moderators.
+func (api *Handler) coalesceReactionCounts(roomID, scope string, counts []pgstore.GetReactionCountsRow) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	pending := api.pendingCountsLocked(roomID)
+	reactions := pending.reactions
+	if scope == events.ScopeModerator {
+		reactions = pending.moderatorReactions
+	}
+	for _, c := range counts {
+		reactions[c.ID.String()] = c.ReactionCount
+	}
+}
+
+// coalesceEmojiReactions records the new emoji reaction counts of message to
+// be broadcast with the next flush, like coalesceReactionCounts.
+func (api *Handler) coalesceEmojiReactions(message pgstore.Message, counts map[string]int64) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	pending := api.pendingCountsLocked(message.RoomID.String())
+	if messageScope(message) == events.ScopeModerator {
+		pending.moderatorEmoji[message.ID.String()] = counts
+	} else {
+		pending.emoji[message.ID.String()] = counts
+	}
+}
+
+// coalescePollResults records the new results of a poll to be broadcast as
+// poll_vote with the next flush, like coalesceReactionCounts.
+func (api *Handler) coalescePollResults(roomID string, results events.PollResults) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.pendingCountsLocked(roomID).polls[results.ID] = results
+}
+
+// runReactionFlusher broadcasts the coalesced counts of every room each
+// reactionFlushInterval until ctx is done.
+func (api *Handler) runReactionFlusher(ctx context.Context) {
+	ticker := time.NewTicker(api.reactionFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			api.mu.Lock()
+			for roomID := range api.pendingCounts {
+				api.flushCountsLocked(roomID)
+			}
+			api.mu.Unlock()
+		}
+	}
+}
+
+// flushCountsLocked broadcasts the pending counts of roomID as one
+// reaction_counts_updated event, plus one for moderators when messages
+// waiting for approval got reactions, and one poll_vote event per poll
+// voted in. api.mu must be held.
+func (api *Handler) flushCountsLocked(roomID string) {
+	pending, ok := api.pendingCounts[roomID]
+	if !ok {
+		return
+	}
+	delete(api.pendingCounts, roomID)
+
+	// The counts coalesce many requests, so they belong to no trace.
+	if len(pending.reactions) > 0 || len(pending.emoji) > 0 {
+		value := events.ReactionCountsUpdated{Counts: pending.reactions}
+		if len(pending.emoji) > 0 {
+			value.Emoji = pending.emoji
+		}
+		api.broadcastLocked(context.Background(), events.Event{
+			Kind:   events.KindReactionCountsUpdated,
+			RoomID: roomID,
+			Value:  value,
+		})
+	}
+	if len(pending.moderatorReactions) > 0 || len(pending.moderatorEmoji) > 0 {
+		value := events.ReactionCountsUpdated{Counts: pending.moderatorReactions}
+		if len(pending.moderatorEmoji) > 0 {
+			value.Emoji = pending.moderatorEmoji
+		}
+		api.broadcastLocked(context.Background(), events.Event{
+			Kind:   events.KindReactionCountsUpdated,
+			RoomID: roomID,
+			Scope:  events.ScopeModerator,
+			Value:  value,
+		})
+	}
+	for _, results := range pending.polls {
+		api.broadcastLocked(context.Background(), events.Event{
+			Kind:   events.KindPollVote,
+			RoomID: roomID,
+			Value:  results,
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// batchCounts returns the reaction counts of a batch response by message id.
+func batchCounts(t *testing.T, resp response) map[string]int64 {
+	t.Helper()
+	var body struct {
+		Messages []events.MessageReaction `json:"messages"`
+	}
+	if err := json.Unmarshal(resp.body, &body); err != nil {
+		t.Fatalf("decoding %s: %v", resp.body, err)
+	}
+	counts := make(map[string]int64, len(body.Messages))
+	for _, m := range body.Messages {
+		counts[m.ID] = m.Count
+	}
+	return counts
+}
+
+func TestReactionBatch(t *testing.T) {
+	s := newTestServer(t, api.WithReactionFlushInterval(10*time.Millisecond))
+	room := s.createRoom(t, nil)
+	first := s.postMessage(t, room.ID, "first")
+	second := s.postMessage(t, room.ID, "second")
+	path := "/rooms/" + room.ID + "/reactions/batch"
+	c := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPost, path, map[string]any{"add": []string{first, second}}, "X-Client-Id", "offline")
+	expectStatus(t, resp, http.StatusOK)
+	if got := batchCounts(t, resp); got[first] != 1 || got[second] != 1 {
+		t.Errorf("got counts %v, want both at 1", got)
+	}
+	received := c.until(events.KindReactionCountsUpdated)
+	for _, e := range received {
+		if e.Kind == events.KindReactionsBatchUpdated {
+			t.Errorf("got %s, want the counts in %s only", e.Kind, events.KindReactionCountsUpdated)
+		}
+	}
+	updated := received[len(received)-1].Value.(events.ReactionCountsUpdated)
+	if updated.Counts[first] != 1 || updated.Counts[second] != 1 {
+		t.Errorf("broadcast counts %v, want both at 1", updated.Counts)
+	}
+
+	// Replaying a batch doesn't count a client twice.
+	resp = s.do(t, http.MethodPost, path, map[string]any{"add": []string{first}, "remove": []string{second}}, "X-Client-Id", "offline")
+	expectStatus(t, resp, http.StatusOK)
+	if got := batchCounts(t, resp); got[first] != 1 || got[second] != 0 {
+		t.Errorf("got counts %v, want %s at 1 and %s at 0", got, first, second)
+	}
+
+	resp = s.do(t, http.MethodPost, path, map[string]any{"add": []string{first}}, "X-Client-Id", "other")
+	expectStatus(t, resp, http.StatusOK)
+	if got := batchCounts(t, resp); got[first] != 2 {
+		t.Errorf("got counts %v, want %s at 2", got, first)
+	}
+}
+
+func TestReactionBatchRejected(t *testing.T) {
+	tooMany := make([]string, 101)
+	for i := range tooMany {
+		tooMany[i] = "00000000-0000-0000-0000-000000000002"
+	}
+	tests := []struct {
+		name   string
+		body   func(message, elsewhere string) any
+		header []string
+		status int
+		code   string
+	}{
+		{"NoClientID", func(m, _ string) any { return map[string]any{"add": []string{m}} }, nil, http.StatusForbidden, "missing_client_id"},
+		{"InvalidJSON", func(string, string) any { return "{" }, []string{"X-Client-Id", "c"}, http.StatusBadRequest, "invalid_json"},
+		{"Empty", func(string, string) any { return map[string]any{"add": []string{}} }, []string{"X-Client-Id", "c"}, http.StatusBadRequest, "empty_batch"},
+		{"TooLarge", func(string, string) any { return map[string]any{"add": tooMany} }, []string{"X-Client-Id", "c"}, http.StatusBadRequest, "batch_too_large"},
+		{"InvalidID", func(m, _ string) any { return map[string]any{"add": []string{m, "nope"}} }, []string{"X-Client-Id", "c"}, http.StatusUnprocessableEntity, "unknown_messages"},
+		{"OtherRoom", func(m, e string) any { return map[string]any{"add": []string{m}, "remove": []string{e}} }, []string{"X-Client-Id", "c"}, http.StatusUnprocessableEntity, "unknown_messages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, nil)
+			other := s.createRoom(t, nil)
+			message := s.postMessage(t, room.ID, "question")
+			elsewhere := s.postMessage(t, other.ID, "elsewhere")
+
+			resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/reactions/batch", tt.body(message, elsewhere), tt.header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+			got := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+message, nil).object(t)["reaction_count"]
+			if got != float64(0) {
+				t.Errorf("got reaction_count %v, want the batch not applied", got)
+			}
+		})
+	}
+}
+
+func TestReactionBatchUnknownIDs(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	message := s.postMessage(t, room.ID, "question")
+	elsewhere := s.postMessage(t, other.ID, "elsewhere")
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/reactions/batch", map[string]any{"add": []string{message, elsewhere}}, "X-Client-Id", "c")
+	expectStatus(t, resp, http.StatusUnprocessableEntity)
+	ids, _ := resp.object(t)["message_ids"].([]any)
+	if len(ids) != 1 || ids[0] != elsewhere {
+		t.Errorf("got message_ids %v, want [%s]", ids, elsewhere)
+	}
+}
+
+func TestReactionBatchClosedRoom(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	message := s.postMessage(t, room.ID, "question")
+	expectStatus(t, s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/close", nil, "Authorization", "Bearer "+room.HostToken), http.StatusOK)
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/reactions/batch", map[string]any{"add": []string{message}}, "X-Client-Id", "c")
+	expectStatus(t, resp, http.StatusConflict)
+	if code := resp.code(t); code != "room_closed" {
+		t.Errorf("got code %q, want room_closed", code)
+	}
+}
+
+// countUpdates reads the events of c until done returns true for a
+// reaction_counts_updated event, returning how many of those were received.
+func countUpdates(c *wsClient, done func(events.ReactionCountsUpdated) bool) int {
+	c.t.Helper()
+	n := 0
+	for {
+		event := c.next()
+		if event.Kind != events.KindReactionCountsUpdated {
+			continue
+		}
+		n++
+		if done(event.Value.(events.ReactionCountsUpdated)) {
+			return n
+		}
+	}
+}
+
+func TestReactionCoalescing(t *testing.T) {
+	const (
+		reactions = 200
+		interval  = 100 * time.Millisecond
+	)
+	s := newTestServer(t, api.WithReactionFlushInterval(interval))
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+	c := s.subscribe(t, room.ID, "")
+
+	start := time.Now()
+	for i := range reactions {
+		resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+id+"/react", nil, "X-Client-Id", "client "+strconv.Itoa(i))
+		expectStatus(t, resp, http.StatusOK)
+	}
+	var last int64
+	n := countUpdates(c, func(updated events.ReactionCountsUpdated) bool {
+		count := updated.Counts[id]
+		if count < last {
+			t.Errorf("count went from %d back to %d", last, count)
+		}
+		last = count
+		return count == reactions
+	})
+
+	// At most one event per flush, however many reactions happened meanwhile.
+	if limit := int(time.Since(start)/interval) + 1; n > limit {
+		t.Errorf("got %d reaction_counts_updated events for %d reactions in %v, want at most %d", n, reactions, time.Since(start), limit)
+	}
+}
+
+func TestReactionCoalescingOrder(t *testing.T) {
+	// Counts are never flushed by the timer during the test, so the message
+	// events can't be waiting for it.
+	s := newTestServer(t, api.WithReactionFlushInterval(time.Hour))
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+	path := "/rooms/" + room.ID + "/messages/" + id
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	c := s.subscribe(t, room.ID, "")
+
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/react", nil, "X-Client-Id", "c"), http.StatusOK)
+	s.postMessage(t, room.ID, "another question")
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", nil, host...), http.StatusOK)
+	expectStatus(t, s.do(t, http.MethodDelete, path, nil, host...), http.StatusNoContent)
+
+	// The pending counts are sent right before the next event, which never
+	// overtakes them.
+	want := []string{events.KindReactionCountsUpdated, events.KindMessageCreated, events.KindMessageAnswered, events.KindMessageDeleted}
+	for _, kind := range want {
+		event := c.next()
+		if event.Kind != kind {
+			t.Fatalf("got %s event, want %s", event.Kind, kind)
+		}
+		if updated, ok := event.Value.(events.ReactionCountsUpdated); ok && updated.Counts[id] != 1 {
+			t.Errorf("got counts %v, want %s at 1", updated.Counts, id)
+		}
+	}
+}
+
+func TestEmojiReactionCoalescing(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	s := newTestServer(t, api.WithReactionFlushInterval(interval))
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "question")
+	path := "/rooms/" + room.ID + "/messages/" + id
+	c := s.subscribe(t, room.ID, "")
+
+	start := time.Now()
+	for _, client := range []string{"a", "b", "c"} {
+		expectStatus(t, s.do(t, http.MethodPut, path+"/reactions/"+url.PathEscape("🎉"), nil, "X-Client-Id", client), http.StatusOK)
+	}
+	expectStatus(t, s.do(t, http.MethodPut, path+"/reactions/"+url.PathEscape("🤔"), nil, "X-Client-Id", "a"), http.StatusOK)
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/react", nil, "X-Client-Id", "a"), http.StatusOK)
+
+	var updated events.ReactionCountsUpdated
+	n := countUpdates(c, func(u events.ReactionCountsUpdated) bool {
+		updated = u
+		return u.Emoji[id]["🎉"] == 3 && u.Emoji[id]["🤔"] == 1
+	})
+	if limit := int(time.Since(start)/interval) + 1; n > limit {
+		t.Errorf("got %d reaction_counts_updated events in %v, want at most %d", n, time.Since(start), limit)
+	}
+	if n == 1 && updated.Counts[id] != 1 {
+		t.Errorf("got counts %v, want the upvote flushed with the emoji", updated.Counts)
+	}
+}
+
+func TestReactionsToPendingMessages(t *testing.T) {
+	s := newTestServer(t, api.WithReactionFlushInterval(10*time.Millisecond))
+	room := s.createRoom(t, map[string]any{"require_approval": true, "duplicate_threshold": 0.5})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	pending := s.postMessage(t, room.ID, "How do you test websocket servers?")
+	visible := s.postMessage(t, room.ID, "visible question", host...)
+	public := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/reactions/batch", map[string]any{"add": []string{pending}}, "X-Client-Id", "c")
+	expectStatus(t, resp, http.StatusUnprocessableEntity)
+	if code := resp.code(t); code != "unknown_messages" {
+		t.Errorf("got code %q, want unknown_messages", code)
+	}
+	for _, header := range [][]string{{"X-Client-Id", "c"}, append([]string{"X-Client-Id", "c"}, host...)} {
+		resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+pending+"/react", nil, header...)
+		expectStatus(t, resp, http.StatusNotFound)
+	}
+	// A duplicate of a pending question is asked rather than upvoting it.
+	resp = s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages?on_duplicate=upvote", map[string]any{"message": "how do you test websocket servers"}, "X-Client-Id", "c")
+	expectStatus(t, resp, http.StatusCreated)
+
+	expectStatus(t, s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/messages/"+visible+"/react", nil, "X-Client-Id", "c"), http.StatusOK)
+	updated := public.expect(events.KindReactionCountsUpdated).Value.(events.ReactionCountsUpdated)
+	if _, ok := updated.Counts[pending]; ok || updated.Counts[visible] != 1 {
+		t.Errorf("public got counts %v, want only %s at 1", updated.Counts, visible)
+	}
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/"+pending, nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["reaction_count"]; got != 0.0 {
+		t.Errorf("got reaction_count %v, want the pending message unreacted", got)
+	}
+}
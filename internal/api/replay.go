@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// eventsExpired is the error code, and the close reason, given to clients
+// resuming from events that aren't kept anymore. They must reload the room.
+const eventsExpired = "events_expired"
+
+// resumePoint reads where a reconnecting client left off, from the
+// Last-Event-ID header of SSE clients or the last_event_id parameter. It is
+// either the id of the last message the client got, in which case the
+// messages created after it are read from the store and returned, or the seq
+// of the last event, in which case the events after it are replayed from the
+// recent events of the room once the client is registered, and returned as
+// resumeSeq. It responds itself and reports false when the client can't be
+// resumed.
+func (api *Handler) resumePoint(w http.ResponseWriter, r *http.Request, roomID uuid.UUID, rawRoomID string) (replay []events.Event, resumeSeq *uint64, ok bool) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID == "" {
+		return nil, nil, true
+	}
+
+	if seq, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+		api.mu.Lock()
+		_, ok := api.recentEventsLocked(rawRoomID, seq)
+		api.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusGone, eventsExpired, "the events after last_event_id are gone, reload the room")
+			return nil, nil, false
+		}
+		return nil, &seq, true
+	}
+
+	if _, err := uuid.Parse(lastEventID); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_last_event_id", "invalid last_event_id")
+		return nil, nil, false
+	}
+	replay, err := api.missedMessages(r.Context(), roomID, rawRoomID, lastEventID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return nil, nil, false
+	}
+	return replay, nil, true
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// reply is the JSON representation of a reply to a message.
+type reply struct {
+	ID        string    `json:"id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// messageReplies returns the replies to messageID, oldest first.
+func (api *Handler) messageReplies(ctx context.Context, messageID uuid.UUID) ([]reply, error) {
+	stored, err := api.queries.GetMessageReplies(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	replies := make([]reply, 0, len(stored))
+	for _, r := range stored {
+		replies = append(replies, reply{
+			ID:        r.ID.String(),
+			Author:    r.Author,
+			Body:      r.Body,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	return replies, nil
+}
+
+// handleCreateMessageReply adds a written answer of a host to a message. A
+// message can have any number of them; unlike the answer set with
+// handleMarkMessageAsAnswered, replies don't mark it as answered.
+func (api *Handler) handleCreateMessageReply(w http.ResponseWriter, r *http.Request) {
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	if message.Pending {
+		writeError(w, http.StatusConflict, "message_pending", "approve the message before replying to it")
+		return
+	}
+
+	body := struct {
+		Body string `json:"body"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", "invalid json")
+		return
+	}
+	var v validator
+	v.text("body", body.Body, maxAnswerLength)
+	if !v.valid(w) {
+		return
+	}
+
+	id := api.ids.NewID()
+	author := authFrom(r.Context()).actor()
+	createdAt := storedTime(api.now())
+	err := api.queries.InsertMessageReply(r.Context(), pgstore.InsertMessageReplyParams{
+		ID:        id,
+		MessageID: message.ID,
+		Author:    author,
+		Body:      body.Body,
+		CreatedAt: createdAt,
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindReplyCreated,
+		RoomID: message.RoomID.String(),
+		Value: events.ReplyCreated{
+			ID:        id.String(),
+			MessageID: message.ID.String(),
+			Author:    author,
+			Body:      body.Body,
+		},
+	})
+
+	data, err := json.Marshal(map[string]any{
+		"id":         id.String(),
+		"message_id": message.ID.String(),
+		"author":     author,
+		"body":       body.Body,
+		"created_at": createdAt,
+		"seq":        seq,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	writeCreated(w, r, id, data)
+}
+
+// handleGetMessageReplies lists the replies to a message, oldest first.
+func (api *Handler) handleGetMessageReplies(w http.ResponseWriter, r *http.Request) {
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+
+	replies, err := api.messageReplies(r.Context(), message.ID)
+	if err != nil {
+		api.writeStoreError(w, err, "message_not_found")
+		return
+	}
+
+	data, err := json.Marshal(replies)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleGetMessageReply returns one of the replies to a message.
+func (api *Handler) handleGetMessageReply(w http.ResponseWriter, r *http.Request) {
+	message, ok := api.roomMessage(w, r)
+	if !ok {
+		return
+	}
+	replyID, err := uuid.Parse(chi.URLParam(r, "reply_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_reply_id", "invalid reply id")
+		return
+	}
+
+	stored, err := api.queries.GetMessageReply(r.Context(), replyID)
+	if err != nil {
+		api.writeStoreError(w, err, "reply_not_found")
+		return
+	}
+	if stored.MessageID != message.ID {
+		writeError(w, http.StatusNotFound, "reply_not_found", "reply not found")
+		return
+	}
+
+	data, err := json.Marshal(reply{
+		ID:        stored.ID.String(),
+		Author:    stored.Author,
+		Body:      stored.Body,
+		CreatedAt: stored.CreatedAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
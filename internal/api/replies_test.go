@@ -0,0 +1,100 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestMessageReplies(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "How do you deploy?")
+	path := "/rooms/" + room.ID + "/messages/" + id + "/replies"
+	c := s.subscribe(t, room.ID, "")
+
+	var replies []string
+	for _, body := range []string{"With a pipeline.", "And a canary."} {
+		resp := s.do(t, http.MethodPost, path, map[string]any{"body": body}, host...)
+		expectStatus(t, resp, http.StatusCreated)
+		created := resp.object(t)
+		if created["author"] != "host" || created["body"] != body || created["message_id"] != id {
+			t.Errorf("got %v, want the reply of the host", created)
+		}
+		if location := resp.header.Get("Location"); !strings.HasSuffix(location, path+"/"+created["id"].(string)) {
+			t.Errorf("got Location %q, want the reply", location)
+		}
+		replies = append(replies, created["id"].(string))
+
+		got := c.expect(events.KindReplyCreated).Value.(events.ReplyCreated)
+		if want := (events.ReplyCreated{ID: created["id"].(string), MessageID: id, Author: "host", Body: body}); got != want {
+			t.Errorf("got reply_created %+v, want %+v", got, want)
+		}
+	}
+
+	resp := s.do(t, http.MethodGet, path, nil)
+	expectStatus(t, resp, http.StatusOK)
+	listed := resp.list(t)
+	if len(listed) != 2 || listed[0]["id"] != replies[0] || listed[1]["id"] != replies[1] {
+		t.Errorf("got replies %v, want %v oldest first", listed, replies)
+	}
+
+	resp = s.do(t, http.MethodGet, path+"/"+replies[1], nil)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["body"]; got != "And a canary." {
+		t.Errorf("got body %v, want the second reply", got)
+	}
+
+	// Replies don't answer the message.
+	if answered := s.messages(t, room.ID)[0]["answered"]; answered != false {
+		t.Errorf("got answered %v, want false", answered)
+	}
+}
+
+func TestMessageRepliesRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	pendingRoom := s.createRoom(t, map[string]any{"require_approval": true})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "question")
+	other := s.postMessage(t, room.ID, "other question")
+	pending := s.postMessage(t, pendingRoom.ID, "pending question")
+	path := "/rooms/" + room.ID + "/messages/" + id + "/replies"
+
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages/"+other+"/replies", map[string]any{"body": "reply"}, host...)
+	expectStatus(t, resp, http.StatusCreated)
+	otherReply := resp.object(t)["id"].(string)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		header []string
+		status int
+		code   string
+	}{
+		{"NotHost", http.MethodPost, path, map[string]any{"body": "reply"}, nil, http.StatusUnauthorized, "unauthorized"},
+		{"BlankBody", http.MethodPost, path, map[string]any{"body": " "}, host, http.StatusUnprocessableEntity, "validation_failed"},
+		{"InvalidJSON", http.MethodPost, path, "{", host, http.StatusBadRequest, "invalid_json"},
+		{"Pending", http.MethodPost, "/rooms/" + pendingRoom.ID + "/messages/" + pending + "/replies", map[string]any{"body": "reply"}, []string{"Authorization", "Bearer " + pendingRoom.HostToken}, http.StatusConflict, "message_pending"},
+		{"InvalidReplyID", http.MethodGet, path + "/nope", nil, nil, http.StatusBadRequest, "invalid_reply_id"},
+		{"ReplyOfOtherMessage", http.MethodGet, path + "/" + otherReply, nil, nil, http.StatusNotFound, "reply_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, tt.method, tt.path, tt.body, tt.header...)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+
+	if replies := s.do(t, http.MethodGet, path, nil).list(t); len(replies) != 0 {
+		t.Errorf("got replies %v, want none", replies)
+	}
+}
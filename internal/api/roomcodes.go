@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// roomCodeAlphabet is Crockford's base32: digits and capitals without
+	// I, L, O and U, which are easily misread.
+	roomCodeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	roomCodeLength   = 6
+	// maxRoomCodeAttempts bounds how many codes are tried when creating a
+	// room before giving up on the one already taken.
+	maxRoomCodeAttempts = 3
+)
+
+var errInvalidRoomID = errors.New("invalid room id")
+
+// newRoomCode returns a random room code. Each byte picks a letter of the 32
+// of the alphabet, so they are all as likely.
+func newRoomCode() (string, error) {
+	b := make([]byte, roomCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = roomCodeAlphabet[int(b[i])%len(roomCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// normalizeRoomCode returns code as it is stored, reading it the way
+// Crockford's base32 does: ignoring case and taking I and L for 1 and O for
+// 0. It reports false for anything that can't be a room code.
+func normalizeRoomCode(code string) (string, bool) {
+	if len(code) != roomCodeLength {
+		return "", false
+	}
+	normalized := []byte(strings.ToUpper(code))
+	for i, c := range normalized {
+		switch c {
+		case 'I', 'L':
+			normalized[i] = '1'
+		case 'O':
+			normalized[i] = '0'
+		}
+		if !strings.ContainsRune(roomCodeAlphabet, rune(normalized[i])) {
+			return "", false
+		}
+	}
+	return string(normalized), true
+}
+
+// resolveRoomID returns the id of the room raw stands for, which is either
+// the id itself or the code of the room. It fails with errInvalidRoomID when
+// raw is neither.
+func (api *Handler) resolveRoomID(ctx context.Context, raw string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(raw); err == nil {
+		return id, nil
+	}
+	code, ok := normalizeRoomCode(raw)
+	if !ok {
+		return uuid.Nil, errInvalidRoomID
+	}
+	return api.queries.GetRoomIDByCode(ctx, code)
+}
+
+// resolveRoomCode lets the routes taking a room_id take the code of the room
+// instead. The code is replaced by the room's id in the route parameters and
+// the path, so handlers and host checks only ever see room ids. It is only
+// used on those routes, after authentication and rate limiting, so other
+// requests never look codes up.
+func (api *Handler) resolveRoomCode(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := chi.URLParam(r, "room_id")
+		id, err := api.resolveRoomID(r.Context(), raw)
+		if errors.Is(err, errInvalidRoomID) {
+			// Left to the handler to reject.
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			api.writeStoreError(w, err, "room_not_found")
+			return
+		}
+		if id.String() == raw {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rctx := chi.RouteContext(r.Context())
+		for i, key := range rctx.URLParams.Keys {
+			if key == "room_id" {
+				rctx.URLParams.Values[i] = id.String()
+			}
+		}
+		segments := strings.Split(r.URL.Path, "/")
+		for i, segment := range segments {
+			if segment == raw {
+				segments[i] = id.String()
+				break
+			}
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = strings.Join(segments, "/")
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
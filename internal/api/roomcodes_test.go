@@ -0,0 +1,54 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRoomCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"ABC123", "ABC123", true},
+		{"abc123", "ABC123", true},
+		{"aBcXyZ", "ABCXYZ", true},
+		// Misread letters are read as the digits they look like.
+		{"I1L1O0", "111100", true},
+		{"il0o1l", "110011", true},
+		{"ABC12", "", false},
+		{"ABC1234", "", false},
+		{"", "", false},
+		{"ABCU12", "", false},
+		{"ABC-12", "", false},
+		{"ABC 12", "", false},
+		{"ÀBC12", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeRoomCode(tt.code)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeRoomCode(%q) = %q, %v, want %q, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewRoomCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		code, err := newRoomCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != roomCodeLength || strings.Trim(code, roomCodeAlphabet) != "" {
+			t.Fatalf("got code %q, want %d letters of %s", code, roomCodeLength, roomCodeAlphabet)
+		}
+		if normalized, ok := normalizeRoomCode(code); !ok || normalized != code {
+			t.Fatalf("code %q normalizes to %q, %v, want itself", code, normalized, ok)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 99 {
+		t.Errorf("got %d distinct codes out of 100", len(seen))
+	}
+}
@@ -0,0 +1,522 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const (
+	maxRoomThemeQueryLength = 200
+	maxRoomsLimit           = 100
+)
+
+func (api *Handler) handleGetRoom(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "room_not_found", "room not found")
+		return
+	}
+
+	room, err := api.getRoom(r.Context(), roomID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, http.StatusNotFound, "room_not_found", "room not found")
+			return
+		}
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	counts, err := api.queries.GetRoomStats(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"id":                            room.ID.String(),
+		"code":                          room.Code,
+		"theme":                         room.Theme,
+		"description":                   room.Description,
+		"host_name":                     room.HostName,
+		"starts_at":                     room.StartsAt,
+		"created_at":                    room.CreatedAt,
+		"message_count":                 counts.TotalMessages,
+		"answered_count":                counts.AnsweredMessages,
+		"subscriber_count":              api.subscriberCount(rawRoomID),
+		"max_messages":                  room.MaxMessages,
+		"require_name":                  room.RequireName,
+		"expires_at":                    room.ExpiresAt,
+		"duplicate_threshold":           room.DuplicateThreshold,
+		"max_questions_per_participant": room.MaxQuestionsPerParticipant,
+		"require_approval":              room.RequireApproval,
+		"closed_at":                     room.ClosedAt,
+		"closes_at":                     room.ClosesAt,
+		"reaction_kinds":                api.reactionKinds,
+		"seq":                           api.roomSequence(rawRoomID),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleGetRooms lists the rooms that haven't expired, or every room flagged
+// with whether it expired when ?include_expired=true. Rooms can be narrowed
+// down to those whose theme contains ?theme, ordered with ?sort=newest (the
+// default) or oldest, and capped with ?limit.
+func (api *Handler) handleGetRooms(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	includeExpired := query.Get("include_expired") == "true"
+
+	theme := strings.TrimSpace(query.Get("theme"))
+	if utf8.RuneCountInString(theme) > maxRoomThemeQueryLength {
+		writeError(w, http.StatusBadRequest, "invalid_theme", "theme must be at most 200 characters")
+		return
+	}
+
+	var newestFirst bool
+	switch query.Get("sort") {
+	case "", "newest":
+		newestFirst = true
+	case "oldest":
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_sort", "sort must be newest or oldest")
+		return
+	}
+
+	var limit int
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxRoomsLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	now := api.now()
+	rooms, err := api.queries.ListRooms(r.Context(), pgstore.ListRoomsParams{
+		ThemeQuery:     theme,
+		IncludeExpired: includeExpired,
+		Now:            now,
+		NewestFirst:    newestFirst,
+		MaxResults:     int32(limit),
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	type response struct {
+		ID          string     `json:"id"`
+		Code        string     `json:"code"`
+		Theme       string     `json:"theme"`
+		Description string     `json:"description"`
+		HostName    string     `json:"host_name"`
+		StartsAt    *time.Time `json:"starts_at"`
+		CreatedAt   time.Time  `json:"created_at"`
+		ExpiresAt   *time.Time `json:"expires_at"`
+		Expired     bool       `json:"expired,omitempty"`
+		ClosedAt    *time.Time `json:"closed_at"`
+		ClosesAt    *time.Time `json:"closes_at"`
+	}
+
+	resp := make([]response, 0, len(rooms))
+	for _, room := range rooms {
+		expired := roomExpired(room, now)
+		resp = append(resp, response{
+			ID:          room.ID.String(),
+			Code:        room.Code,
+			Theme:       room.Theme,
+			Description: room.Description,
+			HostName:    room.HostName,
+			StartsAt:    room.StartsAt,
+			CreatedAt:   room.CreatedAt,
+			ExpiresAt:   room.ExpiresAt,
+			Expired:     expired,
+			ClosedAt:    room.ClosedAt,
+			ClosesAt:    room.ClosesAt,
+		})
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// roomClosed reports whether room is read-only: closed by its host, or past
+// the time it was scheduled to close even before the sweeper got to it.
+func roomClosed(room pgstore.Room, now time.Time) bool {
+	return room.ClosedAt != nil || (room.ClosesAt != nil && !room.ClosesAt.After(now))
+}
+
+// openRoom loads a room about to have its questions or reactions changed,
+// answering 409 room_closed once it is closed.
+func (api *Handler) openRoom(w http.ResponseWriter, r *http.Request, roomID uuid.UUID) (pgstore.Room, bool) {
+	room, err := api.getRoom(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return pgstore.Room{}, false
+	}
+	if roomClosed(room, api.now()) {
+		writeRoomClosed(w)
+		return pgstore.Room{}, false
+	}
+	return room, true
+}
+
+func writeRoomClosed(w http.ResponseWriter) {
+	writeError(w, http.StatusConflict, "room_closed", "the room is closed and read-only")
+}
+
+// handleCloseRoom makes a room read-only: its questions and reactions can't
+// change anymore, while everything can still be read. Closing can't be
+// undone.
+func (api *Handler) handleCloseRoom(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, ok := api.openRoom(w, r, roomID); !ok {
+		return
+	}
+
+	closedAt := storedTime(api.now())
+	room, err := api.queries.CloseRoom(r.Context(), pgstore.CloseRoomParams{
+		ID:       roomID,
+		ClosedAt: &closedAt,
+	})
+	if errors.Is(err, ErrNotFound) {
+		// Closed by another host in the meantime.
+		writeRoomClosed(w)
+		return
+	}
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	seq := api.notifyClients(r.Context(), events.Event{
+		Kind:   events.KindRoomClosed,
+		RoomID: room.ID.String(),
+		Value:  events.RoomClosed{ID: room.ID.String()},
+	})
+
+	data, err := json.Marshal(map[string]any{
+		"id":        room.ID.String(),
+		"closed_at": room.ClosedAt,
+		"seq":       seq,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleUpdateRoom changes the theme and settings of a room. Only the fields
+// present in the body change; expires_in_minutes and closes_in_minutes count
+// from now. The new settings apply to what happens next: lowering
+// max_messages or turning off require_approval leaves the existing messages
+// as they are. Updates that change nothing aren't broadcast.
+func (api *Handler) handleUpdateRoom(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	body := struct {
+		Theme                      *string    `json:"theme"`
+		MaxMessages                *int32     `json:"max_messages"`
+		Prune                      *bool      `json:"prune"`
+		RequireName                *bool      `json:"require_name"`
+		ExpiresInMinutes           *int       `json:"expires_in_minutes"`
+		DuplicateThreshold         *float32   `json:"duplicate_threshold"`
+		MaxQuestionsPerParticipant *int32     `json:"max_questions_per_participant"`
+		RequireApproval            *bool      `json:"require_approval"`
+		ClosesInMinutes            *int       `json:"closes_in_minutes"`
+		Description                *string    `json:"description"`
+		HostName                   *string    `json:"host_name"`
+		StartsAt                   *time.Time `json:"starts_at"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_json", "invalid json")
+		return
+	}
+
+	room, err := api.getRoom(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	now := api.now()
+	params := pgstore.UpdateRoomParams{
+		ID:                         room.ID,
+		Theme:                      room.Theme,
+		MaxMessages:                room.MaxMessages,
+		Prune:                      room.Prune,
+		RequireName:                room.RequireName,
+		ExpiresAt:                  room.ExpiresAt,
+		DuplicateThreshold:         room.DuplicateThreshold,
+		MaxQuestionsPerParticipant: room.MaxQuestionsPerParticipant,
+		RequireApproval:            room.RequireApproval,
+		ClosesAt:                   room.ClosesAt,
+		Description:                room.Description,
+		HostName:                   room.HostName,
+		StartsAt:                   room.StartsAt,
+	}
+	current := params
+	var v validator
+	if body.Theme != nil {
+		v.text("theme", *body.Theme, maxThemeLength)
+		params.Theme = *body.Theme
+	}
+	if body.MaxMessages != nil {
+		v.check(*body.MaxMessages >= 0, "max_messages", "invalid_max_messages", "max_messages must not be negative")
+		params.MaxMessages = *body.MaxMessages
+	}
+	if body.Prune != nil {
+		params.Prune = *body.Prune
+	}
+	if body.RequireName != nil {
+		params.RequireName = *body.RequireName
+	}
+	if body.ExpiresInMinutes != nil {
+		lifetime := time.Duration(*body.ExpiresInMinutes) * time.Minute
+		v.check(lifetime >= minRoomLifetime && lifetime <= maxRoomLifetime, "expires_in_minutes", "invalid_expiry", "expires_in_minutes must be between 10 and 43200")
+		at := now.Add(lifetime)
+		params.ExpiresAt = &at
+	}
+	if body.DuplicateThreshold != nil {
+		v.check(*body.DuplicateThreshold >= 0 && *body.DuplicateThreshold <= 1, "duplicate_threshold", "invalid_duplicate_threshold", "duplicate_threshold must be between 0 and 1")
+		params.DuplicateThreshold = *body.DuplicateThreshold
+	}
+	if body.MaxQuestionsPerParticipant != nil {
+		v.check(*body.MaxQuestionsPerParticipant >= 0, "max_questions_per_participant", "invalid_max_questions_per_participant", "max_questions_per_participant must not be negative")
+		params.MaxQuestionsPerParticipant = *body.MaxQuestionsPerParticipant
+	}
+	if body.RequireApproval != nil {
+		params.RequireApproval = *body.RequireApproval
+	}
+	if body.ClosesInMinutes != nil {
+		openFor := time.Duration(*body.ClosesInMinutes) * time.Minute
+		v.check(openFor >= minRoomLifetime && openFor <= maxRoomLifetime, "closes_in_minutes", "invalid_closing", "closes_in_minutes must be between 10 and 43200")
+		at := now.Add(openFor)
+		params.ClosesAt = &at
+	}
+	if body.Description != nil {
+		if *body.Description != "" {
+			v.text("description", *body.Description, maxDescriptionLength)
+		}
+		params.Description = *body.Description
+	}
+	if body.HostName != nil {
+		if *body.HostName != "" {
+			v.text("host_name", *body.HostName, maxHostNameLength)
+		}
+		params.HostName = *body.HostName
+	}
+	if body.StartsAt != nil {
+		v.check(body.StartsAt.After(now), "starts_at", "invalid_start", "starts_at must be in the future")
+		at := storedTime(*body.StartsAt)
+		params.StartsAt = &at
+	}
+	if !v.valid(w) {
+		return
+	}
+
+	updated := room
+	changed := !sameRoomSettings(params, current)
+	if changed {
+		updated, err = api.queries.UpdateRoom(r.Context(), params)
+		if err != nil {
+			// Deleted in the meantime.
+			api.writeStoreError(w, err, "room_not_found")
+			return
+		}
+	}
+
+	settings := events.RoomUpdated{
+		ID:                         updated.ID.String(),
+		Theme:                      updated.Theme,
+		MaxMessages:                updated.MaxMessages,
+		Prune:                      updated.Prune,
+		RequireName:                updated.RequireName,
+		ExpiresAt:                  updated.ExpiresAt,
+		DuplicateThreshold:         updated.DuplicateThreshold,
+		MaxQuestionsPerParticipant: updated.MaxQuestionsPerParticipant,
+		RequireApproval:            updated.RequireApproval,
+		ClosesAt:                   updated.ClosesAt,
+		Description:                updated.Description,
+		HostName:                   updated.HostName,
+		StartsAt:                   updated.StartsAt,
+	}
+	// Subscribers only hear about actual changes.
+	seq := api.roomSequence(updated.ID.String())
+	if changed {
+		seq = api.notifyClients(r.Context(), events.Event{
+			Kind:   events.KindRoomUpdated,
+			RoomID: updated.ID.String(),
+			Value:  settings,
+		})
+	}
+
+	data, err := json.Marshal(struct {
+		events.RoomUpdated
+		CreatedAt time.Time  `json:"created_at"`
+		ClosedAt  *time.Time `json:"closed_at"`
+		Seq       uint64     `json:"seq"`
+	}{settings, updated.CreatedAt, updated.ClosedAt, seq})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// sameRoomSettings reports whether updating a room with a leaves it as b
+// would.
+func sameRoomSettings(a, b pgstore.UpdateRoomParams) bool {
+	return a.Theme == b.Theme &&
+		a.MaxMessages == b.MaxMessages &&
+		a.Prune == b.Prune &&
+		a.RequireName == b.RequireName &&
+		sameTime(a.ExpiresAt, b.ExpiresAt) &&
+		a.DuplicateThreshold == b.DuplicateThreshold &&
+		a.MaxQuestionsPerParticipant == b.MaxQuestionsPerParticipant &&
+		a.RequireApproval == b.RequireApproval &&
+		sameTime(a.ClosesAt, b.ClosesAt) &&
+		a.Description == b.Description &&
+		a.HostName == b.HostName &&
+		sameTime(a.StartsAt, b.StartsAt)
+}
+
+// sameTime reports whether a and b are both unset or the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// handleDeleteRoom hides a room: it disappears from the listings, answers 404
+// and its subscribers are disconnected with room_deleted. Hosts can restore
+// it for roomRetention, after which the sweeper deletes it for good.
+func (api *Handler) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	deletedAt := storedTime(api.now())
+	_, err = api.queries.SoftDeleteRoom(r.Context(), pgstore.SoftDeleteRoomParams{
+		ID:        roomID,
+		DeletedAt: &deletedAt,
+	})
+	if err != nil {
+		// Deleted by another host in the meantime.
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	api.closeRoom(roomID.String(), events.KindRoomDeleted)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRestoreRoom undoes the deletion of a room, as long as it was deleted
+// less than roomRetention ago. Restoring a room that isn't deleted is a
+// conflict.
+func (api *Handler) handleRestoreRoom(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	// getRoom hides deleted rooms.
+	room, err := api.queries.GetRoom(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+	now := api.now()
+	if roomExpired(room, now) {
+		writeError(w, http.StatusNotFound, "room_not_found", "room not found")
+		return
+	}
+	if room.DeletedAt == nil {
+		writeError(w, http.StatusConflict, "not_deleted", "room is not deleted")
+		return
+	}
+	deletedAfter := now.Add(-api.roomRetention)
+	if !room.DeletedAt.After(deletedAfter) {
+		writeError(w, http.StatusGone, "retention_expired", "the room was deleted too long ago to be restored")
+		return
+	}
+
+	restored, err := api.queries.RestoreRoom(r.Context(), pgstore.RestoreRoomParams{
+		ID:           roomID,
+		DeletedAfter: &deletedAfter,
+	})
+	if err != nil {
+		// Another host restored it in the meantime.
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, http.StatusConflict, "not_deleted", "room is not deleted")
+			return
+		}
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"id":         restored.ID.String(),
+		"code":       restored.Code,
+		"theme":      restored.Theme,
+		"created_at": restored.CreatedAt,
+		"expires_at": restored.ExpiresAt,
+		"closed_at":  restored.ClosedAt,
+		"closes_at":  restored.ClosesAt,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
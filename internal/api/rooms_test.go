@@ -0,0 +1,442 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// countingLimiter allows limit.Requests requests by key, however long ago the
+// previous ones were.
+type countingLimiter struct {
+	mu    sync.Mutex
+	taken map[string]int
+}
+
+func (l *countingLimiter) Take(_ context.Context, key string, limit api.RateLimit) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.taken[key] >= limit.Requests {
+		return false, limit.Per, nil
+	}
+	l.taken[key]++
+	return true, 0, nil
+}
+
+func TestRoomCode(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	if len(room.Code) != 6 {
+		t.Fatalf("got code %q, want 6 characters", room.Code)
+	}
+
+	resp := s.do(t, http.MethodGet, "/rooms", nil)
+	expectStatus(t, resp, http.StatusOK)
+	if rooms := resp.list(t); len(rooms) != 1 || rooms[0]["code"] != room.Code {
+		t.Errorf("got rooms %v, want the room listed with its code %s", rooms, room.Code)
+	}
+
+	for _, code := range []string{room.Code, strings.ToLower(room.Code)} {
+		resp := s.do(t, http.MethodGet, "/rooms/"+code, nil)
+		expectStatus(t, resp, http.StatusOK)
+		if got := resp.object(t); got["id"] != room.ID || got["code"] != room.Code {
+			t.Errorf("GET /rooms/%s: got %v, want room %s", code, got, room.ID)
+		}
+	}
+}
+
+func TestRoomCodeRoutes(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	c := s.subscribe(t, room.Code, "")
+
+	id := s.postMessage(t, room.Code, "asked by code")
+	if created := c.expect(events.KindMessageCreated).Value.(events.MessageCreated); created.ID != id {
+		t.Errorf("got message_created of %s, want %s", created.ID, id)
+	}
+	if messages := s.messages(t, room.ID); len(messages) != 1 || messages[0]["id"] != id {
+		t.Errorf("got messages %v, want the one asked by code", messages)
+	}
+
+	// Host checks see the room id, whichever way the room is named.
+	path := "/rooms/" + room.Code + "/messages/" + id + "/answer"
+	expectStatus(t, s.do(t, http.MethodPatch, path, nil, "Authorization", "Bearer "+other.HostToken), http.StatusUnauthorized)
+	expectStatus(t, s.do(t, http.MethodPatch, path, nil, "Authorization", "Bearer "+room.HostToken), http.StatusOK)
+	c.expect(events.KindMessageAnswered)
+
+	// A message of another room isn't found through this room's code.
+	otherID := s.postMessage(t, other.ID, "elsewhere")
+	expectStatus(t, s.do(t, http.MethodGet, "/rooms/"+room.Code+"/messages/"+otherID, nil), http.StatusNotFound)
+}
+
+func TestRoomCodeRejected(t *testing.T) {
+	s := newTestServer(t)
+	s.createRoom(t, nil)
+
+	tests := []struct {
+		name   string
+		roomID string
+		status int
+		code   string
+	}{
+		{"UnknownCode", "ZZZZZZ", http.StatusNotFound, "room_not_found"},
+		{"UnknownID", "00000000-0000-0000-0000-000000000000", http.StatusNotFound, "room_not_found"},
+		{"TooShort", "ZZZZZ", http.StatusBadRequest, "invalid_room_id"},
+		{"NotBase32", "ZZZZU!", http.StatusBadRequest, "invalid_room_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodGet, "/rooms/"+tt.roomID+"/messages", nil)
+			expectStatus(t, resp, tt.status)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+
+	if _, resp, err := s.dial(t, "/subscribe/ZZZZZZ"); err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("subscribing to an unknown code: got %v, %v, want a 404", resp, err)
+	}
+}
+
+func TestRoomCodeRateLimited(t *testing.T) {
+	s := newTestServer(t,
+		api.WithRateLimiter(&countingLimiter{taken: make(map[string]int)}),
+		api.WithRateLimits(map[string]api.RateLimit{"POST /rooms/{room_id}/messages": {Requests: 1, Per: time.Hour}}),
+	)
+	room := s.createRoom(t, nil)
+
+	s.postMessage(t, room.ID, "question")
+	// The code shares the limit of the id it stands for.
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.Code+"/messages", map[string]any{"message": "again"})
+	expectStatus(t, resp, http.StatusTooManyRequests)
+	// Limited requests are refused before their code is looked up, so codes
+	// can't be guessed faster than the limit.
+	resp = s.do(t, http.MethodPost, "/rooms/ZZZZZZ/messages", map[string]any{"message": "guess"})
+	expectStatus(t, resp, http.StatusTooManyRequests)
+}
+
+func TestCloseRoom(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "asked before closing")
+	c := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/close", nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["closed_at"]; got != testStart.Format(time.RFC3339) {
+		t.Errorf("got closed_at %v, want %s", got, testStart.Format(time.RFC3339))
+	}
+	if got := c.expect(events.KindRoomClosed).Value.(events.RoomClosed).ID; got != room.ID {
+		t.Errorf("got room_closed for %s, want %s", got, room.ID)
+	}
+
+	// Questions and reactions can't change anymore.
+	path := "/rooms/" + room.ID + "/messages"
+	client := []string{"X-Client-Id", "client"}
+	for _, req := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPost, path, map[string]any{"message": "asked after closing"}},
+		{http.MethodPatch, path + "/" + id + "/react", nil},
+		{http.MethodDelete, path + "/" + id + "/react", nil},
+		{http.MethodPut, path + "/" + id + "/reactions/" + url.PathEscape("🎉"), nil},
+		{http.MethodPatch, "/rooms/" + room.ID + "/close", nil},
+	} {
+		resp := s.do(t, req.method, req.path, req.body, append(client, host...)...)
+		expectStatus(t, resp, http.StatusConflict)
+		if code := resp.code(t); code != "room_closed" {
+			t.Errorf("%s %s: got code %q, want room_closed", req.method, req.path, code)
+		}
+	}
+
+	// The archive can still be read.
+	if messages := s.messages(t, room.ID); len(messages) != 1 || messages[0]["id"] != id || messages[0]["reaction_count"] != 0.0 {
+		t.Errorf("got messages %v, want %s unchanged", messages, id)
+	}
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.ID, nil)
+	expectStatus(t, resp, http.StatusOK)
+	if resp.object(t)["closed_at"] == nil {
+		t.Errorf("got %s, want the room closed", resp.body)
+	}
+}
+
+func TestCloseRoomRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+
+	for name, header := range map[string][]string{
+		"Anonymous":     nil,
+		"OtherRoomHost": {"Authorization", "Bearer " + other.HostToken},
+	} {
+		resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID+"/close", nil, header...)
+		expectStatus(t, resp, http.StatusForbidden)
+		if code := resp.code(t); code != "forbidden" {
+			t.Errorf("%s: got code %q, want forbidden", name, code)
+		}
+	}
+	s.postMessage(t, room.ID, "the room is still open")
+}
+
+// listedRoomIDs returns the ids of the rooms listed.
+func listedRoomIDs(t *testing.T, s *testServer) []string {
+	t.Helper()
+	resp := s.do(t, http.MethodGet, "/rooms", nil)
+	expectStatus(t, resp, http.StatusOK)
+	var ids []string
+	for _, room := range resp.list(t) {
+		ids = append(ids, room["id"].(string))
+	}
+	return ids
+}
+
+func TestDeleteRoom(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	kept := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "question")
+	c := s.subscribe(t, room.ID, "")
+
+	expectStatus(t, s.do(t, http.MethodDelete, "/rooms/"+room.ID, nil, host...), http.StatusNoContent)
+	if got := c.expect(events.KindRoomDeleted).Value.(events.RoomDeleted).ID; got != room.ID {
+		t.Errorf("got room_deleted for %s, want %s", got, room.ID)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(waitTimeout))
+	if _, _, err := c.conn.ReadMessage(); err == nil {
+		t.Fatal("subscription still open after room_deleted")
+	}
+
+	if ids := listedRoomIDs(t, s); len(ids) != 1 || ids[0] != kept.ID {
+		t.Errorf("got rooms %v, want only %s", ids, kept.ID)
+	}
+	for _, path := range []string{"/rooms/" + room.ID, "/rooms/" + room.ID + "/messages", "/rooms/" + room.Code} {
+		resp := s.do(t, http.MethodGet, path, nil)
+		expectStatus(t, resp, http.StatusNotFound)
+		if code := resp.code(t); code != "room_not_found" {
+			t.Errorf("GET %s: got code %q, want room_not_found", path, code)
+		}
+	}
+	expectStatus(t, s.do(t, http.MethodDelete, "/rooms/"+room.ID, nil, host...), http.StatusNotFound)
+
+	// Restoring brings the room back as it was.
+	resp := s.do(t, http.MethodPost, "/rooms/"+room.ID+"/restore", nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t); got["id"] != room.ID || got["code"] != room.Code {
+		t.Errorf("got %v, want room %s with code %s", got, room.ID, room.Code)
+	}
+	if ids := listedRoomIDs(t, s); len(ids) != 2 {
+		t.Errorf("got rooms %v, want both", ids)
+	}
+	if messages := s.messages(t, room.ID); len(messages) != 1 || messages[0]["id"] != id {
+		t.Errorf("got messages %v, want %s", messages, id)
+	}
+}
+
+func TestRestoreRoomRejected(t *testing.T) {
+	s := newTestServer(t, api.WithRoomRetention(time.Hour))
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	path := "/rooms/" + room.ID
+
+	resp := s.do(t, http.MethodPost, path+"/restore", nil, host...)
+	expectStatus(t, resp, http.StatusConflict)
+	if code := resp.code(t); code != "not_deleted" {
+		t.Errorf("restoring a room that isn't deleted got code %q, want not_deleted", code)
+	}
+
+	expectStatus(t, s.do(t, http.MethodDelete, path, nil), http.StatusForbidden)
+	expectStatus(t, s.do(t, http.MethodDelete, path, nil, host...), http.StatusNoContent)
+	expectStatus(t, s.do(t, http.MethodPost, path+"/restore", nil), http.StatusForbidden)
+
+	s.clock.Advance(time.Hour)
+	resp = s.do(t, http.MethodPost, path+"/restore", nil, host...)
+	expectStatus(t, resp, http.StatusGone)
+	if code := resp.code(t); code != "retention_expired" {
+		t.Errorf("restoring past the retention got code %q, want retention_expired", code)
+	}
+}
+
+func TestUpdateRoom(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"theme": "Q&A with the CTO"})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	c := s.subscribe(t, room.ID, "")
+
+	resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID, map[string]any{
+		"theme":                         "Q&A with the CTO and the CFO",
+		"require_approval":              true,
+		"max_questions_per_participant": 3,
+		"expires_in_minutes":            60,
+	}, host...)
+	expectStatus(t, resp, http.StatusOK)
+	expiresAt := testStart.Add(time.Hour)
+	want := events.RoomUpdated{
+		ID:                         room.ID,
+		Theme:                      "Q&A with the CTO and the CFO",
+		ExpiresAt:                  &expiresAt,
+		MaxQuestionsPerParticipant: 3,
+		RequireApproval:            true,
+	}
+	got := c.expect(events.KindRoomUpdated).Value.(events.RoomUpdated)
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("got expires_at %v, want %v", got.ExpiresAt, expiresAt)
+	}
+	got.ExpiresAt = want.ExpiresAt
+	if got != want {
+		t.Errorf("got room_updated %+v, want %+v", got, want)
+	}
+	if updated := resp.object(t); updated["theme"] != want.Theme || updated["seq"] == 0.0 {
+		t.Errorf("got %v, want the new theme and the seq of room_updated", updated)
+	}
+
+	resp = s.do(t, http.MethodGet, "/rooms/"+room.ID, nil)
+	expectStatus(t, resp, http.StatusOK)
+	if theme := resp.object(t)["theme"]; theme != want.Theme {
+		t.Errorf("got theme %v, want %q", theme, want.Theme)
+	}
+
+	// The new settings apply to the next questions.
+	resp = s.do(t, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "held for approval"})
+	expectStatus(t, resp, http.StatusCreated)
+	if pending := resp.object(t)["pending"]; pending != true {
+		t.Errorf("got pending %v, want the question held", pending)
+	}
+}
+
+func TestUpdateRoomUnchanged(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"theme": "unchanged"})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	c := s.subscribe(t, room.ID, "")
+
+	for _, body := range []map[string]any{{}, {"theme": "unchanged"}, {"require_approval": false}} {
+		resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID, body, host...)
+		expectStatus(t, resp, http.StatusOK)
+		if seq := resp.object(t)["seq"]; seq != 0.0 {
+			t.Errorf("%v: got seq %v, want nothing broadcast", body, seq)
+		}
+	}
+
+	// The first event the subscriber gets is the one sent now.
+	id := s.postMessage(t, room.ID, "question")
+	if e := c.next(); e.Kind != events.KindMessageCreated || e.Value.(events.MessageCreated).ID != id {
+		t.Errorf("got %s %+v, want message_created of %s", e.Kind, e.Value, id)
+	}
+}
+
+func TestUpdateRoomRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"theme": "kept"})
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+
+	tests := []struct {
+		name   string
+		body   any
+		header []string
+		status int
+		field  string
+		code   string
+	}{
+		{"NotHost", map[string]any{"theme": "renamed"}, nil, http.StatusForbidden, "", "forbidden"},
+		{"InvalidJSON", "{", host, http.StatusUnprocessableEntity, "", "invalid_json"},
+		{"BlankTheme", map[string]any{"theme": " "}, host, http.StatusUnprocessableEntity, "theme", ""},
+		{"NegativeMaxMessages", map[string]any{"theme": "renamed", "max_messages": -1}, host, http.StatusUnprocessableEntity, "max_messages", "invalid_max_messages"},
+		{"ShortExpiry", map[string]any{"expires_in_minutes": 5}, host, http.StatusUnprocessableEntity, "expires_in_minutes", "invalid_expiry"},
+		{"DuplicateThreshold", map[string]any{"duplicate_threshold": 2}, host, http.StatusUnprocessableEntity, "duplicate_threshold", "invalid_duplicate_threshold"},
+		{"NegativeQuota", map[string]any{"max_questions_per_participant": -1}, host, http.StatusUnprocessableEntity, "max_questions_per_participant", "invalid_max_questions_per_participant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.do(t, http.MethodPatch, "/rooms/"+room.ID, tt.body, tt.header...)
+			expectStatus(t, resp, tt.status)
+			switch {
+			case tt.field == "":
+				if code := resp.code(t); code != tt.code {
+					t.Errorf("got code %q, want %q", code, tt.code)
+				}
+			case tt.code != "":
+				if code := resp.fieldErrors(t)[tt.field]; code != tt.code {
+					t.Errorf("got %s error %q, want %q", tt.field, code, tt.code)
+				}
+			default:
+				if _, ok := resp.fieldErrors(t)[tt.field]; !ok {
+					t.Errorf("got %s, want an error about %s", resp.body, tt.field)
+				}
+			}
+		})
+	}
+
+	if theme := s.do(t, http.MethodGet, "/rooms/"+room.ID, nil).object(t)["theme"]; theme != "kept" {
+		t.Errorf("got theme %v, want it kept", theme)
+	}
+}
+
+func TestRoomMetadata(t *testing.T) {
+	s := newTestServer(t)
+	startsAt := testStart.Add(24 * time.Hour).Format(time.RFC3339)
+	resp := s.do(t, http.MethodPost, "/rooms", map[string]any{
+		"theme":       "Ask the platform team",
+		"description": "Deploys, on-call and the new cluster.",
+		"host_name":   "Platform team",
+		"starts_at":   startsAt,
+	})
+	expectStatus(t, resp, http.StatusCreated)
+	created := resp.object(t)
+	id := created["id"].(string)
+
+	resp = s.do(t, http.MethodGet, "/rooms/"+id, nil)
+	expectStatus(t, resp, http.StatusOK)
+	got := resp.object(t)
+
+	resp = s.do(t, http.MethodGet, "/rooms", nil)
+	expectStatus(t, resp, http.StatusOK)
+	listed := resp.list(t)
+	if len(listed) != 1 {
+		t.Fatalf("got rooms %v, want one", listed)
+	}
+
+	for name, room := range map[string]map[string]any{"created": created, "get": got, "listed": listed[0]} {
+		if room["description"] != "Deploys, on-call and the new cluster." || room["host_name"] != "Platform team" || room["starts_at"] != startsAt {
+			t.Errorf("%s room: got %v, want its metadata", name, room)
+		}
+	}
+
+	// Without metadata the fields are empty.
+	plain := s.createRoom(t, nil)
+	room := s.do(t, http.MethodGet, "/rooms/"+plain.ID, nil).object(t)
+	if room["description"] != "" || room["host_name"] != "" || room["starts_at"] != nil {
+		t.Errorf("got %v, want no metadata", room)
+	}
+}
+
+func TestRoomMetadataValidation(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		field string
+		value any
+		code  string
+	}{
+		{"description", strings.Repeat("a", 1001), "too_long"},
+		{"host_name", strings.Repeat("a", 51), "too_long"},
+		{"starts_at", testStart.Format(time.RFC3339), "invalid_start"},
+	}
+	for _, tt := range tests {
+		resp := s.do(t, http.MethodPost, "/rooms", map[string]any{"theme": "metadata", tt.field: tt.value})
+		expectStatus(t, resp, http.StatusUnprocessableEntity)
+		if code := resp.fieldErrors(t)[tt.field]; code != tt.code {
+			t.Errorf("%s: got error %q, want %q", tt.field, code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// tokenProtocolPrefix marks the Sec-WebSocket-Protocol entry carrying the
+// token of a moderator subscription, for browsers that can't set headers on
+// websocket requests.
+const tokenProtocolPrefix = "token."
+
+// subscriptionScope returns the scope requested by a subscription through
+// ?scope=. The moderator scope requires the admin token or a host token of
+// roomID, given as ?token= or as a token.<token> websocket subprotocol;
+// protocol is the subprotocol to accept when it came that way. Subscriptions
+// not bound to a room pass an empty roomID and need the admin token. When
+// the scope is invalid or not allowed an error response is written and ok is
+// false.
+func (api *Handler) subscriptionScope(w http.ResponseWriter, r *http.Request, roomID string) (scope, protocol string, ok bool) {
+	switch r.URL.Query().Get("scope") {
+	case "", "public":
+		return events.ScopePublic, "", true
+	case events.ScopeModerator:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_scope", "scope must be public or moderator")
+		return "", "", false
+	}
+
+	token := r.URL.Query().Get("token")
+	for _, p := range websocketProtocols(r) {
+		if t, found := strings.CutPrefix(p, tokenProtocolPrefix); found {
+			token, protocol = t, p
+			break
+		}
+	}
+
+	if auth, ok := api.resolveToken(token); !ok || !auth.canModerate(roomID) {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "the moderator scope requires a valid token")
+		return "", "", false
+	}
+	return events.ScopeModerator, protocol, true
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(header, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
+
+// receives reports whether msg may be delivered to the subscriber, keeping
+// moderator events away from public subscriptions.
+func (s *subscriber) receives(msg events.Event) bool {
+	return msg.Scope != events.ScopeModerator || s.scope == events.ScopeModerator
+}
@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestModeratorScope(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"require_approval": true})
+	public := s.subscribe(t, room.ID, "")
+	moderator := s.subscribe(t, room.ID, "scope=moderator&token="+room.HostToken)
+
+	// Questions waiting for approval are broadcast to moderators only.
+	pending := s.postMessage(t, room.ID, "pending question")
+	if e := moderator.next(); e.Kind != events.KindMessagePending || e.Value.(events.MessageCreated).ID != pending {
+		t.Errorf("moderator got %s %+v, want message_pending of %s", e.Kind, e.Value, pending)
+	}
+
+	// The public event sent next is the first the public connection gets.
+	visible := s.postMessage(t, room.ID, "host question", "Authorization", "Bearer "+room.HostToken)
+	if e := public.next(); e.Kind != events.KindMessageCreated || e.Value.(events.MessageCreated).ID != visible {
+		t.Errorf("public got %s %+v, want message_created of %s", e.Kind, e.Value, visible)
+	}
+	if e := moderator.next(); e.Kind != events.KindMessageCreated || e.Value.(events.MessageCreated).ID != visible {
+		t.Errorf("moderator got %s %+v, want message_created of %s", e.Kind, e.Value, visible)
+	}
+}
+
+func TestModeratorScopeSubprotocol(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+
+	c, resp, err := s.dial(t, "/subscribe/"+room.ID+"?scope=moderator", "Sec-WebSocket-Protocol", "ama, token."+room.HostToken)
+	if err != nil {
+		t.Fatalf("subscribing: %v", err)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Protocol"); got != "token."+room.HostToken {
+		t.Errorf("got subprotocol %q, want the token one accepted", got)
+	}
+	s.waitSubscribers(t, room.ID, 1)
+
+	listed := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/subscribers", nil, "Authorization", "Bearer "+room.HostToken)
+	expectStatus(t, listed, http.StatusOK)
+	if subs := listed.list(t); len(subs) != 1 || subs[0]["scope"] != events.ScopeModerator {
+		t.Errorf("got subscribers %v, want one moderator", subs)
+	}
+	s.postMessage(t, room.ID, "question")
+	c.expect(events.KindMessageCreated)
+}
+
+func TestModeratorScopeRejected(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"InvalidScope", "scope=admin", http.StatusBadRequest},
+		{"NoToken", "scope=moderator", http.StatusUnauthorized},
+		{"InvalidToken", "scope=moderator&token=nope", http.StatusUnauthorized},
+		{"OtherRoomToken", "scope=moderator&token=" + other.HostToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp, err := s.dial(t, "/subscribe/"+room.ID+"?"+tt.query)
+			if err == nil {
+				t.Fatal("subscription accepted")
+			}
+			if resp == nil || resp.StatusCode != tt.status {
+				t.Fatalf("got response %v, want status %d", resp, tt.status)
+			}
+		})
+	}
+}
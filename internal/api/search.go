@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+const (
+	maxSearchQueryLength = 200
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 50
+)
+
+// handleSearchRoomMessages runs a full-text search over the messages of a
+// room, returning the matches ranked by relevance. The query accepts web
+// search syntax: quoted phrases, "or" and a leading "-" to exclude words.
+// Deleted messages are only searched when a host passes include_deleted.
+func (api *Handler) handleSearchRoomMessages(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	withDeleted, ok := includeDeleted(w, r, rawRoomID)
+	if !ok {
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLength {
+		writeError(w, http.StatusBadRequest, "invalid_query", "q must be between 1 and 200 characters")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_offset", "invalid offset")
+			return
+		}
+		offset = n
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	matches, err := api.queries.SearchRoomMessages(r.Context(), pgstore.SearchRoomMessagesParams{
+		Query:          query,
+		RoomID:         roomID,
+		IncludeDeleted: withDeleted,
+		MaxResults:     int32(limit),
+		SkipResults:    int32(offset),
+	})
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	type match struct {
+		ID            string     `json:"id"`
+		Message       string     `json:"message"`
+		AuthorName    *string    `json:"author_name"`
+		ReactionCount int64      `json:"reaction_count"`
+		Answered      bool       `json:"answered"`
+		Answer        *string    `json:"answer"`
+		CreatedAt     time.Time  `json:"created_at"`
+		Version       int64      `json:"version"`
+		DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+		Rank          float32    `json:"rank"`
+	}
+
+	results := make([]match, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, match{
+			ID:            m.ID.String(),
+			Message:       m.Message,
+			AuthorName:    m.AuthorName,
+			ReactionCount: m.ReactionCount,
+			Answered:      m.Answered,
+			Answer:        m.Answer,
+			CreatedAt:     m.CreatedAt,
+			Version:       m.Version,
+			DeletedAt:     m.DeletedAt,
+			Rank:          m.Rank,
+		})
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"messages":     results,
+		"limit":        limit,
+		"offset":       offset,
+		"can_moderate": authFrom(r.Context()).canModerate(rawRoomID),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
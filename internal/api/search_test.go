@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// search returns the ids of the messages of roomID matching q, best first.
+func (s *testServer) search(t *testing.T, roomID, query string, header ...string) []string {
+	t.Helper()
+	resp := s.do(t, http.MethodGet, "/rooms/"+roomID+"/messages/search?"+query, nil, header...)
+	expectStatus(t, resp, http.StatusOK)
+	var page struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(resp.body, &page); err != nil {
+		t.Fatalf("decoding %s: %v", resp.body, err)
+	}
+	ids := make([]string, 0, len(page.Messages))
+	for _, m := range page.Messages {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestSearchRoomMessages(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	dense := s.postMessage(t, room.ID, "deploy deploy deploy")
+	sparse := s.postMessage(t, room.ID, "how often do you deploy to production")
+	tested := s.postMessage(t, room.ID, "what about testing")
+	s.postMessage(t, other.ID, "deploy in the other room")
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"deploy", []string{dense, sparse}},
+		{"DEPLOY production", []string{sparse}},
+		{`"to production"`, []string{sparse}},
+		{"testing or production", []string{tested, sparse}},
+		{"deploy -production", []string{dense}},
+		{"kubernetes", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			got := s.search(t, room.ID, "q="+url.QueryEscape(tt.q))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPaging(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	for range 3 {
+		s.postMessage(t, room.ID, "deploy question")
+	}
+
+	first := s.search(t, room.ID, "q=deploy&limit=2")
+	rest := s.search(t, room.ID, "q=deploy&limit=2&offset=2")
+	if len(first) != 2 || len(rest) != 1 {
+		t.Fatalf("got pages of %d and %d, want 2 and 1", len(first), len(rest))
+	}
+	for _, id := range first {
+		if id == rest[0] {
+			t.Errorf("%s is on both pages", id)
+		}
+	}
+}
+
+func TestSearchDeletedMessages(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	id := s.postMessage(t, room.ID, "deploy question")
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	expectStatus(t, s.do(t, http.MethodDelete, "/rooms/"+room.ID+"/messages/"+id, nil, host...), http.StatusNoContent)
+
+	if got := s.search(t, room.ID, "q=deploy"); len(got) != 0 {
+		t.Errorf("got %v, want deleted messages left out", got)
+	}
+	if got := s.search(t, room.ID, "q=deploy&include_deleted=true", host...); len(got) != 1 || got[0] != id {
+		t.Errorf("got %v, want the deleted message for hosts", got)
+	}
+	resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/search?q=deploy&include_deleted=true", nil)
+	expectStatus(t, resp, http.StatusForbidden)
+}
+
+func TestSearchInvalid(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{"", "invalid_query"},
+		{"q=%20%20", "invalid_query"},
+		{"q=" + strings.Repeat("a", 201), "invalid_query"},
+		{"q=deploy&limit=0", "invalid_limit"},
+		{"q=deploy&limit=51", "invalid_limit"},
+		{"q=deploy&limit=ten", "invalid_limit"},
+		{"q=deploy&offset=-1", "invalid_offset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp := s.do(t, http.MethodGet, "/rooms/"+room.ID+"/messages/search?"+tt.query, nil)
+			expectStatus(t, resp, http.StatusBadRequest)
+			if code := resp.code(t); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
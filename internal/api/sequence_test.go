@@ -0,0 +1,145 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestSequenceOrdering(t *testing.T) {
+	const posts = 100
+	// The queues hold the whole burst, so no subscriber is dropped as slow.
+	s := newTestServer(t, api.WithSendQueueSize(2*posts))
+	room := s.createRoom(t, nil)
+
+	// Subscribers read while the questions are posted, collecting the seq of
+	// every message_created event.
+	var readers sync.WaitGroup
+	received := make([][]uint64, 3)
+	for i := range received {
+		c := s.subscribe(t, room.ID, "")
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			c.conn.SetReadDeadline(time.Now().Add(waitTimeout))
+			for len(received[i]) < posts {
+				_, data, err := c.conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if e, err := events.UnmarshalEvent(data); err == nil && e.Kind == events.KindMessageCreated {
+					received[i] = append(received[i], e.Seq)
+				}
+			}
+		}()
+	}
+
+	seqs := make(chan uint64, posts)
+	errs := make(chan error, posts)
+	var posters sync.WaitGroup
+	for i := range posts {
+		posters.Add(1)
+		go func() {
+			defer posters.Done()
+			data, _ := json.Marshal(map[string]any{"message": fmt.Sprintf("question %d", i)})
+			resp, err := s.Client().Post(s.URL+"/api/v1/rooms/"+room.ID+"/messages", "application/json", bytes.NewReader(data))
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer resp.Body.Close()
+			var created struct {
+				Seq uint64 `json:"seq"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || resp.StatusCode != http.StatusCreated {
+				errs <- fmt.Errorf("posting question %d: status %d, error %v", i, resp.StatusCode, err)
+				return
+			}
+			seqs <- created.Seq
+		}()
+	}
+	posters.Wait()
+	readers.Wait()
+	close(errs)
+	close(seqs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	responded := make(map[uint64]bool, posts)
+	for seq := range seqs {
+		responded[seq] = true
+	}
+
+	for i, got := range received {
+		if len(got) != posts {
+			t.Fatalf("subscriber %d got %d of %d events", i, len(got), posts)
+		}
+		var last uint64
+		for _, seq := range got {
+			if seq <= last {
+				t.Fatalf("subscriber %d got seq %d after %d", i, seq, last)
+			}
+			if !responded[seq] {
+				t.Errorf("subscriber %d got seq %d, which no response carried", i, seq)
+			}
+			last = seq
+		}
+	}
+	if got := s.do(t, http.MethodGet, "/rooms/"+room.ID, nil).object(t)["seq"]; got != float64(posts) {
+		t.Errorf("got room seq %v, want %d", got, posts)
+	}
+}
+
+func TestResumeFromSeq(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	for _, text := range []string{"first", "second", "third"} {
+		s.postMessage(t, room.ID, text)
+	}
+
+	c := s.subscribe(t, room.ID, "last_event_id=1")
+	for _, want := range []uint64{2, 3} {
+		if e := c.next(); e.Kind != events.KindMessageCreated || e.Seq != want {
+			t.Fatalf("got %s seq %d, want message_created seq %d", e.Kind, e.Seq, want)
+		}
+	}
+	s.postMessage(t, room.ID, "fourth")
+	if e := c.next(); e.Seq != 4 {
+		t.Errorf("got seq %d after the replay, want 4", e.Seq)
+	}
+}
+
+func TestResumeFromExpiredSeq(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, map[string]any{"max_messages": 1000})
+	// More events than the room keeps.
+	for i := range 300 {
+		s.postMessage(t, room.ID, "question "+strconv.Itoa(i))
+	}
+
+	for _, seq := range []string{"1", "301"} {
+		_, resp, err := s.dial(t, "/subscribe/"+room.ID+"?last_event_id="+seq)
+		if err == nil {
+			t.Fatalf("resuming from %s was accepted", seq)
+		}
+		if resp == nil || resp.StatusCode != http.StatusGone {
+			t.Fatalf("resuming from %s: got %v, want status 410", seq, resp)
+		}
+		var problem struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Code != "events_expired" {
+			t.Errorf("resuming from %s: got code %q (error %v), want events_expired", seq, problem.Code, err)
+		}
+	}
+	// The latest events are still there.
+	s.subscribe(t, room.ID, "last_event_id=299").expect(events.KindMessageCreated)
+}
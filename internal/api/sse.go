@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// sseTransport delivers events as server-sent events. Events carrying a
+// message id use it as the SSE id so reconnecting clients can resume through
+// the Last-Event-ID header.
+type sseTransport struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func newSSETransport(w http.ResponseWriter) sseTransport {
+	return sseTransport{w: w, rc: http.NewResponseController(w)}
+}
+
+func (t sseTransport) Name() string {
+	return "sse"
+}
+
+func (t sseTransport) WriteEvent(p *payload, deadline time.Time) error {
+	var b strings.Builder
+	if created, ok := p.msg.Value.(events.MessageCreated); ok && p.msg.Kind == events.KindMessageCreated {
+		fmt.Fprintf(&b, "id: %s\n", created.ID)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", p.msg.Kind, p.data)
+	return t.write(b.String(), deadline)
+}
+
+func (t sseTransport) HeartbeatInterval() time.Duration {
+	return sseHeartbeatInterval
+}
+
+func (t sseTransport) Heartbeat(deadline time.Time) error {
+	return t.write(": heartbeat\n\n", deadline)
+}
+
+func (t sseTransport) write(s string, deadline time.Time) error {
+	if err := t.rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := t.w.Write([]byte(s)); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
+
+func (t sseTransport) Close(string) error {
+	return nil
+}
+
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// missedMessages returns message_created events for every message of the room
+// created after lastEventID, oldest first.
+func (api *Handler) missedMessages(ctx context.Context, roomID uuid.UUID, rawRoomID, lastEventID string) ([]events.Event, error) {
+	afterID, err := uuid.Parse(lastEventID)
+	if err != nil {
+		return nil, nil
+	}
+
+	messages, err := api.queries.GetRoomMessagesCreatedAfter(ctx, pgstore.GetRoomMessagesCreatedAfterParams{
+		RoomID:  roomID,
+		AfterID: afterID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	replay := make([]events.Event, 0, len(messages))
+	for _, m := range messages {
+		replay = append(replay, events.Event{
+			Kind:   events.KindMessageCreated,
+			RoomID: rawRoomID,
+			Value: events.MessageCreated{
+				ID:         m.ID.String(),
+				Message:    m.Message,
+				AuthorName: derefString(m.AuthorName),
+				Language:   m.Language,
+				Version:    m.Version,
+			},
+		})
+	}
+	return replay, nil
+}
@@ -0,0 +1,179 @@
+package api_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// sseEvent is an event read from a server-sent event stream.
+type sseEvent struct {
+	id    string
+	event events.Event
+}
+
+// sseClient is a server-sent event subscription of a test.
+type sseClient struct {
+	t      *testing.T
+	resp   *http.Response
+	events chan sseEvent
+}
+
+// subscribeSSE streams the events of path, relative to the server root, and
+// waits until the subscription of roomID is registered. header holds header
+// names and values in turn.
+func (s *testServer) subscribeSSE(t *testing.T, roomID, path string, header ...string) *sseClient {
+	t.Helper()
+	before := s.do(t, http.MethodGet, "/rooms/"+roomID, nil).object(t)["subscriber_count"].(float64)
+	resp := s.openSSE(t, path, header...)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got content type %q, want text/event-stream", ct)
+	}
+
+	c := &sseClient{t: t, resp: resp, events: make(chan sseEvent, 100)}
+	go c.read()
+	s.waitSubscribers(t, roomID, int(before)+1)
+	return c
+}
+
+// openSSE sends the request of an SSE subscription to path, relative to the
+// server root. The response is closed when the test ends.
+func (s *testServer) openSSE(t *testing.T, path string, header ...string) *http.Response {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// read parses the stream until it ends.
+func (c *sseClient) read() {
+	defer close(c.events)
+	scanner := bufio.NewScanner(c.resp.Body)
+	var id, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && data != "":
+			event, err := events.UnmarshalEvent([]byte(data))
+			if err != nil {
+				c.t.Errorf("decoding event %s: %v", data, err)
+				return
+			}
+			c.events <- sseEvent{id: id, event: event}
+			id, data = "", ""
+		}
+	}
+}
+
+// expect returns the next event of kind, skipping the others.
+func (c *sseClient) expect(kind string) sseEvent {
+	c.t.Helper()
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case e, ok := <-c.events:
+			if !ok {
+				c.t.Fatalf("stream ended before %s", kind)
+			}
+			if e.event.Kind == kind {
+				return e
+			}
+		case <-timeout:
+			c.t.Fatalf("no %s received", kind)
+		}
+	}
+}
+
+func TestSSESubscription(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   func(roomID string) string
+		header []string
+	}{
+		{"SSEPath", func(roomID string) string { return "/subscribe/" + roomID + "/sse" }, nil},
+		{"Negotiated", func(roomID string) string { return "/subscribe/" + roomID }, []string{"Accept", "text/event-stream"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, nil)
+			c := s.subscribeSSE(t, room.ID, tt.path(room.ID), tt.header...)
+
+			id := s.postMessage(t, room.ID, "question over sse")
+			e := c.expect(events.KindMessageCreated)
+			if e.id != id {
+				t.Errorf("got event id %q, want the message id %s", e.id, id)
+			}
+			created := e.event.Value.(events.MessageCreated)
+			if created.ID != id || created.Message != "question over sse" {
+				t.Errorf("got message_created %+v, want message %s", created, id)
+			}
+		})
+	}
+}
+
+func TestSSEResumeFromLastEventID(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	first := s.postMessage(t, room.ID, "first")
+	missed := []string{
+		s.postMessage(t, room.ID, "second"),
+		s.postMessage(t, room.ID, "third"),
+	}
+
+	c := s.subscribeSSE(t, room.ID, "/subscribe/"+room.ID+"/sse", "Last-Event-ID", first)
+	for _, id := range missed {
+		if e := c.expect(events.KindMessageCreated); e.id != id {
+			t.Fatalf("got replayed message %s, want %s", e.id, id)
+		}
+	}
+
+	// Live events follow the replayed ones, without repeating them.
+	live := s.postMessage(t, room.ID, "fourth")
+	if e := c.expect(events.KindMessageCreated); e.id != live {
+		t.Errorf("got message %s after the replay, want %s", e.id, live)
+	}
+}
+
+func TestSSEInvalidLastEventID(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+
+	resp := s.openSSE(t, "/subscribe/"+room.ID+"/sse", "Last-Event-ID", "not-an-id")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestSSEUnknownRoom(t *testing.T) {
+	s := newTestServer(t)
+
+	resp := s.openSSE(t, "/subscribe/00000000-0000-0000-0000-0000000000ff/sse")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("got status %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+const (
+	roomStatsTTL         = 5 * time.Second
+	roomStatsTopMessages = 3
+)
+
+// roomStats is the database part of a room's statistics. It is cached for
+// roomStatsTTL per room so the stats endpoint can't be used to hammer the
+// database.
+type roomStats struct {
+	Counts      pgstore.GetRoomStatsRow
+	TopMessages []pgstore.Message
+	fetchedAt   time.Time
+}
+
+type roomStatsCache struct {
+	mu    sync.Mutex
+	rooms map[uuid.UUID]roomStats
+}
+
+func newRoomStatsCache() *roomStatsCache {
+	return &roomStatsCache{rooms: make(map[uuid.UUID]roomStats)}
+}
+
+func (c *roomStatsCache) get(roomID uuid.UUID, now time.Time) (roomStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, ok := c.rooms[roomID]
+	if !ok || now.Sub(stats.fetchedAt) >= roomStatsTTL {
+		delete(c.rooms, roomID)
+		return roomStats{}, false
+	}
+	return stats, true
+}
+
+func (c *roomStatsCache) put(roomID uuid.UUID, stats roomStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rooms[roomID] = stats
+}
+
+func (api *Handler) roomStats(ctx context.Context, roomID uuid.UUID) (roomStats, error) {
+	now := api.now()
+	if stats, ok := api.statsCache.get(roomID, now); ok {
+		return stats, nil
+	}
+
+	counts, err := api.queries.GetRoomStats(ctx, roomID)
+	if err != nil {
+		return roomStats{}, err
+	}
+
+	top, err := api.queries.GetTopUnansweredMessages(ctx, pgstore.GetTopUnansweredMessagesParams{
+		RoomID: roomID,
+		Limit:  roomStatsTopMessages,
+	})
+	if err != nil {
+		return roomStats{}, err
+	}
+
+	stats := roomStats{Counts: counts, TopMessages: top, fetchedAt: now}
+	api.statsCache.put(roomID, stats)
+	return stats, nil
+}
+
+// broadcastCount returns the number of events broadcast to roomID since the
+// process started.
+func (api *Handler) broadcastCount(roomID string) uint64 {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.broadcasts[roomID]
+}
+
+// subscriberCount returns the number of websocket clients currently subscribed
+// to roomID.
+func (api *Handler) subscriberCount(roomID string) int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return len(api.subscribers[roomID])
+}
+
+func (api *Handler) handleGetRoomStats(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := uuid.Parse(rawRoomID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "room_not_found", "room not found")
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	stats, err := api.roomStats(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	type topMessage struct {
+		ID            string  `json:"id"`
+		Message       string  `json:"message"`
+		AuthorName    *string `json:"author_name"`
+		ReactionCount int64   `json:"reaction_count"`
+	}
+	top := make([]topMessage, 0, len(stats.TopMessages))
+	for _, m := range stats.TopMessages {
+		top = append(top, topMessage{
+			ID:            m.ID.String(),
+			Message:       m.Message,
+			AuthorName:    m.AuthorName,
+			ReactionCount: m.ReactionCount,
+		})
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"total_messages":          stats.Counts.TotalMessages,
+		"answered_messages":       stats.Counts.AnsweredMessages,
+		"unanswered_messages":     stats.Counts.UnansweredMessages,
+		"total_reactions":         stats.Counts.TotalReactions,
+		"top_unanswered_messages": top,
+		"subscribers":             api.subscriberCount(rawRoomID),
+		"events_broadcast":        api.broadcastCount(rawRoomID),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
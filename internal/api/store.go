@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+const defaultDBTimeout = 3 * time.Second
+
+// Store is the data access the handlers depend on. It is implemented by
+// *pgstore.Queries, by *sqlitestore.Store for single-binary deployments, and
+// by *memstore.Store for running and testing the API without a database.
+type Store interface {
+	pgstore.Querier
+	InsertMessageWithinCapacity(ctx context.Context, arg pgstore.InsertMessageWithinCapacityParams) (uuid.UUID, uuid.UUID, error)
+	DeleteRoomWithMessages(ctx context.Context, id uuid.UUID) error
+	InsertRoomWithWebhooks(ctx context.Context, room pgstore.InsertRoomParams, webhooks []pgstore.InsertWebhookParams) (uuid.UUID, error)
+	UpdateRoomWebhooks(ctx context.Context, arg pgstore.UpdateRoomWebhooksParams) ([]pgstore.Webhook, error)
+	ApplyReactionBatch(ctx context.Context, arg pgstore.ApplyReactionBatchParams) ([]pgstore.GetReactionCountsRow, error)
+	InsertPollWithOptions(ctx context.Context, poll pgstore.InsertPollParams, options []string) error
+}
+
+var _ Store = (*pgstore.Queries)(nil)
+
+// Classes of store errors. Errors returned by the handler's store match
+// exactly one of them with errors.Is, and still match the underlying error.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrUnavailable = errors.New("unavailable")
+	ErrInternal    = errors.New("internal error")
+)
+
+type storeError struct {
+	class error
+	err   error
+}
+
+func (e *storeError) Error() string {
+	return e.class.Error() + ": " + e.err.Error()
+}
+
+func (e *storeError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// dbStore wraps a Store, bounding every call by a timeout, retrying once on
+// transient failures and classifying the errors it returns.
+type dbStore struct {
+	next    Store
+	timeout time.Duration
+}
+
+func call[T any](ctx context.Context, s *dbStore, fn func(context.Context) (T, error)) (T, error) {
+	v, err := try(ctx, s.timeout, fn)
+	if err != nil && ctx.Err() == nil && isTransient(err) {
+		v, err = try(ctx, s.timeout, fn)
+	}
+	return v, classify(err)
+}
+
+func callErr(ctx context.Context, s *dbStore, fn func(context.Context) error) error {
+	_, err := call(ctx, s, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+func try[T any](ctx context.Context, timeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// isTransient reports whether err is worth retrying: serialization failures,
+// deadlocks, and connection errors that happened before anything was sent.
+func isTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return pgconn.SafeToRetry(err)
+}
+
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	class := ErrInternal
+	var pgErr *pgconn.PgError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		class = ErrNotFound
+	case errors.As(err, &pgErr):
+		switch {
+		// unique_violation, exclusion_violation, serialization_failure, deadlock_detected
+		case pgErr.Code == "23505", pgErr.Code == "23P01", pgErr.Code == "40001", pgErr.Code == "40P01":
+			class = ErrConflict
+		// connection exceptions, insufficient resources and operator intervention
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "53"), strings.HasPrefix(pgErr.Code, "57"):
+			class = ErrUnavailable
+		}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr), pgconn.SafeToRetry(err):
+		class = ErrUnavailable
+	}
+	return &storeError{class: class, err: err}
+}
+
+// writeStoreError responds to a failed store call with the status matching
+// the error's class. notFound is the error code of missing rows, such as
+// room_not_found.
+func (api *Handler) writeStoreError(w http.ResponseWriter, err error, notFound string) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, notFound, strings.ReplaceAll(notFound, "_", " "))
+	case errors.Is(err, ErrConflict):
+		writeError(w, http.StatusConflict, "conflict", "conflicting change, try again")
+	case errors.Is(err, ErrUnavailable):
+		api.logger.Warn("store unavailable", "error", err)
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "service unavailable")
+	default:
+		api.logger.Error("store call failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+	}
+}
@@ -0,0 +1,495 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// The methods below apply dbStore's timeout, retry and error classification to
+// every query of the underlying store.
+
+func (s *dbStore) ApplyReactionBatch(ctx context.Context, arg pgstore.ApplyReactionBatchParams) ([]pgstore.GetReactionCountsRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.GetReactionCountsRow, error) {
+		return s.next.ApplyReactionBatch(ctx, arg)
+	})
+}
+
+func (s *dbStore) ApproveMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Message, error) {
+		return s.next.ApproveMessage(ctx, id)
+	})
+}
+
+func (s *dbStore) CastPollVote(ctx context.Context, arg pgstore.CastPollVoteParams) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.CastPollVote(ctx, arg)
+	})
+}
+
+func (s *dbStore) ClosePoll(ctx context.Context, arg pgstore.ClosePollParams) (pgstore.Poll, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Poll, error) {
+		return s.next.ClosePoll(ctx, arg)
+	})
+}
+
+func (s *dbStore) CloseDueRooms(ctx context.Context, closesAt *time.Time) ([]uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) ([]uuid.UUID, error) {
+		return s.next.CloseDueRooms(ctx, closesAt)
+	})
+}
+
+func (s *dbStore) CloseRoom(ctx context.Context, arg pgstore.CloseRoomParams) (pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Room, error) {
+		return s.next.CloseRoom(ctx, arg)
+	})
+}
+
+func (s *dbStore) CountMessageFlags(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.CountMessageFlags(ctx, messageID)
+	})
+}
+
+func (s *dbStore) CountRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.CountRoomMessages(ctx, roomID)
+	})
+}
+
+func (s *dbStore) CountRoomMessagesWithoutConsent(ctx context.Context, arg pgstore.CountRoomMessagesWithoutConsentParams) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.CountRoomMessagesWithoutConsent(ctx, arg)
+	})
+}
+
+func (s *dbStore) DecrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.DecrementReactionCounts(ctx, ids)
+	})
+}
+
+func (s *dbStore) DeleteClientReactions(ctx context.Context, arg pgstore.DeleteClientReactionsParams) ([]uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) ([]uuid.UUID, error) {
+		return s.next.DeleteClientReactions(ctx, arg)
+	})
+}
+
+func (s *dbStore) DeleteEmojiReaction(ctx context.Context, arg pgstore.DeleteEmojiReactionParams) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.DeleteEmojiReaction(ctx, arg)
+	})
+}
+
+func (s *dbStore) DeleteOldestPrunableMessage(ctx context.Context, roomID uuid.UUID) (uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) (uuid.UUID, error) {
+		return s.next.DeleteOldestPrunableMessage(ctx, roomID)
+	})
+}
+
+func (s *dbStore) DeleteRoom(ctx context.Context, id uuid.UUID) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.DeleteRoom(ctx, id)
+	})
+}
+
+func (s *dbStore) DeleteRoomMessages(ctx context.Context, roomID uuid.UUID) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.DeleteRoomMessages(ctx, roomID)
+	})
+}
+
+func (s *dbStore) DeleteRoomWebhook(ctx context.Context, arg pgstore.DeleteRoomWebhookParams) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.DeleteRoomWebhook(ctx, arg)
+	})
+}
+
+func (s *dbStore) DeleteRoomWithMessages(ctx context.Context, id uuid.UUID) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.DeleteRoomWithMessages(ctx, id)
+	})
+}
+
+func (s *dbStore) FindSimilarUnansweredMessages(ctx context.Context, arg pgstore.FindSimilarUnansweredMessagesParams) ([]pgstore.FindSimilarUnansweredMessagesRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.FindSimilarUnansweredMessagesRow, error) {
+		return s.next.FindSimilarUnansweredMessages(ctx, arg)
+	})
+}
+
+func (s *dbStore) GetAnnouncement(ctx context.Context, id uuid.UUID) (pgstore.Announcement, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Announcement, error) {
+		return s.next.GetAnnouncement(ctx, id)
+	})
+}
+
+func (s *dbStore) GetDeletedRoomIDs(ctx context.Context, deletedAt *time.Time) ([]uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) ([]uuid.UUID, error) {
+		return s.next.GetDeletedRoomIDs(ctx, deletedAt)
+	})
+}
+
+func (s *dbStore) GetEmojiReactionCounts(ctx context.Context, ids []uuid.UUID) ([]pgstore.GetEmojiReactionCountsRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.GetEmojiReactionCountsRow, error) {
+		return s.next.GetEmojiReactionCounts(ctx, ids)
+	})
+}
+
+func (s *dbStore) GetExpiredRoomIDs(ctx context.Context, expiresAt *time.Time) ([]uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) ([]uuid.UUID, error) {
+		return s.next.GetExpiredRoomIDs(ctx, expiresAt)
+	})
+}
+
+func (s *dbStore) GetMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Message, error) {
+		return s.next.GetMessage(ctx, id)
+	})
+}
+
+func (s *dbStore) GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]pgstore.MessageEdit, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.MessageEdit, error) {
+		return s.next.GetMessageEdits(ctx, messageID)
+	})
+}
+
+func (s *dbStore) GetMessageReplies(ctx context.Context, messageID uuid.UUID) ([]pgstore.MessageReply, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.MessageReply, error) {
+		return s.next.GetMessageReplies(ctx, messageID)
+	})
+}
+
+func (s *dbStore) GetMessageReply(ctx context.Context, id uuid.UUID) (pgstore.MessageReply, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.MessageReply, error) {
+		return s.next.GetMessageReply(ctx, id)
+	})
+}
+
+func (s *dbStore) GetPoll(ctx context.Context, id uuid.UUID) (pgstore.Poll, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Poll, error) {
+		return s.next.GetPoll(ctx, id)
+	})
+}
+
+func (s *dbStore) GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]pgstore.PollOption, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.PollOption, error) {
+		return s.next.GetPollOptions(ctx, pollID)
+	})
+}
+
+func (s *dbStore) GetPollVoteCounts(ctx context.Context, pollID uuid.UUID) ([]pgstore.GetPollVoteCountsRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.GetPollVoteCountsRow, error) {
+		return s.next.GetPollVoteCounts(ctx, pollID)
+	})
+}
+
+func (s *dbStore) GetReactionCounts(ctx context.Context, ids []uuid.UUID) ([]pgstore.GetReactionCountsRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.GetReactionCountsRow, error) {
+		return s.next.GetReactionCounts(ctx, ids)
+	})
+}
+
+func (s *dbStore) GetRoom(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Room, error) {
+		return s.next.GetRoom(ctx, id)
+	})
+}
+
+func (s *dbStore) GetRoomAnnouncements(ctx context.Context, roomID uuid.UUID) ([]pgstore.Announcement, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Announcement, error) {
+		return s.next.GetRoomAnnouncements(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomFlaggedMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.GetRoomFlaggedMessagesRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.GetRoomFlaggedMessagesRow, error) {
+		return s.next.GetRoomFlaggedMessages(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomForUpdate(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Room, error) {
+		return s.next.GetRoomForUpdate(ctx, id)
+	})
+}
+
+func (s *dbStore) GetRoomIDByCode(ctx context.Context, code string) (uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) (uuid.UUID, error) {
+		return s.next.GetRoomIDByCode(ctx, code)
+	})
+}
+
+func (s *dbStore) GetRoomMessageIDs(ctx context.Context, arg pgstore.GetRoomMessageIDsParams) ([]uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) ([]uuid.UUID, error) {
+		return s.next.GetRoomMessageIDs(ctx, arg)
+	})
+}
+
+func (s *dbStore) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Message, error) {
+		return s.next.GetRoomMessages(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomMessagesCreatedAfter(ctx context.Context, arg pgstore.GetRoomMessagesCreatedAfterParams) ([]pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Message, error) {
+		return s.next.GetRoomMessagesCreatedAfter(ctx, arg)
+	})
+}
+
+func (s *dbStore) GetRoomMessagesPage(ctx context.Context, arg pgstore.GetRoomMessagesPageParams) ([]pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Message, error) {
+		return s.next.GetRoomMessagesPage(ctx, arg)
+	})
+}
+
+func (s *dbStore) GetRoomModerationAudit(ctx context.Context, roomID uuid.UUID) ([]pgstore.ModerationAudit, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.ModerationAudit, error) {
+		return s.next.GetRoomModerationAudit(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomPendingMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Message, error) {
+		return s.next.GetRoomPendingMessages(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomPollOptions(ctx context.Context, roomID uuid.UUID) ([]pgstore.GetRoomPollOptionsRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.GetRoomPollOptionsRow, error) {
+		return s.next.GetRoomPollOptions(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomPolls(ctx context.Context, roomID uuid.UUID) ([]pgstore.Poll, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Poll, error) {
+		return s.next.GetRoomPolls(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomStats(ctx context.Context, roomID uuid.UUID) (pgstore.GetRoomStatsRow, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.GetRoomStatsRow, error) {
+		return s.next.GetRoomStats(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRoomWebhooks(ctx context.Context, roomID uuid.UUID) ([]pgstore.Webhook, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Webhook, error) {
+		return s.next.GetRoomWebhooks(ctx, roomID)
+	})
+}
+
+func (s *dbStore) GetRooms(ctx context.Context) ([]pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Room, error) {
+		return s.next.GetRooms(ctx)
+	})
+}
+
+func (s *dbStore) GetTopUnansweredMessages(ctx context.Context, arg pgstore.GetTopUnansweredMessagesParams) ([]pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Message, error) {
+		return s.next.GetTopUnansweredMessages(ctx, arg)
+	})
+}
+
+func (s *dbStore) IncrementParticipantQuestions(ctx context.Context, arg pgstore.IncrementParticipantQuestionsParams) (int32, error) {
+	return call(ctx, s, func(ctx context.Context) (int32, error) {
+		return s.next.IncrementParticipantQuestions(ctx, arg)
+	})
+}
+
+func (s *dbStore) IncrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.IncrementReactionCounts(ctx, ids)
+	})
+}
+
+func (s *dbStore) InsertAnnouncement(ctx context.Context, arg pgstore.InsertAnnouncementParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertAnnouncement(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertClientReactions(ctx context.Context, arg pgstore.InsertClientReactionsParams) ([]uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) ([]uuid.UUID, error) {
+		return s.next.InsertClientReactions(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertEmojiReaction(ctx context.Context, arg pgstore.InsertEmojiReactionParams) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.InsertEmojiReaction(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertMessage(ctx context.Context, arg pgstore.InsertMessageParams) (uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) (uuid.UUID, error) {
+		return s.next.InsertMessage(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertMessageEdit(ctx context.Context, arg pgstore.InsertMessageEditParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertMessageEdit(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertMessageFlag(ctx context.Context, arg pgstore.InsertMessageFlagParams) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.InsertMessageFlag(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertMessageWithinCapacity(ctx context.Context, arg pgstore.InsertMessageWithinCapacityParams) (uuid.UUID, uuid.UUID, error) {
+	var id, pruned uuid.UUID
+	err := callErr(ctx, s, func(ctx context.Context) (err error) {
+		id, pruned, err = s.next.InsertMessageWithinCapacity(ctx, arg)
+		return err
+	})
+	return id, pruned, err
+}
+
+func (s *dbStore) InsertMessageReply(ctx context.Context, arg pgstore.InsertMessageReplyParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertMessageReply(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertModerationAudit(ctx context.Context, arg pgstore.InsertModerationAuditParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertModerationAudit(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertPoll(ctx context.Context, arg pgstore.InsertPollParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertPoll(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertPollOption(ctx context.Context, arg pgstore.InsertPollOptionParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertPollOption(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertPollWithOptions(ctx context.Context, poll pgstore.InsertPollParams, options []string) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertPollWithOptions(ctx, poll, options)
+	})
+}
+
+func (s *dbStore) InsertRoom(ctx context.Context, arg pgstore.InsertRoomParams) (uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) (uuid.UUID, error) {
+		return s.next.InsertRoom(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertRoomWithWebhooks(ctx context.Context, room pgstore.InsertRoomParams, webhooks []pgstore.InsertWebhookParams) (uuid.UUID, error) {
+	return call(ctx, s, func(ctx context.Context) (uuid.UUID, error) {
+		return s.next.InsertRoomWithWebhooks(ctx, room, webhooks)
+	})
+}
+
+func (s *dbStore) InsertWebhook(ctx context.Context, arg pgstore.InsertWebhookParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertWebhook(ctx, arg)
+	})
+}
+
+func (s *dbStore) InsertWebhookDeliveryFailure(ctx context.Context, arg pgstore.InsertWebhookDeliveryFailureParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.InsertWebhookDeliveryFailure(ctx, arg)
+	})
+}
+
+func (s *dbStore) ListRooms(ctx context.Context, arg pgstore.ListRoomsParams) ([]pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Room, error) {
+		return s.next.ListRooms(ctx, arg)
+	})
+}
+
+func (s *dbStore) MarkMessageAsAnswered(ctx context.Context, arg pgstore.MarkMessageAsAnsweredParams) (pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Message, error) {
+		return s.next.MarkMessageAsAnswered(ctx, arg)
+	})
+}
+
+func (s *dbStore) ReactToMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.ReactToMessage(ctx, id)
+	})
+}
+
+func (s *dbStore) RecordWebhookDelivery(ctx context.Context, arg pgstore.RecordWebhookDeliveryParams) error {
+	return callErr(ctx, s, func(ctx context.Context) error {
+		return s.next.RecordWebhookDelivery(ctx, arg)
+	})
+}
+
+func (s *dbStore) RemoveReactionFromMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	return call(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.next.RemoveReactionFromMessage(ctx, id)
+	})
+}
+
+func (s *dbStore) RestoreMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Message, error) {
+		return s.next.RestoreMessage(ctx, id)
+	})
+}
+
+func (s *dbStore) RestoreRoom(ctx context.Context, arg pgstore.RestoreRoomParams) (pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Room, error) {
+		return s.next.RestoreRoom(ctx, arg)
+	})
+}
+
+func (s *dbStore) SearchRoomMessages(ctx context.Context, arg pgstore.SearchRoomMessagesParams) ([]pgstore.SearchRoomMessagesRow, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.SearchRoomMessagesRow, error) {
+		return s.next.SearchRoomMessages(ctx, arg)
+	})
+}
+
+func (s *dbStore) SoftDeleteMessage(ctx context.Context, arg pgstore.SoftDeleteMessageParams) (pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Message, error) {
+		return s.next.SoftDeleteMessage(ctx, arg)
+	})
+}
+
+func (s *dbStore) SoftDeleteRoom(ctx context.Context, arg pgstore.SoftDeleteRoomParams) (pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Room, error) {
+		return s.next.SoftDeleteRoom(ctx, arg)
+	})
+}
+
+func (s *dbStore) UpdateMessage(ctx context.Context, arg pgstore.UpdateMessageParams) (pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Message, error) {
+		return s.next.UpdateMessage(ctx, arg)
+	})
+}
+
+func (s *dbStore) UpdateMessageConsent(ctx context.Context, arg pgstore.UpdateMessageConsentParams) (bool, error) {
+	return call(ctx, s, func(ctx context.Context) (bool, error) {
+		return s.next.UpdateMessageConsent(ctx, arg)
+	})
+}
+
+func (s *dbStore) UpdateMessageLanguage(ctx context.Context, arg pgstore.UpdateMessageLanguageParams) (pgstore.Message, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Message, error) {
+		return s.next.UpdateMessageLanguage(ctx, arg)
+	})
+}
+
+func (s *dbStore) UpdateRoom(ctx context.Context, arg pgstore.UpdateRoomParams) (pgstore.Room, error) {
+	return call(ctx, s, func(ctx context.Context) (pgstore.Room, error) {
+		return s.next.UpdateRoom(ctx, arg)
+	})
+}
+
+func (s *dbStore) UpdateRoomWebhooks(ctx context.Context, arg pgstore.UpdateRoomWebhooksParams) ([]pgstore.Webhook, error) {
+	return call(ctx, s, func(ctx context.Context) ([]pgstore.Webhook, error) {
+		return s.next.UpdateRoomWebhooks(ctx, arg)
+	})
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lohanguedes/AMA-Backend/internal/store/memstore"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// failingStore is the in-memory store with GetRoom failing with errs in turn,
+// then succeeding.
+type failingStore struct {
+	*memstore.Store
+	errs  []error
+	calls atomic.Int32
+	// block makes GetRoom wait for its context to be done instead.
+	block bool
+}
+
+func (s *failingStore) GetRoom(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	n := int(s.calls.Add(1))
+	if s.block {
+		<-ctx.Done()
+		return pgstore.Room{}, ctx.Err()
+	}
+	if n <= len(s.errs) {
+		return pgstore.Room{}, s.errs[n-1]
+	}
+	return pgstore.Room{ID: id}, nil
+}
+
+// safeToRetryError is a connection error pgconn reports as happening before
+// anything was sent.
+type safeToRetryError struct{}
+
+func (safeToRetryError) Error() string     { return "connection refused" }
+func (safeToRetryError) SafeToRetry() bool { return true }
+
+func pgError(code string) error {
+	return &pgconn.PgError{Code: code, Message: "pg error " + code}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class error
+	}{
+		{"NoRows", pgx.ErrNoRows, ErrNotFound},
+		{"WrappedNoRows", errors.Join(errors.New("reading room"), pgx.ErrNoRows), ErrNotFound},
+		{"UniqueViolation", pgError("23505"), ErrConflict},
+		{"ExclusionViolation", pgError("23P01"), ErrConflict},
+		{"SerializationFailure", pgError("40001"), ErrConflict},
+		{"Deadlock", pgError("40P01"), ErrConflict},
+		{"ConnectionFailure", pgError("08006"), ErrUnavailable},
+		{"TooManyConnections", pgError("53300"), ErrUnavailable},
+		{"AdminShutdown", pgError("57P01"), ErrUnavailable},
+		{"ForeignKeyViolation", pgError("23503"), ErrInternal},
+		{"SyntaxError", pgError("42601"), ErrInternal},
+		{"Deadline", context.DeadlineExceeded, ErrUnavailable},
+		{"Network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, ErrUnavailable},
+		{"SafeToRetry", safeToRetryError{}, ErrUnavailable},
+		{"Other", errors.New("boom"), ErrInternal},
+	}
+	classes := []error{ErrNotFound, ErrConflict, ErrUnavailable, ErrInternal}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classify(tt.err)
+			for _, class := range classes {
+				if got, want := errors.Is(err, class), class == tt.class; got != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, class, got, want)
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%v doesn't match the underlying error", err)
+			}
+		})
+	}
+	if err := classify(nil); err != nil {
+		t.Errorf("classify(nil) = %v, want nil", err)
+	}
+}
+
+func TestDBStoreRetry(t *testing.T) {
+	tests := []struct {
+		name  string
+		errs  []error
+		calls int32
+		class error
+	}{
+		{"Success", nil, 1, nil},
+		{"SerializationFailureOnce", []error{pgError("40001")}, 2, nil},
+		{"DeadlockOnce", []error{pgError("40P01")}, 2, nil},
+		{"ConnectionRefusedOnce", []error{safeToRetryError{}}, 2, nil},
+		{"SerializationFailureTwice", []error{pgError("40001"), pgError("40001")}, 2, ErrConflict},
+		{"UniqueViolation", []error{pgError("23505")}, 1, ErrConflict},
+		{"NoRows", []error{pgx.ErrNoRows}, 1, ErrNotFound},
+		{"Other", []error{errors.New("boom")}, 1, ErrInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &failingStore{Store: memstore.New(), errs: tt.errs}
+			s := &dbStore{next: store, timeout: time.Second}
+
+			_, err := s.GetRoom(context.Background(), uuid.New())
+			if tt.class == nil && err != nil {
+				t.Errorf("got error %v, want none", err)
+			}
+			if tt.class != nil && !errors.Is(err, tt.class) {
+				t.Errorf("got error %v, want %v", err, tt.class)
+			}
+			if got := store.calls.Load(); got != tt.calls {
+				t.Errorf("store called %d times, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestDBStoreNoRetryOnceCancelled(t *testing.T) {
+	store := &failingStore{Store: memstore.New(), errs: []error{pgError("40001")}}
+	s := &dbStore{next: store, timeout: time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.GetRoom(ctx, uuid.New()); !errors.Is(err, ErrConflict) {
+		t.Errorf("got error %v, want ErrConflict", err)
+	}
+	if got := store.calls.Load(); got != 1 {
+		t.Errorf("store called %d times, want 1", got)
+	}
+}
+
+func TestDBStoreTimeout(t *testing.T) {
+	store := &failingStore{Store: memstore.New(), block: true}
+	s := &dbStore{next: store, timeout: 10 * time.Millisecond}
+
+	start := time.Now()
+	_, err := s.GetRoom(context.Background(), uuid.New())
+	if !errors.Is(err, ErrUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want ErrUnavailable from the deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("call took %v, want it bounded by the timeout", elapsed)
+	}
+	if got := store.calls.Load(); got != 1 {
+		t.Errorf("store called %d times, want 1", got)
+	}
+}
+
+func TestWriteStoreError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"NotFound", pgx.ErrNoRows, http.StatusNotFound, "room_not_found"},
+		{"Conflict", pgError("23505"), http.StatusConflict, "conflict"},
+		{"Unavailable", pgError("53300"), http.StatusServiceUnavailable, "unavailable"},
+		{"Internal", errors.New("boom"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestHandler(t)
+			w := httptest.NewRecorder()
+			api.writeStoreError(w, classify(tt.err), "room_not_found")
+			if w.Code != tt.status {
+				t.Errorf("got status %d, want %d", w.Code, tt.status)
+			}
+			if code := problemCode(t, w.Body.Bytes()); code != tt.code {
+				t.Errorf("got code %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestStoreErrorResponses(t *testing.T) {
+	store := &failingStore{Store: memstore.New(), errs: []error{pgError("08006")}}
+	api := NewHandler(store, WithLogger(discardLogger()))
+	t.Cleanup(func() { api.Shutdown(context.Background()) })
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/rooms/"+uuid.NewString(), nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want 503", w.Code)
+	}
+}
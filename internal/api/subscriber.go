@@ -0,0 +1,433 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// closeReasonShutdown is the close reason of the subscriptions ended by
+// Shutdown.
+const closeReasonShutdown = "server_shutdown"
+
+const (
+	defaultSendQueueSize = 64
+	defaultWriteTimeout  = 5 * time.Second
+	// defaultWSPingInterval is how often websocket clients are pinged. A
+	// client that sends nothing, not even a pong, for two intervals is
+	// dropped.
+	defaultWSPingInterval = 30 * time.Second
+	// defaultWSBufferSize is the size of the read and write buffers of
+	// every websocket connection.
+	defaultWSBufferSize = 4096
+)
+
+// transport delivers serialized events to a single client. It is implemented
+// by every way a client can subscribe to a room (websocket, server-sent
+// events) so the fan-out path doesn't need to know which one it talks to.
+type transport interface {
+	// Name identifies the transport in the subscriber listing.
+	Name() string
+	WriteEvent(p *payload, deadline time.Time) error
+	// Close ends the connection, telling the client why when the transport
+	// supports it.
+	Close(reason string) error
+}
+
+// payload is an event serialized once for every subscriber it is sent to. The
+// prepared websocket message additionally caches the compressed frame, so a
+// broadcast is compressed at most once no matter how many clients receive it.
+type payload struct {
+	msg      events.Event
+	data     []byte
+	prepared *websocket.PreparedMessage
+
+	// tagged is the event with its room_id, for connections subscribed
+	// to several rooms. It is only built when one of them receives it.
+	tagOnce   sync.Once
+	tagged    *websocket.PreparedMessage
+	taggedErr error
+}
+
+func newPayload(msg events.Event) (*payload, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	prepared, err := websocket.NewPreparedMessage(websocket.TextMessage, data)
+	if err != nil {
+		return nil, err
+	}
+	return &payload{msg: msg, data: data, prepared: prepared}, nil
+}
+
+// withRoomID returns the event serialized with its room_id field.
+func (p *payload) withRoomID() (*websocket.PreparedMessage, error) {
+	p.tagOnce.Do(func() {
+		data, err := json.Marshal(struct {
+			Kind   string `json:"kind"`
+			RoomID string `json:"room_id,omitempty"`
+			Seq    uint64 `json:"seq,omitempty"`
+			Value  any    `json:"value"`
+		}{p.msg.Kind, p.msg.RoomID, p.msg.Seq, p.msg.Value})
+		if err != nil {
+			p.taggedErr = err
+			return
+		}
+		p.tagged, p.taggedErr = websocket.NewPreparedMessage(websocket.TextMessage, data)
+	})
+	return p.tagged, p.taggedErr
+}
+
+// heartbeater is implemented by transports that need periodic traffic to keep
+// idle connections alive.
+type heartbeater interface {
+	HeartbeatInterval() time.Duration
+	Heartbeat(deadline time.Time) error
+}
+
+// subscriber is a single client listening to a room. Events are queued on send
+// and written by writePump, so a slow client never blocks the broadcast loop.
+type subscriber struct {
+	// id identifies the connection to admins.
+	id        string
+	transport transport
+	// roomID is the room the subscription was opened for, empty for
+	// multiplexed connections which join rooms later on.
+	roomID string
+	// rooms are the rooms the subscriber is registered with. It is guarded
+	// by the handler's mu.
+	rooms map[string]struct{}
+	// remoteAddr is the address the subscriber connected from, see
+	// clientIP.
+	remoteAddr string
+	// scope decides which events the subscriber receives.
+	scope  string
+	send   chan *payload
+	cancel context.CancelFunc
+	logger *slog.Logger
+	stats  *wsStats
+	clock  Clock
+	// metrics counts the subscribers dropped for being too slow.
+	metrics *metrics
+	// connectedAt is when the subscription started.
+	connectedAt time.Time
+	// warned is set once the client was sent a slow consumer warning and
+	// cleared when its queue drains again.
+	warned atomic.Bool
+	// skip holds ids of messages already delivered to the client while
+	// replaying missed events, so their live broadcast isn't sent twice.
+	skip map[string]struct{}
+	// resumeSeq is the seq of the last event the client received before
+	// reconnecting, when it gave one. The recent events of the room after
+	// it are replayed first.
+	resumeSeq *uint64
+	// delivered and dropped count the events written to the client and the
+	// ones that didn't fit its queue.
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	// closeReason is sent to the client when the subscription is ended by
+	// the server rather than by the client going away.
+	closeReason atomic.Value
+}
+
+func (api *Handler) newSubscriber(t transport, roomID, remoteAddr string, cancel context.CancelFunc) *subscriber {
+	return &subscriber{
+		id:          api.ids.NewID().String(),
+		transport:   t,
+		roomID:      roomID,
+		rooms:       make(map[string]struct{}),
+		remoteAddr:  remoteAddr,
+		send:        make(chan *payload, api.sendQueueSize),
+		cancel:      cancel,
+		logger:      api.logger,
+		stats:       api.wsStats,
+		clock:       api.clock,
+		metrics:     api.metrics,
+		connectedAt: api.now(),
+	}
+}
+
+// slowConsumerThreshold is the queue length above which a client is warned
+// that it is about to be disconnected.
+func (s *subscriber) slowConsumerThreshold() int {
+	return cap(s.send) * 3 / 4
+}
+
+// deliver queues p for the client, warning it when its queue is getting full
+// and evicting it when the queue overflows.
+func (s *subscriber) deliver(p *payload) {
+	if !s.enqueue(p) {
+		s.dropped.Add(1)
+		s.metrics.droppedClients.Inc()
+		s.logger.Warn("dropping slow subscriber",
+			"room_id", s.roomID,
+			"client_ip", s.remoteAddr,
+			"subscription_duration", s.clock.Now().Sub(s.connectedAt),
+			"dropped_events", len(s.send)+1,
+		)
+		s.cancel()
+		return
+	}
+
+	queued := len(s.send)
+	if queued > s.slowConsumerThreshold() && s.warned.CompareAndSwap(false, true) {
+		warning, err := newPayload(events.Event{
+			Kind:   events.KindSlowConsumerWarning,
+			RoomID: s.roomID,
+			Value: events.SlowConsumerWarning{
+				QueuedEvents:  queued,
+				QueueCapacity: cap(s.send),
+			},
+		})
+		if err != nil {
+			s.logger.Error("failed to marshal message", "kind", events.KindSlowConsumerWarning, "error", err)
+			return
+		}
+		s.enqueue(warning)
+	}
+}
+
+// enqueue queues p without blocking. It returns false when the queue is full.
+func (s *subscriber) enqueue(p *payload) bool {
+	select {
+	case s.send <- p:
+		return true
+	default:
+		return false
+	}
+}
+
+// write writes p to the client right away.
+func (s *subscriber) write(p *payload, timeout time.Duration) error {
+	if err := s.transport.WriteEvent(p, time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	s.delivered.Add(1)
+	s.stats.record(p.msg.RoomID, p.msg.Kind, len(p.data), s.clock.Now())
+	return nil
+}
+
+// writePump writes queued events until ctx is done or a write fails or times
+// out, in which case the subscription is cancelled.
+func (s *subscriber) writePump(ctx context.Context, timeout time.Duration) {
+	var heartbeat <-chan time.Time
+	hb, ok := s.transport.(heartbeater)
+	if ok {
+		ticker := time.NewTicker(hb.HeartbeatInterval())
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat:
+			if err := hb.Heartbeat(time.Now().Add(timeout)); err != nil {
+				s.evict("failed to send heartbeat to client", err)
+				return
+			}
+		case p := <-s.send:
+			if s.skipped(p.msg) {
+				continue
+			}
+			if err := s.write(p, timeout); err != nil {
+				s.evict("failed to send message to client", err)
+				return
+			}
+			// Multiplexed connections only leave the ended room,
+			// see closeRoom.
+			if endsRoom(p.msg.Kind) && s.roomID != "" {
+				s.close(p.msg.Kind)
+				return
+			}
+			if len(s.send) <= s.slowConsumerThreshold() {
+				s.warned.Store(false)
+			}
+		}
+	}
+}
+
+func (s *subscriber) skipped(msg events.Event) bool {
+	if len(s.skip) == 0 || msg.Kind != events.KindMessageCreated {
+		return false
+	}
+	created, ok := msg.Value.(events.MessageCreated)
+	if !ok {
+		return false
+	}
+	_, ok = s.skip[created.ID]
+	return ok
+}
+
+// close ends the subscription, sending reason to the client.
+func (s *subscriber) close(reason string) {
+	s.closeReason.Store(reason)
+	s.cancel()
+}
+
+func (s *subscriber) evict(reason string, err error) {
+	s.logger.Warn(reason,
+		"room_id", s.roomID,
+		"client_ip", s.remoteAddr,
+		"queued_events", len(s.send),
+		"error", err,
+	)
+	s.cancel()
+}
+
+// serveSubscriber registers sub with its room and delivers events to it until
+// the subscription ends.
+func (api *Handler) serveSubscriber(ctx context.Context, sub *subscriber, replay []events.Event) {
+	defer func() {
+		reason, _ := sub.closeReason.Load().(string)
+		sub.transport.Close(reason)
+	}()
+
+	api.mu.Lock()
+	// Shutdown may have begun since the subscription was accepted.
+	if api.isShuttingDown() {
+		api.mu.Unlock()
+		sub.close(closeReasonShutdown)
+		return
+	}
+	api.subscriptions.Add(1)
+	defer api.subscriptions.Done()
+	if sub.roomID != "" {
+		api.joinLocked(sub, sub.roomID)
+	}
+	// The recent events are read under the lock that registered sub, so
+	// every later event reaches it live and none twice.
+	resumed := true
+	if sub.resumeSeq != nil {
+		var missed []events.Event
+		missed, resumed = api.recentEventsLocked(sub.roomID, *sub.resumeSeq)
+		replay = append(replay, missed...)
+	}
+	api.logger.Info("new client connected", "room_id", sub.roomID, "client_ip", sub.remoteAddr)
+	api.mu.Unlock()
+
+	defer func() {
+		api.mu.Lock()
+		api.logger.Info("client disconnected",
+			"room_id", sub.roomID,
+			"client_ip", sub.remoteAddr,
+			"subscription_duration", sub.clock.Now().Sub(sub.connectedAt),
+		)
+		for roomID := range sub.rooms {
+			api.leaveLocked(sub, roomID)
+		}
+		api.mu.Unlock()
+	}()
+
+	// The events after resumeSeq were dropped since it was checked.
+	if !resumed {
+		sub.close(eventsExpired)
+		return
+	}
+
+	// Replayed events are written after registering so nothing broadcast in
+	// between is lost; duplicates of them are skipped by the pump instead.
+	if len(replay) > 0 {
+		sub.skip = make(map[string]struct{}, len(replay))
+		for _, msg := range replay {
+			if created, ok := msg.Value.(events.MessageCreated); ok {
+				sub.skip[created.ID] = struct{}{}
+			}
+			p, err := newPayload(msg)
+			if err != nil {
+				api.logger.Error("failed to marshal message", "kind", msg.Kind, "error", err)
+				continue
+			}
+			if err := sub.write(p, api.writeTimeout); err != nil {
+				sub.evict("failed to replay message to client", err)
+				return
+			}
+		}
+	}
+
+	sub.writePump(ctx, api.writeTimeout)
+}
+
+// joinLocked registers sub with roomID. api.mu must be held.
+func (api *Handler) joinLocked(sub *subscriber, roomID string) {
+	if _, ok := api.subscribers[roomID]; !ok {
+		api.subscribers[roomID] = make(map[*subscriber]struct{})
+	}
+	api.subscribers[roomID][sub] = struct{}{}
+	sub.rooms[roomID] = struct{}{}
+}
+
+// leaveLocked removes sub from roomID. api.mu must be held.
+func (api *Handler) leaveLocked(sub *subscriber, roomID string) {
+	delete(api.subscribers[roomID], sub)
+	if len(api.subscribers[roomID]) == 0 {
+		delete(api.subscribers, roomID)
+	}
+	delete(sub.rooms, roomID)
+}
+
+// wsTransport delivers events over a websocket connection, pinging the client
+// every pingInterval.
+type wsTransport struct {
+	conn         *websocket.Conn
+	pingInterval time.Duration
+}
+
+func (t wsTransport) Name() string {
+	return "ws"
+}
+
+func (t wsTransport) WriteEvent(p *payload, deadline time.Time) error {
+	if err := t.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return t.conn.WritePreparedMessage(p.prepared)
+}
+
+func (t wsTransport) HeartbeatInterval() time.Duration {
+	return t.pingInterval
+}
+
+func (t wsTransport) Heartbeat(deadline time.Time) error {
+	return t.conn.WriteControl(websocket.PingMessage, nil, deadline)
+}
+
+// readFrames reads the client's messages, passing them to handle, until the
+// connection fails or the client stays silent for two ping intervals. Reading
+// is also what processes the pongs and close frames of the client.
+func (t wsTransport) readFrames(handle func(data []byte)) error {
+	extend := func(string) error {
+		return t.conn.SetReadDeadline(time.Now().Add(2 * t.pingInterval))
+	}
+	t.conn.SetPongHandler(extend)
+	for {
+		if err := extend(""); err != nil {
+			return err
+		}
+		_, data, err := t.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if handle != nil {
+			handle(data)
+		}
+	}
+}
+
+func (t wsTransport) Close(reason string) error {
+	code := websocket.CloseNormalClosure
+	if reason == closeReasonShutdown {
+		code = websocket.CloseGoingAway
+	}
+	closing := websocket.FormatCloseMessage(code, reason)
+	t.conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(time.Second))
+	return t.conn.Close()
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/store/memstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// fakeTransport records the events written to it. When stalled, writes
+// block until their deadline and fail, like those to a client that stopped
+// reading.
+type fakeTransport struct {
+	stalled bool
+	written chan events.Event
+	closed  chan string
+}
+
+func newFakeTransport(stalled bool) *fakeTransport {
+	return &fakeTransport{
+		stalled: stalled,
+		written: make(chan events.Event, 100),
+		closed:  make(chan string, 1),
+	}
+}
+
+func (t *fakeTransport) Name() string { return "fake" }
+
+func (t *fakeTransport) WriteEvent(p *payload, deadline time.Time) error {
+	if t.stalled {
+		time.Sleep(time.Until(deadline))
+		return os.ErrDeadlineExceeded
+	}
+	t.written <- p.msg
+	return nil
+}
+
+func (t *fakeTransport) Close(reason string) error {
+	t.closed <- reason
+	return nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// problemCode returns the code of the problem response body.
+func problemCode(t *testing.T, body []byte) string {
+	t.Helper()
+	var problem struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &problem); err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+	return problem.Code
+}
+
+func newTestHandler(t *testing.T, opts ...Option) *Handler {
+	t.Helper()
+	opts = append([]Option{WithLogger(discardLogger())}, opts...)
+	api := NewHandler(memstore.New(), opts...)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := api.Shutdown(ctx); err != nil {
+			t.Errorf("shutting down handler: %v", err)
+		}
+	})
+	return api
+}
+
+// serve runs a subscriber of roomID writing to tr until it ends, which the
+// returned channel is closed on. It returns once the subscriber is
+// registered.
+func serve(t *testing.T, api *Handler, roomID string, tr transport) (*subscriber, <-chan struct{}) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	sub := api.newSubscriber(tr, roomID, "192.0.2.1", cancel)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		api.serveSubscriber(ctx, sub, nil)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		api.mu.Lock()
+		_, ok := api.subscribers[roomID][sub]
+		api.mu.Unlock()
+		if ok {
+			return sub, done
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber was not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func answered(roomID, id string) events.Event {
+	return events.Event{
+		Kind:   events.KindMessageAnswered,
+		RoomID: roomID,
+		Value:  events.MessageAnswered{ID: id},
+	}
+}
+
+func TestSubscriberQueueOverflow(t *testing.T) {
+	api := newTestHandler(t, WithSendQueueSize(8))
+	cancelled := false
+	sub := api.newSubscriber(newFakeTransport(false), "room", "192.0.2.1", func() { cancelled = true })
+
+	p, err := newPayload(answered("room", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing drains the queue: the 7th event is past the threshold of 6
+	// and brings the warning, which fills the queue.
+	for range 7 {
+		sub.deliver(p)
+	}
+	if cancelled || sub.dropped.Load() != 0 {
+		t.Fatal("subscriber dropped before its queue was full")
+	}
+	if len(sub.send) != 8 {
+		t.Fatalf("got %d queued events, want 8", len(sub.send))
+	}
+	for range 7 {
+		<-sub.send
+	}
+	if warning := <-sub.send; warning.msg.Kind != events.KindSlowConsumerWarning {
+		t.Fatalf("got %s after the queued events, want %s", warning.msg.Kind, events.KindSlowConsumerWarning)
+	}
+
+	for range 8 {
+		sub.deliver(p)
+	}
+	if cancelled {
+		t.Fatal("subscriber dropped while its queue had room")
+	}
+	sub.deliver(p)
+	if !cancelled || sub.dropped.Load() != 1 {
+		t.Errorf("got cancelled %v with %d dropped events, want the subscriber dropped", cancelled, sub.dropped.Load())
+	}
+}
+
+func TestSlowSubscriberDoesNotHoldOthers(t *testing.T) {
+	api := newTestHandler(t, WithSendQueueSize(4), WithWriteTimeout(time.Second))
+	fast := newFakeTransport(false)
+	slow := newFakeTransport(true)
+	serve(t, api, "room", fast)
+	slowSub, slowDone := serve(t, api, "room", slow)
+
+	// Each broadcast is read by the fast subscriber before the next, so
+	// only the slow one falls behind.
+	const broadcasts = 20
+	for i := range broadcasts {
+		id := string(rune('a' + i))
+		start := time.Now()
+		api.notifyClients(context.Background(), answered("room", id))
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("broadcast %d took %v, want it not to wait for the slow subscriber", i, elapsed)
+		}
+		select {
+		case msg := <-fast.written:
+			if got := msg.Value.(events.MessageAnswered).ID; got != id {
+				t.Fatalf("fast subscriber got %s, want %s", got, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fast subscriber got %d of %d events", i, broadcasts)
+		}
+	}
+
+	select {
+	case <-slowDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow subscriber was not dropped")
+	}
+	if slowSub.dropped.Load() == 0 {
+		t.Error("slow subscriber dropped no events")
+	}
+	api.mu.Lock()
+	_, registered := api.subscribers["room"][slowSub]
+	api.mu.Unlock()
+	if registered {
+		t.Error("slow subscriber is still registered")
+	}
+}
+
+func TestSubscriberWriteTimeout(t *testing.T) {
+	api := newTestHandler(t, WithWriteTimeout(20*time.Millisecond))
+	tr := newFakeTransport(true)
+	_, done := serve(t, api, "room", tr)
+
+	start := time.Now()
+	api.notifyClients(context.Background(), answered("room", "1"))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not dropped after its write timed out")
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("subscriber dropped after %v, before the write timeout", elapsed)
+	}
+	select {
+	case <-tr.closed:
+	default:
+		t.Error("transport was not closed")
+	}
+}
@@ -0,0 +1,73 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestKickSubscriber(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	kicked := s.subscribe(t, room.ID, "")
+	s.clock.Advance(time.Second)
+	other := s.subscribe(t, room.ID, "")
+	path := "/rooms/" + room.ID + "/subscribers"
+
+	resp := s.do(t, http.MethodGet, path, nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	subscribers := resp.list(t)
+	if len(subscribers) != 2 {
+		t.Fatalf("got %d subscribers, want 2", len(subscribers))
+	}
+	for _, sub := range subscribers {
+		if sub["id"] == "" || sub["transport"] != "ws" || sub["remote_addr"] == "" || sub["connected_at"] == "" {
+			t.Errorf("got subscriber %v, want its connection details", sub)
+		}
+	}
+	// Subscribers are listed in the order they connected.
+	connID := subscribers[0]["id"].(string)
+
+	expectStatus(t, s.do(t, http.MethodDelete, path+"/"+connID, nil, host...), http.StatusNoContent)
+
+	kicked.conn.SetReadDeadline(time.Now().Add(waitTimeout))
+	_, _, err := kicked.conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != "kicked" {
+		t.Fatalf("got %v, want the connection closed with the kicked reason", err)
+	}
+
+	s.waitSubscribers(t, room.ID, 1)
+	id := s.postMessage(t, room.ID, "still there?")
+	if created := other.expect(events.KindMessageCreated).Value.(events.MessageCreated); created.ID != id {
+		t.Errorf("got message %s, want %s", created.ID, id)
+	}
+
+	resp = s.do(t, http.MethodGet, path, nil, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if remaining := resp.list(t); len(remaining) != 1 || remaining[0]["id"] == connID || remaining[0]["events_delivered"] != float64(1) {
+		t.Errorf("got subscribers %v, want only the other one, with 1 event delivered", remaining)
+	}
+
+	resp = s.do(t, http.MethodDelete, path+"/"+connID, nil, host...)
+	expectStatus(t, resp, http.StatusNotFound)
+	if code := resp.code(t); code != "subscriber_not_found" {
+		t.Errorf("got code %q, want subscriber_not_found", code)
+	}
+}
+
+func TestSubscribersRequireHost(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	other := s.createRoom(t, nil)
+	path := "/rooms/" + room.ID + "/subscribers"
+
+	expectStatus(t, s.do(t, http.MethodGet, path, nil), http.StatusUnauthorized)
+	expectStatus(t, s.do(t, http.MethodDelete, path+"/1", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do(t, http.MethodGet, path, nil, "Authorization", "Bearer "+other.HostToken), http.StatusUnauthorized)
+}
@@ -0,0 +1,65 @@
+// Package testutil provides deterministic implementations of the clock and id
+// generator the api handler can be configured with.
+package testutil
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+)
+
+var (
+	_ api.Clock       = (*FakeClock)(nil)
+	_ api.IDGenerator = (*SequentialIDs)(nil)
+)
+
+// FakeClock is a clock that only moves when told to. It is safe for
+// concurrent use.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a clock stopped at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SequentialIDs generates the ids 00000000-0000-0000-0000-000000000001,
+// ...0002 and so on. It is safe for concurrent use.
+type SequentialIDs struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+func (g *SequentialIDs) NewID() uuid.UUID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[8:], g.next)
+	return id
+}
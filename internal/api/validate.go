@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxThemeLength and maxMessageLength are the sizes of the theme and
+	// message columns.
+	maxThemeLength   = 255
+	maxMessageLength = 255
+	// maxDescriptionLength and maxHostNameLength are the sizes of the
+	// description and host_name columns of rooms.
+	maxDescriptionLength = 1000
+	maxHostNameLength    = 50
+)
+
+// fieldError is what is wrong with one field of a request body. Code tells
+// the problems apart, Message describes it to users.
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// validator collects what is wrong with a request body, so clients learn about
+// every invalid field at once rather than one per request.
+type validator struct {
+	errs []fieldError
+}
+
+func (v *validator) add(field, code, message string) {
+	v.errs = append(v.errs, fieldError{Field: field, Code: code, Message: message})
+}
+
+// check adds the error when ok is false.
+func (v *validator) check(ok bool, field, code, message string) {
+	if !ok {
+		v.add(field, code, message)
+	}
+}
+
+// text checks a required free text field: it must hold something besides
+// whitespace, at most max characters, and no control characters other than
+// tabs and line breaks. Postgres rejects NUL bytes in text, so letting them
+// through would fail the insert.
+func (v *validator) text(field, value string, max int) {
+	switch {
+	case strings.TrimSpace(value) == "":
+		v.add(field, "required", field+" must not be empty")
+	case utf8.RuneCountInString(value) > max:
+		v.add(field, "too_long", field+" must be at most "+strconv.Itoa(max)+" characters")
+	case !saneText(value):
+		v.add(field, "invalid_characters", field+" must be UTF-8 text without control characters")
+	}
+}
+
+func saneText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// valid responds 422 with the collected errors, as the errors member of a
+// validation_failed problem, and reports false when there are any.
+func (v *validator) valid(w http.ResponseWriter) bool {
+	if len(v.errs) == 0 {
+		return true
+	}
+	writeProblem(w, http.StatusUnprocessableEntity, "validation_failed", "the request has invalid fields", map[string]any{
+		"errors": v.errs,
+	})
+	return false
+}
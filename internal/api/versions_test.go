@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// versionedChanges are the changes of a message accepting If-Match, made by
+// the author or the host of room as needed.
+var versionedChanges = []struct {
+	name   string
+	method string
+	path   string
+	body   map[string]any
+	host   bool
+	status int
+}{
+	{"Edit", http.MethodPut, "", map[string]any{"message": "edited"}, false, http.StatusOK},
+	{"Answer", http.MethodPatch, "/answer", map[string]any{"answer": "the answer"}, true, http.StatusOK},
+	{"Delete", http.MethodDelete, "", nil, true, http.StatusNoContent},
+}
+
+func TestIfMatch(t *testing.T) {
+	for _, change := range versionedChanges {
+		t.Run(change.name, func(t *testing.T) {
+			s := newTestServer(t)
+			room := s.createRoom(t, nil)
+			id := s.postMessage(t, room.ID, "question", "X-Client-Id", "author")
+			path := "/rooms/" + room.ID + "/messages/" + id
+			header := []string{"X-Client-Id", "author"}
+			if change.host {
+				header = []string{"Authorization", "Bearer " + room.HostToken}
+			}
+
+			resp := s.do(t, change.method, path+change.path, change.body, append(header, "If-Match", "2")...)
+			expectStatus(t, resp, http.StatusPreconditionFailed)
+			body := resp.object(t)
+			if body["code"] != "version_conflict" {
+				t.Errorf("got code %v, want version_conflict", body["code"])
+			}
+			current, _ := body["current"].(map[string]any)
+			if current["id"] != id || current["message"] != "question" || current["version"] != float64(1) {
+				t.Errorf("got current %v, want version 1 of the message", current)
+			}
+
+			message := s.do(t, http.MethodGet, path, nil).object(t)
+			if message["message"] != "question" || message["answered"] != false || message["version"] != float64(1) {
+				t.Errorf("got message %v, want it unchanged", message)
+			}
+
+			// Quoted ETag forms name the same version.
+			resp = s.do(t, change.method, path+change.path, change.body, append(header, "If-Match", `W/"1"`)...)
+			expectStatus(t, resp, change.status)
+			if change.status == http.StatusOK && resp.object(t)["version"] != float64(2) {
+				t.Errorf("got version %v, want 2", resp.object(t)["version"])
+			}
+		})
+	}
+}
+
+func TestIfMatchInvalid(t *testing.T) {
+	for _, change := range versionedChanges {
+		for _, ifMatch := range []string{"latest", "0", "-1"} {
+			t.Run(change.name+"/"+ifMatch, func(t *testing.T) {
+				s := newTestServer(t)
+				room := s.createRoom(t, nil)
+				id := s.postMessage(t, room.ID, "question", "X-Client-Id", "author")
+				header := []string{"X-Client-Id", "author", "If-Match", ifMatch}
+				if change.host {
+					header = append(header, "Authorization", "Bearer "+room.HostToken)
+				}
+
+				resp := s.do(t, change.method, "/rooms/"+room.ID+"/messages/"+id+change.path, change.body, header...)
+				expectStatus(t, resp, http.StatusBadRequest)
+				if code := resp.code(t); code != "invalid_if_match" {
+					t.Errorf("got code %q, want invalid_if_match", code)
+				}
+			})
+		}
+	}
+}
+
+// TestConcurrentHosts plays two hosts changing the same message, each
+// against the version they last read.
+func TestConcurrentHosts(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "question")
+	path := "/rooms/" + room.ID + "/messages/" + id
+
+	read := s.do(t, http.MethodGet, path, nil).object(t)["version"].(float64)
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "first"}, append(host, "If-Match", "1")...), http.StatusOK)
+
+	resp := s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "second"}, append(host, "If-Match", "1")...)
+	expectStatus(t, resp, http.StatusPreconditionFailed)
+	current := resp.object(t)["current"].(map[string]any)
+	if current["answer"] != "first" || current["version"] != read+1 {
+		t.Errorf("got current %v, want the first answer at version %v", current, read+1)
+	}
+	resp = s.do(t, http.MethodDelete, path, nil, append(host, "If-Match", "1")...)
+	expectStatus(t, resp, http.StatusPreconditionFailed)
+
+	// Rebased on the current version, the change applies.
+	expectStatus(t, s.do(t, http.MethodDelete, path, nil, append(host, "If-Match", "2")...), http.StatusNoContent)
+}
+
+func TestWithoutIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	host := []string{"Authorization", "Bearer " + room.HostToken}
+	id := s.postMessage(t, room.ID, "question")
+	path := "/rooms/" + room.ID + "/messages/" + id
+
+	// The last write wins.
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "first"}, host...), http.StatusOK)
+	resp := s.do(t, http.MethodPatch, path+"/answer", map[string]any{"answer": "second"}, host...)
+	expectStatus(t, resp, http.StatusOK)
+	if got := resp.object(t)["version"]; got != float64(3) {
+		t.Errorf("got version %v, want 3", got)
+	}
+	expectStatus(t, s.do(t, http.MethodDelete, path, nil, host...), http.StatusNoContent)
+}
+
+func TestMessageVersions(t *testing.T) {
+	s := newTestServer(t)
+	room := s.createRoom(t, nil)
+	c := s.subscribe(t, room.ID, "")
+	id := s.postMessage(t, room.ID, "question", "X-Client-Id", "author")
+	path := "/rooms/" + room.ID + "/messages/" + id
+
+	if created := c.expect(events.KindMessageCreated).Value.(events.MessageCreated); created.Version != 1 {
+		t.Errorf("got message_created version %d, want 1", created.Version)
+	}
+	expectStatus(t, s.do(t, http.MethodPut, path, map[string]any{"message": "edited"}, "X-Client-Id", "author"), http.StatusOK)
+	if edited := c.expect(events.KindMessageEdited).Value.(events.MessageEdited); edited.Version != 2 {
+		t.Errorf("got message_edited version %d, want 2", edited.Version)
+	}
+	expectStatus(t, s.do(t, http.MethodPatch, path+"/answer", nil, "Authorization", "Bearer "+room.HostToken), http.StatusOK)
+	if answered := c.expect(events.KindMessageAnswered).Value.(events.MessageAnswered); answered.Version != 3 {
+		t.Errorf("got message_answered version %d, want 3", answered.Version)
+	}
+
+	if got := s.do(t, http.MethodGet, path, nil).object(t)["version"]; got != float64(3) {
+		t.Errorf("got version %v, want 3", got)
+	}
+	if got := s.messages(t, room.ID)[0]["version"]; got != float64(3) {
+		t.Errorf("got listed version %v, want 3", got)
+	}
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	maxRoomWebhooks        = 5
+	webhookWorkers         = 4
+	webhookQueueSize       = 256
+	webhookTimeout         = 5 * time.Second
+	webhookMaxRetries      = 3
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookBackoff is the delay before the first retry of a failed delivery,
+// doubled on every further retry.
+var webhookBackoff = time.Second
+
+// webhookKinds are the event kinds delivered to webhooks.
+var webhookKinds = map[string]bool{
+	events.KindMessageCreated:  true,
+	events.KindMessageAnswered: true,
+	events.KindReplyCreated:    true,
+	events.KindRoomClosed:      true,
+}
+
+// webhookJob is an event waiting to be delivered to the webhooks of its room.
+// span is the span of the request the event happened in, which the delivery
+// continues the trace of.
+type webhookJob struct {
+	msg  events.Event
+	span trace.SpanContext
+}
+
+// startWebhookWorkers starts the pool delivering queued events to webhooks
+// until ctx is done.
+func (api *Handler) startWebhookWorkers(ctx context.Context) {
+	api.webhookJobs = make(chan webhookJob, webhookQueueSize)
+	for range webhookWorkers {
+		api.goBackground(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-api.webhookJobs:
+					api.deliverWebhooks(trace.ContextWithSpanContext(ctx, job.span), job.msg)
+				}
+			}
+		})
+	}
+}
+
+// queueWebhooks hands msg to the webhook workers without blocking, dropping
+// it when they are too far behind so broadcasts are never delayed.
+func (api *Handler) queueWebhooks(ctx context.Context, msg events.Event) {
+	if !webhookKinds[msg.Kind] {
+		return
+	}
+	select {
+	case api.webhookJobs <- webhookJob{msg: msg, span: trace.SpanContextFromContext(ctx)}:
+	default:
+		api.logger.Warn("webhook queue full, dropping event", "room_id", msg.RoomID, "kind", msg.Kind)
+	}
+}
+
+func (api *Handler) deliverWebhooks(ctx context.Context, msg events.Event) {
+	roomID, err := uuid.Parse(msg.RoomID)
+	if err != nil {
+		return
+	}
+
+	hooks, err := api.queries.GetRoomWebhooks(ctx, roomID)
+	if err != nil {
+		api.logger.Warn("failed to load webhooks", "room_id", msg.RoomID, "error", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	ctx, span := tracer().Start(ctx, "webhooks.deliver", trace.WithAttributes(
+		attribute.String("room_id", msg.RoomID),
+		attribute.String("event.kind", msg.Kind),
+		attribute.Int("webhooks", len(hooks)),
+	))
+	defer span.End()
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		api.logger.Error("failed to marshal webhook payload", "kind", msg.Kind, "error", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		api.deliverWebhook(ctx, hook, msg.Kind, payload)
+	}
+}
+
+// deliverWebhook posts payload to hook, retrying network errors and 5xx
+// responses with exponential backoff, and records the outcome.
+func (api *Handler) deliverWebhook(ctx context.Context, hook pgstore.Webhook, kind string, payload []byte) {
+	var (
+		status   int
+		err      error
+		attempts int
+		backoff  = webhookBackoff
+	)
+	for attempts = 1; ; attempts++ {
+		status, err = api.postWebhook(ctx, hook, kind, payload)
+		retry := err != nil || status >= http.StatusInternalServerError
+		if !retry || attempts > webhookMaxRetries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if err == nil && status >= http.StatusMultipleChoices {
+		err = fmt.Errorf("unexpected status %d", status)
+	}
+
+	now := api.now()
+	record := pgstore.RecordWebhookDeliveryParams{ID: hook.ID, LastDeliveryAt: &now}
+	if status != 0 {
+		s := int32(status)
+		record.LastStatus = &s
+	}
+	if err != nil {
+		msg := err.Error()
+		record.LastError = &msg
+	}
+	if err := api.queries.RecordWebhookDelivery(ctx, record); err != nil {
+		api.logger.Warn("failed to record webhook delivery", "webhook_id", hook.ID, "error", err)
+	}
+
+	if err == nil {
+		return
+	}
+	api.logger.Warn("webhook delivery failed",
+		"webhook_id", hook.ID,
+		"room_id", hook.RoomID,
+		"kind", kind,
+		"attempts", attempts,
+		"error", err,
+	)
+	if err := api.queries.InsertWebhookDeliveryFailure(ctx, pgstore.InsertWebhookDeliveryFailureParams{
+		WebhookID: hook.ID,
+		EventKind: kind,
+		Attempts:  int32(attempts),
+		Error:     err.Error(),
+		FailedAt:  now,
+	}); err != nil {
+		api.logger.Warn("failed to record webhook failure", "webhook_id", hook.ID, "error", err)
+	}
+}
+
+func (api *Handler) postWebhook(ctx context.Context, hook pgstore.Webhook, kind string, payload []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", kind)
+	req.Header.Set("X-Webhook-Room-Id", hook.RoomID.String())
+	req.Header.Set(webhookSignatureHeader, signWebhook(hook.Secret, payload))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := api.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the HMAC-SHA256 of payload keyed with the webhook
+// secret, as sent in the X-Webhook-Signature header.
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// roomWebhook is a webhook as listed to hosts. Its secret is only set in the
+// response registering it.
+type roomWebhook struct {
+	ID             string     `json:"id"`
+	URL            string     `json:"url"`
+	Secret         string     `json:"secret,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastDeliveryAt *time.Time `json:"last_delivery_at"`
+	LastStatus     *int32     `json:"last_status"`
+	LastError      *string    `json:"last_error"`
+}
+
+// writeRoomWebhooks responds with hooks, revealing the secrets of the ones
+// in added.
+func writeRoomWebhooks(w http.ResponseWriter, hooks []pgstore.Webhook, added map[uuid.UUID]bool) {
+	resp := make([]roomWebhook, 0, len(hooks))
+	for _, hook := range hooks {
+		webhook := roomWebhook{
+			ID:             hook.ID.String(),
+			URL:            hook.Url,
+			CreatedAt:      hook.CreatedAt,
+			LastDeliveryAt: hook.LastDeliveryAt,
+			LastStatus:     hook.LastStatus,
+			LastError:      hook.LastError,
+		}
+		if added[hook.ID] {
+			webhook.Secret = hook.Secret
+		}
+		resp = append(resp, webhook)
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (api *Handler) handleGetRoomWebhooks(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	hooks, err := api.queries.GetRoomWebhooks(r.Context(), roomID)
+	if err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	writeRoomWebhooks(w, hooks, nil)
+}
+
+// handleUpdateRoomWebhooks unregisters the webhooks in remove and registers
+// the URLs in add, responding with the webhooks of the room afterwards. The
+// secrets of the new webhooks are only ever returned here.
+func (api *Handler) handleUpdateRoomWebhooks(w http.ResponseWriter, r *http.Request) {
+	roomID, err := uuid.Parse(chi.URLParam(r, "room_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_room_id", "invalid room id")
+		return
+	}
+
+	body := struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid_json", "invalid json")
+		return
+	}
+
+	var v validator
+	v.check(len(body.Add) <= maxRoomWebhooks, "add", "too_many_webhooks", "a room can have at most 5 webhooks")
+	for _, rawURL := range body.Add {
+		if !validWebhookURL(rawURL) {
+			v.add("add", "invalid_webhook_url", "webhooks must be absolute http or https URLs")
+			break
+		}
+	}
+	remove := make([]uuid.UUID, 0, len(body.Remove))
+	for _, raw := range body.Remove {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			v.add("remove", "invalid_webhook_id", "remove must be webhook ids")
+			break
+		}
+		remove = append(remove, id)
+	}
+	if !v.valid(w) {
+		return
+	}
+
+	if _, err := api.getRoom(r.Context(), roomID); err != nil {
+		api.writeStoreError(w, err, "room_not_found")
+		return
+	}
+
+	add := make([]pgstore.InsertWebhookParams, 0, len(body.Add))
+	added := make(map[uuid.UUID]bool, len(body.Add))
+	for _, rawURL := range body.Add {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_error", "something went wrong")
+			return
+		}
+		webhook := pgstore.InsertWebhookParams{
+			ID:        api.ids.NewID(),
+			Url:       rawURL,
+			Secret:    secret,
+			CreatedAt: api.now(),
+		}
+		add = append(add, webhook)
+		added[webhook.ID] = true
+	}
+
+	hooks, err := api.queries.UpdateRoomWebhooks(r.Context(), pgstore.UpdateRoomWebhooksParams{
+		RoomID: roomID,
+		Add:    add,
+		Remove: remove,
+		Max:    maxRoomWebhooks,
+	})
+	if errors.Is(err, pgstore.ErrTooManyWebhooks) {
+		v.add("add", "too_many_webhooks", "a room can have at most 5 webhooks")
+		v.valid(w)
+		return
+	}
+	if err != nil {
+		// The room was checked above, so what's missing is a webhook.
+		api.writeStoreError(w, err, "webhook_not_found")
+		return
+	}
+
+	writeRoomWebhooks(w, hooks, added)
+}
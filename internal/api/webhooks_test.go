@@ -0,0 +1,301 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/memstore"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+// webhookStore is the in-memory store reporting the outcome of every webhook
+// delivery.
+type webhookStore struct {
+	*memstore.Store
+	delivered chan pgstore.RecordWebhookDeliveryParams
+	mu        sync.Mutex
+	failures  []pgstore.InsertWebhookDeliveryFailureParams
+}
+
+func (s *webhookStore) RecordWebhookDelivery(ctx context.Context, arg pgstore.RecordWebhookDeliveryParams) error {
+	err := s.Store.RecordWebhookDelivery(ctx, arg)
+	s.delivered <- arg
+	return err
+}
+
+func (s *webhookStore) InsertWebhookDeliveryFailure(ctx context.Context, arg pgstore.InsertWebhookDeliveryFailureParams) error {
+	s.mu.Lock()
+	s.failures = append(s.failures, arg)
+	s.mu.Unlock()
+	return s.Store.InsertWebhookDeliveryFailure(ctx, arg)
+}
+
+// webhookReceiver is a webhook endpoint answering its requests with statuses
+// in turn, then with 200.
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	t.Helper()
+	rcv := &webhookReceiver{statuses: statuses}
+	rcv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rcv.mu.Lock()
+		defer rcv.mu.Unlock()
+		rcv.requests = append(rcv.requests, r)
+		rcv.bodies = append(rcv.bodies, body)
+		status := http.StatusOK
+		if n := len(rcv.requests); n <= len(rcv.statuses) {
+			status = rcv.statuses[n-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rcv.Close)
+	return rcv
+}
+
+func (rcv *webhookReceiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+// fastWebhookRetries shortens the backoff between delivery attempts for the
+// test.
+func fastWebhookRetries(t *testing.T) {
+	backoff := webhookBackoff
+	webhookBackoff = time.Millisecond
+	t.Cleanup(func() { webhookBackoff = backoff })
+}
+
+// serveRequest serves a request of path, relative to /api/v1, with api.
+func serveRequest(t *testing.T, api *Handler, method, path string, body any, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, "/api/v1"+path, r)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+	return w
+}
+
+func newWebhookHandler(t *testing.T) (*Handler, *webhookStore) {
+	t.Helper()
+	store := &webhookStore{Store: memstore.New(), delivered: make(chan pgstore.RecordWebhookDeliveryParams, 10)}
+	api := NewHandler(store, WithLogger(discardLogger()))
+	t.Cleanup(func() { api.Shutdown(context.Background()) })
+	return api, store
+}
+
+func (s *webhookStore) waitDelivery(t *testing.T) pgstore.RecordWebhookDeliveryParams {
+	t.Helper()
+	select {
+	case d := <-s.delivered:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook delivery not recorded")
+		return pgstore.RecordWebhookDeliveryParams{}
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	fastWebhookRetries(t)
+	rcv := newWebhookReceiver(t, http.StatusBadGateway)
+	api, store := newWebhookHandler(t)
+
+	w := serveRequest(t, api, http.MethodPost, "/rooms", map[string]any{"theme": "hooks", "webhooks": []string{rcv.URL}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating room: got status %d: %s", w.Code, w.Body)
+	}
+	var room struct {
+		ID       string `json:"id"`
+		Webhooks []struct {
+			Secret string `json:"secret"`
+		} `json:"webhooks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &room); err != nil || len(room.Webhooks) != 1 {
+		t.Fatalf("decoding room %s: %v", w.Body, err)
+	}
+
+	w = serveRequest(t, api, http.MethodPost, "/rooms/"+room.ID+"/messages", map[string]any{"message": "hooked"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("posting message: got status %d: %s", w.Code, w.Body)
+	}
+	if d := store.waitDelivery(t); d.LastStatus == nil || *d.LastStatus != http.StatusOK || d.LastError != nil {
+		t.Errorf("got delivery %+v, want it delivered on retry", d)
+	}
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if len(rcv.requests) != 2 {
+		t.Fatalf("got %d requests, want the failed one retried once", len(rcv.requests))
+	}
+	req, body := rcv.requests[1], rcv.bodies[1]
+	if got := req.Header.Get("X-Webhook-Event"); got != events.KindMessageCreated {
+		t.Errorf("got event header %q, want %s", got, events.KindMessageCreated)
+	}
+	if got, want := req.Header.Get(webhookSignatureHeader), signWebhook(room.Webhooks[0].Secret, body); got != want {
+		t.Errorf("got signature %q, want %q", got, want)
+	}
+	msg, err := events.UnmarshalEvent(body)
+	if err != nil {
+		t.Fatalf("decoding payload %s: %v", body, err)
+	}
+	if got := req.Header.Get("X-Webhook-Room-Id"); got != room.ID {
+		t.Errorf("got room header %q, want %s", got, room.ID)
+	}
+	if msg.Kind != events.KindMessageCreated || msg.Value.(events.MessageCreated).Message != "hooked" {
+		t.Errorf("got payload %+v, want the message_created event", msg)
+	}
+	if len(store.failures) != 0 {
+		t.Errorf("got failures %+v, want none", store.failures)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		status   int32
+		failed   bool
+	}{
+		{"Success", nil, 1, http.StatusOK, false},
+		{"FailsIntermittently", []int{http.StatusInternalServerError, http.StatusServiceUnavailable}, 3, http.StatusOK, false},
+		{"FailsUntilLastRetry", []int{500, 502, 503}, 4, http.StatusOK, false},
+		{"FailsEveryAttempt", []int{500, 502, 503, 504}, 4, http.StatusGatewayTimeout, true},
+		{"ClientError", []int{http.StatusNotFound}, 1, http.StatusNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastWebhookRetries(t)
+			rcv := newWebhookReceiver(t, tt.statuses...)
+			api, store := newWebhookHandler(t)
+			room := insertWebhookRoom(t, store, rcv.URL)
+
+			api.notifyClients(context.Background(), answered(room.RoomID.String(), "1"))
+			d := store.waitDelivery(t)
+			if d.LastStatus == nil || *d.LastStatus != tt.status {
+				t.Errorf("got status %v, want %d", d.LastStatus, tt.status)
+			}
+			if got := rcv.count(); got != tt.attempts {
+				t.Errorf("got %d attempts, want %d", got, tt.attempts)
+			}
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			if !tt.failed {
+				if d.LastError != nil || len(store.failures) != 0 {
+					t.Errorf("got error %v and failures %+v, want the delivery to succeed", d.LastError, store.failures)
+				}
+				return
+			}
+			if d.LastError == nil || len(store.failures) != 1 {
+				t.Fatalf("got error %v and failures %+v, want the delivery failure recorded", d.LastError, store.failures)
+			}
+			if f := store.failures[0]; f.Attempts != int32(tt.attempts) || f.EventKind != events.KindMessageAnswered {
+				t.Errorf("got failure %+v, want %d attempts of %s", f, tt.attempts, events.KindMessageAnswered)
+			}
+		})
+	}
+}
+
+func TestWebhookNetworkError(t *testing.T) {
+	fastWebhookRetries(t)
+	rcv := newWebhookReceiver(t)
+	rcv.Close()
+	api, store := newWebhookHandler(t)
+	room := insertWebhookRoom(t, store, rcv.URL)
+
+	api.notifyClients(context.Background(), answered(room.RoomID.String(), "1"))
+	if d := store.waitDelivery(t); d.LastStatus != nil || d.LastError == nil {
+		t.Errorf("got delivery %+v, want the network error recorded", d)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.failures) != 1 || store.failures[0].Attempts != webhookMaxRetries+1 {
+		t.Errorf("got failures %+v, want one after %d attempts", store.failures, webhookMaxRetries+1)
+	}
+}
+
+func TestWebhookDoesNotDelayBroadcasts(t *testing.T) {
+	block := make(chan struct{})
+	rcv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	t.Cleanup(rcv.Close)
+	t.Cleanup(func() { close(block) })
+	api, store := newWebhookHandler(t)
+	room := insertWebhookRoom(t, store, rcv.URL)
+	tr := newFakeTransport(false)
+	serve(t, api, room.RoomID.String(), tr)
+
+	for i := range webhookWorkers + 2 {
+		api.notifyClients(context.Background(), answered(room.RoomID.String(), string(rune('a'+i))))
+		select {
+		case <-tr.written:
+		case <-time.After(time.Second):
+			t.Fatalf("broadcast %d waited for the webhook", i)
+		}
+	}
+}
+
+func TestWebhookKinds(t *testing.T) {
+	rcv := newWebhookReceiver(t)
+	api, store := newWebhookHandler(t)
+	room := insertWebhookRoom(t, store, rcv.URL)
+
+	// Only the second event is delivered; the first would be recorded before
+	// it.
+	api.notifyClients(context.Background(), events.Event{Kind: events.KindMessageDeleted, RoomID: room.RoomID.String(), Value: events.MessageDeleted{ID: "1"}})
+	api.notifyClients(context.Background(), answered(room.RoomID.String(), "1"))
+	store.waitDelivery(t)
+	if got := rcv.count(); got != 1 {
+		t.Fatalf("got %d requests, want 1", got)
+	}
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if got := rcv.requests[0].Header.Get("X-Webhook-Event"); got != events.KindMessageAnswered {
+		t.Errorf("got %s delivered, want only %s", got, events.KindMessageAnswered)
+	}
+}
+
+// insertWebhookRoom stores a room with a webhook posting to url.
+func insertWebhookRoom(t *testing.T, store *webhookStore, url string) pgstore.InsertWebhookParams {
+	t.Helper()
+	hook := pgstore.InsertWebhookParams{
+		ID:        uuid.New(),
+		Url:       url,
+		Secret:    "secret",
+		CreatedAt: time.Now(),
+	}
+	roomID, err := store.InsertRoomWithWebhooks(context.Background(), pgstore.InsertRoomParams{ID: uuid.New(), Theme: "hooks"}, []pgstore.InsertWebhookParams{hook})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook.RoomID = roomID
+	return hook
+}
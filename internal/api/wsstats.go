@@ -0,0 +1,166 @@
+package api
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	wsStatsShards = 16
+	// wsStatsWindow is the longest window the top endpoint can report on.
+	// Traffic is bucketed per minute, so windows are rounded up to minutes.
+	wsStatsWindow = 60
+)
+
+// wsStats accounts websocket frames and bytes written per room and event kind.
+// Totals are process-lifetime counters that only ever grow; they restart from
+// zero with the process, which is reported through the since timestamp so
+// dashboards can treat a smaller value as a reset rather than a drop.
+type wsStats struct {
+	since  time.Time
+	shards [wsStatsShards]wsStatsShard
+}
+
+type wsStatsShard struct {
+	mu       sync.RWMutex
+	counters map[wsStatsKey]*wsStatsCounter
+}
+
+type wsStatsKey struct {
+	RoomID string
+	Kind   string
+}
+
+type wsStatsCounter struct {
+	frames  atomic.Uint64
+	bytes   atomic.Uint64
+	buckets [wsStatsWindow]wsStatsBucket
+}
+
+// wsStatsBucket holds the traffic of a single minute. A bucket is reused once
+// its minute falls out of the window; the first writer of the new minute
+// resets it.
+type wsStatsBucket struct {
+	minute atomic.Int64
+	frames atomic.Uint64
+	bytes  atomic.Uint64
+}
+
+type wsStatsEntry struct {
+	RoomID string `json:"room_id"`
+	Kind   string `json:"kind"`
+	Frames uint64 `json:"frames"`
+	Bytes  uint64 `json:"bytes"`
+}
+
+func newWSStats(now time.Time) *wsStats {
+	s := &wsStats{since: now}
+	for i := range s.shards {
+		s.shards[i].counters = make(map[wsStatsKey]*wsStatsCounter)
+	}
+	return s
+}
+
+// record accounts one frame of n bytes sent to a subscriber of roomID.
+func (s *wsStats) record(roomID, kind string, n int, now time.Time) {
+	c := s.counter(wsStatsKey{RoomID: roomID, Kind: kind})
+	c.frames.Add(1)
+	c.bytes.Add(uint64(n))
+
+	minute := now.Unix() / 60
+	b := &c.buckets[minute%wsStatsWindow]
+	if cur := b.minute.Load(); cur != minute && b.minute.CompareAndSwap(cur, minute) {
+		b.frames.Store(0)
+		b.bytes.Store(0)
+	}
+	b.frames.Add(1)
+	b.bytes.Add(uint64(n))
+}
+
+func (s *wsStats) counter(key wsStatsKey) *wsStatsCounter {
+	h := fnv.New32a()
+	h.Write([]byte(key.RoomID))
+	shard := &s.shards[h.Sum32()%wsStatsShards]
+
+	shard.mu.RLock()
+	c, ok := shard.counters[key]
+	shard.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if c, ok := shard.counters[key]; ok {
+		return c
+	}
+	c = &wsStatsCounter{}
+	shard.counters[key] = c
+	return c
+}
+
+// totals returns the lifetime counters of every room and kind.
+func (s *wsStats) totals() []wsStatsEntry {
+	var entries []wsStatsEntry
+	s.each(func(key wsStatsKey, c *wsStatsCounter) {
+		entries = append(entries, wsStatsEntry{
+			RoomID: key.RoomID,
+			Kind:   key.Kind,
+			Frames: c.frames.Load(),
+			Bytes:  c.bytes.Load(),
+		})
+	})
+	sortByBytes(entries)
+	return entries
+}
+
+// top returns up to limit room/kind pairs that wrote the most bytes within the
+// last window.
+func (s *wsStats) top(window time.Duration, limit int, now time.Time) []wsStatsEntry {
+	minutes := int64((window + time.Minute - 1) / time.Minute)
+	if minutes > wsStatsWindow {
+		minutes = wsStatsWindow
+	}
+	current := now.Unix() / 60
+
+	var entries []wsStatsEntry
+	s.each(func(key wsStatsKey, c *wsStatsCounter) {
+		entry := wsStatsEntry{RoomID: key.RoomID, Kind: key.Kind}
+		for i := range c.buckets {
+			b := &c.buckets[i]
+			if m := b.minute.Load(); m > current-minutes && m <= current {
+				entry.Frames += b.frames.Load()
+				entry.Bytes += b.bytes.Load()
+			}
+		}
+		if entry.Frames > 0 {
+			entries = append(entries, entry)
+		}
+	})
+
+	sortByBytes(entries)
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+func (s *wsStats) each(fn func(wsStatsKey, *wsStatsCounter)) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		for key, c := range shard.counters {
+			fn(key, c)
+		}
+		shard.mu.RUnlock()
+	}
+}
+
+func sortByBytes(entries []wsStatsEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Bytes > entries[j].Bytes
+	})
+}
@@ -0,0 +1,74 @@
+// Package pgbroker relays events between server instances with Postgres
+// LISTEN/NOTIFY, so running several of them needs nothing besides the
+// database they already share.
+package pgbroker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultChannel is the notification channel used when none is given.
+const DefaultChannel = "wsrs_events"
+
+// maxPayloadSize is the largest payload NOTIFY accepts.
+const maxPayloadSize = 7999
+
+// Broker implements api.Broker on a Postgres notification channel.
+type Broker struct {
+	pool    *pgxpool.Pool
+	channel string
+}
+
+// New returns a broker notifying on channel, or DefaultChannel when it is
+// empty.
+func New(pool *pgxpool.Pool, channel string) *Broker {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &Broker{pool: pool, channel: channel}
+}
+
+// Publish notifies every listener of the channel, this instance included.
+// Postgres limits payloads to just under 8000 bytes; larger ones are
+// rejected.
+func (b *Broker) Publish(ctx context.Context, data []byte) error {
+	if len(data) > maxPayloadSize {
+		return fmt.Errorf("pgbroker: payload of %d bytes exceeds the %d bytes NOTIFY accepts", len(data), maxPayloadSize)
+	}
+	_, err := b.pool.Exec(ctx, "SELECT pg_notify($1, $2)", b.channel, string(data))
+	return err
+}
+
+// Ping checks that the database relaying the events is reachable.
+func (b *Broker) Ping(ctx context.Context) error {
+	return b.pool.Ping(ctx)
+}
+
+// Listen holds a connection of the pool to receive the notifications of the
+// channel until ctx is done or the connection fails.
+func (b *Broker) Listen(ctx context.Context, handle func(data []byte)) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection keeps listening, so it is closed rather than returned
+	// to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle([]byte(n.Payload))
+	}
+}
@@ -0,0 +1,323 @@
+// Package config loads the server configuration from WSRS_* environment
+// variables.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+)
+
+// Stores the server can run on, selected with WSRS_STORE.
+const (
+	StorePostgres = "postgres"
+	StoreMemory   = "memory"
+	StoreSQLite   = "sqlite"
+)
+
+// Brokers relaying events between instances, selected with WSRS_BROKER.
+// BrokerNone runs a single instance.
+const (
+	BrokerNone     = "none"
+	BrokerPostgres = "postgres"
+)
+
+// Rate limiters keeping the buckets of the API rate limits, selected with
+// WSRS_RATE_LIMITER. RateLimiterRedis shares them between instances.
+const (
+	RateLimiterMemory = "memory"
+	RateLimiterRedis  = "redis"
+)
+
+// ContentFilterOff disables the content filter, selected with
+// WSRS_CONTENT_FILTER. The other values are the api.ContentFilterAction taken
+// on the questions it matches.
+const ContentFilterOff = "off"
+
+// Config is the whole server configuration.
+type Config struct {
+	// Addr is the address the HTTP server listens on (WSRS_ADDR).
+	Addr string
+	// Store is StorePostgres, StoreMemory or StoreSQLite (WSRS_STORE).
+	Store string
+	// Broker is BrokerNone or BrokerPostgres (WSRS_BROKER). The Postgres
+	// broker requires the Postgres store.
+	Broker string
+	// Database is where the Postgres store connects to.
+	Database Database
+	// SQLitePath is the database file of the SQLite store
+	// (WSRS_SQLITE_PATH), created on first run.
+	SQLitePath string
+	// Tracing exports OpenTelemetry traces over OTLP (WSRS_TRACING), to
+	// the collector set by the standard OTEL_EXPORTER_OTLP_* variables.
+	Tracing bool
+	// RateLimiter is RateLimiterMemory or RateLimiterRedis
+	// (WSRS_RATE_LIMITER). The Redis limiter requires RedisURL.
+	RateLimiter string
+	// RedisURL is the redis:// URL of the Redis rate limiter
+	// (WSRS_REDIS_URL).
+	RedisURL string
+	// ContentFilter is ContentFilterOff or the action taken on questions
+	// containing a word of the built-in list or of ContentFilterWords
+	// (WSRS_CONTENT_FILTER): reject, mask or flag.
+	ContentFilter string
+	// ContentFilterWords are words filtered besides the built-in list
+	// (WSRS_CONTENT_FILTER_WORDS).
+	ContentFilterWords []string
+	// API configures the handler.
+	API api.Config
+}
+
+// Database holds the WSRS_DATABASE_* connection settings. URL
+// (WSRS_DATABASE_URL), a postgres:// URL, takes precedence over the other
+// fields.
+type Database struct {
+	URL      string
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Name     string
+}
+
+// ConnString returns the pgx connection string of d.
+func (d Database) ConnString() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s", d.User, d.Password, d.Host, d.Port, d.Name)
+}
+
+// Load reads the configuration from the environment.
+func Load() (Config, error) {
+	return Parse(os.LookupEnv)
+}
+
+// Parse reads the configuration through lookup, which has the signature of
+// os.LookupEnv. Unset variables keep their default. Every invalid variable is
+// reported in the returned error, not only the first one.
+func Parse(lookup func(string) (string, bool)) (Config, error) {
+	p := parser{lookup: lookup}
+	cfg := Config{
+		Addr:   p.string("WSRS_ADDR", ":8080"),
+		Store:  p.oneOf("WSRS_STORE", StorePostgres, StoreMemory, StoreSQLite),
+		Broker: p.oneOf("WSRS_BROKER", BrokerNone, BrokerPostgres),
+		Database: Database{
+			URL:      p.url("WSRS_DATABASE_URL", "postgres", "postgresql"),
+			User:     p.string("WSRS_DATABASE_USER", ""),
+			Password: p.string("WSRS_DATABASE_PASSWORD", ""),
+			Host:     p.string("WSRS_DATABASE_HOST", ""),
+			Port:     p.string("WSRS_DATABASE_PORT", ""),
+			Name:     p.string("WSRS_DATABASE_NAME", ""),
+		},
+		SQLitePath:  p.string("WSRS_SQLITE_PATH", "ama.db"),
+		Tracing:     p.bool("WSRS_TRACING", false),
+		RateLimiter: p.oneOf("WSRS_RATE_LIMITER", RateLimiterMemory, RateLimiterRedis),
+		RedisURL:    p.url("WSRS_REDIS_URL", "redis", "rediss"),
+		ContentFilter: p.oneOf("WSRS_CONTENT_FILTER", ContentFilterOff,
+			string(api.ContentFilterReject), string(api.ContentFilterMask), string(api.ContentFilterFlag)),
+		ContentFilterWords: p.list("WSRS_CONTENT_FILTER_WORDS"),
+	}
+
+	defaults := api.DefaultConfig()
+	cfg.API = api.Config{
+		AdminToken:            p.string("WSRS_ADMIN_TOKEN", ""),
+		HostTokenSecret:       p.string("WSRS_HOST_TOKEN_SECRET", ""),
+		AllowedOrigins:        p.list("WSRS_ALLOWED_ORIGINS"),
+		DetectLanguage:        p.bool("WSRS_DETECT_LANGUAGE", false),
+		SendQueueSize:         p.positiveInt("WSRS_SEND_QUEUE_SIZE", defaults.SendQueueSize),
+		WriteTimeout:          p.positiveDuration("WSRS_WRITE_TIMEOUT", defaults.WriteTimeout),
+		DBTimeout:             p.positiveDuration("WSRS_DB_TIMEOUT", defaults.DBTimeout),
+		RequestTimeout:        p.positiveDuration("WSRS_REQUEST_TIMEOUT", defaults.RequestTimeout),
+		SweepInterval:         p.positiveDuration("WSRS_SWEEP_INTERVAL", defaults.SweepInterval),
+		FlagThreshold:         p.positiveInt("WSRS_FLAG_THRESHOLD", defaults.FlagThreshold),
+		ReactionFlushInterval: p.positiveDuration("WSRS_REACTION_FLUSH_INTERVAL", defaults.ReactionFlushInterval),
+		ViewerCountInterval:   p.positiveDuration("WSRS_VIEWER_COUNT_INTERVAL", defaults.ViewerCountInterval),
+		RoomRetention:         p.positiveDuration("WSRS_ROOM_RETENTION", defaults.RoomRetention),
+		Pprof:                 p.bool("WSRS_PPROF", false),
+		WSPingInterval:        p.positiveDuration("WSRS_WS_PING_INTERVAL", defaults.WSPingInterval),
+		WSReadBufferSize:      p.positiveInt("WSRS_WS_READ_BUFFER_SIZE", defaults.WSReadBufferSize),
+		WSWriteBufferSize:     p.positiveInt("WSRS_WS_WRITE_BUFFER_SIZE", defaults.WSWriteBufferSize),
+		RateLimits:            p.rateLimits("WSRS_RATE_LIMITS"),
+		TrustedProxies:        p.prefixes("WSRS_TRUSTED_PROXIES"),
+		ReactionKinds:         p.list("WSRS_REACTION_KINDS"),
+	}
+
+	if cfg.Broker == BrokerPostgres && cfg.Store != StorePostgres {
+		p.errs = append(p.errs, fmt.Errorf("WSRS_BROKER=%s: requires WSRS_STORE=%s", BrokerPostgres, StorePostgres))
+	}
+
+	if cfg.RateLimiter == RateLimiterRedis && cfg.RedisURL == "" {
+		p.errs = append(p.errs, fmt.Errorf("WSRS_RATE_LIMITER=%s: requires WSRS_REDIS_URL", RateLimiterRedis))
+	}
+
+	if len(p.errs) > 0 {
+		return Config{}, fmt.Errorf("config: invalid environment:\n%w", errors.Join(p.errs...))
+	}
+	return cfg, nil
+}
+
+// parser reads variables, collecting an error for every invalid one.
+type parser struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+func (p *parser) get(name string) (string, bool) {
+	v, ok := p.lookup(name)
+	v = strings.TrimSpace(v)
+	return v, ok && v != ""
+}
+
+func (p *parser) fail(name, value, want string) {
+	p.errs = append(p.errs, fmt.Errorf("%s=%q: must be %s", name, value, want))
+}
+
+func (p *parser) string(name, def string) string {
+	if v, ok := p.get(name); ok {
+		return v
+	}
+	return def
+}
+
+// oneOf returns the value of name, which must be one of allowed. The first
+// allowed value is the default.
+func (p *parser) oneOf(name string, allowed ...string) string {
+	v, ok := p.get(name)
+	if !ok {
+		return allowed[0]
+	}
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	p.fail(name, v, "one of "+strings.Join(allowed, ", "))
+	return allowed[0]
+}
+
+// list splits a comma separated variable, dropping empty entries.
+func (p *parser) list(name string) []string {
+	v, ok := p.get(name)
+	if !ok {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// url returns the value of name, which must be a URL with one of schemes.
+func (p *parser) url(name string, schemes ...string) string {
+	v, ok := p.get(name)
+	if !ok {
+		return ""
+	}
+	u, err := url.Parse(v)
+	if err == nil {
+		for _, scheme := range schemes {
+			if u.Scheme == scheme {
+				return v
+			}
+		}
+	}
+	// The URL may hold a password, so it isn't repeated in the error.
+	p.errs = append(p.errs, fmt.Errorf("%s: must be a %s:// URL", name, schemes[0]))
+	return ""
+}
+
+func (p *parser) bool(name string, def bool) bool {
+	v, ok := p.get(name)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.fail(name, v, "a boolean")
+		return def
+	}
+	return b
+}
+
+func (p *parser) positiveInt(name string, def int) int {
+	v, ok := p.get(name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		p.fail(name, v, "a positive integer")
+		return def
+	}
+	return n
+}
+
+func (p *parser) positiveDuration(name string, def time.Duration) time.Duration {
+	v, ok := p.get(name)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		p.fail(name, v, `a positive duration such as "5s"`)
+		return def
+	}
+	return d
+}
+
+// prefixes reads comma separated IP prefixes such as "10.0.0.0/8", a bare
+// address standing for itself.
+func (p *parser) prefixes(name string) []netip.Prefix {
+	items := p.list(name)
+	if items == nil {
+		return nil
+	}
+	prefixes := make([]netip.Prefix, 0, len(items))
+	for _, item := range items {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, err2 := netip.ParseAddr(item)
+			if err2 != nil {
+				p.fail(name, item, `IP prefixes such as "10.0.0.0/8"`)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// rateLimits reads comma separated limits such as "POST /rooms=10/10m", the
+// method and route relative to the API root being limited to the requests
+// before the slash every duration after it. A limit of 0 lifts the limit.
+func (p *parser) rateLimits(name string) map[string]api.RateLimit {
+	items := p.list(name)
+	if items == nil {
+		return nil
+	}
+	limits := make(map[string]api.RateLimit, len(items))
+	for _, item := range items {
+		route, limit, _ := strings.Cut(item, "=")
+		requests, per, _ := strings.Cut(limit, "/")
+		n, err := strconv.Atoi(strings.TrimSpace(requests))
+		d, err2 := time.ParseDuration(strings.TrimSpace(per))
+		if route == "" || err != nil || err2 != nil || n < 0 || d <= 0 {
+			p.fail(name, item, `limits such as "POST /rooms=10/10m"`)
+			continue
+		}
+		limits[strings.TrimSpace(route)] = api.RateLimit{Requests: n, Per: d}
+	}
+	return limits
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+)
+
+// env returns a lookup function reading vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	cfg, err := Parse(env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":8080" || cfg.Store != StorePostgres || cfg.Broker != BrokerNone ||
+		cfg.RateLimiter != RateLimiterMemory || cfg.ContentFilter != ContentFilterOff || cfg.SQLitePath != "ama.db" {
+		t.Errorf("got %+v, want the defaults", cfg)
+	}
+	defaults := api.DefaultConfig()
+	if cfg.API.SendQueueSize != defaults.SendQueueSize || cfg.API.WriteTimeout != defaults.WriteTimeout ||
+		cfg.API.SweepInterval != defaults.SweepInterval || cfg.API.RoomRetention != defaults.RoomRetention {
+		t.Errorf("got API config %+v, want the defaults %+v", cfg.API, defaults)
+	}
+	if cfg.API.AllowedOrigins != nil || cfg.API.TrustedProxies != nil || cfg.API.RateLimits != nil {
+		t.Errorf("got API config %+v, want no lists", cfg.API)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse(env(map[string]string{
+		"WSRS_ADDR":                 ":9000",
+		"WSRS_STORE":                StoreMemory,
+		"WSRS_TRACING":              "true",
+		"WSRS_RATE_LIMITER":         RateLimiterRedis,
+		"WSRS_REDIS_URL":            "redis://localhost:6379/0",
+		"WSRS_CONTENT_FILTER":       "mask",
+		"WSRS_CONTENT_FILTER_WORDS": "foo, bar,,",
+		"WSRS_SEND_QUEUE_SIZE":      " 16 ",
+		"WSRS_WRITE_TIMEOUT":        "1500ms",
+		"WSRS_ROOM_RETENTION":       "72h",
+		"WSRS_ALLOWED_ORIGINS":      "https://a.example, https://b.example",
+		"WSRS_TRUSTED_PROXIES":      "10.0.0.0/8,192.0.2.1",
+		"WSRS_RATE_LIMITS":          "POST /rooms=10/10m, POST /rooms/{room_id}/messages=0/1s",
+		"WSRS_DATABASE_USER":        "ama",
+		"WSRS_DATABASE_HOST":        "db",
+		"WSRS_ADMIN_TOKEN":          "admin",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":9000" || cfg.Store != StoreMemory || !cfg.Tracing || cfg.RateLimiter != RateLimiterRedis ||
+		cfg.RedisURL != "redis://localhost:6379/0" || cfg.ContentFilter != "mask" {
+		t.Errorf("got %+v", cfg)
+	}
+	if want := []string{"foo", "bar"}; !slices.Equal(cfg.ContentFilterWords, want) {
+		t.Errorf("got content filter words %q, want %q", cfg.ContentFilterWords, want)
+	}
+	if cfg.API.SendQueueSize != 16 {
+		t.Errorf("got send queue size %d, want 16", cfg.API.SendQueueSize)
+	}
+	if cfg.API.WriteTimeout != 1500*time.Millisecond || cfg.API.RoomRetention != 72*time.Hour {
+		t.Errorf("got write timeout %v and room retention %v, want 1.5s and 72h", cfg.API.WriteTimeout, cfg.API.RoomRetention)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !slices.Equal(cfg.API.AllowedOrigins, want) {
+		t.Errorf("got allowed origins %q, want %q", cfg.API.AllowedOrigins, want)
+	}
+	wantProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.1/32")}
+	if !slices.Equal(cfg.API.TrustedProxies, wantProxies) {
+		t.Errorf("got trusted proxies %v, want %v", cfg.API.TrustedProxies, wantProxies)
+	}
+	wantLimits := map[string]api.RateLimit{
+		"POST /rooms":                    {Requests: 10, Per: 10 * time.Minute},
+		"POST /rooms/{room_id}/messages": {Requests: 0, Per: time.Second},
+	}
+	if len(cfg.API.RateLimits) != len(wantLimits) {
+		t.Errorf("got rate limits %v, want %v", cfg.API.RateLimits, wantLimits)
+	}
+	for route, want := range wantLimits {
+		if got := cfg.API.RateLimits[route]; got != want {
+			t.Errorf("got rate limit %v for %q, want %v", got, route, want)
+		}
+	}
+	if cfg.API.AdminToken != "admin" {
+		t.Errorf("got admin token %q, want admin", cfg.API.AdminToken)
+	}
+	if got, want := cfg.Database.ConnString(), "user=ama password= host=db port= dbname="; got != want {
+		t.Errorf("got connection string %q, want %q", got, want)
+	}
+}
+
+func TestDatabaseURL(t *testing.T) {
+	cfg, err := Parse(env(map[string]string{
+		"WSRS_DATABASE_URL":  "postgres://ama:secret@db/ama",
+		"WSRS_DATABASE_USER": "ignored",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Database.ConnString(); got != "postgres://ama:secret@db/ama" {
+		t.Errorf("got connection string %q, want the URL", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name, value string
+	}{
+		{"WSRS_STORE", "mysql"},
+		{"WSRS_TRACING", "maybe"},
+		{"WSRS_SEND_QUEUE_SIZE", "0"},
+		{"WSRS_FLAG_THRESHOLD", "many"},
+		{"WSRS_WRITE_TIMEOUT", "5"},
+		{"WSRS_SWEEP_INTERVAL", "-1m"},
+		{"WSRS_TRUSTED_PROXIES", "10.0.0.0/8,proxy"},
+		{"WSRS_RATE_LIMITS", "POST /rooms=ten/1m"},
+		{"WSRS_RATE_LIMITS", "POST /rooms=10"},
+		{"WSRS_CONTENT_FILTER", "block"},
+		{"WSRS_REDIS_URL", "localhost:6379"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			_, err := Parse(env(map[string]string{tt.name: tt.value}))
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("got error %q, want it to name %s", err, tt.name)
+			}
+		})
+	}
+}
+
+func TestParseReportsEveryInvalidVariable(t *testing.T) {
+	_, err := Parse(env(map[string]string{
+		"WSRS_STORE":           "mysql",
+		"WSRS_SEND_QUEUE_SIZE": "-3",
+		"WSRS_WRITE_TIMEOUT":   "soon",
+		"WSRS_DATABASE_URL":    "mysql://root:hunter2@db/ama",
+		"WSRS_ADDR":            ":9000",
+	}))
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	msg := err.Error()
+	for _, name := range []string{"WSRS_STORE", "WSRS_SEND_QUEUE_SIZE", "WSRS_WRITE_TIMEOUT", "WSRS_DATABASE_URL"} {
+		if !strings.Contains(msg, name) {
+			t.Errorf("error %q doesn't report %s", msg, name)
+		}
+	}
+	if strings.Contains(msg, "WSRS_ADDR") {
+		t.Errorf("error %q reports the valid WSRS_ADDR", msg)
+	}
+	if strings.Contains(msg, "hunter2") {
+		t.Errorf("error %q reveals the database password", msg)
+	}
+}
+
+func TestParseDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{"PostgresBrokerWithoutPostgres", map[string]string{"WSRS_BROKER": BrokerPostgres, "WSRS_STORE": StoreMemory}, "WSRS_BROKER"},
+		{"RedisWithoutURL", map[string]string{"WSRS_RATE_LIMITER": RateLimiterRedis}, "WSRS_REDIS_URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(env(tt.vars))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want it to mention %s", err, tt.want)
+			}
+		})
+	}
+	if _, err := Parse(env(map[string]string{"WSRS_BROKER": BrokerPostgres})); err != nil {
+		t.Errorf("postgres broker with the default store: %v", err)
+	}
+}
+
+func TestParseBlankIsUnset(t *testing.T) {
+	cfg, err := Parse(env(map[string]string{"WSRS_ADDR": "  ", "WSRS_SEND_QUEUE_SIZE": ""}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":8080" || cfg.API.SendQueueSize != api.DefaultConfig().SendQueueSize {
+		t.Errorf("got %+v, want blank variables to keep their default", cfg)
+	}
+}
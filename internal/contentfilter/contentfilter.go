@@ -0,0 +1,63 @@
+// Package contentfilter finds offending words in the questions asked, with a
+// wordlist matched against the whole words of a text regardless of case.
+package contentfilter
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"strings"
+	"unicode"
+)
+
+//go:embed words.txt
+var defaultWords string
+
+// DefaultWords returns the built-in list of English profanity.
+func DefaultWords() []string {
+	var words []string
+	scanner := bufio.NewScanner(strings.NewReader(defaultWords))
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// Wordlist implements api.ContentFilter with a list of words.
+type Wordlist struct {
+	words map[string]struct{}
+}
+
+// New returns a filter matching words, which are compared case-insensitively.
+func New(words []string) *Wordlist {
+	w := &Wordlist{words: make(map[string]struct{}, len(words))}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			w.words[word] = struct{}{}
+		}
+	}
+	return w
+}
+
+// Match returns the byte ranges of the words of text that are in the list.
+// Words are runs of letters and digits, so "shitty" doesn't match "shit"
+// but "shit!" does.
+func (w *Wordlist) Match(_ context.Context, text string) ([][2]int, error) {
+	var matches [][2]int
+	start := -1
+	for i, r := range text + " " {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			if _, ok := w.words[strings.ToLower(text[start:i])]; ok {
+				matches = append(matches, [2]int{start, i})
+			}
+			start = -1
+		}
+	}
+	return matches, nil
+}
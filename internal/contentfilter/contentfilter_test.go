@@ -0,0 +1,46 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	w := New([]string{"darn", " Heck ", ""})
+	tests := []struct {
+		text string
+		want [][2]int
+	}{
+		{"nothing to see", nil},
+		{"darn", [][2]int{{0, 4}}},
+		{"DARN it", [][2]int{{0, 4}}},
+		{"oh heck, darn!", [][2]int{{3, 7}, {9, 13}}},
+		{"darned heckler", nil},
+		{"já darn", [][2]int{{4, 8}}},
+	}
+	for _, tt := range tests {
+		got, err := w.Match(context.Background(), tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultWords(t *testing.T) {
+	words := DefaultWords()
+	if len(words) == 0 {
+		t.Fatal("got no default words")
+	}
+	w := New(words)
+	for _, word := range words {
+		text := fmt.Sprintf("say %s now", word)
+		if got, _ := w.Match(context.Background(), text); len(got) != 1 || text[got[0][0]:got[0][1]] != word {
+			t.Errorf("%q: got %v, want %q matched", text, got, word)
+		}
+	}
+}
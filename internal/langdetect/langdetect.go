@@ -0,0 +1,137 @@
+// Package langdetect guesses the language of short texts by comparing their
+// character trigram frequencies against small embedded language profiles.
+package langdetect
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Undetermined is the tag returned when a text is too short or doesn't look
+// like any known language.
+const Undetermined = "und"
+
+const (
+	// profileSize is the number of most frequent trigrams kept per profile.
+	profileSize = 300
+	// minLetters is the minimum number of letters needed to attempt detection.
+	minLetters = 20
+)
+
+//go:embed samples/*.txt
+var samples embed.FS
+
+// profiles maps a language tag to the rank of each of its most frequent
+// trigrams.
+var profiles = loadProfiles()
+
+func loadProfiles() map[string]map[string]int {
+	entries, err := samples.ReadDir("samples")
+	if err != nil {
+		panic(err)
+	}
+
+	profiles := make(map[string]map[string]int, len(entries))
+	for _, entry := range entries {
+		data, err := samples.ReadFile(path.Join("samples", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		tag := strings.TrimSuffix(entry.Name(), ".txt")
+		profiles[tag] = rank(trigrams(string(data)), profileSize)
+	}
+	return profiles
+}
+
+// Detect returns the language tag of text and a confidence between 0 and 1.
+// Texts with fewer than 20 letters are tagged Undetermined.
+func Detect(text string) (string, float64) {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLetters {
+		return Undetermined, 0
+	}
+
+	doc := rank(trigrams(text), profileSize)
+	maxDistance := len(doc) * profileSize
+
+	best := Undetermined
+	bestDistance, secondDistance := maxDistance, maxDistance
+	for tag, profile := range profiles {
+		d := distance(doc, profile)
+		switch {
+		case d < bestDistance || (d == bestDistance && tag < best):
+			secondDistance = bestDistance
+			best, bestDistance = tag, d
+		case d < secondDistance:
+			secondDistance = d
+		}
+	}
+	if best == Undetermined {
+		return Undetermined, 0
+	}
+
+	// Confidence is how much closer the best profile is than the runner up.
+	confidence := float64(secondDistance-bestDistance) / float64(maxDistance)
+	return best, confidence
+}
+
+// distance is the out-of-place measure between a document and a profile: the
+// sum of rank differences, with a maximum penalty for missing trigrams.
+func distance(doc, profile map[string]int) int {
+	d := 0
+	for gram, r := range doc {
+		if pr, ok := profile[gram]; ok {
+			if pr > r {
+				d += pr - r
+			} else {
+				d += r - pr
+			}
+		} else {
+			d += profileSize
+		}
+	}
+	return d
+}
+
+func trigrams(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+	return counts
+}
+
+func rank(counts map[string]int, limit int) map[string]int {
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > limit {
+		grams = grams[:limit]
+	}
+
+	ranks := make(map[string]int, len(grams))
+	for i, gram := range grams {
+		ranks[gram] = i
+	}
+	return ranks
+}
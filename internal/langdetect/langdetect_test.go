@@ -0,0 +1,63 @@
+package langdetect
+
+import (
+	"testing"
+	"time"
+)
+
+var questions = []struct {
+	tag  string
+	text string
+}{
+	{"en", "What are the plans for hiring new engineers next year?"},
+	{"pt", "Quais são os planos para contratar novos engenheiros no próximo ano?"},
+	{"es", "¿Cuáles son los planes para contratar nuevos ingenieros el próximo año?"},
+	{"fr", "Quels sont les projets pour recruter de nouveaux ingénieurs l'année prochaine ?"},
+	{"de", "Welche Pläne gibt es für die Einstellung neuer Ingenieure im nächsten Jahr?"},
+}
+
+func TestDetect(t *testing.T) {
+	for _, tt := range questions {
+		t.Run(tt.tag, func(t *testing.T) {
+			tag, confidence := Detect(tt.text)
+			if tag != tt.tag {
+				t.Errorf("got %q, want %q", tag, tt.tag)
+			}
+			if confidence <= 0 || confidence > 1 {
+				t.Errorf("got confidence %v, want it in (0, 1]", confidence)
+			}
+		})
+	}
+}
+
+func TestDetectShortText(t *testing.T) {
+	// The third has 19 letters, one short of what detection needs.
+	for _, text := range []string{"", "Why?", "How do you deploy it now", "1234567890 1234567890 1234567890"} {
+		if tag, confidence := Detect(text); tag != Undetermined || confidence != 0 {
+			t.Errorf("%q: got %q with confidence %v, want %q with 0", text, tag, confidence, Undetermined)
+		}
+	}
+}
+
+// TestDetectFast checks that detecting the language of a question takes well
+// under a millisecond. The average of many runs keeps it from failing on a
+// single slow one.
+func TestDetectFast(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+	const runs = 1000
+	start := time.Now()
+	for i := range runs {
+		Detect(questions[i%len(questions)].text)
+	}
+	if took := time.Since(start) / runs; took >= time.Millisecond {
+		t.Errorf("detection took %v on average, want under 1ms", took)
+	}
+}
+
+func BenchmarkDetect(b *testing.B) {
+	for i := range b.N {
+		Detect(questions[i%len(questions)].text)
+	}
+}
@@ -0,0 +1,70 @@
+// Package redislimit keeps the rate limits of the API in Redis, so that every
+// server instance draws from the same buckets.
+package redislimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPrefix is put before the keys of the buckets when no prefix is given.
+const DefaultPrefix = "wsrs:ratelimit:"
+
+// take refills the bucket KEYS[1], which holds up to ARGV[1] tokens and gains
+// one every ARGV[2] microseconds, and takes a token from it. It returns 1, or
+// 0 and the microseconds until a token is available. Time is the one of Redis,
+// so the instances needn't agree on it.
+var take = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local per_token = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens = tonumber(bucket[1]) or capacity
+local at = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + (now - at) / per_token)
+
+if tokens < 1 then
+	return {0, math.ceil((1 - tokens) * per_token)}
+end
+tokens = tokens - 1
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "at", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((capacity - tokens) * per_token / 1000))
+return {1, 0}
+`)
+
+// Limiter implements api.RateLimiter on Redis.
+type Limiter struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+var _ api.RateLimiter = (*Limiter)(nil)
+
+// New returns a limiter keeping its buckets under prefix, or DefaultPrefix
+// when it is empty.
+func New(client redis.UniversalClient, prefix string) *Limiter {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Limiter{client: client, prefix: prefix}
+}
+
+// Take takes a token from the bucket of key.
+func (l *Limiter) Take(ctx context.Context, key string, limit api.RateLimit) (bool, time.Duration, error) {
+	perToken := limit.Per / time.Duration(limit.Requests)
+	res, err := take.Run(ctx, l.client, []string{l.prefix + key}, limit.Requests, perToken.Microseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Microsecond, nil
+}
+
+// Ping checks that Redis is reachable.
+func (l *Limiter) Ping(ctx context.Context) error {
+	return l.client.Ping(ctx).Err()
+}
@@ -0,0 +1,1645 @@
+// Package memstore is an in-memory implementation of the store used by the
+// api package, for local development and tests without a database.
+//
+// It mirrors the behaviour of pgstore, including its errors: missing rows are
+// reported as pgx.ErrNoRows and constraint violations as *pgconn.PgError with
+// the matching SQLSTATE, so handlers behave the same on either store.
+package memstore
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/internal/store/textsearch"
+)
+
+type clientKey struct {
+	messageID uuid.UUID
+	clientID  string
+}
+
+type emojiReactionKey struct {
+	clientKey
+	kind string
+}
+
+type pollVoteKey struct {
+	pollID   uuid.UUID
+	clientID string
+}
+
+type participantKey struct {
+	roomID      uuid.UUID
+	participant string
+}
+
+// Store keeps rooms, messages and everything attached to them in maps guarded
+// by a single mutex. The zero value is not usable, create one with New.
+type Store struct {
+	mu              sync.Mutex
+	rooms           map[uuid.UUID]pgstore.Room
+	messages        map[uuid.UUID]pgstore.Message
+	reactions       map[clientKey]time.Time
+	emojiReactions  map[emojiReactionKey]time.Time
+	flags           map[clientKey]pgstore.MessageFlag
+	edits           map[uuid.UUID][]pgstore.MessageEdit
+	replies         map[uuid.UUID][]pgstore.MessageReply
+	webhooks        map[uuid.UUID]pgstore.Webhook
+	webhookFailures []pgstore.WebhookDeliveryFailure
+	audit           []pgstore.ModerationAudit
+	announcements   []pgstore.Announcement
+	questions       map[participantKey]int32
+	polls           map[uuid.UUID]pgstore.Poll
+	pollOptions     map[uuid.UUID][]pgstore.PollOption
+	pollVotes       map[pollVoteKey]pgstore.PollVote
+}
+
+func New() *Store {
+	return &Store{
+		rooms:          make(map[uuid.UUID]pgstore.Room),
+		messages:       make(map[uuid.UUID]pgstore.Message),
+		reactions:      make(map[clientKey]time.Time),
+		emojiReactions: make(map[emojiReactionKey]time.Time),
+		flags:          make(map[clientKey]pgstore.MessageFlag),
+		edits:          make(map[uuid.UUID][]pgstore.MessageEdit),
+		replies:        make(map[uuid.UUID][]pgstore.MessageReply),
+		webhooks:       make(map[uuid.UUID]pgstore.Webhook),
+		questions:      make(map[participantKey]int32),
+		polls:          make(map[uuid.UUID]pgstore.Poll),
+		pollOptions:    make(map[uuid.UUID][]pgstore.PollOption),
+		pollVotes:      make(map[pollVoteKey]pgstore.PollVote),
+	}
+}
+
+var _ pgstore.Querier = (*Store)(nil)
+
+func uniqueViolation(constraint string) error {
+	return &pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint", ConstraintName: constraint}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &pgconn.PgError{Code: "23503", Message: "insert or update violates foreign key constraint", ConstraintName: constraint}
+}
+
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+func compareMessages(a, b pgstore.Message) int {
+	return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), compareIDs(a.ID, b.ID))
+}
+
+// roomMessages returns the messages of a room, oldest first.
+func (s *Store) roomMessages(roomID uuid.UUID) []pgstore.Message {
+	var messages []pgstore.Message
+	for _, m := range s.messages {
+		if m.RoomID == roomID {
+			messages = append(messages, m)
+		}
+	}
+	slices.SortFunc(messages, compareMessages)
+	return messages
+}
+
+func (s *Store) deleteMessage(id uuid.UUID) {
+	delete(s.messages, id)
+	delete(s.edits, id)
+	delete(s.replies, id)
+	for key := range s.emojiReactions {
+		if key.messageID == id {
+			delete(s.emojiReactions, key)
+		}
+	}
+	for key := range s.reactions {
+		if key.messageID == id {
+			delete(s.reactions, key)
+		}
+	}
+	for key := range s.flags {
+		if key.messageID == id {
+			delete(s.flags, key)
+		}
+	}
+}
+
+func (s *Store) CloseDueRooms(ctx context.Context, closesAt *time.Time) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if closesAt == nil {
+		return nil, nil
+	}
+	var ids []uuid.UUID
+	for _, room := range s.sortedRooms() {
+		if room.ClosedAt == nil && room.ClosesAt != nil && !room.ClosesAt.After(*closesAt) {
+			room.ClosedAt = room.ClosesAt
+			s.rooms[room.ID] = room
+			ids = append(ids, room.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) CloseRoom(ctx context.Context, arg pgstore.CloseRoomParams) (pgstore.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[arg.ID]
+	if !ok || room.ClosedAt != nil {
+		return pgstore.Room{}, pgx.ErrNoRows
+	}
+	room.ClosedAt = arg.ClosedAt
+	s.rooms[room.ID] = room
+	return room, nil
+}
+
+func (s *Store) SoftDeleteRoom(ctx context.Context, arg pgstore.SoftDeleteRoomParams) (pgstore.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[arg.ID]
+	if !ok || room.DeletedAt != nil {
+		return pgstore.Room{}, pgx.ErrNoRows
+	}
+	room.DeletedAt = arg.DeletedAt
+	s.rooms[room.ID] = room
+	return room, nil
+}
+
+func (s *Store) RestoreRoom(ctx context.Context, arg pgstore.RestoreRoomParams) (pgstore.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[arg.ID]
+	if !ok || room.DeletedAt == nil || arg.DeletedAfter == nil || !room.DeletedAt.After(*arg.DeletedAfter) {
+		return pgstore.Room{}, pgx.ErrNoRows
+	}
+	room.DeletedAt = nil
+	s.rooms[room.ID] = room
+	return room, nil
+}
+
+func (s *Store) CountMessageFlags(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for key := range s.flags {
+		if key.messageID == messageID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) CountRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.countLiveRoomMessages(roomID), nil
+}
+
+// countLiveRoomMessages counts the messages of roomID taking room capacity:
+// those not deleted, pending ones included.
+func (s *Store) countLiveRoomMessages(roomID uuid.UUID) int64 {
+	var count int64
+	for _, m := range s.messages {
+		if m.RoomID == roomID && m.DeletedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Store) countRoomMessages(roomID uuid.UUID) int64 {
+	var count int64
+	for _, m := range s.messages {
+		if m.RoomID == roomID {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Store) CountRoomMessagesWithoutConsent(ctx context.Context, arg pgstore.CountRoomMessagesWithoutConsentParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, m := range s.roomMessages(arg.RoomID) {
+		if !m.Pending && !m.ConsentToPublish && (m.DeletedAt == nil || arg.IncludeDeleted) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) DecrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addReactionCounts(ids, -1)
+	return nil
+}
+
+func (s *Store) IncrementParticipantQuestions(ctx context.Context, arg pgstore.IncrementParticipantQuestionsParams) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rooms[arg.RoomID]; !ok {
+		return 0, foreignKeyViolation("participant_questions_room_id_fkey")
+	}
+	key := participantKey{roomID: arg.RoomID, participant: arg.Participant}
+	s.questions[key]++
+	return s.questions[key], nil
+}
+
+func (s *Store) IncrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addReactionCounts(ids, 1)
+	return nil
+}
+
+// addReactionCounts changes the reaction count of every message in ids once,
+// never below zero.
+func (s *Store) addReactionCounts(ids []uuid.UUID, delta int64) {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		m, ok := s.messages[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		m.ReactionCount = max(m.ReactionCount+delta, 0)
+		m.Version++
+		s.messages[id] = m
+	}
+}
+
+func (s *Store) DeleteClientReactions(ctx context.Context, arg pgstore.DeleteClientReactionsParams) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteClientReactions(arg), nil
+}
+
+func (s *Store) deleteClientReactions(arg pgstore.DeleteClientReactionsParams) []uuid.UUID {
+	var removed []uuid.UUID
+	for _, id := range arg.MessageIds {
+		key := clientKey{messageID: id, clientID: arg.ClientID}
+		if _, ok := s.reactions[key]; ok {
+			delete(s.reactions, key)
+			removed = append(removed, id)
+		}
+	}
+	return removed
+}
+
+func (s *Store) DeleteEmojiReaction(ctx context.Context, arg pgstore.DeleteEmojiReactionParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := emojiReactionKey{clientKey{messageID: arg.MessageID, clientID: arg.ClientID}, arg.Kind}
+	if _, ok := s.emojiReactions[key]; !ok {
+		return 0, nil
+	}
+	delete(s.emojiReactions, key)
+	return 1, nil
+}
+
+func (s *Store) DeleteOldestPrunableMessage(ctx context.Context, roomID uuid.UUID) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteOldestPrunableMessage(roomID)
+}
+
+func (s *Store) deleteOldestPrunableMessage(roomID uuid.UUID) (uuid.UUID, error) {
+	for _, m := range s.roomMessages(roomID) {
+		if !m.Answered && m.ReactionCount == 0 && !m.Pending && m.DeletedAt == nil {
+			s.deleteMessage(m.ID)
+			return m.ID, nil
+		}
+	}
+	return uuid.Nil, pgx.ErrNoRows
+}
+
+func (s *Store) DeleteRoom(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteRoom(id)
+}
+
+func (s *Store) deleteRoom(id uuid.UUID) error {
+	if s.countRoomMessages(id) > 0 {
+		return foreignKeyViolation("messages_room_id_fkey")
+	}
+	delete(s.rooms, id)
+	for key := range s.questions {
+		if key.roomID == id {
+			delete(s.questions, key)
+		}
+	}
+	for hookID, hook := range s.webhooks {
+		if hook.RoomID == id {
+			delete(s.webhooks, hookID)
+		}
+	}
+	s.audit = slices.DeleteFunc(s.audit, func(entry pgstore.ModerationAudit) bool {
+		return entry.RoomID == id
+	})
+	s.announcements = slices.DeleteFunc(s.announcements, func(announcement pgstore.Announcement) bool {
+		return announcement.RoomID == id
+	})
+	for pollID, poll := range s.polls {
+		if poll.RoomID == id {
+			s.deletePoll(pollID)
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteRoomMessages(ctx context.Context, roomID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteRoomMessages(roomID)
+	return nil
+}
+
+func (s *Store) deleteRoomMessages(roomID uuid.UUID) {
+	for id, m := range s.messages {
+		if m.RoomID == roomID {
+			s.deleteMessage(id)
+		}
+	}
+}
+
+func (s *Store) DeleteRoomWebhook(ctx context.Context, arg pgstore.DeleteRoomWebhookParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook, ok := s.webhooks[arg.ID]
+	if !ok || hook.RoomID != arg.RoomID {
+		return 0, nil
+	}
+	delete(s.webhooks, arg.ID)
+	s.webhookFailures = slices.DeleteFunc(s.webhookFailures, func(failure pgstore.WebhookDeliveryFailure) bool {
+		return failure.WebhookID == arg.ID
+	})
+	return 1, nil
+}
+
+func (s *Store) FindSimilarUnansweredMessages(ctx context.Context, arg pgstore.FindSimilarUnansweredMessagesParams) ([]pgstore.FindSimilarUnansweredMessagesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var similar []pgstore.FindSimilarUnansweredMessagesRow
+	target := textsearch.ExtractTrigrams(arg.Message)
+	for _, m := range s.roomMessages(arg.RoomID) {
+		if m.Answered || m.DeletedAt != nil || m.Pending {
+			continue
+		}
+		similarity := textsearch.TrigramSimilarity(target, textsearch.ExtractTrigrams(m.Message))
+		if similarity >= arg.Threshold {
+			similar = append(similar, pgstore.FindSimilarUnansweredMessagesRow{
+				ID:            m.ID,
+				Message:       m.Message,
+				ReactionCount: m.ReactionCount,
+				Similarity:    similarity,
+			})
+		}
+	}
+	// Messages are visited oldest first and the sort is stable, so ties keep
+	// the oldest one first.
+	slices.SortStableFunc(similar, func(a, b pgstore.FindSimilarUnansweredMessagesRow) int {
+		return cmp.Compare(b.Similarity, a.Similarity)
+	})
+	return similar[:min(len(similar), int(arg.MaxResults))], nil
+}
+
+func (s *Store) GetEmojiReactionCounts(ctx context.Context, ids []uuid.UUID) ([]pgstore.GetEmojiReactionCountsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	counts := make(map[emojiReactionKey]int64)
+	for key := range s.emojiReactions {
+		if wanted[key.messageID] {
+			counts[emojiReactionKey{clientKey{messageID: key.messageID}, key.kind}]++
+		}
+	}
+
+	rows := make([]pgstore.GetEmojiReactionCountsRow, 0, len(counts))
+	for key, count := range counts {
+		rows = append(rows, pgstore.GetEmojiReactionCountsRow{MessageID: key.messageID, Kind: key.kind, Count: count})
+	}
+	slices.SortFunc(rows, func(a, b pgstore.GetEmojiReactionCountsRow) int {
+		return cmp.Or(compareIDs(a.MessageID, b.MessageID), cmp.Compare(a.Kind, b.Kind))
+	})
+	return rows, nil
+}
+
+func (s *Store) GetExpiredRoomIDs(ctx context.Context, expiresAt *time.Time) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if expiresAt == nil {
+		return nil, nil
+	}
+	var ids []uuid.UUID
+	for _, room := range s.sortedRooms() {
+		if room.ExpiresAt != nil && !room.ExpiresAt.After(*expiresAt) {
+			ids = append(ids, room.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) GetDeletedRoomIDs(ctx context.Context, deletedAt *time.Time) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if deletedAt == nil {
+		return nil, nil
+	}
+	var ids []uuid.UUID
+	for _, room := range s.sortedRooms() {
+		if room.DeletedAt != nil && !room.DeletedAt.After(*deletedAt) {
+			ids = append(ids, room.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Store) GetMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[id]
+	if !ok {
+		return pgstore.Message{}, pgx.ErrNoRows
+	}
+	return m, nil
+}
+
+func (s *Store) GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]pgstore.MessageEdit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.edits[messageID]), nil
+}
+
+func (s *Store) GetMessageReplies(ctx context.Context, messageID uuid.UUID) ([]pgstore.MessageReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.replies[messageID]), nil
+}
+
+func (s *Store) GetMessageReply(ctx context.Context, id uuid.UUID) (pgstore.MessageReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, replies := range s.replies {
+		for _, reply := range replies {
+			if reply.ID == id {
+				return reply, nil
+			}
+		}
+	}
+	return pgstore.MessageReply{}, pgx.ErrNoRows
+}
+
+func (s *Store) GetReactionCounts(ctx context.Context, ids []uuid.UUID) ([]pgstore.GetReactionCountsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reactionCounts(ids), nil
+}
+
+func (s *Store) reactionCounts(ids []uuid.UUID) []pgstore.GetReactionCountsRow {
+	var counts []pgstore.GetReactionCountsRow
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		m, ok := s.messages[id]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		counts = append(counts, pgstore.GetReactionCountsRow{ID: m.ID, ReactionCount: m.ReactionCount, Version: m.Version})
+	}
+	slices.SortFunc(counts, func(a, b pgstore.GetReactionCountsRow) int {
+		return compareIDs(a.ID, b.ID)
+	})
+	return counts
+}
+
+func (s *Store) GetRoom(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[id]
+	if !ok {
+		return pgstore.Room{}, pgx.ErrNoRows
+	}
+	return room, nil
+}
+
+// GetRoomForUpdate is GetRoom: every call already holds the store's lock.
+func (s *Store) GetRoomForUpdate(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	return s.GetRoom(ctx, id)
+}
+
+func (s *Store) GetAnnouncement(ctx context.Context, id uuid.UUID) (pgstore.Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, announcement := range s.announcements {
+		if announcement.ID == id {
+			return announcement, nil
+		}
+	}
+	return pgstore.Announcement{}, pgx.ErrNoRows
+}
+
+func (s *Store) GetRoomAnnouncements(ctx context.Context, roomID uuid.UUID) ([]pgstore.Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var announcements []pgstore.Announcement
+	for _, announcement := range s.announcements {
+		if announcement.RoomID == roomID {
+			announcements = append(announcements, announcement)
+		}
+	}
+	slices.SortFunc(announcements, func(a, b pgstore.Announcement) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), compareIDs(b.ID, a.ID))
+	})
+	return announcements, nil
+}
+
+func (s *Store) GetRoomFlaggedMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.GetRoomFlaggedMessagesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := make(map[uuid.UUID]*pgstore.GetRoomFlaggedMessagesRow)
+	for key, flag := range s.flags {
+		m, ok := s.messages[key.messageID]
+		if !ok || m.RoomID != roomID {
+			continue
+		}
+		row, ok := rows[m.ID]
+		if !ok {
+			row = &pgstore.GetRoomFlaggedMessagesRow{ID: m.ID, Message: m.Message, Answered: m.Answered}
+			rows[m.ID] = row
+		}
+		row.FlagCount++
+		switch {
+		case flag.Reason == nil:
+			row.UnspecifiedCount++
+		case *flag.Reason == "spam":
+			row.SpamCount++
+		case *flag.Reason == "abuse":
+			row.AbuseCount++
+		case *flag.Reason == "off_topic":
+			row.OffTopicCount++
+		}
+	}
+
+	var flagged []pgstore.GetRoomFlaggedMessagesRow
+	for _, row := range rows {
+		flagged = append(flagged, *row)
+	}
+	slices.SortFunc(flagged, func(a, b pgstore.GetRoomFlaggedMessagesRow) int {
+		return cmp.Or(cmp.Compare(b.FlagCount, a.FlagCount), compareMessages(s.messages[a.ID], s.messages[b.ID]))
+	})
+	return flagged, nil
+}
+
+func (s *Store) GetRoomIDByCode(ctx context.Context, code string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.roomIDByCode(code)
+}
+
+func (s *Store) roomIDByCode(code string) (uuid.UUID, error) {
+	for _, room := range s.rooms {
+		if room.Code == code {
+			return room.ID, nil
+		}
+	}
+	return uuid.Nil, pgx.ErrNoRows
+}
+
+func (s *Store) GetRoomMessageIDs(ctx context.Context, arg pgstore.GetRoomMessageIDsParams) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.roomMessageIDs(arg), nil
+}
+
+func (s *Store) roomMessageIDs(arg pgstore.GetRoomMessageIDsParams) []uuid.UUID {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool, len(arg.Ids))
+	for _, id := range arg.Ids {
+		if m, ok := s.messages[id]; ok && m.RoomID == arg.RoomID && !m.Pending && m.DeletedAt == nil && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (s *Store) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.roomMessages(roomID), nil
+}
+
+func (s *Store) GetRoomPendingMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []pgstore.Message
+	for _, m := range s.roomMessages(roomID) {
+		if m.Pending && m.DeletedAt == nil {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (s *Store) GetRoomMessagesCreatedAfter(ctx context.Context, arg pgstore.GetRoomMessagesCreatedAfterParams) ([]pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	after, ok := s.messages[arg.AfterID]
+	if !ok {
+		return nil, nil
+	}
+	var messages []pgstore.Message
+	for _, m := range s.roomMessages(arg.RoomID) {
+		if m.DeletedAt == nil && !m.Pending && compareMessages(m, after) > 0 {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (s *Store) GetRoomMessagesPage(ctx context.Context, arg pgstore.GetRoomMessagesPageParams) ([]pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	after := pgstore.Message{ID: arg.AfterID, CreatedAt: arg.AfterCreatedAt}
+	var messages []pgstore.Message
+	for _, m := range s.roomMessages(arg.RoomID) {
+		if len(messages) >= int(arg.MaxResults) {
+			break
+		}
+		if !m.Pending &&
+			(m.DeletedAt == nil || arg.IncludeDeleted) &&
+			(arg.Language == nil || m.Language == *arg.Language) &&
+			compareMessages(m, after) > 0 {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (s *Store) GetRoomStats(ctx context.Context, roomID uuid.UUID) (pgstore.GetRoomStatsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats pgstore.GetRoomStatsRow
+	for _, m := range s.messages {
+		if m.RoomID != roomID || m.DeletedAt != nil || m.Pending {
+			continue
+		}
+		stats.TotalMessages++
+		if m.Answered {
+			stats.AnsweredMessages++
+		} else {
+			stats.UnansweredMessages++
+		}
+		stats.TotalReactions += m.ReactionCount
+	}
+	return stats, nil
+}
+
+func (s *Store) GetRoomWebhooks(ctx context.Context, roomID uuid.UUID) ([]pgstore.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.roomWebhooks(roomID), nil
+}
+
+func (s *Store) roomWebhooks(roomID uuid.UUID) []pgstore.Webhook {
+	var hooks []pgstore.Webhook
+	for _, hook := range s.webhooks {
+		if hook.RoomID == roomID {
+			hooks = append(hooks, hook)
+		}
+	}
+	slices.SortFunc(hooks, func(a, b pgstore.Webhook) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), compareIDs(a.ID, b.ID))
+	})
+	return hooks
+}
+
+func (s *Store) GetRooms(ctx context.Context) ([]pgstore.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedRooms(), nil
+}
+
+func (s *Store) sortedRooms() []pgstore.Room {
+	rooms := make([]pgstore.Room, 0, len(s.rooms))
+	for _, room := range s.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.SortFunc(rooms, func(a, b pgstore.Room) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), compareIDs(a.ID, b.ID))
+	})
+	return rooms
+}
+
+func (s *Store) GetTopUnansweredMessages(ctx context.Context, arg pgstore.GetTopUnansweredMessagesParams) ([]pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []pgstore.Message
+	for _, m := range s.roomMessages(arg.RoomID) {
+		if !m.Answered && m.DeletedAt == nil && !m.Pending {
+			messages = append(messages, m)
+		}
+	}
+	slices.SortStableFunc(messages, func(a, b pgstore.Message) int {
+		return cmp.Compare(b.ReactionCount, a.ReactionCount)
+	})
+	if len(messages) > int(arg.Limit) {
+		messages = messages[:arg.Limit]
+	}
+	return messages, nil
+}
+
+func (s *Store) InsertAnnouncement(ctx context.Context, arg pgstore.InsertAnnouncementParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rooms[arg.RoomID]; !ok {
+		return foreignKeyViolation("announcements_room_id_fkey")
+	}
+	s.announcements = append(s.announcements, pgstore.Announcement{
+		ID:        arg.ID,
+		RoomID:    arg.RoomID,
+		Author:    arg.Author,
+		Body:      arg.Body,
+		CreatedAt: arg.CreatedAt,
+	})
+	return nil
+}
+
+func (s *Store) InsertClientReactions(ctx context.Context, arg pgstore.InsertClientReactionsParams) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertClientReactions(arg)
+}
+
+func (s *Store) insertClientReactions(arg pgstore.InsertClientReactionsParams) ([]uuid.UUID, error) {
+	for _, id := range arg.MessageIds {
+		if _, ok := s.messages[id]; !ok {
+			return nil, foreignKeyViolation("message_reactions_message_id_fkey")
+		}
+	}
+
+	var added []uuid.UUID
+	for _, id := range arg.MessageIds {
+		key := clientKey{messageID: id, clientID: arg.ClientID}
+		if _, ok := s.reactions[key]; !ok {
+			s.reactions[key] = time.Now()
+			added = append(added, id)
+		}
+	}
+	return added, nil
+}
+
+func (s *Store) InsertEmojiReaction(ctx context.Context, arg pgstore.InsertEmojiReactionParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[arg.MessageID]; !ok {
+		return 0, foreignKeyViolation("message_emoji_reactions_message_id_fkey")
+	}
+	key := emojiReactionKey{clientKey{messageID: arg.MessageID, clientID: arg.ClientID}, arg.Kind}
+	if _, ok := s.emojiReactions[key]; ok {
+		return 0, nil
+	}
+	s.emojiReactions[key] = arg.CreatedAt
+	return 1, nil
+}
+
+func (s *Store) InsertMessage(ctx context.Context, arg pgstore.InsertMessageParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertMessage(arg)
+}
+
+func (s *Store) insertMessage(arg pgstore.InsertMessageParams) (uuid.UUID, error) {
+	if _, ok := s.rooms[arg.RoomID]; !ok {
+		return uuid.Nil, foreignKeyViolation("messages_room_id_fkey")
+	}
+	if _, ok := s.messages[arg.ID]; ok {
+		return uuid.Nil, uniqueViolation("messages_pkey")
+	}
+
+	s.messages[arg.ID] = pgstore.Message{
+		ID:                 arg.ID,
+		RoomID:             arg.RoomID,
+		Message:            arg.Message,
+		AuthorID:           arg.AuthorID,
+		CreatedAt:          arg.CreatedAt,
+		ConsentToPublish:   arg.ConsentToPublish,
+		AuthorName:         arg.AuthorName,
+		Language:           arg.Language,
+		LanguageConfidence: arg.LanguageConfidence,
+		Version:            1,
+		Pending:            arg.Pending,
+	}
+	return arg.ID, nil
+}
+
+func (s *Store) InsertMessageEdit(ctx context.Context, arg pgstore.InsertMessageEditParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[arg.MessageID]; !ok {
+		return foreignKeyViolation("message_edits_message_id_fkey")
+	}
+	edits := s.edits[arg.MessageID]
+	i, found := slices.BinarySearchFunc(edits, arg.Version, func(e pgstore.MessageEdit, version int64) int {
+		return cmp.Compare(e.Version, version)
+	})
+	if found {
+		return nil
+	}
+	s.edits[arg.MessageID] = slices.Insert(edits, i, pgstore.MessageEdit{
+		MessageID: arg.MessageID,
+		Version:   arg.Version,
+		Message:   arg.Message,
+		EditedAt:  arg.EditedAt,
+	})
+	return nil
+}
+
+func (s *Store) InsertMessageFlag(ctx context.Context, arg pgstore.InsertMessageFlagParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[arg.MessageID]; !ok {
+		return 0, foreignKeyViolation("message_flags_message_id_fkey")
+	}
+	key := clientKey{messageID: arg.MessageID, clientID: arg.ClientID}
+	if _, ok := s.flags[key]; ok {
+		return 0, nil
+	}
+	s.flags[key] = pgstore.MessageFlag{
+		MessageID: arg.MessageID,
+		ClientID:  arg.ClientID,
+		Reason:    arg.Reason,
+		CreatedAt: arg.CreatedAt,
+	}
+	return 1, nil
+}
+
+func (s *Store) InsertMessageReply(ctx context.Context, arg pgstore.InsertMessageReplyParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[arg.MessageID]; !ok {
+		return foreignKeyViolation("message_replies_message_id_fkey")
+	}
+	s.replies[arg.MessageID] = append(s.replies[arg.MessageID], pgstore.MessageReply{
+		ID:        arg.ID,
+		MessageID: arg.MessageID,
+		Author:    arg.Author,
+		Body:      arg.Body,
+		CreatedAt: arg.CreatedAt,
+	})
+	return nil
+}
+
+func (s *Store) InsertRoom(ctx context.Context, arg pgstore.InsertRoomParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertRoom(arg)
+}
+
+func (s *Store) insertRoom(arg pgstore.InsertRoomParams) (uuid.UUID, error) {
+	if _, ok := s.rooms[arg.ID]; ok {
+		return uuid.Nil, uniqueViolation("rooms_pkey")
+	}
+	if _, err := s.roomIDByCode(arg.Code); err == nil {
+		return uuid.Nil, uniqueViolation("rooms_code_idx")
+	}
+
+	s.rooms[arg.ID] = pgstore.Room{
+		ID:                         arg.ID,
+		Theme:                      arg.Theme,
+		MaxMessages:                arg.MaxMessages,
+		Prune:                      arg.Prune,
+		RequireName:                arg.RequireName,
+		CreatedAt:                  arg.CreatedAt,
+		ExpiresAt:                  arg.ExpiresAt,
+		DuplicateThreshold:         arg.DuplicateThreshold,
+		MaxQuestionsPerParticipant: arg.MaxQuestionsPerParticipant,
+		RequireApproval:            arg.RequireApproval,
+		ClosesAt:                   arg.ClosesAt,
+		Description:                arg.Description,
+		HostName:                   arg.HostName,
+		StartsAt:                   arg.StartsAt,
+		Code:                       arg.Code,
+	}
+	return arg.ID, nil
+}
+
+func (s *Store) InsertWebhook(ctx context.Context, arg pgstore.InsertWebhookParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertWebhook(arg)
+}
+
+func (s *Store) insertWebhook(arg pgstore.InsertWebhookParams) error {
+	if _, ok := s.rooms[arg.RoomID]; !ok {
+		return foreignKeyViolation("webhooks_room_id_fkey")
+	}
+	if _, ok := s.webhooks[arg.ID]; ok {
+		return uniqueViolation("webhooks_pkey")
+	}
+
+	s.webhooks[arg.ID] = pgstore.Webhook{
+		ID:        arg.ID,
+		RoomID:    arg.RoomID,
+		Url:       arg.Url,
+		Secret:    arg.Secret,
+		CreatedAt: arg.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) InsertWebhookDeliveryFailure(ctx context.Context, arg pgstore.InsertWebhookDeliveryFailureParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[arg.WebhookID]; !ok {
+		return foreignKeyViolation("webhook_delivery_failures_webhook_id_fkey")
+	}
+	s.webhookFailures = append(s.webhookFailures, pgstore.WebhookDeliveryFailure{
+		ID:        uuid.New(),
+		WebhookID: arg.WebhookID,
+		EventKind: arg.EventKind,
+		Attempts:  arg.Attempts,
+		Error:     arg.Error,
+		FailedAt:  arg.FailedAt,
+	})
+	return nil
+}
+
+func (s *Store) ListRooms(ctx context.Context, arg pgstore.ListRoomsParams) ([]pgstore.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := strings.ToLower(arg.ThemeQuery)
+	var rooms []pgstore.Room
+	for _, room := range s.sortedRooms() {
+		if !strings.Contains(strings.ToLower(room.Theme), query) {
+			continue
+		}
+		if !arg.IncludeExpired && room.ExpiresAt != nil && !room.ExpiresAt.After(arg.Now) {
+			continue
+		}
+		if room.DeletedAt != nil {
+			continue
+		}
+		rooms = append(rooms, room)
+	}
+	if arg.NewestFirst {
+		slices.SortStableFunc(rooms, func(a, b pgstore.Room) int {
+			return b.CreatedAt.Compare(a.CreatedAt)
+		})
+	}
+	if arg.MaxResults > 0 && len(rooms) > int(arg.MaxResults) {
+		rooms = rooms[:arg.MaxResults]
+	}
+	return rooms, nil
+}
+
+func (s *Store) MarkMessageAsAnswered(ctx context.Context, arg pgstore.MarkMessageAsAnsweredParams) (pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[arg.ID]
+	if !ok || m.DeletedAt != nil || (arg.ExpectedVersion != nil && m.Version != *arg.ExpectedVersion) {
+		return pgstore.Message{}, pgx.ErrNoRows
+	}
+	m.Answered = true
+	if arg.Answer != nil {
+		m.Answer = arg.Answer
+	}
+	m.Version++
+	s.messages[m.ID] = m
+	return m, nil
+}
+
+func (s *Store) SoftDeleteMessage(ctx context.Context, arg pgstore.SoftDeleteMessageParams) (pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[arg.ID]
+	if !ok || m.DeletedAt != nil || (arg.ExpectedVersion != nil && m.Version != *arg.ExpectedVersion) {
+		return pgstore.Message{}, pgx.ErrNoRows
+	}
+	m.DeletedAt = arg.DeletedAt
+	m.DeletedBy = arg.DeletedBy
+	m.Version++
+	s.messages[m.ID] = m
+	return m, nil
+}
+
+func (s *Store) RestoreMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[id]
+	if !ok || m.DeletedAt == nil {
+		return pgstore.Message{}, pgx.ErrNoRows
+	}
+	m.DeletedAt = nil
+	m.DeletedBy = nil
+	m.Version++
+	s.messages[m.ID] = m
+	return m, nil
+}
+
+func (s *Store) ApproveMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[id]
+	if !ok || !m.Pending || m.DeletedAt != nil {
+		return pgstore.Message{}, pgx.ErrNoRows
+	}
+	m.Pending = false
+	m.Version++
+	s.messages[m.ID] = m
+	return m, nil
+}
+
+func (s *Store) InsertModerationAudit(ctx context.Context, arg pgstore.InsertModerationAuditParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rooms[arg.RoomID]; !ok {
+		return foreignKeyViolation("moderation_audit_room_id_fkey")
+	}
+	s.audit = append(s.audit, pgstore.ModerationAudit{
+		ID:            arg.ID,
+		RoomID:        arg.RoomID,
+		MessageID:     arg.MessageID,
+		Actor:         arg.Actor,
+		Action:        arg.Action,
+		PreviousValue: arg.PreviousValue,
+		CreatedAt:     arg.CreatedAt,
+	})
+	return nil
+}
+
+func (s *Store) GetRoomModerationAudit(ctx context.Context, roomID uuid.UUID) ([]pgstore.ModerationAudit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []pgstore.ModerationAudit
+	for _, entry := range s.audit {
+		if entry.RoomID == roomID {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b pgstore.ModerationAudit) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), compareIDs(b.ID, a.ID))
+	})
+	return entries, nil
+}
+
+func (s *Store) ReactToMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.addReaction(id, 1)
+}
+
+func (s *Store) RemoveReactionFromMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.addReaction(id, -1)
+}
+
+func (s *Store) addReaction(id uuid.UUID, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[id]
+	if !ok {
+		return 0, pgx.ErrNoRows
+	}
+	m.ReactionCount = max(m.ReactionCount+delta, 0)
+	m.Version++
+	s.messages[id] = m
+	return m.ReactionCount, nil
+}
+
+func (s *Store) RecordWebhookDelivery(ctx context.Context, arg pgstore.RecordWebhookDeliveryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook, ok := s.webhooks[arg.ID]
+	if !ok {
+		return nil
+	}
+	hook.LastDeliveryAt = arg.LastDeliveryAt
+	hook.LastStatus = arg.LastStatus
+	hook.LastError = arg.LastError
+	s.webhooks[arg.ID] = hook
+	return nil
+}
+
+func (s *Store) SearchRoomMessages(ctx context.Context, arg pgstore.SearchRoomMessagesParams) ([]pgstore.SearchRoomMessagesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := textsearch.ParseQuery(arg.Query)
+	var rows []pgstore.SearchRoomMessagesRow
+	for _, m := range s.roomMessages(arg.RoomID) {
+		if m.Pending || m.DeletedAt != nil && !arg.IncludeDeleted {
+			continue
+		}
+		rank, ok := query.Match(m.Message)
+		if !ok {
+			continue
+		}
+		rows = append(rows, pgstore.SearchRoomMessagesRow{
+			ID:                 m.ID,
+			RoomID:             m.RoomID,
+			Message:            m.Message,
+			ReactionCount:      m.ReactionCount,
+			Answered:           m.Answered,
+			AuthorID:           m.AuthorID,
+			CreatedAt:          m.CreatedAt,
+			ConsentToPublish:   m.ConsentToPublish,
+			AuthorName:         m.AuthorName,
+			Language:           m.Language,
+			LanguageConfidence: m.LanguageConfidence,
+			Answer:             m.Answer,
+			Version:            m.Version,
+			DeletedAt:          m.DeletedAt,
+			DeletedBy:          m.DeletedBy,
+			Pending:            m.Pending,
+			Rank:               rank,
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b pgstore.SearchRoomMessagesRow) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+
+	start := min(int(arg.SkipResults), len(rows))
+	end := min(start+int(arg.MaxResults), len(rows))
+	return rows[start:end], nil
+}
+
+func (s *Store) UpdateMessage(ctx context.Context, arg pgstore.UpdateMessageParams) (pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[arg.ID]
+	if !ok ||
+		m.AuthorID != arg.AuthorID ||
+		m.Answered ||
+		m.DeletedAt != nil ||
+		!m.CreatedAt.After(arg.EditWindowStart) ||
+		(arg.ExpectedVersion != nil && m.Version != *arg.ExpectedVersion) {
+		return pgstore.Message{}, pgx.ErrNoRows
+	}
+	m.Message = arg.Message
+	m.Version++
+	s.messages[m.ID] = m
+	return m, nil
+}
+
+func (s *Store) UpdateMessageConsent(ctx context.Context, arg pgstore.UpdateMessageConsentParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[arg.ID]
+	if !ok || m.AuthorID != arg.AuthorID {
+		return false, pgx.ErrNoRows
+	}
+	m.ConsentToPublish = arg.ConsentToPublish
+	m.Version++
+	s.messages[m.ID] = m
+	return m.ConsentToPublish, nil
+}
+
+func (s *Store) UpdateMessageLanguage(ctx context.Context, arg pgstore.UpdateMessageLanguageParams) (pgstore.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.messages[arg.ID]
+	if !ok || m.DeletedAt != nil {
+		return pgstore.Message{}, pgx.ErrNoRows
+	}
+	m.Language = arg.Language
+	m.LanguageConfidence = arg.LanguageConfidence
+	m.Version++
+	s.messages[m.ID] = m
+	return m, nil
+}
+
+func (s *Store) UpdateRoom(ctx context.Context, arg pgstore.UpdateRoomParams) (pgstore.Room, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[arg.ID]
+	if !ok || room.DeletedAt != nil {
+		return pgstore.Room{}, pgx.ErrNoRows
+	}
+	room.Theme = arg.Theme
+	room.MaxMessages = arg.MaxMessages
+	room.Prune = arg.Prune
+	room.RequireName = arg.RequireName
+	room.ExpiresAt = arg.ExpiresAt
+	room.DuplicateThreshold = arg.DuplicateThreshold
+	room.MaxQuestionsPerParticipant = arg.MaxQuestionsPerParticipant
+	room.RequireApproval = arg.RequireApproval
+	room.ClosesAt = arg.ClosesAt
+	room.Description = arg.Description
+	room.HostName = arg.HostName
+	room.StartsAt = arg.StartsAt
+	s.rooms[room.ID] = room
+	return room, nil
+}
+
+func comparePolls(a, b pgstore.Poll) int {
+	return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), compareIDs(b.ID, a.ID))
+}
+
+func (s *Store) deletePoll(id uuid.UUID) {
+	delete(s.polls, id)
+	delete(s.pollOptions, id)
+	for key := range s.pollVotes {
+		if key.pollID == id {
+			delete(s.pollVotes, key)
+		}
+	}
+}
+
+func (s *Store) CastPollVote(ctx context.Context, arg pgstore.CastPollVoteParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, ok := s.polls[arg.PollID]
+	if !ok || poll.ClosedAt != nil {
+		return 0, nil
+	}
+	hasChoice := slices.ContainsFunc(s.pollOptions[arg.PollID], func(option pgstore.PollOption) bool {
+		return option.Position == arg.Choice
+	})
+	if !hasChoice {
+		return 0, foreignKeyViolation("poll_votes_poll_id_choice_fkey")
+	}
+	key := pollVoteKey{pollID: arg.PollID, clientID: arg.ClientID}
+	if vote, ok := s.pollVotes[key]; ok && vote.Choice == arg.Choice {
+		return 0, nil
+	}
+	s.pollVotes[key] = pgstore.PollVote{
+		PollID:    arg.PollID,
+		ClientID:  arg.ClientID,
+		Choice:    arg.Choice,
+		CreatedAt: arg.CreatedAt,
+	}
+	return 1, nil
+}
+
+func (s *Store) ClosePoll(ctx context.Context, arg pgstore.ClosePollParams) (pgstore.Poll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, ok := s.polls[arg.ID]
+	if !ok || poll.ClosedAt != nil {
+		return pgstore.Poll{}, pgx.ErrNoRows
+	}
+	poll.ClosedAt = arg.ClosedAt
+	s.polls[poll.ID] = poll
+	return poll, nil
+}
+
+func (s *Store) GetPoll(ctx context.Context, id uuid.UUID) (pgstore.Poll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	poll, ok := s.polls[id]
+	if !ok {
+		return pgstore.Poll{}, pgx.ErrNoRows
+	}
+	return poll, nil
+}
+
+func (s *Store) GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]pgstore.PollOption, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.pollOptions[pollID]), nil
+}
+
+func (s *Store) GetPollVoteCounts(ctx context.Context, pollID uuid.UUID) ([]pgstore.GetPollVoteCountsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[int32]int64)
+	for key, vote := range s.pollVotes {
+		if key.pollID == pollID {
+			counts[vote.Choice]++
+		}
+	}
+
+	rows := make([]pgstore.GetPollVoteCountsRow, 0, len(counts))
+	for choice, votes := range counts {
+		rows = append(rows, pgstore.GetPollVoteCountsRow{Choice: choice, Votes: votes})
+	}
+	slices.SortFunc(rows, func(a, b pgstore.GetPollVoteCountsRow) int {
+		return cmp.Compare(a.Choice, b.Choice)
+	})
+	return rows, nil
+}
+
+func (s *Store) GetRoomPollOptions(ctx context.Context, roomID uuid.UUID) ([]pgstore.GetRoomPollOptionsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type choice struct {
+		pollID   uuid.UUID
+		position int32
+	}
+	votes := make(map[choice]int64)
+	for key, vote := range s.pollVotes {
+		votes[choice{key.pollID, vote.Choice}]++
+	}
+
+	var rows []pgstore.GetRoomPollOptionsRow
+	for pollID, options := range s.pollOptions {
+		if s.polls[pollID].RoomID != roomID {
+			continue
+		}
+		for _, option := range options {
+			rows = append(rows, pgstore.GetRoomPollOptionsRow{
+				PollID:   option.PollID,
+				Position: option.Position,
+				Label:    option.Label,
+				Votes:    votes[choice{pollID, option.Position}],
+			})
+		}
+	}
+	slices.SortFunc(rows, func(a, b pgstore.GetRoomPollOptionsRow) int {
+		return cmp.Or(compareIDs(a.PollID, b.PollID), cmp.Compare(a.Position, b.Position))
+	})
+	return rows, nil
+}
+
+func (s *Store) GetRoomPolls(ctx context.Context, roomID uuid.UUID) ([]pgstore.Poll, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var polls []pgstore.Poll
+	for _, poll := range s.polls {
+		if poll.RoomID == roomID {
+			polls = append(polls, poll)
+		}
+	}
+	slices.SortFunc(polls, comparePolls)
+	return polls, nil
+}
+
+func (s *Store) InsertPoll(ctx context.Context, arg pgstore.InsertPollParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insertPoll(arg)
+}
+
+func (s *Store) insertPoll(arg pgstore.InsertPollParams) error {
+	if _, ok := s.rooms[arg.RoomID]; !ok {
+		return foreignKeyViolation("polls_room_id_fkey")
+	}
+	if _, ok := s.polls[arg.ID]; ok {
+		return uniqueViolation("polls_pkey")
+	}
+	s.polls[arg.ID] = pgstore.Poll{
+		ID:        arg.ID,
+		RoomID:    arg.RoomID,
+		Question:  arg.Question,
+		CreatedAt: arg.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) InsertPollOption(ctx context.Context, arg pgstore.InsertPollOptionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.polls[arg.PollID]; !ok {
+		return foreignKeyViolation("poll_options_poll_id_fkey")
+	}
+	for _, option := range s.pollOptions[arg.PollID] {
+		if option.Position == arg.Position {
+			return uniqueViolation("poll_options_pkey")
+		}
+	}
+	options := append(s.pollOptions[arg.PollID], pgstore.PollOption{
+		PollID:   arg.PollID,
+		Position: arg.Position,
+		Label:    arg.Label,
+	})
+	slices.SortFunc(options, func(a, b pgstore.PollOption) int {
+		return cmp.Compare(a.Position, b.Position)
+	})
+	s.pollOptions[arg.PollID] = options
+	return nil
+}
+
+// The methods below are the transactional ones of pgstore. Holding the lock
+// for their whole duration makes them atomic; they check everything that can
+// fail before changing anything.
+
+func (s *Store) InsertMessageWithinCapacity(ctx context.Context, arg pgstore.InsertMessageWithinCapacityParams) (uuid.UUID, uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[arg.RoomID]
+	if !ok {
+		return uuid.Nil, uuid.Nil, pgx.ErrNoRows
+	}
+	if room.ClosedAt != nil {
+		return uuid.Nil, uuid.Nil, pgstore.ErrRoomClosed
+	}
+	if _, ok := s.messages[arg.ID]; ok {
+		return uuid.Nil, uuid.Nil, uniqueViolation("messages_pkey")
+	}
+
+	quota := participantKey{roomID: arg.RoomID, participant: arg.Participant}
+	if room.MaxQuestionsPerParticipant > 0 && arg.Participant != "" && s.questions[quota] >= room.MaxQuestionsPerParticipant {
+		return uuid.Nil, uuid.Nil, pgstore.ErrQuestionQuotaReached
+	}
+
+	var pruned uuid.UUID
+	if room.MaxMessages > 0 && s.countLiveRoomMessages(arg.RoomID) >= int64(room.MaxMessages) {
+		if !room.Prune {
+			return uuid.Nil, uuid.Nil, pgstore.ErrRoomAtCapacity
+		}
+		var err error
+		if pruned, err = s.deleteOldestPrunableMessage(arg.RoomID); err != nil {
+			return uuid.Nil, uuid.Nil, pgstore.ErrRoomAtCapacity
+		}
+	}
+
+	id, err := s.insertMessage(arg.InsertMessageParams)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if room.MaxQuestionsPerParticipant > 0 && arg.Participant != "" {
+		s.questions[quota]++
+	}
+	return id, pruned, nil
+}
+
+func (s *Store) DeleteRoomWithMessages(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteRoomMessages(id)
+	return s.deleteRoom(id)
+}
+
+func (s *Store) InsertRoomWithWebhooks(ctx context.Context, room pgstore.InsertRoomParams, webhooks []pgstore.InsertWebhookParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rooms[room.ID]; ok {
+		return uuid.Nil, uniqueViolation("rooms_pkey")
+	}
+	for _, webhook := range webhooks {
+		if _, ok := s.webhooks[webhook.ID]; ok {
+			return uuid.Nil, uniqueViolation("webhooks_pkey")
+		}
+	}
+
+	id, err := s.insertRoom(room)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for _, webhook := range webhooks {
+		webhook.RoomID = id
+		if err := s.insertWebhook(webhook); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return id, nil
+}
+
+func (s *Store) UpdateRoomWebhooks(ctx context.Context, arg pgstore.UpdateRoomWebhooksParams) ([]pgstore.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rooms[arg.RoomID]; !ok {
+		return nil, pgx.ErrNoRows
+	}
+	count := len(s.roomWebhooks(arg.RoomID))
+	for _, id := range arg.Remove {
+		if hook, ok := s.webhooks[id]; !ok || hook.RoomID != arg.RoomID {
+			return nil, pgx.ErrNoRows
+		}
+		count--
+	}
+	for _, webhook := range arg.Add {
+		if _, ok := s.webhooks[webhook.ID]; ok {
+			return nil, uniqueViolation("webhooks_pkey")
+		}
+		count++
+	}
+	if count > arg.Max {
+		return nil, pgstore.ErrTooManyWebhooks
+	}
+
+	for _, id := range arg.Remove {
+		delete(s.webhooks, id)
+		s.webhookFailures = slices.DeleteFunc(s.webhookFailures, func(failure pgstore.WebhookDeliveryFailure) bool {
+			return failure.WebhookID == id
+		})
+	}
+	for _, webhook := range arg.Add {
+		webhook.RoomID = arg.RoomID
+		if err := s.insertWebhook(webhook); err != nil {
+			return nil, err
+		}
+	}
+	return s.roomWebhooks(arg.RoomID), nil
+}
+
+func (s *Store) ApplyReactionBatch(ctx context.Context, arg pgstore.ApplyReactionBatchParams) ([]pgstore.GetReactionCountsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]uuid.UUID, 0, len(arg.Add)+len(arg.Remove))
+	ids = append(append(ids, arg.Add...), arg.Remove...)
+
+	found := s.roomMessageIDs(pgstore.GetRoomMessageIDsParams{RoomID: arg.RoomID, Ids: ids})
+	if len(found) != len(uniqueIDs(ids)) {
+		var unknown []uuid.UUID
+		for _, id := range ids {
+			if !slices.Contains(found, id) {
+				unknown = append(unknown, id)
+			}
+		}
+		return nil, &pgstore.UnknownMessagesError{IDs: unknown}
+	}
+
+	added, err := s.insertClientReactions(pgstore.InsertClientReactionsParams{MessageIds: arg.Add, ClientID: arg.ClientID})
+	if err != nil {
+		return nil, fmt.Errorf("memstore: applying reactions: %w", err)
+	}
+	s.addReactionCounts(added, 1)
+	removed := s.deleteClientReactions(pgstore.DeleteClientReactionsParams{ClientID: arg.ClientID, MessageIds: arg.Remove})
+	s.addReactionCounts(removed, -1)
+
+	return s.reactionCounts(ids), nil
+}
+
+func uniqueIDs(ids []uuid.UUID) map[uuid.UUID]struct{} {
+	set := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+func (s *Store) InsertPollWithOptions(ctx context.Context, poll pgstore.InsertPollParams, options []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.insertPoll(poll); err != nil {
+		return err
+	}
+	for i, label := range options {
+		s.pollOptions[poll.ID] = append(s.pollOptions[poll.ID], pgstore.PollOption{
+			PollID:   poll.ID,
+			Position: int32(i),
+			Label:    label,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "author_id"    VARCHAR(255)    NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "created_at"   TIMESTAMPTZ     NOT NULL DEFAULT now();
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "created_at",
+    DROP COLUMN IF EXISTS "author_id";
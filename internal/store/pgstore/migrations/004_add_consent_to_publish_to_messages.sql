@@ -0,0 +1,7 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "consent_to_publish" BOOLEAN NOT NULL DEFAULT false;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "consent_to_publish";
@@ -0,0 +1,10 @@
+-- max_messages = 0 means the room has no message limit.
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "max_messages" INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "prune"        BOOLEAN NOT NULL DEFAULT false;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "prune",
+    DROP COLUMN IF EXISTS "max_messages";
@@ -0,0 +1,13 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "author_name" VARCHAR(50);
+
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "require_name" BOOLEAN NOT NULL DEFAULT false;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "require_name";
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "author_name";
@@ -0,0 +1,9 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "language"             VARCHAR(8)  NOT NULL DEFAULT 'und',
+    ADD COLUMN IF NOT EXISTS "language_confidence"  REAL        NOT NULL DEFAULT 0;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "language_confidence",
+    DROP COLUMN IF EXISTS "language";
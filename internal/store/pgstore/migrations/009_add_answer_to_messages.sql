@@ -0,0 +1,7 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "answer" TEXT;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "answer";
@@ -0,0 +1,11 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "expires_at" TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS rooms_expires_at_idx ON rooms (expires_at) WHERE expires_at IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS rooms_expires_at_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "expires_at";
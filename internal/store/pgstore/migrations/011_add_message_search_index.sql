@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS messages_message_search_idx ON messages USING GIN (to_tsvector('simple', "message"));
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS messages_message_search_idx;
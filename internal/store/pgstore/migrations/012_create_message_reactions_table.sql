@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS message_reactions (
+    "message_id"    uuid            NOT NULL,
+    "client_id"     TEXT            NOT NULL,
+    "created_at"    TIMESTAMPTZ     NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (message_id, client_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_reactions;
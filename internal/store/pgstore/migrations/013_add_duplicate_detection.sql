@@ -0,0 +1,13 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "duplicate_threshold" REAL NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS messages_message_trgm_idx ON messages USING GIN ("message" gin_trgm_ops);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS messages_message_trgm_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "duplicate_threshold";
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    "id"                uuid            PRIMARY KEY NOT NULL,
+    "room_id"           uuid                        NOT NULL,
+    "url"               TEXT                        NOT NULL,
+    "secret"            TEXT                        NOT NULL,
+    "created_at"        TIMESTAMPTZ                 NOT NULL DEFAULT now(),
+    "last_delivery_at"  TIMESTAMPTZ,
+    "last_status"       INTEGER,
+    "last_error"        TEXT,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_failures (
+    "id"            uuid            PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    "webhook_id"    uuid                        NOT NULL,
+    "event_kind"    TEXT                        NOT NULL,
+    "attempts"      INTEGER                     NOT NULL,
+    "error"         TEXT                        NOT NULL,
+    "failed_at"     TIMESTAMPTZ                 NOT NULL,
+
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS webhook_delivery_failures;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,7 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "version" BIGINT NOT NULL DEFAULT 1;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "version";
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS message_flags (
+    "message_id"    uuid            NOT NULL,
+    "client_id"     TEXT            NOT NULL,
+    "reason"        TEXT            CHECK (reason IN ('spam', 'abuse', 'off_topic')),
+    "created_at"    TIMESTAMPTZ     NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (message_id, client_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_flags;
@@ -0,0 +1,25 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS "deleted_by" TEXT;
+
+CREATE TABLE IF NOT EXISTS moderation_audit (
+    "id"                uuid            PRIMARY KEY NOT NULL,
+    "room_id"           uuid                        NOT NULL,
+    "message_id"        uuid                        NOT NULL,
+    "actor"             TEXT                        NOT NULL,
+    "action"            TEXT                        NOT NULL CHECK (action IN ('delete', 'restore', 'answer', 'edit')),
+    "previous_value"    TEXT,
+    "created_at"        TIMESTAMPTZ                 NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS moderation_audit_room_id_created_at_idx ON moderation_audit (room_id, created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS moderation_audit;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "deleted_by",
+    DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,18 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "max_questions_per_participant" INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS participant_questions (
+    "room_id"       uuid            NOT NULL,
+    "participant"   TEXT            NOT NULL,
+    "questions"     INTEGER         NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (room_id, participant),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS participant_questions;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "max_questions_per_participant";
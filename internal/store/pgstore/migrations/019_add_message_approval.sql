@@ -0,0 +1,27 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "require_approval" BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS "pending" BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS messages_room_id_pending_idx ON messages (room_id, created_at) WHERE pending;
+
+ALTER TABLE moderation_audit
+    DROP CONSTRAINT IF EXISTS moderation_audit_action_check,
+    ADD CONSTRAINT moderation_audit_action_check CHECK (action IN ('delete', 'restore', 'answer', 'edit', 'approve', 'reject'));
+
+---- create above / drop below ----
+
+DELETE FROM moderation_audit WHERE action IN ('approve', 'reject');
+
+ALTER TABLE moderation_audit
+    DROP CONSTRAINT IF EXISTS moderation_audit_action_check,
+    ADD CONSTRAINT moderation_audit_action_check CHECK (action IN ('delete', 'restore', 'answer', 'edit'));
+
+DROP INDEX IF EXISTS messages_room_id_pending_idx;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS "pending";
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "require_approval";
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS message_edits (
+    "message_id"    uuid            NOT NULL,
+    "version"       BIGINT          NOT NULL,
+    "message"       VARCHAR(255)    NOT NULL,
+    "edited_at"     TIMESTAMPTZ     NOT NULL,
+
+    PRIMARY KEY (message_id, version),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_edits;
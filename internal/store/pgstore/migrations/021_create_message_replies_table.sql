@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS message_replies (
+    "id"            uuid            PRIMARY KEY NOT NULL,
+    "message_id"    uuid                        NOT NULL,
+    "author"        TEXT                        NOT NULL,
+    "body"          TEXT                        NOT NULL,
+    "created_at"    TIMESTAMPTZ                 NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS message_replies_message_id_created_at_idx ON message_replies (message_id, created_at, id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS message_replies;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS polls (
+    "id"            uuid            PRIMARY KEY NOT NULL,
+    "room_id"       uuid                        NOT NULL,
+    "question"      VARCHAR(255)                NOT NULL,
+    "created_at"    TIMESTAMPTZ                 NOT NULL DEFAULT now(),
+    "closed_at"     TIMESTAMPTZ,
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS polls_room_id_created_at_idx ON polls (room_id, created_at);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    "poll_id"       uuid            NOT NULL,
+    "position"      INTEGER         NOT NULL,
+    "label"         VARCHAR(100)    NOT NULL,
+
+    PRIMARY KEY (poll_id, position),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    "poll_id"       uuid            NOT NULL,
+    "client_id"     TEXT            NOT NULL,
+    "choice"        INTEGER         NOT NULL,
+    "created_at"    TIMESTAMPTZ     NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (poll_id, client_id),
+    FOREIGN KEY (poll_id, choice) REFERENCES poll_options(poll_id, position) ON DELETE CASCADE
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS polls;
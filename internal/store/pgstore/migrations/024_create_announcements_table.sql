@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS announcements (
+    "id"            uuid            PRIMARY KEY NOT NULL,
+    "room_id"       uuid                        NOT NULL,
+    "author"        TEXT                        NOT NULL,
+    "body"          VARCHAR(255)                NOT NULL,
+    "created_at"    TIMESTAMPTZ                 NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS announcements_room_id_created_at_idx ON announcements (room_id, created_at, id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS announcements;
@@ -0,0 +1,7 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "closed_at" TIMESTAMPTZ;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "closed_at";
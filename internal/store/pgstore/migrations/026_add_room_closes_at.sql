@@ -0,0 +1,11 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "closes_at" TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS rooms_closes_at_idx ON rooms (closes_at) WHERE closes_at IS NOT NULL AND closed_at IS NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS rooms_closes_at_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "closes_at";
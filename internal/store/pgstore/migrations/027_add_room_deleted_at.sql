@@ -0,0 +1,11 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS rooms_deleted_at_idx ON rooms (deleted_at) WHERE deleted_at IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS rooms_deleted_at_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "deleted_at";
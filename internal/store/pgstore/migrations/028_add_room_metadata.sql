@@ -0,0 +1,11 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "description" VARCHAR(1000) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "host_name"   VARCHAR(50)   NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "starts_at"   TIMESTAMPTZ;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "starts_at",
+    DROP COLUMN IF EXISTS "host_name",
+    DROP COLUMN IF EXISTS "description";
@@ -0,0 +1,11 @@
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS "code" VARCHAR(6);
+
+CREATE UNIQUE INDEX IF NOT EXISTS rooms_code_idx ON rooms (code);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS rooms_code_idx;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS "code";
@@ -0,0 +1,32 @@
+-- The rooms created before codes get a random one, drawn from the alphabet of
+-- the api package's codes. A code taken already is drawn again.
+DO $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    room_id UUID;
+    candidate TEXT;
+BEGIN
+    FOR room_id IN SELECT id FROM rooms WHERE code IS NULL LOOP
+        LOOP
+            candidate := '';
+            FOR i IN 1..6 LOOP
+                candidate := candidate || substr(alphabet, 1 + floor(random() * 32)::INT, 1);
+            END LOOP;
+            BEGIN
+                UPDATE rooms SET code = candidate WHERE id = room_id;
+                EXIT;
+            EXCEPTION WHEN unique_violation THEN
+                -- Drawn again.
+            END;
+        END LOOP;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE rooms
+    ALTER COLUMN "code" SET NOT NULL;
+
+---- create above / drop below ----
+
+ALTER TABLE rooms
+    ALTER COLUMN "code" DROP NOT NULL;
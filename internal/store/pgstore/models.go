@@ -5,18 +5,146 @@
 package pgstore
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+type Announcement struct {
+	ID        uuid.UUID
+	RoomID    uuid.UUID
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
 type Message struct {
+	ID                 uuid.UUID
+	RoomID             uuid.UUID
+	Message            string
+	ReactionCount      int64
+	Answered           bool
+	AuthorID           string
+	CreatedAt          time.Time
+	ConsentToPublish   bool
+	AuthorName         *string
+	Language           string
+	LanguageConfidence float32
+	Answer             *string
+	Version            int64
+	DeletedAt          *time.Time
+	DeletedBy          *string
+	Pending            bool
+}
+
+type MessageEdit struct {
+	MessageID uuid.UUID
+	Version   int64
+	Message   string
+	EditedAt  time.Time
+}
+
+type MessageEmojiReaction struct {
+	MessageID uuid.UUID
+	ClientID  string
+	Kind      string
+	CreatedAt time.Time
+}
+
+type MessageFlag struct {
+	MessageID uuid.UUID
+	ClientID  string
+	Reason    *string
+	CreatedAt time.Time
+}
+
+type MessageReaction struct {
+	MessageID uuid.UUID
+	ClientID  string
+	CreatedAt time.Time
+}
+
+type MessageReply struct {
+	ID        uuid.UUID
+	MessageID uuid.UUID
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+type ModerationAudit struct {
 	ID            uuid.UUID
 	RoomID        uuid.UUID
-	Message       string
-	ReactionCount int64
-	Answered      bool
+	MessageID     uuid.UUID
+	Actor         string
+	Action        string
+	PreviousValue *string
+	CreatedAt     time.Time
+}
+
+type ParticipantQuestion struct {
+	RoomID      uuid.UUID
+	Participant string
+	Questions   int32
+}
+
+type Poll struct {
+	ID        uuid.UUID
+	RoomID    uuid.UUID
+	Question  string
+	CreatedAt time.Time
+	ClosedAt  *time.Time
+}
+
+type PollOption struct {
+	PollID   uuid.UUID
+	Position int32
+	Label    string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
+	ClientID  string
+	Choice    int32
+	CreatedAt time.Time
 }
 
 type Room struct {
-	ID    uuid.UUID
-	Theme string
+	ID                         uuid.UUID
+	Theme                      string
+	MaxMessages                int32
+	Prune                      bool
+	RequireName                bool
+	CreatedAt                  time.Time
+	ExpiresAt                  *time.Time
+	DuplicateThreshold         float32
+	MaxQuestionsPerParticipant int32
+	RequireApproval            bool
+	ClosedAt                   *time.Time
+	ClosesAt                   *time.Time
+	DeletedAt                  *time.Time
+	Description                string
+	HostName                   string
+	StartsAt                   *time.Time
+	Code                       string
+}
+
+type Webhook struct {
+	ID             uuid.UUID
+	RoomID         uuid.UUID
+	Url            string
+	Secret         string
+	CreatedAt      time.Time
+	LastDeliveryAt *time.Time
+	LastStatus     *int32
+	LastError      *string
+}
+
+type WebhookDeliveryFailure struct {
+	ID        uuid.UUID
+	WebhookID uuid.UUID
+	EventKind string
+	Attempts  int32
+	Error     string
+	FailedAt  time.Time
 }
@@ -0,0 +1,93 @@
+package pgstore_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/internal/store/storetest"
+)
+
+// testDatabaseEnv names the variable holding the URL of the database the
+// tests run against. They are skipped when it isn't set.
+const testDatabaseEnv = "WSRS_TEST_DATABASE_URL"
+
+func TestStore(t *testing.T) {
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("%s not set", testDatabaseEnv)
+	}
+	migrations, err := filepath.Glob("migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	storetest.Run(t, func(t *testing.T) api.Store {
+		n++
+		return pgstore.New(openSchema(t, url, fmt.Sprintf("storetest_%d_%d", os.Getpid(), n), migrations))
+	})
+}
+
+// openSchema creates schema, migrates it and returns a pool using it. The
+// schema is dropped when the test ends.
+func openSchema(t *testing.T, url, schema string, migrations []string) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close(ctx)
+	// Extensions belong to the database rather than to a schema, so they are
+	// kept out of the schemas that are dropped.
+	for _, stmt := range []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public",
+		"CREATE SCHEMA " + schema,
+	} {
+		if _, err := admin.Exec(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		conn, err := pgx.Connect(context.Background(), url)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	for _, path := range migrations {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(data), "---- create above / drop below ----")
+		if _, err := pool.Exec(ctx, up); err != nil {
+			t.Fatalf("migrating %s: %v", filepath.Base(path), err)
+		}
+	}
+	return pool
+}
@@ -0,0 +1,17 @@
+package pgstore
+
+import "context"
+
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that the database is reachable, with the Ping of the pool or
+// connection q runs on when it has one.
+func (q *Queries) Ping(ctx context.Context) error {
+	if p, ok := q.db.(pinger); ok {
+		return p.Ping(ctx)
+	}
+	_, err := q.db.Exec(ctx, "SELECT 1")
+	return err
+}
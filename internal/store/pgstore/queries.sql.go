@@ -7,20 +7,25 @@ package pgstore
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-const getMessage = `-- name: GetMessage :one
-SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
-FROM messages
+const approveMessage = `-- name: ApproveMessage :one
+UPDATE messages
+SET
+    pending = false,
+    version = version + 1
 WHERE
     id = $1
+    AND pending = true
+    AND deleted_at IS NULL
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
 `
 
-func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error) {
-	row := q.db.QueryRow(ctx, getMessage, id)
+func (q *Queries) ApproveMessage(ctx context.Context, id uuid.UUID) (Message, error) {
+	row := q.db.QueryRow(ctx, approveMessage, id)
 	var i Message
 	err := row.Scan(
 		&i.ID,
@@ -28,52 +33,107 @@ func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error)
 		&i.Message,
 		&i.ReactionCount,
 		&i.Answered,
+		&i.AuthorID,
+		&i.CreatedAt,
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Pending,
 	)
 	return i, err
 }
 
-const getRoom = `-- name: GetRoom :one
+const castPollVote = `-- name: CastPollVote :execrows
+INSERT INTO poll_votes
+    ( "poll_id", "client_id", "choice", "created_at" )
 SELECT
-    "id", "theme"
-FROM rooms
+    $1::uuid, $2::text, $3::integer, $4::timestamptz
+WHERE
+    EXISTS (SELECT 1 FROM polls WHERE id = $1::uuid AND closed_at IS NULL)
+ON CONFLICT ("poll_id", "client_id") DO UPDATE
+SET
+    choice = excluded.choice,
+    created_at = excluded.created_at
+WHERE
+    poll_votes.choice <> excluded.choice
+`
+
+type CastPollVoteParams struct {
+	PollID    uuid.UUID
+	ClientID  string
+	Choice    int32
+	CreatedAt time.Time
+}
+
+func (q *Queries) CastPollVote(ctx context.Context, arg CastPollVoteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, castPollVote,
+		arg.PollID,
+		arg.ClientID,
+		arg.Choice,
+		arg.CreatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const closePoll = `-- name: ClosePoll :one
+UPDATE polls
+SET
+    closed_at = $2
 WHERE
     id = $1
+    AND closed_at IS NULL
+RETURNING "id", "room_id", "question", "created_at", "closed_at"
 `
 
-func (q *Queries) GetRoom(ctx context.Context, id uuid.UUID) (Room, error) {
-	row := q.db.QueryRow(ctx, getRoom, id)
-	var i Room
-	err := row.Scan(&i.ID, &i.Theme)
+type ClosePollParams struct {
+	ID       uuid.UUID
+	ClosedAt *time.Time
+}
+
+func (q *Queries) ClosePoll(ctx context.Context, arg ClosePollParams) (Poll, error) {
+	row := q.db.QueryRow(ctx, closePoll, arg.ID, arg.ClosedAt)
+	var i Poll
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Question,
+		&i.CreatedAt,
+		&i.ClosedAt,
+	)
 	return i, err
 }
 
-const getRoomMessages = `-- name: GetRoomMessages :many
-SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
-FROM messages
+const closeDueRooms = `-- name: CloseDueRooms :many
+UPDATE rooms
+SET
+    closed_at = closes_at
 WHERE
-    room_id = $1
+    closes_at <= $1
+    AND closed_at IS NULL
+RETURNING "id"
 `
 
-func (q *Queries) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]Message, error) {
-	rows, err := q.db.Query(ctx, getRoomMessages, roomID)
+func (q *Queries) CloseDueRooms(ctx context.Context, closesAt *time.Time) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, closeDueRooms, closesAt)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Message
+	var items []uuid.UUID
 	for rows.Next() {
-		var i Message
-		if err := rows.Scan(
-			&i.ID,
-			&i.RoomID,
-			&i.Message,
-			&i.ReactionCount,
-			&i.Answered,
-		); err != nil {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, id)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -81,25 +141,138 @@ func (q *Queries) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]Mess
 	return items, nil
 }
 
-const getRooms = `-- name: GetRooms :many
+const closeRoom = `-- name: CloseRoom :one
+UPDATE rooms
+SET
+    closed_at = $2
+WHERE
+    id = $1
+    AND closed_at IS NULL
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+`
+
+type CloseRoomParams struct {
+	ID       uuid.UUID
+	ClosedAt *time.Time
+}
+
+func (q *Queries) CloseRoom(ctx context.Context, arg CloseRoomParams) (Room, error) {
+	row := q.db.QueryRow(ctx, closeRoom, arg.ID, arg.ClosedAt)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.MaxMessages,
+		&i.Prune,
+		&i.RequireName,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DuplicateThreshold,
+		&i.MaxQuestionsPerParticipant,
+		&i.RequireApproval,
+		&i.ClosedAt,
+		&i.ClosesAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.HostName,
+		&i.StartsAt,
+		&i.Code,
+	)
+	return i, err
+}
+
+const countMessageFlags = `-- name: CountMessageFlags :one
+SELECT COUNT(*) FROM message_flags
+WHERE
+    message_id = $1
+`
+
+func (q *Queries) CountMessageFlags(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countMessageFlags, messageID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRoomMessages = `-- name: CountRoomMessages :one
 SELECT
-    "id", "theme"
-FROM rooms
+    COUNT(*)
+FROM messages
+WHERE
+    room_id = $1
+    AND deleted_at IS NULL
 `
 
-func (q *Queries) GetRooms(ctx context.Context) ([]Room, error) {
-	rows, err := q.db.Query(ctx, getRooms)
+func (q *Queries) CountRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countRoomMessages, roomID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRoomMessagesWithoutConsent = `-- name: CountRoomMessagesWithoutConsent :one
+SELECT
+    COUNT(*)
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = false
+    AND consent_to_publish = false
+    AND (deleted_at IS NULL OR $2::boolean)
+`
+
+type CountRoomMessagesWithoutConsentParams struct {
+	RoomID         uuid.UUID
+	IncludeDeleted bool
+}
+
+func (q *Queries) CountRoomMessagesWithoutConsent(ctx context.Context, arg CountRoomMessagesWithoutConsentParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countRoomMessagesWithoutConsent, arg.RoomID, arg.IncludeDeleted)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const decrementReactionCounts = `-- name: DecrementReactionCounts :exec
+UPDATE messages
+SET
+    reaction_count = GREATEST(reaction_count - 1, 0),
+    version = version + 1
+WHERE
+    id = ANY($1::uuid[])
+`
+
+func (q *Queries) DecrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.db.Exec(ctx, decrementReactionCounts, ids)
+	return err
+}
+
+const deleteClientReactions = `-- name: DeleteClientReactions :many
+DELETE FROM message_reactions
+WHERE
+    client_id = $1
+    AND message_id = ANY($2::uuid[])
+RETURNING "message_id"
+`
+
+type DeleteClientReactionsParams struct {
+	ClientID   string
+	MessageIds []uuid.UUID
+}
+
+func (q *Queries) DeleteClientReactions(ctx context.Context, arg DeleteClientReactionsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, deleteClientReactions, arg.ClientID, arg.MessageIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Room
+	var items []uuid.UUID
 	for rows.Next() {
-		var i Room
-		if err := rows.Scan(&i.ID, &i.Theme); err != nil {
+		var message_id uuid.UUID
+		if err := rows.Scan(&message_id); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, message_id)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -107,80 +280,2233 @@ func (q *Queries) GetRooms(ctx context.Context) ([]Room, error) {
 	return items, nil
 }
 
-const insertMessage = `-- name: InsertMessage :one
-INSERT INTO messages
-    ( "room_id", "message" ) VALUES
-    ( $1, $2 )
-RETURNING "id"
+const deleteEmojiReaction = `-- name: DeleteEmojiReaction :execrows
+DELETE FROM message_emoji_reactions
+WHERE
+    message_id = $1
+    AND client_id = $2
+    AND kind = $3
 `
 
-type InsertMessageParams struct {
-	RoomID  uuid.UUID
-	Message string
+type DeleteEmojiReactionParams struct {
+	MessageID uuid.UUID
+	ClientID  string
+	Kind      string
 }
 
-func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, insertMessage, arg.RoomID, arg.Message)
-	var id uuid.UUID
-	err := row.Scan(&id)
-	return id, err
+func (q *Queries) DeleteEmojiReaction(ctx context.Context, arg DeleteEmojiReactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteEmojiReaction, arg.MessageID, arg.ClientID, arg.Kind)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const insertRoom = `-- name: InsertRoom :one
-INSERT INTO rooms
-    ( "theme" ) VALUES
-    ( $1 )
+const deleteOldestPrunableMessage = `-- name: DeleteOldestPrunableMessage :one
+DELETE FROM messages
+WHERE id = (
+    SELECT p.id FROM messages p
+    WHERE
+        p.room_id = $1
+        AND p.answered = false
+        AND p.reaction_count = 0
+        AND p.pending = false
+        AND p.deleted_at IS NULL
+    ORDER BY p.created_at ASC, p.id ASC
+    LIMIT 1
+)
 RETURNING "id"
 `
 
-func (q *Queries) InsertRoom(ctx context.Context, theme string) (uuid.UUID, error) {
-	row := q.db.QueryRow(ctx, insertRoom, theme)
+func (q *Queries) DeleteOldestPrunableMessage(ctx context.Context, roomID uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, deleteOldestPrunableMessage, roomID)
 	var id uuid.UUID
 	err := row.Scan(&id)
 	return id, err
 }
 
-const markMessageAsAnswered = `-- name: MarkMessageAsAnswered :exec
-UPDATE messages
-SET
-    answered = true
+const deleteRoom = `-- name: DeleteRoom :exec
+DELETE FROM rooms
 WHERE
     id = $1
 `
 
-func (q *Queries) MarkMessageAsAnswered(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.Exec(ctx, markMessageAsAnswered, id)
+func (q *Queries) DeleteRoom(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRoom, id)
 	return err
 }
 
-const reactToMessage = `-- name: ReactToMessage :one
-UPDATE messages
-SET
-    reaction_count = reaction_count + 1
+const deleteRoomMessages = `-- name: DeleteRoomMessages :exec
+DELETE FROM messages
+WHERE
+    room_id = $1
+`
+
+func (q *Queries) DeleteRoomMessages(ctx context.Context, roomID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteRoomMessages, roomID)
+	return err
+}
+
+const deleteRoomWebhook = `-- name: DeleteRoomWebhook :execrows
+DELETE FROM webhooks
 WHERE
     id = $1
-RETURNING reaction_count
+    AND room_id = $2
 `
 
-func (q *Queries) ReactToMessage(ctx context.Context, id uuid.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, reactToMessage, id)
-	var reaction_count int64
-	err := row.Scan(&reaction_count)
-	return reaction_count, err
+type DeleteRoomWebhookParams struct {
+	ID     uuid.UUID
+	RoomID uuid.UUID
 }
 
-const removeReactionFromMessage = `-- name: RemoveReactionFromMessage :one
-UPDATE messages
-SET
-    reaction_count = reaction_count - 1
+func (q *Queries) DeleteRoomWebhook(ctx context.Context, arg DeleteRoomWebhookParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRoomWebhook, arg.ID, arg.RoomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findSimilarUnansweredMessages = `-- name: FindSimilarUnansweredMessages :many
+SELECT
+    "id", "message", "reaction_count", similarity("message", $1)::real AS similarity
+FROM messages
+WHERE
+    room_id = $2
+    AND pending = false
+    AND answered = false
+    AND deleted_at IS NULL
+    AND similarity("message", $1) >= $3::real
+ORDER BY similarity DESC, created_at ASC
+LIMIT $4::integer
+`
+
+type FindSimilarUnansweredMessagesParams struct {
+	Message    string
+	RoomID     uuid.UUID
+	Threshold  float32
+	MaxResults int32
+}
+
+type FindSimilarUnansweredMessagesRow struct {
+	ID            uuid.UUID
+	Message       string
+	ReactionCount int64
+	Similarity    float32
+}
+
+func (q *Queries) FindSimilarUnansweredMessages(ctx context.Context, arg FindSimilarUnansweredMessagesParams) ([]FindSimilarUnansweredMessagesRow, error) {
+	rows, err := q.db.Query(ctx, findSimilarUnansweredMessages,
+		arg.Message,
+		arg.RoomID,
+		arg.Threshold,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindSimilarUnansweredMessagesRow
+	for rows.Next() {
+		var i FindSimilarUnansweredMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAnnouncement = `-- name: GetAnnouncement :one
+SELECT
+    "id", "room_id", "author", "body", "created_at"
+FROM announcements
 WHERE
     id = $1
-RETURNING reaction_count
 `
 
-func (q *Queries) RemoveReactionFromMessage(ctx context.Context, id uuid.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, removeReactionFromMessage, id)
-	var reaction_count int64
-	err := row.Scan(&reaction_count)
-	return reaction_count, err
+func (q *Queries) GetAnnouncement(ctx context.Context, id uuid.UUID) (Announcement, error) {
+	row := q.db.QueryRow(ctx, getAnnouncement, id)
+	var i Announcement
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Author,
+		&i.Body,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDeletedRoomIDs = `-- name: GetDeletedRoomIDs :many
+SELECT
+    "id"
+FROM rooms
+WHERE
+    deleted_at <= $1
+`
+
+func (q *Queries) GetDeletedRoomIDs(ctx context.Context, deletedAt *time.Time) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getDeletedRoomIDs, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEmojiReactionCounts = `-- name: GetEmojiReactionCounts :many
+SELECT
+    "message_id", "kind", COUNT(*) AS count
+FROM message_emoji_reactions
+WHERE
+    message_id = ANY($1::uuid[])
+GROUP BY message_id, kind
+ORDER BY message_id, kind
+`
+
+type GetEmojiReactionCountsRow struct {
+	MessageID uuid.UUID
+	Kind      string
+	Count     int64
+}
+
+func (q *Queries) GetEmojiReactionCounts(ctx context.Context, ids []uuid.UUID) ([]GetEmojiReactionCountsRow, error) {
+	rows, err := q.db.Query(ctx, getEmojiReactionCounts, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEmojiReactionCountsRow
+	for rows.Next() {
+		var i GetEmojiReactionCountsRow
+		if err := rows.Scan(&i.MessageID, &i.Kind, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredRoomIDs = `-- name: GetExpiredRoomIDs :many
+SELECT
+    "id"
+FROM rooms
+WHERE
+    expires_at <= $1
+`
+
+func (q *Queries) GetExpiredRoomIDs(ctx context.Context, expiresAt *time.Time) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getExpiredRoomIDs, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error) {
+	row := q.db.QueryRow(ctx, getMessage, id)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.AuthorID,
+		&i.CreatedAt,
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Pending,
+	)
+	return i, err
+}
+
+const getMessageEdits = `-- name: GetMessageEdits :many
+SELECT
+    "message_id", "version", "message", "edited_at"
+FROM message_edits
+WHERE
+    message_id = $1
+ORDER BY version ASC
+`
+
+func (q *Queries) GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]MessageEdit, error) {
+	rows, err := q.db.Query(ctx, getMessageEdits, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MessageEdit
+	for rows.Next() {
+		var i MessageEdit
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.Version,
+			&i.Message,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessageReplies = `-- name: GetMessageReplies :many
+SELECT
+    "id", "message_id", "author", "body", "created_at"
+FROM message_replies
+WHERE
+    message_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) GetMessageReplies(ctx context.Context, messageID uuid.UUID) ([]MessageReply, error) {
+	rows, err := q.db.Query(ctx, getMessageReplies, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MessageReply
+	for rows.Next() {
+		var i MessageReply
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.Author,
+			&i.Body,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessageReply = `-- name: GetMessageReply :one
+SELECT
+    "id", "message_id", "author", "body", "created_at"
+FROM message_replies
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetMessageReply(ctx context.Context, id uuid.UUID) (MessageReply, error) {
+	row := q.db.QueryRow(ctx, getMessageReply, id)
+	var i MessageReply
+	err := row.Scan(
+		&i.ID,
+		&i.MessageID,
+		&i.Author,
+		&i.Body,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPoll = `-- name: GetPoll :one
+SELECT
+    "id", "room_id", "question", "created_at", "closed_at"
+FROM polls
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetPoll(ctx context.Context, id uuid.UUID) (Poll, error) {
+	row := q.db.QueryRow(ctx, getPoll, id)
+	var i Poll
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Question,
+		&i.CreatedAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const getPollOptions = `-- name: GetPollOptions :many
+SELECT
+    "poll_id", "position", "label"
+FROM poll_options
+WHERE
+    poll_id = $1
+ORDER BY position ASC
+`
+
+func (q *Queries) GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]PollOption, error) {
+	rows, err := q.db.Query(ctx, getPollOptions, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PollOption
+	for rows.Next() {
+		var i PollOption
+		if err := rows.Scan(&i.PollID, &i.Position, &i.Label); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPollVoteCounts = `-- name: GetPollVoteCounts :many
+SELECT
+    "choice", COUNT(*) AS votes
+FROM poll_votes
+WHERE
+    poll_id = $1
+GROUP BY choice
+ORDER BY choice
+`
+
+type GetPollVoteCountsRow struct {
+	Choice int32
+	Votes  int64
+}
+
+func (q *Queries) GetPollVoteCounts(ctx context.Context, pollID uuid.UUID) ([]GetPollVoteCountsRow, error) {
+	rows, err := q.db.Query(ctx, getPollVoteCounts, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPollVoteCountsRow
+	for rows.Next() {
+		var i GetPollVoteCountsRow
+		if err := rows.Scan(&i.Choice, &i.Votes); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReactionCounts = `-- name: GetReactionCounts :many
+SELECT
+    "id", "reaction_count", "version"
+FROM messages
+WHERE
+    id = ANY($1::uuid[])
+ORDER BY id
+`
+
+type GetReactionCountsRow struct {
+	ID            uuid.UUID
+	ReactionCount int64
+	Version       int64
+}
+
+func (q *Queries) GetReactionCounts(ctx context.Context, ids []uuid.UUID) ([]GetReactionCountsRow, error) {
+	rows, err := q.db.Query(ctx, getReactionCounts, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReactionCountsRow
+	for rows.Next() {
+		var i GetReactionCountsRow
+		if err := rows.Scan(&i.ID, &i.ReactionCount, &i.Version); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoom = `-- name: GetRoom :one
+SELECT
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+FROM rooms
+WHERE
+    id = $1
+`
+
+func (q *Queries) GetRoom(ctx context.Context, id uuid.UUID) (Room, error) {
+	row := q.db.QueryRow(ctx, getRoom, id)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.MaxMessages,
+		&i.Prune,
+		&i.RequireName,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DuplicateThreshold,
+		&i.MaxQuestionsPerParticipant,
+		&i.RequireApproval,
+		&i.ClosedAt,
+		&i.ClosesAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.HostName,
+		&i.StartsAt,
+		&i.Code,
+	)
+	return i, err
+}
+
+const getRoomAnnouncements = `-- name: GetRoomAnnouncements :many
+SELECT
+    "id", "room_id", "author", "body", "created_at"
+FROM announcements
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) GetRoomAnnouncements(ctx context.Context, roomID uuid.UUID) ([]Announcement, error) {
+	rows, err := q.db.Query(ctx, getRoomAnnouncements, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Announcement
+	for rows.Next() {
+		var i Announcement
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Author,
+			&i.Body,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomFlaggedMessages = `-- name: GetRoomFlaggedMessages :many
+SELECT
+    m."id",
+    m."message",
+    m."answered",
+    COUNT(*)                                            AS flag_count,
+    COUNT(*) FILTER (WHERE f.reason = 'spam')           AS spam_count,
+    COUNT(*) FILTER (WHERE f.reason = 'abuse')          AS abuse_count,
+    COUNT(*) FILTER (WHERE f.reason = 'off_topic')      AS off_topic_count,
+    COUNT(*) FILTER (WHERE f.reason IS NULL)            AS unspecified_count
+FROM messages m
+JOIN message_flags f ON f.message_id = m.id
+WHERE
+    m.room_id = $1
+GROUP BY m.id
+ORDER BY flag_count DESC, m.created_at ASC
+`
+
+type GetRoomFlaggedMessagesRow struct {
+	ID               uuid.UUID
+	Message          string
+	Answered         bool
+	FlagCount        int64
+	SpamCount        int64
+	AbuseCount       int64
+	OffTopicCount    int64
+	UnspecifiedCount int64
+}
+
+func (q *Queries) GetRoomFlaggedMessages(ctx context.Context, roomID uuid.UUID) ([]GetRoomFlaggedMessagesRow, error) {
+	rows, err := q.db.Query(ctx, getRoomFlaggedMessages, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomFlaggedMessagesRow
+	for rows.Next() {
+		var i GetRoomFlaggedMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Message,
+			&i.Answered,
+			&i.FlagCount,
+			&i.SpamCount,
+			&i.AbuseCount,
+			&i.OffTopicCount,
+			&i.UnspecifiedCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomForUpdate = `-- name: GetRoomForUpdate :one
+SELECT
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+FROM rooms
+WHERE
+    id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRoomForUpdate(ctx context.Context, id uuid.UUID) (Room, error) {
+	row := q.db.QueryRow(ctx, getRoomForUpdate, id)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.MaxMessages,
+		&i.Prune,
+		&i.RequireName,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DuplicateThreshold,
+		&i.MaxQuestionsPerParticipant,
+		&i.RequireApproval,
+		&i.ClosedAt,
+		&i.ClosesAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.HostName,
+		&i.StartsAt,
+		&i.Code,
+	)
+	return i, err
+}
+
+const getRoomIDByCode = `-- name: GetRoomIDByCode :one
+SELECT
+    "id"
+FROM rooms
+WHERE
+    code = $1
+`
+
+func (q *Queries) GetRoomIDByCode(ctx context.Context, code string) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, getRoomIDByCode, code)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getRoomMessageIDs = `-- name: GetRoomMessageIDs :many
+SELECT
+    "id"
+FROM messages
+WHERE
+    room_id = $1
+    AND id = ANY($2::uuid[])
+    AND pending = false
+    AND deleted_at IS NULL
+`
+
+type GetRoomMessageIDsParams struct {
+	RoomID uuid.UUID
+	Ids    []uuid.UUID
+}
+
+func (q *Queries) GetRoomMessageIDs(ctx context.Context, arg GetRoomMessageIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getRoomMessageIDs, arg.RoomID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMessages = `-- name: GetRoomMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = $1
+`
+
+func (q *Queries) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomMessages, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.AuthorID,
+			&i.CreatedAt,
+			&i.ConsentToPublish,
+			&i.AuthorName,
+			&i.Language,
+			&i.LanguageConfidence,
+			&i.Answer,
+			&i.Version,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Pending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMessagesCreatedAfter = `-- name: GetRoomMessagesCreatedAfter :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = false
+    AND deleted_at IS NULL
+    AND (created_at, id) > (
+        SELECT a.created_at, a.id FROM messages a WHERE a.id = $2
+    )
+ORDER BY created_at ASC, id ASC
+`
+
+type GetRoomMessagesCreatedAfterParams struct {
+	RoomID  uuid.UUID
+	AfterID uuid.UUID
+}
+
+func (q *Queries) GetRoomMessagesCreatedAfter(ctx context.Context, arg GetRoomMessagesCreatedAfterParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomMessagesCreatedAfter, arg.RoomID, arg.AfterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.AuthorID,
+			&i.CreatedAt,
+			&i.ConsentToPublish,
+			&i.AuthorName,
+			&i.Language,
+			&i.LanguageConfidence,
+			&i.Answer,
+			&i.Version,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Pending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMessagesPage = `-- name: GetRoomMessagesPage :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = false
+    AND (created_at, id) > ($2::timestamptz, $3::uuid)
+    AND (deleted_at IS NULL OR $4::boolean)
+    AND ($5::text IS NULL OR language = $5)
+ORDER BY created_at ASC, id ASC
+LIMIT $6
+`
+
+type GetRoomMessagesPageParams struct {
+	RoomID         uuid.UUID
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	IncludeDeleted bool
+	Language       *string
+	MaxResults     int32
+}
+
+func (q *Queries) GetRoomMessagesPage(ctx context.Context, arg GetRoomMessagesPageParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomMessagesPage,
+		arg.RoomID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.IncludeDeleted,
+		arg.Language,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.AuthorID,
+			&i.CreatedAt,
+			&i.ConsentToPublish,
+			&i.AuthorName,
+			&i.Language,
+			&i.LanguageConfidence,
+			&i.Answer,
+			&i.Version,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Pending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomModerationAudit = `-- name: GetRoomModerationAudit :many
+SELECT
+    "id", "room_id", "message_id", "actor", "action", "previous_value", "created_at"
+FROM moderation_audit
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) GetRoomModerationAudit(ctx context.Context, roomID uuid.UUID) ([]ModerationAudit, error) {
+	rows, err := q.db.Query(ctx, getRoomModerationAudit, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationAudit
+	for rows.Next() {
+		var i ModerationAudit
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.MessageID,
+			&i.Actor,
+			&i.Action,
+			&i.PreviousValue,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomPendingMessages = `-- name: GetRoomPendingMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = true
+    AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
+`
+
+func (q *Queries) GetRoomPendingMessages(ctx context.Context, roomID uuid.UUID) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getRoomPendingMessages, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.AuthorID,
+			&i.CreatedAt,
+			&i.ConsentToPublish,
+			&i.AuthorName,
+			&i.Language,
+			&i.LanguageConfidence,
+			&i.Answer,
+			&i.Version,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Pending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomPollOptions = `-- name: GetRoomPollOptions :many
+SELECT
+    o."poll_id", o."position", o."label", COUNT(v.client_id) AS votes
+FROM poll_options o
+JOIN polls p ON p.id = o.poll_id
+LEFT JOIN poll_votes v ON v.poll_id = o.poll_id AND v.choice = o.position
+WHERE
+    p.room_id = $1
+GROUP BY o.poll_id, o.position, o.label
+ORDER BY o.poll_id, o.position
+`
+
+type GetRoomPollOptionsRow struct {
+	PollID   uuid.UUID
+	Position int32
+	Label    string
+	Votes    int64
+}
+
+func (q *Queries) GetRoomPollOptions(ctx context.Context, roomID uuid.UUID) ([]GetRoomPollOptionsRow, error) {
+	rows, err := q.db.Query(ctx, getRoomPollOptions, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomPollOptionsRow
+	for rows.Next() {
+		var i GetRoomPollOptionsRow
+		if err := rows.Scan(
+			&i.PollID,
+			&i.Position,
+			&i.Label,
+			&i.Votes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomPolls = `-- name: GetRoomPolls :many
+SELECT
+    "id", "room_id", "question", "created_at", "closed_at"
+FROM polls
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) GetRoomPolls(ctx context.Context, roomID uuid.UUID) ([]Poll, error) {
+	rows, err := q.db.Query(ctx, getRoomPolls, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Poll
+	for rows.Next() {
+		var i Poll
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Question,
+			&i.CreatedAt,
+			&i.ClosedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomStats = `-- name: GetRoomStats :one
+SELECT
+    COUNT(*)                                    AS total_messages,
+    COUNT(*) FILTER (WHERE answered)            AS answered_messages,
+    COUNT(*) FILTER (WHERE NOT answered)        AS unanswered_messages,
+    COALESCE(SUM(reaction_count), 0)::bigint    AS total_reactions
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = false
+    AND deleted_at IS NULL
+`
+
+type GetRoomStatsRow struct {
+	TotalMessages      int64
+	AnsweredMessages   int64
+	UnansweredMessages int64
+	TotalReactions     int64
+}
+
+func (q *Queries) GetRoomStats(ctx context.Context, roomID uuid.UUID) (GetRoomStatsRow, error) {
+	row := q.db.QueryRow(ctx, getRoomStats, roomID)
+	var i GetRoomStatsRow
+	err := row.Scan(
+		&i.TotalMessages,
+		&i.AnsweredMessages,
+		&i.UnansweredMessages,
+		&i.TotalReactions,
+	)
+	return i, err
+}
+
+const getRoomWebhooks = `-- name: GetRoomWebhooks :many
+SELECT
+    "id", "room_id", "url", "secret", "created_at", "last_delivery_at", "last_status", "last_error"
+FROM webhooks
+WHERE
+    room_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetRoomWebhooks(ctx context.Context, roomID uuid.UUID) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx, getRoomWebhooks, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Url,
+			&i.Secret,
+			&i.CreatedAt,
+			&i.LastDeliveryAt,
+			&i.LastStatus,
+			&i.LastError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRooms = `-- name: GetRooms :many
+SELECT
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+FROM rooms
+`
+
+func (q *Queries) GetRooms(ctx context.Context) ([]Room, error) {
+	rows, err := q.db.Query(ctx, getRooms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Room
+	for rows.Next() {
+		var i Room
+		if err := rows.Scan(
+			&i.ID,
+			&i.Theme,
+			&i.MaxMessages,
+			&i.Prune,
+			&i.RequireName,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.DuplicateThreshold,
+			&i.MaxQuestionsPerParticipant,
+			&i.RequireApproval,
+			&i.ClosedAt,
+			&i.ClosesAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.HostName,
+			&i.StartsAt,
+			&i.Code,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopUnansweredMessages = `-- name: GetTopUnansweredMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = false
+    AND answered = false
+    AND deleted_at IS NULL
+ORDER BY reaction_count DESC, created_at ASC
+LIMIT $2
+`
+
+type GetTopUnansweredMessagesParams struct {
+	RoomID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetTopUnansweredMessages(ctx context.Context, arg GetTopUnansweredMessagesParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, getTopUnansweredMessages, arg.RoomID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.AuthorID,
+			&i.CreatedAt,
+			&i.ConsentToPublish,
+			&i.AuthorName,
+			&i.Language,
+			&i.LanguageConfidence,
+			&i.Answer,
+			&i.Version,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Pending,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incrementParticipantQuestions = `-- name: IncrementParticipantQuestions :one
+INSERT INTO participant_questions
+    ( "room_id", "participant", "questions" ) VALUES
+    ( $1, $2, 1 )
+ON CONFLICT ("room_id", "participant") DO UPDATE
+SET
+    questions = participant_questions.questions + 1
+RETURNING "questions"
+`
+
+type IncrementParticipantQuestionsParams struct {
+	RoomID      uuid.UUID
+	Participant string
+}
+
+func (q *Queries) IncrementParticipantQuestions(ctx context.Context, arg IncrementParticipantQuestionsParams) (int32, error) {
+	row := q.db.QueryRow(ctx, incrementParticipantQuestions, arg.RoomID, arg.Participant)
+	var questions int32
+	err := row.Scan(&questions)
+	return questions, err
+}
+
+const incrementReactionCounts = `-- name: IncrementReactionCounts :exec
+UPDATE messages
+SET
+    reaction_count = reaction_count + 1,
+    version = version + 1
+WHERE
+    id = ANY($1::uuid[])
+`
+
+func (q *Queries) IncrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.db.Exec(ctx, incrementReactionCounts, ids)
+	return err
+}
+
+const insertAnnouncement = `-- name: InsertAnnouncement :exec
+INSERT INTO announcements
+    ( "id", "room_id", "author", "body", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 )
+`
+
+type InsertAnnouncementParams struct {
+	ID        uuid.UUID
+	RoomID    uuid.UUID
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) InsertAnnouncement(ctx context.Context, arg InsertAnnouncementParams) error {
+	_, err := q.db.Exec(ctx, insertAnnouncement,
+		arg.ID,
+		arg.RoomID,
+		arg.Author,
+		arg.Body,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const insertClientReactions = `-- name: InsertClientReactions :many
+INSERT INTO message_reactions
+    ( "message_id", "client_id" )
+SELECT unnest($1::uuid[]), $2
+ON CONFLICT DO NOTHING
+RETURNING "message_id"
+`
+
+type InsertClientReactionsParams struct {
+	MessageIds []uuid.UUID
+	ClientID   string
+}
+
+func (q *Queries) InsertClientReactions(ctx context.Context, arg InsertClientReactionsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, insertClientReactions, arg.MessageIds, arg.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var message_id uuid.UUID
+		if err := rows.Scan(&message_id); err != nil {
+			return nil, err
+		}
+		items = append(items, message_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertEmojiReaction = `-- name: InsertEmojiReaction :execrows
+INSERT INTO message_emoji_reactions
+    ( "message_id", "client_id", "kind", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING
+`
+
+type InsertEmojiReactionParams struct {
+	MessageID uuid.UUID
+	ClientID  string
+	Kind      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) InsertEmojiReaction(ctx context.Context, arg InsertEmojiReactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertEmojiReaction,
+		arg.MessageID,
+		arg.ClientID,
+		arg.Kind,
+		arg.CreatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertMessage = `-- name: InsertMessage :one
+INSERT INTO messages
+    ( "id", "room_id", "message", "author_id", "consent_to_publish", "author_name", "language", "language_confidence", "created_at", "pending" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10 )
+RETURNING "id"
+`
+
+type InsertMessageParams struct {
+	ID                 uuid.UUID
+	RoomID             uuid.UUID
+	Message            string
+	AuthorID           string
+	ConsentToPublish   bool
+	AuthorName         *string
+	Language           string
+	LanguageConfidence float32
+	CreatedAt          time.Time
+	Pending            bool
+}
+
+func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, insertMessage,
+		arg.ID,
+		arg.RoomID,
+		arg.Message,
+		arg.AuthorID,
+		arg.ConsentToPublish,
+		arg.AuthorName,
+		arg.Language,
+		arg.LanguageConfidence,
+		arg.CreatedAt,
+		arg.Pending,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertMessageEdit = `-- name: InsertMessageEdit :exec
+INSERT INTO message_edits
+    ( "message_id", "version", "message", "edited_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING
+`
+
+type InsertMessageEditParams struct {
+	MessageID uuid.UUID
+	Version   int64
+	Message   string
+	EditedAt  time.Time
+}
+
+func (q *Queries) InsertMessageEdit(ctx context.Context, arg InsertMessageEditParams) error {
+	_, err := q.db.Exec(ctx, insertMessageEdit,
+		arg.MessageID,
+		arg.Version,
+		arg.Message,
+		arg.EditedAt,
+	)
+	return err
+}
+
+const insertMessageFlag = `-- name: InsertMessageFlag :execrows
+INSERT INTO message_flags
+    ( "message_id", "client_id", "reason", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING
+`
+
+type InsertMessageFlagParams struct {
+	MessageID uuid.UUID
+	ClientID  string
+	Reason    *string
+	CreatedAt time.Time
+}
+
+func (q *Queries) InsertMessageFlag(ctx context.Context, arg InsertMessageFlagParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertMessageFlag,
+		arg.MessageID,
+		arg.ClientID,
+		arg.Reason,
+		arg.CreatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertMessageReply = `-- name: InsertMessageReply :exec
+INSERT INTO message_replies
+    ( "id", "message_id", "author", "body", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 )
+`
+
+type InsertMessageReplyParams struct {
+	ID        uuid.UUID
+	MessageID uuid.UUID
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) InsertMessageReply(ctx context.Context, arg InsertMessageReplyParams) error {
+	_, err := q.db.Exec(ctx, insertMessageReply,
+		arg.ID,
+		arg.MessageID,
+		arg.Author,
+		arg.Body,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const insertModerationAudit = `-- name: InsertModerationAudit :exec
+INSERT INTO moderation_audit
+    ( "id", "room_id", "message_id", "actor", "action", "previous_value", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7 )
+`
+
+type InsertModerationAuditParams struct {
+	ID            uuid.UUID
+	RoomID        uuid.UUID
+	MessageID     uuid.UUID
+	Actor         string
+	Action        string
+	PreviousValue *string
+	CreatedAt     time.Time
+}
+
+func (q *Queries) InsertModerationAudit(ctx context.Context, arg InsertModerationAuditParams) error {
+	_, err := q.db.Exec(ctx, insertModerationAudit,
+		arg.ID,
+		arg.RoomID,
+		arg.MessageID,
+		arg.Actor,
+		arg.Action,
+		arg.PreviousValue,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const insertPoll = `-- name: InsertPoll :exec
+INSERT INTO polls
+    ( "id", "room_id", "question", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )
+`
+
+type InsertPollParams struct {
+	ID        uuid.UUID
+	RoomID    uuid.UUID
+	Question  string
+	CreatedAt time.Time
+}
+
+func (q *Queries) InsertPoll(ctx context.Context, arg InsertPollParams) error {
+	_, err := q.db.Exec(ctx, insertPoll,
+		arg.ID,
+		arg.RoomID,
+		arg.Question,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const insertPollOption = `-- name: InsertPollOption :exec
+INSERT INTO poll_options
+    ( "poll_id", "position", "label" ) VALUES
+    ( $1, $2, $3 )
+`
+
+type InsertPollOptionParams struct {
+	PollID   uuid.UUID
+	Position int32
+	Label    string
+}
+
+func (q *Queries) InsertPollOption(ctx context.Context, arg InsertPollOptionParams) error {
+	_, err := q.db.Exec(ctx, insertPollOption, arg.PollID, arg.Position, arg.Label)
+	return err
+}
+
+const insertRoom = `-- name: InsertRoom :one
+INSERT INTO rooms
+    ( "id", "theme", "max_messages", "prune", "require_name", "expires_at", "created_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closes_at", "description", "host_name", "starts_at", "code" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15 )
+RETURNING "id"
+`
+
+type InsertRoomParams struct {
+	ID                         uuid.UUID
+	Theme                      string
+	MaxMessages                int32
+	Prune                      bool
+	RequireName                bool
+	ExpiresAt                  *time.Time
+	CreatedAt                  time.Time
+	DuplicateThreshold         float32
+	MaxQuestionsPerParticipant int32
+	RequireApproval            bool
+	ClosesAt                   *time.Time
+	Description                string
+	HostName                   string
+	StartsAt                   *time.Time
+	Code                       string
+}
+
+func (q *Queries) InsertRoom(ctx context.Context, arg InsertRoomParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, insertRoom,
+		arg.ID,
+		arg.Theme,
+		arg.MaxMessages,
+		arg.Prune,
+		arg.RequireName,
+		arg.ExpiresAt,
+		arg.CreatedAt,
+		arg.DuplicateThreshold,
+		arg.MaxQuestionsPerParticipant,
+		arg.RequireApproval,
+		arg.ClosesAt,
+		arg.Description,
+		arg.HostName,
+		arg.StartsAt,
+		arg.Code,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const insertWebhook = `-- name: InsertWebhook :exec
+INSERT INTO webhooks
+    ( "id", "room_id", "url", "secret", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 )
+`
+
+type InsertWebhookParams struct {
+	ID        uuid.UUID
+	RoomID    uuid.UUID
+	Url       string
+	Secret    string
+	CreatedAt time.Time
+}
+
+func (q *Queries) InsertWebhook(ctx context.Context, arg InsertWebhookParams) error {
+	_, err := q.db.Exec(ctx, insertWebhook,
+		arg.ID,
+		arg.RoomID,
+		arg.Url,
+		arg.Secret,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const insertWebhookDeliveryFailure = `-- name: InsertWebhookDeliveryFailure :exec
+INSERT INTO webhook_delivery_failures
+    ( "webhook_id", "event_kind", "attempts", "error", "failed_at" ) VALUES
+    ( $1, $2, $3, $4, $5 )
+`
+
+type InsertWebhookDeliveryFailureParams struct {
+	WebhookID uuid.UUID
+	EventKind string
+	Attempts  int32
+	Error     string
+	FailedAt  time.Time
+}
+
+func (q *Queries) InsertWebhookDeliveryFailure(ctx context.Context, arg InsertWebhookDeliveryFailureParams) error {
+	_, err := q.db.Exec(ctx, insertWebhookDeliveryFailure,
+		arg.WebhookID,
+		arg.EventKind,
+		arg.Attempts,
+		arg.Error,
+		arg.FailedAt,
+	)
+	return err
+}
+
+const listRooms = `-- name: ListRooms :many
+SELECT
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+FROM rooms
+WHERE
+    strpos(lower(theme), lower($1::text)) > 0
+    AND ($2::boolean OR expires_at IS NULL OR expires_at > $3::timestamptz)
+    AND deleted_at IS NULL
+ORDER BY
+    CASE WHEN $4::boolean THEN created_at END DESC,
+    created_at ASC,
+    id ASC
+LIMIT NULLIF($5::integer, 0)
+`
+
+type ListRoomsParams struct {
+	ThemeQuery     string
+	IncludeExpired bool
+	Now            time.Time
+	NewestFirst    bool
+	MaxResults     int32
+}
+
+func (q *Queries) ListRooms(ctx context.Context, arg ListRoomsParams) ([]Room, error) {
+	rows, err := q.db.Query(ctx, listRooms,
+		arg.ThemeQuery,
+		arg.IncludeExpired,
+		arg.Now,
+		arg.NewestFirst,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Room
+	for rows.Next() {
+		var i Room
+		if err := rows.Scan(
+			&i.ID,
+			&i.Theme,
+			&i.MaxMessages,
+			&i.Prune,
+			&i.RequireName,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.DuplicateThreshold,
+			&i.MaxQuestionsPerParticipant,
+			&i.RequireApproval,
+			&i.ClosedAt,
+			&i.ClosesAt,
+			&i.DeletedAt,
+			&i.Description,
+			&i.HostName,
+			&i.StartsAt,
+			&i.Code,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markMessageAsAnswered = `-- name: MarkMessageAsAnswered :one
+UPDATE messages
+SET
+    answered = true,
+    answer = COALESCE($1, answer),
+    version = version + 1
+WHERE
+    id = $2
+    AND deleted_at IS NULL
+    AND ($3::bigint IS NULL OR version = $3)
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+`
+
+type MarkMessageAsAnsweredParams struct {
+	Answer          *string
+	ID              uuid.UUID
+	ExpectedVersion *int64
+}
+
+func (q *Queries) MarkMessageAsAnswered(ctx context.Context, arg MarkMessageAsAnsweredParams) (Message, error) {
+	row := q.db.QueryRow(ctx, markMessageAsAnswered, arg.Answer, arg.ID, arg.ExpectedVersion)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.AuthorID,
+		&i.CreatedAt,
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Pending,
+	)
+	return i, err
+}
+
+const reactToMessage = `-- name: ReactToMessage :one
+UPDATE messages
+SET
+    reaction_count = reaction_count + 1,
+    version = version + 1
+WHERE
+    id = $1
+RETURNING reaction_count
+`
+
+func (q *Queries) ReactToMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, reactToMessage, id)
+	var reaction_count int64
+	err := row.Scan(&reaction_count)
+	return reaction_count, err
+}
+
+const recordWebhookDelivery = `-- name: RecordWebhookDelivery :exec
+UPDATE webhooks
+SET
+    last_delivery_at = $1,
+    last_status = $2,
+    last_error = $3
+WHERE
+    id = $4
+`
+
+type RecordWebhookDeliveryParams struct {
+	LastDeliveryAt *time.Time
+	LastStatus     *int32
+	LastError      *string
+	ID             uuid.UUID
+}
+
+func (q *Queries) RecordWebhookDelivery(ctx context.Context, arg RecordWebhookDeliveryParams) error {
+	_, err := q.db.Exec(ctx, recordWebhookDelivery,
+		arg.LastDeliveryAt,
+		arg.LastStatus,
+		arg.LastError,
+		arg.ID,
+	)
+	return err
+}
+
+const removeReactionFromMessage = `-- name: RemoveReactionFromMessage :one
+UPDATE messages
+SET
+    reaction_count = GREATEST(reaction_count - 1, 0),
+    version = version + 1
+WHERE
+    id = $1
+RETURNING reaction_count
+`
+
+func (q *Queries) RemoveReactionFromMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, removeReactionFromMessage, id)
+	var reaction_count int64
+	err := row.Scan(&reaction_count)
+	return reaction_count, err
+}
+
+const restoreMessage = `-- name: RestoreMessage :one
+UPDATE messages
+SET
+    deleted_at = NULL,
+    deleted_by = NULL,
+    version = version + 1
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+`
+
+func (q *Queries) RestoreMessage(ctx context.Context, id uuid.UUID) (Message, error) {
+	row := q.db.QueryRow(ctx, restoreMessage, id)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.AuthorID,
+		&i.CreatedAt,
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Pending,
+	)
+	return i, err
+}
+
+const restoreRoom = `-- name: RestoreRoom :one
+UPDATE rooms
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at > $2
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+`
+
+type RestoreRoomParams struct {
+	ID           uuid.UUID
+	DeletedAfter *time.Time
+}
+
+func (q *Queries) RestoreRoom(ctx context.Context, arg RestoreRoomParams) (Room, error) {
+	row := q.db.QueryRow(ctx, restoreRoom, arg.ID, arg.DeletedAfter)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.MaxMessages,
+		&i.Prune,
+		&i.RequireName,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DuplicateThreshold,
+		&i.MaxQuestionsPerParticipant,
+		&i.RequireApproval,
+		&i.ClosedAt,
+		&i.ClosesAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.HostName,
+		&i.StartsAt,
+		&i.Code,
+	)
+	return i, err
+}
+
+const searchRoomMessages = `-- name: SearchRoomMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending",
+    ts_rank(to_tsvector('simple', "message"), websearch_to_tsquery('simple', $1)) AS rank
+FROM messages
+WHERE
+    room_id = $2
+    AND pending = false
+    AND to_tsvector('simple', "message") @@ websearch_to_tsquery('simple', $1)
+    AND (deleted_at IS NULL OR $3::boolean)
+ORDER BY rank DESC, created_at ASC
+LIMIT $4 OFFSET $5
+`
+
+type SearchRoomMessagesParams struct {
+	Query          string
+	RoomID         uuid.UUID
+	IncludeDeleted bool
+	MaxResults     int32
+	SkipResults    int32
+}
+
+type SearchRoomMessagesRow struct {
+	ID                 uuid.UUID
+	RoomID             uuid.UUID
+	Message            string
+	ReactionCount      int64
+	Answered           bool
+	AuthorID           string
+	CreatedAt          time.Time
+	ConsentToPublish   bool
+	AuthorName         *string
+	Language           string
+	LanguageConfidence float32
+	Answer             *string
+	Version            int64
+	DeletedAt          *time.Time
+	DeletedBy          *string
+	Pending            bool
+	Rank               float32
+}
+
+func (q *Queries) SearchRoomMessages(ctx context.Context, arg SearchRoomMessagesParams) ([]SearchRoomMessagesRow, error) {
+	rows, err := q.db.Query(ctx, searchRoomMessages,
+		arg.Query,
+		arg.RoomID,
+		arg.IncludeDeleted,
+		arg.MaxResults,
+		arg.SkipResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchRoomMessagesRow
+	for rows.Next() {
+		var i SearchRoomMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.ReactionCount,
+			&i.Answered,
+			&i.AuthorID,
+			&i.CreatedAt,
+			&i.ConsentToPublish,
+			&i.AuthorName,
+			&i.Language,
+			&i.LanguageConfidence,
+			&i.Answer,
+			&i.Version,
+			&i.DeletedAt,
+			&i.DeletedBy,
+			&i.Pending,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteMessage = `-- name: SoftDeleteMessage :one
+UPDATE messages
+SET
+    deleted_at = $1,
+    deleted_by = $2,
+    version = version + 1
+WHERE
+    id = $3
+    AND deleted_at IS NULL
+    AND ($4::bigint IS NULL OR version = $4)
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+`
+
+type SoftDeleteMessageParams struct {
+	DeletedAt       *time.Time
+	DeletedBy       *string
+	ID              uuid.UUID
+	ExpectedVersion *int64
+}
+
+func (q *Queries) SoftDeleteMessage(ctx context.Context, arg SoftDeleteMessageParams) (Message, error) {
+	row := q.db.QueryRow(ctx, softDeleteMessage,
+		arg.DeletedAt,
+		arg.DeletedBy,
+		arg.ID,
+		arg.ExpectedVersion,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.AuthorID,
+		&i.CreatedAt,
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Pending,
+	)
+	return i, err
+}
+
+const softDeleteRoom = `-- name: SoftDeleteRoom :one
+UPDATE rooms
+SET
+    deleted_at = $2
+WHERE
+    id = $1
+    AND deleted_at IS NULL
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+`
+
+type SoftDeleteRoomParams struct {
+	ID        uuid.UUID
+	DeletedAt *time.Time
+}
+
+func (q *Queries) SoftDeleteRoom(ctx context.Context, arg SoftDeleteRoomParams) (Room, error) {
+	row := q.db.QueryRow(ctx, softDeleteRoom, arg.ID, arg.DeletedAt)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.MaxMessages,
+		&i.Prune,
+		&i.RequireName,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DuplicateThreshold,
+		&i.MaxQuestionsPerParticipant,
+		&i.RequireApproval,
+		&i.ClosedAt,
+		&i.ClosesAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.HostName,
+		&i.StartsAt,
+		&i.Code,
+	)
+	return i, err
+}
+
+const updateMessage = `-- name: UpdateMessage :one
+UPDATE messages
+SET
+    message = $1,
+    version = version + 1
+WHERE
+    id = $2
+    AND author_id = $3
+    AND answered = false
+    AND deleted_at IS NULL
+    AND created_at > $4::timestamptz
+    AND ($5::bigint IS NULL OR version = $5)
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+`
+
+type UpdateMessageParams struct {
+	Message         string
+	ID              uuid.UUID
+	AuthorID        string
+	EditWindowStart time.Time
+	ExpectedVersion *int64
+}
+
+func (q *Queries) UpdateMessage(ctx context.Context, arg UpdateMessageParams) (Message, error) {
+	row := q.db.QueryRow(ctx, updateMessage,
+		arg.Message,
+		arg.ID,
+		arg.AuthorID,
+		arg.EditWindowStart,
+		arg.ExpectedVersion,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.AuthorID,
+		&i.CreatedAt,
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Pending,
+	)
+	return i, err
+}
+
+const updateMessageConsent = `-- name: UpdateMessageConsent :one
+UPDATE messages
+SET
+    consent_to_publish = $1,
+    version = version + 1
+WHERE
+    id = $2
+    AND author_id = $3
+RETURNING consent_to_publish
+`
+
+type UpdateMessageConsentParams struct {
+	ConsentToPublish bool
+	ID               uuid.UUID
+	AuthorID         string
+}
+
+func (q *Queries) UpdateMessageConsent(ctx context.Context, arg UpdateMessageConsentParams) (bool, error) {
+	row := q.db.QueryRow(ctx, updateMessageConsent, arg.ConsentToPublish, arg.ID, arg.AuthorID)
+	var consent_to_publish bool
+	err := row.Scan(&consent_to_publish)
+	return consent_to_publish, err
+}
+
+const updateMessageLanguage = `-- name: UpdateMessageLanguage :one
+UPDATE messages
+SET
+    language = $1,
+    language_confidence = $2,
+    version = version + 1
+WHERE
+    id = $3
+    AND deleted_at IS NULL
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+`
+
+type UpdateMessageLanguageParams struct {
+	Language           string
+	LanguageConfidence float32
+	ID                 uuid.UUID
+}
+
+func (q *Queries) UpdateMessageLanguage(ctx context.Context, arg UpdateMessageLanguageParams) (Message, error) {
+	row := q.db.QueryRow(ctx, updateMessageLanguage, arg.Language, arg.LanguageConfidence, arg.ID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.AuthorID,
+		&i.CreatedAt,
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		&i.DeletedAt,
+		&i.DeletedBy,
+		&i.Pending,
+	)
+	return i, err
+}
+
+const updateRoom = `-- name: UpdateRoom :one
+UPDATE rooms
+SET
+    theme = $2,
+    max_messages = $3,
+    prune = $4,
+    require_name = $5,
+    expires_at = $6,
+    duplicate_threshold = $7,
+    max_questions_per_participant = $8,
+    require_approval = $9,
+    closes_at = $10,
+    description = $11,
+    host_name = $12,
+    starts_at = $13
+WHERE
+    id = $1
+    AND deleted_at IS NULL
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+`
+
+type UpdateRoomParams struct {
+	ID                         uuid.UUID
+	Theme                      string
+	MaxMessages                int32
+	Prune                      bool
+	RequireName                bool
+	ExpiresAt                  *time.Time
+	DuplicateThreshold         float32
+	MaxQuestionsPerParticipant int32
+	RequireApproval            bool
+	ClosesAt                   *time.Time
+	Description                string
+	HostName                   string
+	StartsAt                   *time.Time
+}
+
+func (q *Queries) UpdateRoom(ctx context.Context, arg UpdateRoomParams) (Room, error) {
+	row := q.db.QueryRow(ctx, updateRoom,
+		arg.ID,
+		arg.Theme,
+		arg.MaxMessages,
+		arg.Prune,
+		arg.RequireName,
+		arg.ExpiresAt,
+		arg.DuplicateThreshold,
+		arg.MaxQuestionsPerParticipant,
+		arg.RequireApproval,
+		arg.ClosesAt,
+		arg.Description,
+		arg.HostName,
+		arg.StartsAt,
+	)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.MaxMessages,
+		&i.Prune,
+		&i.RequireName,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.DuplicateThreshold,
+		&i.MaxQuestionsPerParticipant,
+		&i.RequireApproval,
+		&i.ClosedAt,
+		&i.ClosesAt,
+		&i.DeletedAt,
+		&i.Description,
+		&i.HostName,
+		&i.StartsAt,
+		&i.Code,
+	)
+	return i, err
 }
@@ -1,45 +1,121 @@
 -- name: GetRoom :one
 SELECT
-    "id", "theme"
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
 FROM rooms
 WHERE
     id = $1;
 
 -- name: GetRooms :many
 SELECT
-    "id", "theme"
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
 FROM rooms;
 
 -- name: InsertRoom :one
 INSERT INTO rooms
-    ( "theme" ) VALUES
-    ( $1 )
+    ( "id", "theme", "max_messages", "prune", "require_name", "expires_at", "created_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closes_at", "description", "host_name", "starts_at", "code" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15 )
 RETURNING "id";
 
 -- name: GetMessage :one
 SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
 FROM messages
 WHERE
     id = $1;
 
 -- name: GetRoomMessages :many
 SELECT
-    "id", "room_id", "message", "reaction_count", "answered"
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
 FROM messages
 WHERE
     room_id = $1;
 
+-- name: GetRoomMessagesCreatedAfter :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = sqlc.arg(room_id)
+    AND pending = false
+    AND deleted_at IS NULL
+    AND (created_at, id) > (
+        SELECT a.created_at, a.id FROM messages a WHERE a.id = sqlc.arg(after_id)
+    )
+ORDER BY created_at ASC, id ASC;
+
 -- name: InsertMessage :one
 INSERT INTO messages
-    ( "room_id", "message" ) VALUES
-    ( $1, $2 )
+    ( "id", "room_id", "message", "author_id", "consent_to_publish", "author_name", "language", "language_confidence", "created_at", "pending" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10 )
 RETURNING "id";
 
+-- name: UpdateMessageConsent :one
+UPDATE messages
+SET
+    consent_to_publish = sqlc.arg(consent_to_publish),
+    version = version + 1
+WHERE
+    id = sqlc.arg(id)
+    AND author_id = sqlc.arg(author_id)
+RETURNING consent_to_publish;
+
+-- name: UpdateMessageLanguage :one
+UPDATE messages
+SET
+    language = sqlc.arg(language),
+    language_confidence = sqlc.arg(language_confidence),
+    version = version + 1
+WHERE
+    id = sqlc.arg(id)
+    AND deleted_at IS NULL
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending";
+
+-- name: UpdateMessage :one
+UPDATE messages
+SET
+    message = sqlc.arg(message),
+    version = version + 1
+WHERE
+    id = sqlc.arg(id)
+    AND author_id = sqlc.arg(author_id)
+    AND answered = false
+    AND deleted_at IS NULL
+    AND created_at > sqlc.arg(edit_window_start)::timestamptz
+    AND (sqlc.narg(expected_version)::bigint IS NULL OR version = sqlc.narg(expected_version))
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending";
+
+-- name: InsertMessageEdit :exec
+INSERT INTO message_edits
+    ( "message_id", "version", "message", "edited_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING;
+
+-- name: GetMessageEdits :many
+SELECT
+    "message_id", "version", "message", "edited_at"
+FROM message_edits
+WHERE
+    message_id = $1
+ORDER BY version ASC;
+
+-- name: InsertMessageReply :exec
+INSERT INTO message_replies
+    ( "id", "message_id", "author", "body", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 );
+
+-- name: GetMessageReplies :many
+SELECT
+    "id", "message_id", "author", "body", "created_at"
+FROM message_replies
+WHERE
+    message_id = $1
+ORDER BY created_at ASC, id ASC;
+
 -- name: ReactToMessage :one
 UPDATE messages
 SET
-    reaction_count = reaction_count + 1
+    reaction_count = reaction_count + 1,
+    version = version + 1
 WHERE
     id = $1
 RETURNING reaction_count;
@@ -47,14 +123,525 @@ RETURNING reaction_count;
 -- name: RemoveReactionFromMessage :one
 UPDATE messages
 SET
-    reaction_count = reaction_count - 1
+    reaction_count = GREATEST(reaction_count - 1, 0),
+    version = version + 1
 WHERE
     id = $1
 RETURNING reaction_count;
 
--- name: MarkMessageAsAnswered :exec
+-- name: MarkMessageAsAnswered :one
 UPDATE messages
 SET
-    answered = true
+    answered = true,
+    answer = COALESCE(sqlc.narg(answer), answer),
+    version = version + 1
+WHERE
+    id = sqlc.arg(id)
+    AND deleted_at IS NULL
+    AND (sqlc.narg(expected_version)::bigint IS NULL OR version = sqlc.narg(expected_version))
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending";
+
+-- name: GetRoomStats :one
+SELECT
+    COUNT(*)                                    AS total_messages,
+    COUNT(*) FILTER (WHERE answered)            AS answered_messages,
+    COUNT(*) FILTER (WHERE NOT answered)        AS unanswered_messages,
+    COALESCE(SUM(reaction_count), 0)::bigint    AS total_reactions
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = false
+    AND deleted_at IS NULL;
+
+-- name: GetTopUnansweredMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = false
+    AND answered = false
+    AND deleted_at IS NULL
+ORDER BY reaction_count DESC, created_at ASC
+LIMIT $2;
+
+-- name: GetRoomForUpdate :one
+SELECT
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+FROM rooms
+WHERE
+    id = $1
+FOR UPDATE;
+
+-- name: CountRoomMessages :one
+SELECT
+    COUNT(*)
+FROM messages
+WHERE
+    room_id = $1
+    AND deleted_at IS NULL;
+
+-- name: DeleteOldestPrunableMessage :one
+DELETE FROM messages
+WHERE id = (
+    SELECT p.id FROM messages p
+    WHERE
+        p.room_id = $1
+        AND p.answered = false
+        AND p.reaction_count = 0
+        AND p.pending = false
+        AND p.deleted_at IS NULL
+    ORDER BY p.created_at ASC, p.id ASC
+    LIMIT 1
+)
+RETURNING "id";
+
+-- name: GetExpiredRoomIDs :many
+SELECT
+    "id"
+FROM rooms
+WHERE
+    expires_at <= $1;
+
+-- name: DeleteRoomMessages :exec
+DELETE FROM messages
+WHERE
+    room_id = $1;
+
+-- name: DeleteRoom :exec
+DELETE FROM rooms
+WHERE
+    id = $1;
+
+-- name: SearchRoomMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending",
+    ts_rank(to_tsvector('simple', "message"), websearch_to_tsquery('simple', sqlc.arg(query))) AS rank
+FROM messages
+WHERE
+    room_id = sqlc.arg(room_id)
+    AND pending = false
+    AND to_tsvector('simple', "message") @@ websearch_to_tsquery('simple', sqlc.arg(query))
+    AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::boolean)
+ORDER BY rank DESC, created_at ASC
+LIMIT sqlc.arg(max_results) OFFSET sqlc.arg(skip_results);
+
+-- name: GetRoomMessageIDs :many
+SELECT
+    "id"
+FROM messages
+WHERE
+    room_id = sqlc.arg(room_id)
+    AND id = ANY(sqlc.arg(ids)::uuid[])
+    AND pending = false
+    AND deleted_at IS NULL;
+
+-- name: InsertClientReactions :many
+INSERT INTO message_reactions
+    ( "message_id", "client_id" )
+SELECT unnest(sqlc.arg(message_ids)::uuid[]), sqlc.arg(client_id)
+ON CONFLICT DO NOTHING
+RETURNING "message_id";
+
+-- name: DeleteClientReactions :many
+DELETE FROM message_reactions
+WHERE
+    client_id = sqlc.arg(client_id)
+    AND message_id = ANY(sqlc.arg(message_ids)::uuid[])
+RETURNING "message_id";
+
+-- name: IncrementReactionCounts :exec
+UPDATE messages
+SET
+    reaction_count = reaction_count + 1,
+    version = version + 1
+WHERE
+    id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: DecrementReactionCounts :exec
+UPDATE messages
+SET
+    reaction_count = GREATEST(reaction_count - 1, 0),
+    version = version + 1
+WHERE
+    id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetReactionCounts :many
+SELECT
+    "id", "reaction_count", "version"
+FROM messages
+WHERE
+    id = ANY(sqlc.arg(ids)::uuid[])
+ORDER BY id;
+
+-- name: InsertEmojiReaction :execrows
+INSERT INTO message_emoji_reactions
+    ( "message_id", "client_id", "kind", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteEmojiReaction :execrows
+DELETE FROM message_emoji_reactions
+WHERE
+    message_id = $1
+    AND client_id = $2
+    AND kind = $3;
+
+-- name: GetEmojiReactionCounts :many
+SELECT
+    "message_id", "kind", COUNT(*) AS count
+FROM message_emoji_reactions
+WHERE
+    message_id = ANY(sqlc.arg(ids)::uuid[])
+GROUP BY message_id, kind
+ORDER BY message_id, kind;
+
+-- name: FindSimilarUnansweredMessages :many
+SELECT
+    "id", "message", "reaction_count", similarity("message", sqlc.arg(message))::real AS similarity
+FROM messages
+WHERE
+    room_id = sqlc.arg(room_id)
+    AND pending = false
+    AND answered = false
+    AND deleted_at IS NULL
+    AND similarity("message", sqlc.arg(message)) >= sqlc.arg(threshold)::real
+ORDER BY similarity DESC, created_at ASC
+LIMIT sqlc.arg(max_results)::integer;
+
+-- name: InsertWebhook :exec
+INSERT INTO webhooks
+    ( "id", "room_id", "url", "secret", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 );
+
+-- name: GetRoomWebhooks :many
+SELECT
+    "id", "room_id", "url", "secret", "created_at", "last_delivery_at", "last_status", "last_error"
+FROM webhooks
+WHERE
+    room_id = $1
+ORDER BY created_at;
+
+-- name: RecordWebhookDelivery :exec
+UPDATE webhooks
+SET
+    last_delivery_at = sqlc.arg(last_delivery_at),
+    last_status = sqlc.narg(last_status),
+    last_error = sqlc.narg(last_error)
+WHERE
+    id = sqlc.arg(id);
+
+-- name: InsertWebhookDeliveryFailure :exec
+INSERT INTO webhook_delivery_failures
+    ( "webhook_id", "event_kind", "attempts", "error", "failed_at" ) VALUES
+    ( $1, $2, $3, $4, $5 );
+
+-- name: InsertMessageFlag :execrows
+INSERT INTO message_flags
+    ( "message_id", "client_id", "reason", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING;
+
+-- name: CountMessageFlags :one
+SELECT COUNT(*) FROM message_flags
+WHERE
+    message_id = $1;
+
+-- name: GetRoomFlaggedMessages :many
+SELECT
+    m."id",
+    m."message",
+    m."answered",
+    COUNT(*)                                            AS flag_count,
+    COUNT(*) FILTER (WHERE f.reason = 'spam')           AS spam_count,
+    COUNT(*) FILTER (WHERE f.reason = 'abuse')          AS abuse_count,
+    COUNT(*) FILTER (WHERE f.reason = 'off_topic')      AS off_topic_count,
+    COUNT(*) FILTER (WHERE f.reason IS NULL)            AS unspecified_count
+FROM messages m
+JOIN message_flags f ON f.message_id = m.id
+WHERE
+    m.room_id = $1
+GROUP BY m.id
+ORDER BY flag_count DESC, m.created_at ASC;
+
+-- name: GetRoomMessagesPage :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = sqlc.arg(room_id)
+    AND pending = false
+    AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+    AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::boolean)
+    AND (sqlc.narg(language)::text IS NULL OR language = sqlc.narg(language))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(max_results);
+
+-- name: SoftDeleteMessage :one
+UPDATE messages
+SET
+    deleted_at = sqlc.arg(deleted_at),
+    deleted_by = sqlc.arg(deleted_by),
+    version = version + 1
+WHERE
+    id = sqlc.arg(id)
+    AND deleted_at IS NULL
+    AND (sqlc.narg(expected_version)::bigint IS NULL OR version = sqlc.narg(expected_version))
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending";
+
+-- name: RestoreMessage :one
+UPDATE messages
+SET
+    deleted_at = NULL,
+    deleted_by = NULL,
+    version = version + 1
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending";
+
+-- name: InsertModerationAudit :exec
+INSERT INTO moderation_audit
+    ( "id", "room_id", "message_id", "actor", "action", "previous_value", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7 );
+
+-- name: GetRoomModerationAudit :many
+SELECT
+    "id", "room_id", "message_id", "actor", "action", "previous_value", "created_at"
+FROM moderation_audit
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: ListRooms :many
+SELECT
+    "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"
+FROM rooms
+WHERE
+    strpos(lower(theme), lower(sqlc.arg(theme_query)::text)) > 0
+    AND (sqlc.arg(include_expired)::boolean OR expires_at IS NULL OR expires_at > sqlc.arg(now)::timestamptz)
+    AND deleted_at IS NULL
+ORDER BY
+    CASE WHEN sqlc.arg(newest_first)::boolean THEN created_at END DESC,
+    created_at ASC,
+    id ASC
+LIMIT NULLIF(sqlc.arg(max_results)::integer, 0);
+
+-- name: IncrementParticipantQuestions :one
+INSERT INTO participant_questions
+    ( "room_id", "participant", "questions" ) VALUES
+    ( $1, $2, 1 )
+ON CONFLICT ("room_id", "participant") DO UPDATE
+SET
+    questions = participant_questions.questions + 1
+RETURNING "questions";
+
+-- name: GetRoomPendingMessages :many
+SELECT
+    "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = true
+    AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC;
+
+-- name: ApproveMessage :one
+UPDATE messages
+SET
+    pending = false,
+    version = version + 1
+WHERE
+    id = $1
+    AND pending = true
+    AND deleted_at IS NULL
+RETURNING "id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending";
+
+-- name: InsertPoll :exec
+INSERT INTO polls
+    ( "id", "room_id", "question", "created_at" ) VALUES
+    ( $1, $2, $3, $4 );
+
+-- name: InsertPollOption :exec
+INSERT INTO poll_options
+    ( "poll_id", "position", "label" ) VALUES
+    ( $1, $2, $3 );
+
+-- name: GetPoll :one
+SELECT
+    "id", "room_id", "question", "created_at", "closed_at"
+FROM polls
+WHERE
+    id = $1;
+
+-- name: GetRoomPolls :many
+SELECT
+    "id", "room_id", "question", "created_at", "closed_at"
+FROM polls
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetPollOptions :many
+SELECT
+    "poll_id", "position", "label"
+FROM poll_options
+WHERE
+    poll_id = $1
+ORDER BY position ASC;
+
+-- name: GetPollVoteCounts :many
+SELECT
+    "choice", COUNT(*) AS votes
+FROM poll_votes
+WHERE
+    poll_id = $1
+GROUP BY choice
+ORDER BY choice;
+
+-- name: CastPollVote :execrows
+INSERT INTO poll_votes
+    ( "poll_id", "client_id", "choice", "created_at" )
+SELECT
+    sqlc.arg(poll_id)::uuid, sqlc.arg(client_id)::text, sqlc.arg(choice)::integer, sqlc.arg(created_at)::timestamptz
+WHERE
+    EXISTS (SELECT 1 FROM polls WHERE id = sqlc.arg(poll_id)::uuid AND closed_at IS NULL)
+ON CONFLICT ("poll_id", "client_id") DO UPDATE
+SET
+    choice = excluded.choice,
+    created_at = excluded.created_at
+WHERE
+    poll_votes.choice <> excluded.choice;
+
+-- name: ClosePoll :one
+UPDATE polls
+SET
+    closed_at = $2
+WHERE
+    id = $1
+    AND closed_at IS NULL
+RETURNING "id", "room_id", "question", "created_at", "closed_at";
+
+-- name: InsertAnnouncement :exec
+INSERT INTO announcements
+    ( "id", "room_id", "author", "body", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 );
+
+-- name: GetRoomAnnouncements :many
+SELECT
+    "id", "room_id", "author", "body", "created_at"
+FROM announcements
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: CloseRoom :one
+UPDATE rooms
+SET
+    closed_at = $2
+WHERE
+    id = $1
+    AND closed_at IS NULL
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code";
+
+-- name: CloseDueRooms :many
+UPDATE rooms
+SET
+    closed_at = closes_at
+WHERE
+    closes_at <= $1
+    AND closed_at IS NULL
+RETURNING "id";
+
+-- name: SoftDeleteRoom :one
+UPDATE rooms
+SET
+    deleted_at = $2
+WHERE
+    id = $1
+    AND deleted_at IS NULL
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code";
+
+-- name: RestoreRoom :one
+UPDATE rooms
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at > sqlc.arg(deleted_after)
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code";
+
+-- name: GetDeletedRoomIDs :many
+SELECT
+    "id"
+FROM rooms
+WHERE
+    deleted_at <= $1;
+
+-- name: UpdateRoom :one
+UPDATE rooms
+SET
+    theme = $2,
+    max_messages = $3,
+    prune = $4,
+    require_name = $5,
+    expires_at = $6,
+    duplicate_threshold = $7,
+    max_questions_per_participant = $8,
+    require_approval = $9,
+    closes_at = $10,
+    description = $11,
+    host_name = $12,
+    starts_at = $13
+WHERE
+    id = $1
+    AND deleted_at IS NULL
+RETURNING "id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code";
+
+-- name: GetRoomIDByCode :one
+SELECT
+    "id"
+FROM rooms
+WHERE
+    code = $1;
+
+-- name: DeleteRoomWebhook :execrows
+DELETE FROM webhooks
+WHERE
+    id = $1
+    AND room_id = $2;
+
+-- name: CountRoomMessagesWithoutConsent :one
+SELECT
+    COUNT(*)
+FROM messages
+WHERE
+    room_id = sqlc.arg(room_id)
+    AND pending = false
+    AND consent_to_publish = false
+    AND (deleted_at IS NULL OR sqlc.arg(include_deleted)::boolean);
+
+-- name: GetRoomPollOptions :many
+SELECT
+    o."poll_id", o."position", o."label", COUNT(v.client_id) AS votes
+FROM poll_options o
+JOIN polls p ON p.id = o.poll_id
+LEFT JOIN poll_votes v ON v.poll_id = o.poll_id AND v.choice = o.position
+WHERE
+    p.room_id = $1
+GROUP BY o.poll_id, o.position, o.label
+ORDER BY o.poll_id, o.position;
+
+-- name: GetMessageReply :one
+SELECT
+    "id", "message_id", "author", "body", "created_at"
+FROM message_replies
+WHERE
+    id = $1;
+
+-- name: GetAnnouncement :one
+SELECT
+    "id", "room_id", "author", "body", "created_at"
+FROM announcements
 WHERE
     id = $1;
@@ -0,0 +1,302 @@
+package pgstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrRoomAtCapacity is returned when a room reached its message limit and no
+// message could be pruned to make space.
+var ErrRoomAtCapacity = errors.New("pgstore: room at capacity")
+
+// ErrQuestionQuotaReached is returned when a participant already asked the
+// most questions the room allows.
+var ErrQuestionQuotaReached = errors.New("pgstore: question quota reached")
+
+// ErrTooManyWebhooks is returned when an update would leave a room with more
+// webhooks than it may have.
+var ErrTooManyWebhooks = errors.New("pgstore: too many webhooks")
+
+// ErrRoomClosed is returned when a room was closed by its host and takes no
+// more questions.
+var ErrRoomClosed = errors.New("pgstore: room closed")
+
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// execTx runs fn inside a transaction, committing when it returns nil. When q
+// already runs inside a transaction a nested one (savepoint) is used.
+func (q *Queries) execTx(ctx context.Context, fn func(*Queries) error) error {
+	b, ok := q.db.(txBeginner)
+	if !ok {
+		return fmt.Errorf("pgstore: %T does not support transactions", q.db)
+	}
+
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// InsertMessageWithinCapacityParams is the message to insert and the
+// participant asking it, such as a client id. An empty Participant isn't held
+// to the question quota of the room.
+type InsertMessageWithinCapacityParams struct {
+	InsertMessageParams
+	Participant string
+}
+
+// InsertMessageWithinCapacity inserts a message while enforcing the room's
+// max_messages limit and question quota. The room row is locked for the
+// duration of the transaction so concurrent posts can't push the room over its
+// limit. Deleted messages don't count toward the limit; messages waiting for
+// approval do, since they may yet be shown. When the room is full and was
+// created with prune, the oldest visible unanswered message without reactions
+// is deleted to make space and its id returned as pruned: pending messages are
+// left to the moderators. Otherwise ErrRoomAtCapacity is returned. When the
+// participant already asked max_questions_per_participant questions,
+// ErrQuestionQuotaReached is returned, and when the room is closed,
+// ErrRoomClosed.
+func (q *Queries) InsertMessageWithinCapacity(ctx context.Context, arg InsertMessageWithinCapacityParams) (uuid.UUID, uuid.UUID, error) {
+	var id, pruned uuid.UUID
+	err := q.execTx(ctx, func(q *Queries) error {
+		room, err := q.GetRoomForUpdate(ctx, arg.RoomID)
+		if err != nil {
+			return err
+		}
+		if room.ClosedAt != nil {
+			return ErrRoomClosed
+		}
+
+		if room.MaxQuestionsPerParticipant > 0 && arg.Participant != "" {
+			asked, err := q.IncrementParticipantQuestions(ctx, IncrementParticipantQuestionsParams{
+				RoomID:      arg.RoomID,
+				Participant: arg.Participant,
+			})
+			if err != nil {
+				return err
+			}
+			if asked > room.MaxQuestionsPerParticipant {
+				return ErrQuestionQuotaReached
+			}
+		}
+
+		if room.MaxMessages > 0 {
+			count, err := q.CountRoomMessages(ctx, arg.RoomID)
+			if err != nil {
+				return err
+			}
+
+			if count >= int64(room.MaxMessages) {
+				if !room.Prune {
+					return ErrRoomAtCapacity
+				}
+				pruned, err = q.DeleteOldestPrunableMessage(ctx, arg.RoomID)
+				if err != nil {
+					if errors.Is(err, pgx.ErrNoRows) {
+						return ErrRoomAtCapacity
+					}
+					return err
+				}
+			}
+		}
+
+		id, err = q.InsertMessage(ctx, arg.InsertMessageParams)
+		return err
+	})
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return id, pruned, nil
+}
+
+// DeleteRoomWithMessages deletes a room together with all of its messages.
+func (q *Queries) DeleteRoomWithMessages(ctx context.Context, id uuid.UUID) error {
+	return q.execTx(ctx, func(q *Queries) error {
+		if err := q.DeleteRoomMessages(ctx, id); err != nil {
+			return err
+		}
+		return q.DeleteRoom(ctx, id)
+	})
+}
+
+// UnknownMessagesError is returned by ApplyReactionBatch when some of the
+// message ids don't belong to the room.
+type UnknownMessagesError struct {
+	IDs []uuid.UUID
+}
+
+func (e *UnknownMessagesError) Error() string {
+	return fmt.Sprintf("pgstore: %d unknown message ids", len(e.IDs))
+}
+
+type ApplyReactionBatchParams struct {
+	RoomID   uuid.UUID
+	ClientID string
+	Add      []uuid.UUID
+	Remove   []uuid.UUID
+}
+
+// ApplyReactionBatch adds and then removes the client's reactions to messages
+// of a room in a single transaction, returning the resulting counts of every
+// message in the batch. A client reacts to a message at most once, so adding
+// an existing reaction or removing a missing one leaves the count alone. When
+// any id is not a message of the room nothing is applied and an
+// *UnknownMessagesError is returned.
+func (q *Queries) ApplyReactionBatch(ctx context.Context, arg ApplyReactionBatchParams) ([]GetReactionCountsRow, error) {
+	ids := make([]uuid.UUID, 0, len(arg.Add)+len(arg.Remove))
+	ids = append(append(ids, arg.Add...), arg.Remove...)
+
+	var counts []GetReactionCountsRow
+	err := q.execTx(ctx, func(q *Queries) error {
+		found, err := q.GetRoomMessageIDs(ctx, GetRoomMessageIDsParams{RoomID: arg.RoomID, Ids: ids})
+		if err != nil {
+			return err
+		}
+		known := make(map[uuid.UUID]struct{}, len(found))
+		for _, id := range found {
+			known[id] = struct{}{}
+		}
+		var unknown []uuid.UUID
+		for _, id := range ids {
+			if _, ok := known[id]; !ok {
+				unknown = append(unknown, id)
+			}
+		}
+		if len(unknown) > 0 {
+			return &UnknownMessagesError{IDs: unknown}
+		}
+
+		if len(arg.Add) > 0 {
+			added, err := q.InsertClientReactions(ctx, InsertClientReactionsParams{MessageIds: arg.Add, ClientID: arg.ClientID})
+			if err != nil {
+				return err
+			}
+			if err := q.IncrementReactionCounts(ctx, added); err != nil {
+				return err
+			}
+		}
+		if len(arg.Remove) > 0 {
+			removed, err := q.DeleteClientReactions(ctx, DeleteClientReactionsParams{ClientID: arg.ClientID, MessageIds: arg.Remove})
+			if err != nil {
+				return err
+			}
+			if err := q.DecrementReactionCounts(ctx, removed); err != nil {
+				return err
+			}
+		}
+
+		counts, err = q.GetReactionCounts(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// InsertRoomWithWebhooks inserts a room together with its webhooks.
+func (q *Queries) InsertRoomWithWebhooks(ctx context.Context, room InsertRoomParams, webhooks []InsertWebhookParams) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := q.execTx(ctx, func(q *Queries) error {
+		var err error
+		id, err = q.InsertRoom(ctx, room)
+		if err != nil {
+			return err
+		}
+		for _, webhook := range webhooks {
+			webhook.RoomID = id
+			if err := q.InsertWebhook(ctx, webhook); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+type UpdateRoomWebhooksParams struct {
+	RoomID uuid.UUID
+	Add    []InsertWebhookParams
+	Remove []uuid.UUID
+	// Max is the most webhooks the room may be left with.
+	Max int
+}
+
+// UpdateRoomWebhooks removes and then adds webhooks of a room in a single
+// transaction, returning all of the room's webhooks afterwards. The room row
+// is locked so concurrent updates can't leave it with more than Max webhooks;
+// ErrTooManyWebhooks is returned instead. Removing a webhook the room doesn't
+// have fails with pgx.ErrNoRows.
+func (q *Queries) UpdateRoomWebhooks(ctx context.Context, arg UpdateRoomWebhooksParams) ([]Webhook, error) {
+	var hooks []Webhook
+	err := q.execTx(ctx, func(q *Queries) error {
+		if _, err := q.GetRoomForUpdate(ctx, arg.RoomID); err != nil {
+			return err
+		}
+		for _, id := range arg.Remove {
+			deleted, err := q.DeleteRoomWebhook(ctx, DeleteRoomWebhookParams{ID: id, RoomID: arg.RoomID})
+			if err != nil {
+				return err
+			}
+			if deleted == 0 {
+				return pgx.ErrNoRows
+			}
+		}
+		for _, webhook := range arg.Add {
+			webhook.RoomID = arg.RoomID
+			if err := q.InsertWebhook(ctx, webhook); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		hooks, err = q.GetRoomWebhooks(ctx, arg.RoomID)
+		if err != nil {
+			return err
+		}
+		if len(hooks) > arg.Max {
+			return ErrTooManyWebhooks
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// InsertPollWithOptions inserts a poll together with its options, numbered
+// from 0 in order.
+func (q *Queries) InsertPollWithOptions(ctx context.Context, poll InsertPollParams, options []string) error {
+	return q.execTx(ctx, func(q *Queries) error {
+		if err := q.InsertPoll(ctx, poll); err != nil {
+			return err
+		}
+		for i, label := range options {
+			err := q.InsertPollOption(ctx, InsertPollOptionParams{
+				PollID:   poll.ID,
+				Position: int32(i),
+				Label:    label,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,1242 @@
+package sqlitestore
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+const roomColumns = `"id", "theme", "max_messages", "prune", "require_name", "created_at", "expires_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closed_at", "closes_at", "deleted_at", "description", "host_name", "starts_at", "code"`
+
+const messageColumns = `"id", "room_id", "message", "reaction_count", "answered", "author_id", "created_at", "consent_to_publish", "author_name", "language", "language_confidence", "answer", "version", "deleted_at", "deleted_by", "pending"`
+
+func scanRoom(sc scanner) (pgstore.Room, error) {
+	var i pgstore.Room
+	err := sc.Scan(
+		&i.ID,
+		&i.Theme,
+		&i.MaxMessages,
+		&i.Prune,
+		&i.RequireName,
+		timestamp{&i.CreatedAt},
+		nullTimestamp{&i.ExpiresAt},
+		&i.DuplicateThreshold,
+		&i.MaxQuestionsPerParticipant,
+		&i.RequireApproval,
+		nullTimestamp{&i.ClosedAt},
+		nullTimestamp{&i.ClosesAt},
+		nullTimestamp{&i.DeletedAt},
+		&i.Description,
+		&i.HostName,
+		nullTimestamp{&i.StartsAt},
+		&i.Code,
+	)
+	return i, err
+}
+
+func messageFields(i *pgstore.Message) []any {
+	return []any{
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.ReactionCount,
+		&i.Answered,
+		&i.AuthorID,
+		timestamp{&i.CreatedAt},
+		&i.ConsentToPublish,
+		&i.AuthorName,
+		&i.Language,
+		&i.LanguageConfidence,
+		&i.Answer,
+		&i.Version,
+		nullTimestamp{&i.DeletedAt},
+		&i.DeletedBy,
+		&i.Pending,
+	}
+}
+
+func scanMessage(sc scanner) (pgstore.Message, error) {
+	var i pgstore.Message
+	err := sc.Scan(messageFields(&i)...)
+	return i, err
+}
+
+const pollColumns = `"id", "room_id", "question", "created_at", "closed_at"`
+
+func scanPoll(sc scanner) (pgstore.Poll, error) {
+	var i pgstore.Poll
+	err := sc.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Question,
+		timestamp{&i.CreatedAt},
+		nullTimestamp{&i.ClosedAt},
+	)
+	return i, err
+}
+
+const approveMessage = `UPDATE messages
+SET
+    pending = 0,
+    version = version + 1
+WHERE
+    id = $1
+    AND pending = 1
+    AND deleted_at IS NULL
+RETURNING ` + messageColumns
+
+func (s *Store) ApproveMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	return scanMessage(s.queryRow(ctx, approveMessage, id))
+}
+
+const castPollVote = `INSERT INTO poll_votes
+    ( "poll_id", "client_id", "choice", "created_at" )
+SELECT
+    $1, $2, $3, $4
+WHERE
+    EXISTS (SELECT 1 FROM polls WHERE id = $1 AND closed_at IS NULL)
+ON CONFLICT ("poll_id", "client_id") DO UPDATE
+SET
+    choice = excluded.choice,
+    created_at = excluded.created_at
+WHERE
+    poll_votes.choice <> excluded.choice`
+
+func (s *Store) CastPollVote(ctx context.Context, arg pgstore.CastPollVoteParams) (int64, error) {
+	result, err := s.exec(ctx, castPollVote,
+		arg.PollID,
+		arg.ClientID,
+		arg.Choice,
+		unixNano(arg.CreatedAt),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const closePoll = `UPDATE polls
+SET
+    closed_at = $2
+WHERE
+    id = $1
+    AND closed_at IS NULL
+RETURNING ` + pollColumns
+
+func (s *Store) ClosePoll(ctx context.Context, arg pgstore.ClosePollParams) (pgstore.Poll, error) {
+	return scanPoll(s.queryRow(ctx, closePoll, arg.ID, nullUnixNano(arg.ClosedAt)))
+}
+
+const closeDueRooms = `UPDATE rooms
+SET
+    closed_at = closes_at
+WHERE
+    closes_at <= $1
+    AND closed_at IS NULL
+RETURNING "id"`
+
+func (s *Store) CloseDueRooms(ctx context.Context, closesAt *time.Time) ([]uuid.UUID, error) {
+	return queryAll(ctx, s, scanID, closeDueRooms, nullUnixNano(closesAt))
+}
+
+const closeRoom = `UPDATE rooms
+SET
+    closed_at = $2
+WHERE
+    id = $1
+    AND closed_at IS NULL
+RETURNING ` + roomColumns
+
+func (s *Store) CloseRoom(ctx context.Context, arg pgstore.CloseRoomParams) (pgstore.Room, error) {
+	return scanRoom(s.queryRow(ctx, closeRoom, arg.ID, nullUnixNano(arg.ClosedAt)))
+}
+
+const countMessageFlags = `SELECT COUNT(*) FROM message_flags
+WHERE
+    message_id = $1`
+
+func (s *Store) CountMessageFlags(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	var count int64
+	err := s.queryRow(ctx, countMessageFlags, messageID).Scan(&count)
+	return count, err
+}
+
+const countRoomMessages = `SELECT
+    COUNT(*)
+FROM messages
+WHERE
+    room_id = $1
+    AND deleted_at IS NULL`
+
+func (s *Store) CountRoomMessages(ctx context.Context, roomID uuid.UUID) (int64, error) {
+	var count int64
+	err := s.queryRow(ctx, countRoomMessages, roomID).Scan(&count)
+	return count, err
+}
+
+const countRoomMessagesWithoutConsent = `SELECT
+    COUNT(*)
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = 0
+    AND consent_to_publish = 0
+    AND (deleted_at IS NULL OR $2)`
+
+func (s *Store) CountRoomMessagesWithoutConsent(ctx context.Context, arg pgstore.CountRoomMessagesWithoutConsentParams) (int64, error) {
+	var count int64
+	err := s.queryRow(ctx, countRoomMessagesWithoutConsent, arg.RoomID, arg.IncludeDeleted).Scan(&count)
+	return count, err
+}
+
+const decrementReactionCounts = `UPDATE messages
+SET
+    reaction_count = MAX(reaction_count - 1, 0),
+    version = version + 1
+WHERE
+    id IN (SELECT value FROM json_each($1))`
+
+func (s *Store) DecrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	_, err := s.exec(ctx, decrementReactionCounts, idList(ids))
+	return err
+}
+
+const deleteClientReactions = `DELETE FROM message_reactions
+WHERE
+    client_id = $1
+    AND message_id IN (SELECT value FROM json_each($2))
+RETURNING "message_id"`
+
+func (s *Store) DeleteClientReactions(ctx context.Context, arg pgstore.DeleteClientReactionsParams) ([]uuid.UUID, error) {
+	return queryAll(ctx, s, scanID, deleteClientReactions, arg.ClientID, idList(arg.MessageIds))
+}
+
+const deleteEmojiReaction = `DELETE FROM message_emoji_reactions
+WHERE
+    message_id = $1
+    AND client_id = $2
+    AND kind = $3`
+
+func (s *Store) DeleteEmojiReaction(ctx context.Context, arg pgstore.DeleteEmojiReactionParams) (int64, error) {
+	result, err := s.exec(ctx, deleteEmojiReaction, arg.MessageID, arg.ClientID, arg.Kind)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOldestPrunableMessage = `DELETE FROM messages
+WHERE id = (
+    SELECT p.id FROM messages p
+    WHERE
+        p.room_id = $1
+        AND p.answered = 0
+        AND p.reaction_count = 0
+        AND p.pending = 0
+        AND p.deleted_at IS NULL
+    ORDER BY p.created_at ASC, p.id ASC
+    LIMIT 1
+)
+RETURNING "id"`
+
+func (s *Store) DeleteOldestPrunableMessage(ctx context.Context, roomID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := s.queryRow(ctx, deleteOldestPrunableMessage, roomID).Scan(&id)
+	return id, err
+}
+
+const deleteRoom = `DELETE FROM rooms
+WHERE
+    id = $1`
+
+func (s *Store) DeleteRoom(ctx context.Context, id uuid.UUID) error {
+	_, err := s.exec(ctx, deleteRoom, id)
+	return err
+}
+
+const deleteRoomMessages = `DELETE FROM messages
+WHERE
+    room_id = $1`
+
+func (s *Store) DeleteRoomMessages(ctx context.Context, roomID uuid.UUID) error {
+	_, err := s.exec(ctx, deleteRoomMessages, roomID)
+	return err
+}
+
+const deleteRoomWebhook = `DELETE FROM webhooks
+WHERE
+    id = $1
+    AND room_id = $2`
+
+func (s *Store) DeleteRoomWebhook(ctx context.Context, arg pgstore.DeleteRoomWebhookParams) (int64, error) {
+	result, err := s.exec(ctx, deleteRoomWebhook, arg.ID, arg.RoomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const findSimilarUnansweredMessages = `SELECT
+    "id", "message", "reaction_count", similarity("message", $1) AS similarity
+FROM messages
+WHERE
+    room_id = $2
+    AND pending = 0
+    AND answered = 0
+    AND deleted_at IS NULL
+    AND similarity("message", $1) >= $3
+ORDER BY similarity DESC, created_at ASC
+LIMIT $4`
+
+func (s *Store) FindSimilarUnansweredMessages(ctx context.Context, arg pgstore.FindSimilarUnansweredMessagesParams) ([]pgstore.FindSimilarUnansweredMessagesRow, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.FindSimilarUnansweredMessagesRow, error) {
+		var i pgstore.FindSimilarUnansweredMessagesRow
+		err := sc.Scan(&i.ID, &i.Message, &i.ReactionCount, &i.Similarity)
+		return i, err
+	}, findSimilarUnansweredMessages, arg.Message, arg.RoomID, arg.Threshold, arg.MaxResults)
+}
+
+const getAnnouncement = `SELECT
+    "id", "room_id", "author", "body", "created_at"
+FROM announcements
+WHERE
+    id = $1`
+
+func (s *Store) GetAnnouncement(ctx context.Context, id uuid.UUID) (pgstore.Announcement, error) {
+	var i pgstore.Announcement
+	err := s.queryRow(ctx, getAnnouncement, id).Scan(&i.ID, &i.RoomID, &i.Author, &i.Body, timestamp{&i.CreatedAt})
+	return i, err
+}
+
+const getDeletedRoomIDs = `SELECT
+    "id"
+FROM rooms
+WHERE
+    deleted_at <= $1`
+
+func (s *Store) GetDeletedRoomIDs(ctx context.Context, deletedAt *time.Time) ([]uuid.UUID, error) {
+	return queryAll(ctx, s, scanID, getDeletedRoomIDs, nullUnixNano(deletedAt))
+}
+
+const getEmojiReactionCounts = `SELECT
+    "message_id", "kind", COUNT(*) AS count
+FROM message_emoji_reactions
+WHERE
+    message_id IN (SELECT value FROM json_each($1))
+GROUP BY message_id, kind
+ORDER BY message_id, kind`
+
+func (s *Store) GetEmojiReactionCounts(ctx context.Context, ids []uuid.UUID) ([]pgstore.GetEmojiReactionCountsRow, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.GetEmojiReactionCountsRow, error) {
+		var i pgstore.GetEmojiReactionCountsRow
+		err := sc.Scan(&i.MessageID, &i.Kind, &i.Count)
+		return i, err
+	}, getEmojiReactionCounts, idList(ids))
+}
+
+const getExpiredRoomIDs = `SELECT
+    "id"
+FROM rooms
+WHERE
+    expires_at <= $1`
+
+func (s *Store) GetExpiredRoomIDs(ctx context.Context, expiresAt *time.Time) ([]uuid.UUID, error) {
+	return queryAll(ctx, s, scanID, getExpiredRoomIDs, nullUnixNano(expiresAt))
+}
+
+const getMessage = `SELECT
+    ` + messageColumns + `
+FROM messages
+WHERE
+    id = $1`
+
+func (s *Store) GetMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	return scanMessage(s.queryRow(ctx, getMessage, id))
+}
+
+const getMessageEdits = `SELECT
+    "message_id", "version", "message", "edited_at"
+FROM message_edits
+WHERE
+    message_id = $1
+ORDER BY version ASC`
+
+func (s *Store) GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]pgstore.MessageEdit, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.MessageEdit, error) {
+		var i pgstore.MessageEdit
+		err := sc.Scan(&i.MessageID, &i.Version, &i.Message, timestamp{&i.EditedAt})
+		return i, err
+	}, getMessageEdits, messageID)
+}
+
+const getMessageReplies = `SELECT
+    "id", "message_id", "author", "body", "created_at"
+FROM message_replies
+WHERE
+    message_id = $1
+ORDER BY created_at ASC, id ASC`
+
+func (s *Store) GetMessageReplies(ctx context.Context, messageID uuid.UUID) ([]pgstore.MessageReply, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.MessageReply, error) {
+		var i pgstore.MessageReply
+		err := sc.Scan(&i.ID, &i.MessageID, &i.Author, &i.Body, timestamp{&i.CreatedAt})
+		return i, err
+	}, getMessageReplies, messageID)
+}
+
+const getMessageReply = `SELECT
+    "id", "message_id", "author", "body", "created_at"
+FROM message_replies
+WHERE
+    id = $1`
+
+func (s *Store) GetMessageReply(ctx context.Context, id uuid.UUID) (pgstore.MessageReply, error) {
+	var i pgstore.MessageReply
+	err := s.queryRow(ctx, getMessageReply, id).Scan(&i.ID, &i.MessageID, &i.Author, &i.Body, timestamp{&i.CreatedAt})
+	return i, err
+}
+
+const getPoll = `SELECT
+    ` + pollColumns + `
+FROM polls
+WHERE
+    id = $1`
+
+func (s *Store) GetPoll(ctx context.Context, id uuid.UUID) (pgstore.Poll, error) {
+	return scanPoll(s.queryRow(ctx, getPoll, id))
+}
+
+const getPollOptions = `SELECT
+    "poll_id", "position", "label"
+FROM poll_options
+WHERE
+    poll_id = $1
+ORDER BY position ASC`
+
+func (s *Store) GetPollOptions(ctx context.Context, pollID uuid.UUID) ([]pgstore.PollOption, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.PollOption, error) {
+		var i pgstore.PollOption
+		err := sc.Scan(&i.PollID, &i.Position, &i.Label)
+		return i, err
+	}, getPollOptions, pollID)
+}
+
+const getPollVoteCounts = `SELECT
+    "choice", COUNT(*) AS votes
+FROM poll_votes
+WHERE
+    poll_id = $1
+GROUP BY choice
+ORDER BY choice`
+
+func (s *Store) GetPollVoteCounts(ctx context.Context, pollID uuid.UUID) ([]pgstore.GetPollVoteCountsRow, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.GetPollVoteCountsRow, error) {
+		var i pgstore.GetPollVoteCountsRow
+		err := sc.Scan(&i.Choice, &i.Votes)
+		return i, err
+	}, getPollVoteCounts, pollID)
+}
+
+const getReactionCounts = `SELECT
+    "id", "reaction_count", "version"
+FROM messages
+WHERE
+    id IN (SELECT value FROM json_each($1))
+ORDER BY id`
+
+func (s *Store) GetReactionCounts(ctx context.Context, ids []uuid.UUID) ([]pgstore.GetReactionCountsRow, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.GetReactionCountsRow, error) {
+		var i pgstore.GetReactionCountsRow
+		err := sc.Scan(&i.ID, &i.ReactionCount, &i.Version)
+		return i, err
+	}, getReactionCounts, idList(ids))
+}
+
+const getRoom = `SELECT
+    ` + roomColumns + `
+FROM rooms
+WHERE
+    id = $1`
+
+func (s *Store) GetRoom(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	return scanRoom(s.queryRow(ctx, getRoom, id))
+}
+
+const getRoomAnnouncements = `SELECT
+    "id", "room_id", "author", "body", "created_at"
+FROM announcements
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC`
+
+func (s *Store) GetRoomAnnouncements(ctx context.Context, roomID uuid.UUID) ([]pgstore.Announcement, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.Announcement, error) {
+		var i pgstore.Announcement
+		err := sc.Scan(&i.ID, &i.RoomID, &i.Author, &i.Body, timestamp{&i.CreatedAt})
+		return i, err
+	}, getRoomAnnouncements, roomID)
+}
+
+const getRoomFlaggedMessages = `SELECT
+    m."id",
+    m."message",
+    m."answered",
+    COUNT(*)                                            AS flag_count,
+    COUNT(*) FILTER (WHERE f.reason = 'spam')           AS spam_count,
+    COUNT(*) FILTER (WHERE f.reason = 'abuse')          AS abuse_count,
+    COUNT(*) FILTER (WHERE f.reason = 'off_topic')      AS off_topic_count,
+    COUNT(*) FILTER (WHERE f.reason IS NULL)            AS unspecified_count
+FROM messages m
+JOIN message_flags f ON f.message_id = m.id
+WHERE
+    m.room_id = $1
+GROUP BY m.id
+ORDER BY flag_count DESC, m.created_at ASC`
+
+func (s *Store) GetRoomFlaggedMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.GetRoomFlaggedMessagesRow, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.GetRoomFlaggedMessagesRow, error) {
+		var i pgstore.GetRoomFlaggedMessagesRow
+		err := sc.Scan(
+			&i.ID,
+			&i.Message,
+			&i.Answered,
+			&i.FlagCount,
+			&i.SpamCount,
+			&i.AbuseCount,
+			&i.OffTopicCount,
+			&i.UnspecifiedCount,
+		)
+		return i, err
+	}, getRoomFlaggedMessages, roomID)
+}
+
+// GetRoomForUpdate is GetRoom: transactions take the database's write lock
+// when they begin, so the row is already locked.
+func (s *Store) GetRoomForUpdate(ctx context.Context, id uuid.UUID) (pgstore.Room, error) {
+	return s.GetRoom(ctx, id)
+}
+
+const getRoomIDByCode = `SELECT
+    "id"
+FROM rooms
+WHERE
+    code = $1`
+
+func (s *Store) GetRoomIDByCode(ctx context.Context, code string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := s.queryRow(ctx, getRoomIDByCode, code).Scan(&id)
+	return id, err
+}
+
+const getRoomMessageIDs = `SELECT
+    "id"
+FROM messages
+WHERE
+    room_id = $1
+    AND id IN (SELECT value FROM json_each($2))
+    AND pending = 0
+    AND deleted_at IS NULL`
+
+func (s *Store) GetRoomMessageIDs(ctx context.Context, arg pgstore.GetRoomMessageIDsParams) ([]uuid.UUID, error) {
+	return queryAll(ctx, s, scanID, getRoomMessageIDs, arg.RoomID, idList(arg.Ids))
+}
+
+const getRoomMessages = `SELECT
+    ` + messageColumns + `
+FROM messages
+WHERE
+    room_id = $1`
+
+func (s *Store) GetRoomMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.Message, error) {
+	return queryAll(ctx, s, scanMessage, getRoomMessages, roomID)
+}
+
+const getRoomMessagesCreatedAfter = `SELECT
+    ` + messageColumns + `
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = 0
+    AND deleted_at IS NULL
+    AND (created_at, id) > (
+        SELECT a.created_at, a.id FROM messages a WHERE a.id = $2
+    )
+ORDER BY created_at ASC, id ASC`
+
+func (s *Store) GetRoomMessagesCreatedAfter(ctx context.Context, arg pgstore.GetRoomMessagesCreatedAfterParams) ([]pgstore.Message, error) {
+	return queryAll(ctx, s, scanMessage, getRoomMessagesCreatedAfter, arg.RoomID, arg.AfterID)
+}
+
+const getRoomMessagesPage = `SELECT
+    ` + messageColumns + `
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = 0
+    AND (created_at, id) > ($2, $3)
+    AND (deleted_at IS NULL OR $4)
+    AND ($5 IS NULL OR language = $5)
+ORDER BY created_at ASC, id ASC
+LIMIT $6`
+
+func (s *Store) GetRoomMessagesPage(ctx context.Context, arg pgstore.GetRoomMessagesPageParams) ([]pgstore.Message, error) {
+	return queryAll(ctx, s, scanMessage, getRoomMessagesPage,
+		arg.RoomID,
+		unixNano(arg.AfterCreatedAt),
+		arg.AfterID,
+		arg.IncludeDeleted,
+		arg.Language,
+		arg.MaxResults,
+	)
+}
+
+const getRoomModerationAudit = `SELECT
+    "id", "room_id", "message_id", "actor", "action", "previous_value", "created_at"
+FROM moderation_audit
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC`
+
+func (s *Store) GetRoomModerationAudit(ctx context.Context, roomID uuid.UUID) ([]pgstore.ModerationAudit, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.ModerationAudit, error) {
+		var i pgstore.ModerationAudit
+		err := sc.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.MessageID,
+			&i.Actor,
+			&i.Action,
+			&i.PreviousValue,
+			timestamp{&i.CreatedAt},
+		)
+		return i, err
+	}, getRoomModerationAudit, roomID)
+}
+
+const getRoomPendingMessages = `SELECT
+    ` + messageColumns + `
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = 1
+    AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC`
+
+func (s *Store) GetRoomPendingMessages(ctx context.Context, roomID uuid.UUID) ([]pgstore.Message, error) {
+	return queryAll(ctx, s, scanMessage, getRoomPendingMessages, roomID)
+}
+
+const getRoomPollOptions = `SELECT
+    o."poll_id", o."position", o."label", COUNT(v.client_id) AS votes
+FROM poll_options o
+JOIN polls p ON p.id = o.poll_id
+LEFT JOIN poll_votes v ON v.poll_id = o.poll_id AND v.choice = o.position
+WHERE
+    p.room_id = $1
+GROUP BY o.poll_id, o.position, o.label
+ORDER BY o.poll_id, o.position`
+
+func (s *Store) GetRoomPollOptions(ctx context.Context, roomID uuid.UUID) ([]pgstore.GetRoomPollOptionsRow, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.GetRoomPollOptionsRow, error) {
+		var i pgstore.GetRoomPollOptionsRow
+		err := sc.Scan(&i.PollID, &i.Position, &i.Label, &i.Votes)
+		return i, err
+	}, getRoomPollOptions, roomID)
+}
+
+const getRoomPolls = `SELECT
+    ` + pollColumns + `
+FROM polls
+WHERE
+    room_id = $1
+ORDER BY created_at DESC, id DESC`
+
+func (s *Store) GetRoomPolls(ctx context.Context, roomID uuid.UUID) ([]pgstore.Poll, error) {
+	return queryAll(ctx, s, scanPoll, getRoomPolls, roomID)
+}
+
+const getRoomStats = `SELECT
+    COUNT(*)                                    AS total_messages,
+    COUNT(*) FILTER (WHERE answered)            AS answered_messages,
+    COUNT(*) FILTER (WHERE NOT answered)        AS unanswered_messages,
+    COALESCE(SUM(reaction_count), 0)            AS total_reactions
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = 0
+    AND deleted_at IS NULL`
+
+func (s *Store) GetRoomStats(ctx context.Context, roomID uuid.UUID) (pgstore.GetRoomStatsRow, error) {
+	var i pgstore.GetRoomStatsRow
+	err := s.queryRow(ctx, getRoomStats, roomID).Scan(
+		&i.TotalMessages,
+		&i.AnsweredMessages,
+		&i.UnansweredMessages,
+		&i.TotalReactions,
+	)
+	return i, err
+}
+
+const getRoomWebhooks = `SELECT
+    "id", "room_id", "url", "secret", "created_at", "last_delivery_at", "last_status", "last_error"
+FROM webhooks
+WHERE
+    room_id = $1
+ORDER BY created_at`
+
+func (s *Store) GetRoomWebhooks(ctx context.Context, roomID uuid.UUID) ([]pgstore.Webhook, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.Webhook, error) {
+		var i pgstore.Webhook
+		err := sc.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Url,
+			&i.Secret,
+			timestamp{&i.CreatedAt},
+			nullTimestamp{&i.LastDeliveryAt},
+			&i.LastStatus,
+			&i.LastError,
+		)
+		return i, err
+	}, getRoomWebhooks, roomID)
+}
+
+const getRooms = `SELECT
+    ` + roomColumns + `
+FROM rooms`
+
+func (s *Store) GetRooms(ctx context.Context) ([]pgstore.Room, error) {
+	return queryAll(ctx, s, scanRoom, getRooms)
+}
+
+const getTopUnansweredMessages = `SELECT
+    ` + messageColumns + `
+FROM messages
+WHERE
+    room_id = $1
+    AND pending = 0
+    AND answered = 0
+    AND deleted_at IS NULL
+ORDER BY reaction_count DESC, created_at ASC
+LIMIT $2`
+
+func (s *Store) GetTopUnansweredMessages(ctx context.Context, arg pgstore.GetTopUnansweredMessagesParams) ([]pgstore.Message, error) {
+	return queryAll(ctx, s, scanMessage, getTopUnansweredMessages, arg.RoomID, arg.Limit)
+}
+
+const incrementParticipantQuestions = `INSERT INTO participant_questions
+    ( "room_id", "participant", "questions" ) VALUES
+    ( $1, $2, 1 )
+ON CONFLICT ("room_id", "participant") DO UPDATE
+SET
+    questions = participant_questions.questions + 1
+RETURNING "questions"`
+
+func (s *Store) IncrementParticipantQuestions(ctx context.Context, arg pgstore.IncrementParticipantQuestionsParams) (int32, error) {
+	var questions int32
+	err := s.queryRow(ctx, incrementParticipantQuestions, arg.RoomID, arg.Participant).Scan(&questions)
+	return questions, err
+}
+
+const incrementReactionCounts = `UPDATE messages
+SET
+    reaction_count = reaction_count + 1,
+    version = version + 1
+WHERE
+    id IN (SELECT value FROM json_each($1))`
+
+func (s *Store) IncrementReactionCounts(ctx context.Context, ids []uuid.UUID) error {
+	_, err := s.exec(ctx, incrementReactionCounts, idList(ids))
+	return err
+}
+
+// The WHERE clause is required by SQLite to tell the upsert apart from a
+// join in the SELECT.
+const insertAnnouncement = `INSERT INTO announcements
+    ( "id", "room_id", "author", "body", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 )`
+
+func (s *Store) InsertAnnouncement(ctx context.Context, arg pgstore.InsertAnnouncementParams) error {
+	_, err := s.exec(ctx, insertAnnouncement,
+		arg.ID,
+		arg.RoomID,
+		arg.Author,
+		arg.Body,
+		unixNano(arg.CreatedAt),
+	)
+	return err
+}
+
+const insertClientReactions = `INSERT INTO message_reactions
+    ( "message_id", "client_id", "created_at" )
+SELECT value, $2, $3 FROM json_each($1) WHERE true
+ON CONFLICT DO NOTHING
+RETURNING "message_id"`
+
+func (s *Store) InsertClientReactions(ctx context.Context, arg pgstore.InsertClientReactionsParams) ([]uuid.UUID, error) {
+	return queryAll(ctx, s, scanID, insertClientReactions, idList(arg.MessageIds), arg.ClientID, unixNano(time.Now()))
+}
+
+const insertEmojiReaction = `INSERT INTO message_emoji_reactions
+    ( "message_id", "client_id", "kind", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING`
+
+func (s *Store) InsertEmojiReaction(ctx context.Context, arg pgstore.InsertEmojiReactionParams) (int64, error) {
+	result, err := s.exec(ctx, insertEmojiReaction,
+		arg.MessageID,
+		arg.ClientID,
+		arg.Kind,
+		unixNano(arg.CreatedAt),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const insertMessage = `INSERT INTO messages
+    ( "id", "room_id", "message", "author_id", "consent_to_publish", "author_name", "language", "language_confidence", "created_at", "pending" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10 )
+RETURNING "id"`
+
+func (s *Store) InsertMessage(ctx context.Context, arg pgstore.InsertMessageParams) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := s.queryRow(ctx, insertMessage,
+		arg.ID,
+		arg.RoomID,
+		arg.Message,
+		arg.AuthorID,
+		arg.ConsentToPublish,
+		arg.AuthorName,
+		arg.Language,
+		arg.LanguageConfidence,
+		unixNano(arg.CreatedAt),
+		arg.Pending,
+	).Scan(&id)
+	return id, err
+}
+
+const insertMessageEdit = `INSERT INTO message_edits
+    ( "message_id", "version", "message", "edited_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING`
+
+func (s *Store) InsertMessageEdit(ctx context.Context, arg pgstore.InsertMessageEditParams) error {
+	_, err := s.exec(ctx, insertMessageEdit,
+		arg.MessageID,
+		arg.Version,
+		arg.Message,
+		unixNano(arg.EditedAt),
+	)
+	return err
+}
+
+const insertMessageFlag = `INSERT INTO message_flags
+    ( "message_id", "client_id", "reason", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )
+ON CONFLICT DO NOTHING`
+
+func (s *Store) InsertMessageFlag(ctx context.Context, arg pgstore.InsertMessageFlagParams) (int64, error) {
+	result, err := s.exec(ctx, insertMessageFlag,
+		arg.MessageID,
+		arg.ClientID,
+		arg.Reason,
+		unixNano(arg.CreatedAt),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const insertMessageReply = `INSERT INTO message_replies
+    ( "id", "message_id", "author", "body", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 )`
+
+func (s *Store) InsertMessageReply(ctx context.Context, arg pgstore.InsertMessageReplyParams) error {
+	_, err := s.exec(ctx, insertMessageReply,
+		arg.ID,
+		arg.MessageID,
+		arg.Author,
+		arg.Body,
+		unixNano(arg.CreatedAt),
+	)
+	return err
+}
+
+const insertModerationAudit = `INSERT INTO moderation_audit
+    ( "id", "room_id", "message_id", "actor", "action", "previous_value", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7 )`
+
+func (s *Store) InsertModerationAudit(ctx context.Context, arg pgstore.InsertModerationAuditParams) error {
+	_, err := s.exec(ctx, insertModerationAudit,
+		arg.ID,
+		arg.RoomID,
+		arg.MessageID,
+		arg.Actor,
+		arg.Action,
+		arg.PreviousValue,
+		unixNano(arg.CreatedAt),
+	)
+	return err
+}
+
+const insertPoll = `INSERT INTO polls
+    ( "id", "room_id", "question", "created_at" ) VALUES
+    ( $1, $2, $3, $4 )`
+
+func (s *Store) InsertPoll(ctx context.Context, arg pgstore.InsertPollParams) error {
+	_, err := s.exec(ctx, insertPoll,
+		arg.ID,
+		arg.RoomID,
+		arg.Question,
+		unixNano(arg.CreatedAt),
+	)
+	return err
+}
+
+const insertPollOption = `INSERT INTO poll_options
+    ( "poll_id", "position", "label" ) VALUES
+    ( $1, $2, $3 )`
+
+func (s *Store) InsertPollOption(ctx context.Context, arg pgstore.InsertPollOptionParams) error {
+	_, err := s.exec(ctx, insertPollOption, arg.PollID, arg.Position, arg.Label)
+	return err
+}
+
+const insertRoom = `INSERT INTO rooms
+    ( "id", "theme", "max_messages", "prune", "require_name", "expires_at", "created_at", "duplicate_threshold", "max_questions_per_participant", "require_approval", "closes_at", "description", "host_name", "starts_at", "code" ) VALUES
+    ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15 )
+RETURNING "id"`
+
+func (s *Store) InsertRoom(ctx context.Context, arg pgstore.InsertRoomParams) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := s.queryRow(ctx, insertRoom,
+		arg.ID,
+		arg.Theme,
+		arg.MaxMessages,
+		arg.Prune,
+		arg.RequireName,
+		nullUnixNano(arg.ExpiresAt),
+		unixNano(arg.CreatedAt),
+		arg.DuplicateThreshold,
+		arg.MaxQuestionsPerParticipant,
+		arg.RequireApproval,
+		nullUnixNano(arg.ClosesAt),
+		arg.Description,
+		arg.HostName,
+		nullUnixNano(arg.StartsAt),
+		arg.Code,
+	).Scan(&id)
+	return id, err
+}
+
+const insertWebhook = `INSERT INTO webhooks
+    ( "id", "room_id", "url", "secret", "created_at" ) VALUES
+    ( $1, $2, $3, $4, $5 )`
+
+func (s *Store) InsertWebhook(ctx context.Context, arg pgstore.InsertWebhookParams) error {
+	_, err := s.exec(ctx, insertWebhook,
+		arg.ID,
+		arg.RoomID,
+		arg.Url,
+		arg.Secret,
+		unixNano(arg.CreatedAt),
+	)
+	return err
+}
+
+const insertWebhookDeliveryFailure = `INSERT INTO webhook_delivery_failures
+    ( "id", "webhook_id", "event_kind", "attempts", "error", "failed_at" ) VALUES
+    ( $1, $2, $3, $4, $5, $6 )`
+
+func (s *Store) InsertWebhookDeliveryFailure(ctx context.Context, arg pgstore.InsertWebhookDeliveryFailureParams) error {
+	_, err := s.exec(ctx, insertWebhookDeliveryFailure,
+		uuid.New(),
+		arg.WebhookID,
+		arg.EventKind,
+		arg.Attempts,
+		arg.Error,
+		unixNano(arg.FailedAt),
+	)
+	return err
+}
+
+// A negative LIMIT means no limit in SQLite, where Postgres takes NULL.
+const listRooms = `SELECT
+    ` + roomColumns + `
+FROM rooms
+WHERE
+    instr(lower(theme), lower($1)) > 0
+    AND ($2 OR expires_at IS NULL OR expires_at > $3)
+    AND deleted_at IS NULL
+ORDER BY
+    CASE WHEN $4 THEN created_at END DESC,
+    created_at ASC,
+    id ASC
+LIMIT $5`
+
+func (s *Store) ListRooms(ctx context.Context, arg pgstore.ListRoomsParams) ([]pgstore.Room, error) {
+	limit := int64(arg.MaxResults)
+	if limit == 0 {
+		limit = -1
+	}
+	return queryAll(ctx, s, scanRoom, listRooms,
+		arg.ThemeQuery,
+		arg.IncludeExpired,
+		unixNano(arg.Now),
+		arg.NewestFirst,
+		limit,
+	)
+}
+
+const markMessageAsAnswered = `UPDATE messages
+SET
+    answered = 1,
+    answer = COALESCE($1, answer),
+    version = version + 1
+WHERE
+    id = $2
+    AND deleted_at IS NULL
+    AND ($3 IS NULL OR version = $3)
+RETURNING ` + messageColumns
+
+func (s *Store) MarkMessageAsAnswered(ctx context.Context, arg pgstore.MarkMessageAsAnsweredParams) (pgstore.Message, error) {
+	return scanMessage(s.queryRow(ctx, markMessageAsAnswered, arg.Answer, arg.ID, arg.ExpectedVersion))
+}
+
+const reactToMessage = `UPDATE messages
+SET
+    reaction_count = reaction_count + 1,
+    version = version + 1
+WHERE
+    id = $1
+RETURNING reaction_count`
+
+func (s *Store) ReactToMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	var count int64
+	err := s.queryRow(ctx, reactToMessage, id).Scan(&count)
+	return count, err
+}
+
+const recordWebhookDelivery = `UPDATE webhooks
+SET
+    last_delivery_at = $1,
+    last_status = $2,
+    last_error = $3
+WHERE
+    id = $4`
+
+func (s *Store) RecordWebhookDelivery(ctx context.Context, arg pgstore.RecordWebhookDeliveryParams) error {
+	_, err := s.exec(ctx, recordWebhookDelivery,
+		nullUnixNano(arg.LastDeliveryAt),
+		arg.LastStatus,
+		arg.LastError,
+		arg.ID,
+	)
+	return err
+}
+
+const removeReactionFromMessage = `UPDATE messages
+SET
+    reaction_count = MAX(reaction_count - 1, 0),
+    version = version + 1
+WHERE
+    id = $1
+RETURNING reaction_count`
+
+func (s *Store) RemoveReactionFromMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	var count int64
+	err := s.queryRow(ctx, removeReactionFromMessage, id).Scan(&count)
+	return count, err
+}
+
+const restoreMessage = `UPDATE messages
+SET
+    deleted_at = NULL,
+    deleted_by = NULL,
+    version = version + 1
+WHERE
+    id = $1
+    AND deleted_at IS NOT NULL
+RETURNING ` + messageColumns
+
+func (s *Store) RestoreMessage(ctx context.Context, id uuid.UUID) (pgstore.Message, error) {
+	return scanMessage(s.queryRow(ctx, restoreMessage, id))
+}
+
+const restoreRoom = `UPDATE rooms
+SET
+    deleted_at = NULL
+WHERE
+    id = $1
+    AND deleted_at > $2
+RETURNING ` + roomColumns
+
+func (s *Store) RestoreRoom(ctx context.Context, arg pgstore.RestoreRoomParams) (pgstore.Room, error) {
+	return scanRoom(s.queryRow(ctx, restoreRoom, arg.ID, nullUnixNano(arg.DeletedAfter)))
+}
+
+const searchRoomMessages = `SELECT
+    ` + messageColumns + `,
+    search_rank("message", $1) AS rank
+FROM messages
+WHERE
+    room_id = $2
+    AND pending = 0
+    AND search_rank("message", $1) IS NOT NULL
+    AND (deleted_at IS NULL OR $3)
+ORDER BY rank DESC, created_at ASC
+LIMIT $4 OFFSET $5`
+
+func (s *Store) SearchRoomMessages(ctx context.Context, arg pgstore.SearchRoomMessagesParams) ([]pgstore.SearchRoomMessagesRow, error) {
+	return queryAll(ctx, s, func(sc scanner) (pgstore.SearchRoomMessagesRow, error) {
+		var m pgstore.Message
+		var rank float32
+		err := sc.Scan(append(messageFields(&m), &rank)...)
+		return pgstore.SearchRoomMessagesRow{
+			ID:                 m.ID,
+			RoomID:             m.RoomID,
+			Message:            m.Message,
+			ReactionCount:      m.ReactionCount,
+			Answered:           m.Answered,
+			AuthorID:           m.AuthorID,
+			CreatedAt:          m.CreatedAt,
+			ConsentToPublish:   m.ConsentToPublish,
+			AuthorName:         m.AuthorName,
+			Language:           m.Language,
+			LanguageConfidence: m.LanguageConfidence,
+			Answer:             m.Answer,
+			Version:            m.Version,
+			DeletedAt:          m.DeletedAt,
+			DeletedBy:          m.DeletedBy,
+			Pending:            m.Pending,
+			Rank:               rank,
+		}, err
+	}, searchRoomMessages,
+		arg.Query,
+		arg.RoomID,
+		arg.IncludeDeleted,
+		arg.MaxResults,
+		arg.SkipResults,
+	)
+}
+
+const softDeleteMessage = `UPDATE messages
+SET
+    deleted_at = $1,
+    deleted_by = $2,
+    version = version + 1
+WHERE
+    id = $3
+    AND deleted_at IS NULL
+    AND ($4 IS NULL OR version = $4)
+RETURNING ` + messageColumns
+
+func (s *Store) SoftDeleteMessage(ctx context.Context, arg pgstore.SoftDeleteMessageParams) (pgstore.Message, error) {
+	return scanMessage(s.queryRow(ctx, softDeleteMessage, nullUnixNano(arg.DeletedAt), arg.DeletedBy, arg.ID, arg.ExpectedVersion))
+}
+
+const softDeleteRoom = `UPDATE rooms
+SET
+    deleted_at = $2
+WHERE
+    id = $1
+    AND deleted_at IS NULL
+RETURNING ` + roomColumns
+
+func (s *Store) SoftDeleteRoom(ctx context.Context, arg pgstore.SoftDeleteRoomParams) (pgstore.Room, error) {
+	return scanRoom(s.queryRow(ctx, softDeleteRoom, arg.ID, nullUnixNano(arg.DeletedAt)))
+}
+
+const updateMessage = `UPDATE messages
+SET
+    message = $1,
+    version = version + 1
+WHERE
+    id = $2
+    AND author_id = $3
+    AND answered = 0
+    AND deleted_at IS NULL
+    AND created_at > $4
+    AND ($5 IS NULL OR version = $5)
+RETURNING ` + messageColumns
+
+func (s *Store) UpdateMessage(ctx context.Context, arg pgstore.UpdateMessageParams) (pgstore.Message, error) {
+	return scanMessage(s.queryRow(ctx, updateMessage,
+		arg.Message,
+		arg.ID,
+		arg.AuthorID,
+		unixNano(arg.EditWindowStart),
+		arg.ExpectedVersion,
+	))
+}
+
+const updateMessageConsent = `UPDATE messages
+SET
+    consent_to_publish = $1,
+    version = version + 1
+WHERE
+    id = $2
+    AND author_id = $3
+RETURNING consent_to_publish`
+
+func (s *Store) UpdateMessageConsent(ctx context.Context, arg pgstore.UpdateMessageConsentParams) (bool, error) {
+	var consent bool
+	err := s.queryRow(ctx, updateMessageConsent, arg.ConsentToPublish, arg.ID, arg.AuthorID).Scan(&consent)
+	return consent, err
+}
+
+const updateMessageLanguage = `UPDATE messages
+SET
+    language = $1,
+    language_confidence = $2,
+    version = version + 1
+WHERE
+    id = $3
+    AND deleted_at IS NULL
+RETURNING ` + messageColumns
+
+func (s *Store) UpdateMessageLanguage(ctx context.Context, arg pgstore.UpdateMessageLanguageParams) (pgstore.Message, error) {
+	return scanMessage(s.queryRow(ctx, updateMessageLanguage, arg.Language, arg.LanguageConfidence, arg.ID))
+}
+
+const updateRoom = `UPDATE rooms
+SET
+    theme = $2,
+    max_messages = $3,
+    prune = $4,
+    require_name = $5,
+    expires_at = $6,
+    duplicate_threshold = $7,
+    max_questions_per_participant = $8,
+    require_approval = $9,
+    closes_at = $10,
+    description = $11,
+    host_name = $12,
+    starts_at = $13
+WHERE
+    id = $1
+    AND deleted_at IS NULL
+RETURNING ` + roomColumns
+
+func (s *Store) UpdateRoom(ctx context.Context, arg pgstore.UpdateRoomParams) (pgstore.Room, error) {
+	return scanRoom(s.queryRow(ctx, updateRoom,
+		arg.ID,
+		arg.Theme,
+		arg.MaxMessages,
+		arg.Prune,
+		arg.RequireName,
+		nullUnixNano(arg.ExpiresAt),
+		arg.DuplicateThreshold,
+		arg.MaxQuestionsPerParticipant,
+		arg.RequireApproval,
+		nullUnixNano(arg.ClosesAt),
+		arg.Description,
+		arg.HostName,
+		nullUnixNano(arg.StartsAt),
+	))
+}
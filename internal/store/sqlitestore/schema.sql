@@ -0,0 +1,178 @@
+-- The schema of pgstore's migrations, translated to SQLite. Timestamps are
+-- unix nanoseconds, uuids their text form and booleans 0 or 1.
+
+CREATE TABLE IF NOT EXISTS rooms (
+    "id"                    TEXT        PRIMARY KEY NOT NULL,
+    "theme"                 TEXT                    NOT NULL,
+    "max_messages"          INTEGER                 NOT NULL DEFAULT 0,
+    "prune"                 INTEGER                 NOT NULL DEFAULT 0,
+    "require_name"          INTEGER                 NOT NULL DEFAULT 0,
+    "created_at"            INTEGER                 NOT NULL,
+    "expires_at"            INTEGER,
+    "duplicate_threshold"   REAL                    NOT NULL DEFAULT 0,
+    "max_questions_per_participant" INTEGER         NOT NULL DEFAULT 0,
+    "require_approval"      INTEGER                 NOT NULL DEFAULT 0,
+    "closed_at"             INTEGER,
+    "closes_at"             INTEGER,
+    "deleted_at"            INTEGER,
+    "description"           TEXT                    NOT NULL DEFAULT '',
+    "host_name"             TEXT                    NOT NULL DEFAULT '',
+    "starts_at"             INTEGER,
+    "code"                  TEXT                    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rooms_expires_at_idx ON rooms (expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS rooms_closes_at_idx ON rooms (closes_at) WHERE closes_at IS NOT NULL AND closed_at IS NULL;
+CREATE INDEX IF NOT EXISTS rooms_deleted_at_idx ON rooms (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS rooms_code_idx ON rooms (code);
+
+CREATE TABLE IF NOT EXISTS messages (
+    "id"                    TEXT        PRIMARY KEY NOT NULL,
+    "room_id"               TEXT                    NOT NULL,
+    "message"               TEXT                    NOT NULL,
+    "reaction_count"        INTEGER                 NOT NULL DEFAULT 0,
+    "answered"              INTEGER                 NOT NULL DEFAULT 0,
+    "author_id"             TEXT                    NOT NULL DEFAULT '',
+    "created_at"            INTEGER                 NOT NULL,
+    "consent_to_publish"    INTEGER                 NOT NULL DEFAULT 0,
+    "author_name"           TEXT,
+    "language"              TEXT                    NOT NULL DEFAULT 'und',
+    "language_confidence"   REAL                    NOT NULL DEFAULT 0,
+    "answer"                TEXT,
+    "version"               INTEGER                 NOT NULL DEFAULT 1,
+    "deleted_at"            INTEGER,
+    "deleted_by"            TEXT,
+    "pending"               INTEGER                 NOT NULL DEFAULT 0,
+    FOREIGN KEY (room_id) REFERENCES rooms(id)
+);
+
+CREATE INDEX IF NOT EXISTS messages_room_id_created_at_idx ON messages (room_id, created_at, id);
+CREATE INDEX IF NOT EXISTS messages_room_id_pending_idx ON messages (room_id, created_at) WHERE pending = 1;
+
+CREATE TABLE IF NOT EXISTS message_reactions (
+    "message_id"    TEXT        NOT NULL,
+    "client_id"     TEXT        NOT NULL,
+    "created_at"    INTEGER     NOT NULL,
+    PRIMARY KEY (message_id, client_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS message_emoji_reactions (
+    "message_id"    TEXT        NOT NULL,
+    "client_id"     TEXT        NOT NULL,
+    "kind"          TEXT        NOT NULL,
+    "created_at"    INTEGER     NOT NULL,
+    PRIMARY KEY (message_id, client_id, kind),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    "id"                TEXT        PRIMARY KEY NOT NULL,
+    "room_id"           TEXT                    NOT NULL,
+    "url"               TEXT                    NOT NULL,
+    "secret"            TEXT                    NOT NULL,
+    "created_at"        INTEGER                 NOT NULL,
+    "last_delivery_at"  INTEGER,
+    "last_status"       INTEGER,
+    "last_error"        TEXT,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_failures (
+    "id"            TEXT        PRIMARY KEY NOT NULL,
+    "webhook_id"    TEXT                    NOT NULL,
+    "event_kind"    TEXT                    NOT NULL,
+    "attempts"      INTEGER                 NOT NULL,
+    "error"         TEXT                    NOT NULL,
+    "failed_at"     INTEGER                 NOT NULL,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS message_flags (
+    "message_id"    TEXT        NOT NULL,
+    "client_id"     TEXT        NOT NULL,
+    "reason"        TEXT        CHECK (reason IN ('spam', 'abuse', 'off_topic')),
+    "created_at"    INTEGER     NOT NULL,
+    PRIMARY KEY (message_id, client_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS message_edits (
+    "message_id"    TEXT        NOT NULL,
+    "version"       INTEGER     NOT NULL,
+    "message"       TEXT        NOT NULL,
+    "edited_at"     INTEGER     NOT NULL,
+    PRIMARY KEY (message_id, version),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS message_replies (
+    "id"            TEXT        PRIMARY KEY NOT NULL,
+    "message_id"    TEXT                    NOT NULL,
+    "author"        TEXT                    NOT NULL,
+    "body"          TEXT                    NOT NULL,
+    "created_at"    INTEGER                 NOT NULL,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS message_replies_message_id_created_at_idx ON message_replies (message_id, created_at, id);
+
+CREATE TABLE IF NOT EXISTS moderation_audit (
+    "id"                TEXT        PRIMARY KEY NOT NULL,
+    "room_id"           TEXT                    NOT NULL,
+    "message_id"        TEXT                    NOT NULL,
+    "actor"             TEXT                    NOT NULL,
+    "action"            TEXT                    NOT NULL CHECK (action IN ('delete', 'restore', 'answer', 'edit', 'approve', 'reject')),
+    "previous_value"    TEXT,
+    "created_at"        INTEGER                 NOT NULL,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS moderation_audit_room_id_created_at_idx ON moderation_audit (room_id, created_at);
+
+CREATE TABLE IF NOT EXISTS participant_questions (
+    "room_id"       TEXT        NOT NULL,
+    "participant"   TEXT        NOT NULL,
+    "questions"     INTEGER     NOT NULL DEFAULT 0,
+    PRIMARY KEY (room_id, participant),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS polls (
+    "id"            TEXT        PRIMARY KEY NOT NULL,
+    "room_id"       TEXT                    NOT NULL,
+    "question"      TEXT                    NOT NULL,
+    "created_at"    INTEGER                 NOT NULL,
+    "closed_at"     INTEGER,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS polls_room_id_created_at_idx ON polls (room_id, created_at);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    "poll_id"       TEXT        NOT NULL,
+    "position"      INTEGER     NOT NULL,
+    "label"         TEXT        NOT NULL,
+    PRIMARY KEY (poll_id, position),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    "poll_id"       TEXT        NOT NULL,
+    "client_id"     TEXT        NOT NULL,
+    "choice"        INTEGER     NOT NULL,
+    "created_at"    INTEGER     NOT NULL,
+    PRIMARY KEY (poll_id, client_id),
+    FOREIGN KEY (poll_id, choice) REFERENCES poll_options(poll_id, position) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS announcements (
+    "id"            TEXT        PRIMARY KEY NOT NULL,
+    "room_id"       TEXT                    NOT NULL,
+    "author"        TEXT                    NOT NULL,
+    "body"          TEXT                    NOT NULL,
+    "created_at"    INTEGER                 NOT NULL,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS announcements_room_id_created_at_idx ON announcements (room_id, created_at, id);
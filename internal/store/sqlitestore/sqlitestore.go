@@ -0,0 +1,426 @@
+// Package sqlitestore is a SQLite implementation of the store used by the api
+// package, for running the server as a single binary with its data in one
+// file.
+//
+// Like memstore it mirrors the behaviour of pgstore, including its errors:
+// missing rows are reported as pgx.ErrNoRows and constraint violations as
+// *pgconn.PgError with the matching SQLSTATE. The Postgres text matching the
+// queries rely on is approximated by the textsearch package.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+	"github.com/lohanguedes/AMA-Backend/internal/store/textsearch"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// schemaVersion is the version of schema, recorded in the database's
+// user_version so later changes can tell which databases need migrating.
+const schemaVersion = 14
+
+// upgrades bring the databases created by older servers to schemaVersion:
+// upgrades[v-1] migrates a database from version v to v+1. New databases are
+// created from schema directly.
+var upgrades = []string{
+	`ALTER TABLE rooms ADD COLUMN "max_questions_per_participant" INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS participant_questions (
+    "room_id"       TEXT        NOT NULL,
+    "participant"   TEXT        NOT NULL,
+    "questions"     INTEGER     NOT NULL DEFAULT 0,
+    PRIMARY KEY (room_id, participant),
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);`,
+	`ALTER TABLE rooms ADD COLUMN "require_approval" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN "pending" INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS messages_room_id_pending_idx ON messages (room_id, created_at) WHERE pending = 1;
+CREATE TABLE moderation_audit_v3 (
+    "id"                TEXT        PRIMARY KEY NOT NULL,
+    "room_id"           TEXT                    NOT NULL,
+    "message_id"        TEXT                    NOT NULL,
+    "actor"             TEXT                    NOT NULL,
+    "action"            TEXT                    NOT NULL CHECK (action IN ('delete', 'restore', 'answer', 'edit', 'approve', 'reject')),
+    "previous_value"    TEXT,
+    "created_at"        INTEGER                 NOT NULL,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+INSERT INTO moderation_audit_v3 SELECT id, room_id, message_id, actor, action, previous_value, created_at FROM moderation_audit;
+DROP TABLE moderation_audit;
+ALTER TABLE moderation_audit_v3 RENAME TO moderation_audit;
+CREATE INDEX IF NOT EXISTS moderation_audit_room_id_created_at_idx ON moderation_audit (room_id, created_at);`,
+	`CREATE TABLE IF NOT EXISTS message_edits (
+    "message_id"    TEXT        NOT NULL,
+    "version"       INTEGER     NOT NULL,
+    "message"       TEXT        NOT NULL,
+    "edited_at"     INTEGER     NOT NULL,
+    PRIMARY KEY (message_id, version),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);`,
+	`CREATE TABLE IF NOT EXISTS message_replies (
+    "id"            TEXT        PRIMARY KEY NOT NULL,
+    "message_id"    TEXT                    NOT NULL,
+    "author"        TEXT                    NOT NULL,
+    "body"          TEXT                    NOT NULL,
+    "created_at"    INTEGER                 NOT NULL,
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS message_replies_message_id_created_at_idx ON message_replies (message_id, created_at, id);`,
+	`CREATE TABLE IF NOT EXISTS message_emoji_reactions (
+    "message_id"    TEXT        NOT NULL,
+    "client_id"     TEXT        NOT NULL,
+    "kind"          TEXT        NOT NULL,
+    "created_at"    INTEGER     NOT NULL,
+    PRIMARY KEY (message_id, client_id, kind),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);`,
+	`CREATE TABLE IF NOT EXISTS polls (
+    "id"            TEXT        PRIMARY KEY NOT NULL,
+    "room_id"       TEXT                    NOT NULL,
+    "question"      TEXT                    NOT NULL,
+    "created_at"    INTEGER                 NOT NULL,
+    "closed_at"     INTEGER,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS polls_room_id_created_at_idx ON polls (room_id, created_at);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    "poll_id"       TEXT        NOT NULL,
+    "position"      INTEGER     NOT NULL,
+    "label"         TEXT        NOT NULL,
+    PRIMARY KEY (poll_id, position),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    "poll_id"       TEXT        NOT NULL,
+    "client_id"     TEXT        NOT NULL,
+    "choice"        INTEGER     NOT NULL,
+    "created_at"    INTEGER     NOT NULL,
+    PRIMARY KEY (poll_id, client_id),
+    FOREIGN KEY (poll_id, choice) REFERENCES poll_options(poll_id, position) ON DELETE CASCADE
+);`,
+	`CREATE TABLE IF NOT EXISTS announcements (
+    "id"            TEXT        PRIMARY KEY NOT NULL,
+    "room_id"       TEXT                    NOT NULL,
+    "author"        TEXT                    NOT NULL,
+    "body"          TEXT                    NOT NULL,
+    "created_at"    INTEGER                 NOT NULL,
+    FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS announcements_room_id_created_at_idx ON announcements (room_id, created_at, id);`,
+	`ALTER TABLE rooms ADD COLUMN "closed_at" INTEGER;`,
+	`ALTER TABLE rooms ADD COLUMN "closes_at" INTEGER;
+
+CREATE INDEX IF NOT EXISTS rooms_closes_at_idx ON rooms (closes_at) WHERE closes_at IS NOT NULL AND closed_at IS NULL;`,
+	`ALTER TABLE rooms ADD COLUMN "deleted_at" INTEGER;
+
+CREATE INDEX IF NOT EXISTS rooms_deleted_at_idx ON rooms (deleted_at) WHERE deleted_at IS NOT NULL;`,
+	`ALTER TABLE rooms ADD COLUMN "description" TEXT NOT NULL DEFAULT '';
+ALTER TABLE rooms ADD COLUMN "host_name" TEXT NOT NULL DEFAULT '';
+ALTER TABLE rooms ADD COLUMN "starts_at" INTEGER;`,
+	`ALTER TABLE rooms ADD COLUMN "code" TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS rooms_code_idx ON rooms (code);`,
+	// OR IGNORE skips the rooms whose new code is taken already, which the
+	// next passes retry: a third collision in a row is out of reach for the
+	// rooms a SQLite database holds. SQLite can't add NOT NULL to a column,
+	// triggers enforce it instead.
+	backfillRoomCodes + backfillRoomCodes + backfillRoomCodes + `
+CREATE TRIGGER IF NOT EXISTS rooms_code_insert_not_null BEFORE INSERT ON rooms WHEN NEW.code IS NULL
+BEGIN
+    SELECT RAISE(ABORT, 'NOT NULL constraint failed: rooms.code');
+END;
+CREATE TRIGGER IF NOT EXISTS rooms_code_update_not_null BEFORE UPDATE OF code ON rooms WHEN NEW.code IS NULL
+BEGIN
+    SELECT RAISE(ABORT, 'NOT NULL constraint failed: rooms.code');
+END;`,
+}
+
+// backfillRoomCodes gives the rooms created before codes a random one, drawn
+// from the alphabet of the api package's codes.
+const backfillRoomCodes = `UPDATE OR IGNORE rooms SET code =
+    substr('0123456789ABCDEFGHJKMNPQRSTVWXYZ', 1 + (random() & 31), 1) ||
+    substr('0123456789ABCDEFGHJKMNPQRSTVWXYZ', 1 + (random() & 31), 1) ||
+    substr('0123456789ABCDEFGHJKMNPQRSTVWXYZ', 1 + (random() & 31), 1) ||
+    substr('0123456789ABCDEFGHJKMNPQRSTVWXYZ', 1 + (random() & 31), 1) ||
+    substr('0123456789ABCDEFGHJKMNPQRSTVWXYZ', 1 + (random() & 31), 1) ||
+    substr('0123456789ABCDEFGHJKMNPQRSTVWXYZ', 1 + (random() & 31), 1)
+WHERE code IS NULL;
+`
+
+//go:embed schema.sql
+var schema string
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("similarity", 2, similarity)
+	sqlite.MustRegisterDeterministicScalarFunction("search_rank", 2, searchRank)
+}
+
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Store runs the store's queries against a SQLite database. Create one with
+// Open.
+type Store struct {
+	// db is nil when the store runs inside a transaction.
+	db *sql.DB
+	q  dbtx
+}
+
+var _ pgstore.Querier = (*Store)(nil)
+
+// Open opens the database at path, creating it and its schema when it
+// doesn't exist yet.
+func Open(ctx context.Context, path string) (*Store, error) {
+	params := url.Values{
+		"_pragma": {"foreign_keys(1)", "journal_mode(WAL)", "busy_timeout(5000)"},
+		"_txlock": {"immediate"},
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer: sharing one connection queues the writes
+	// here instead of failing them with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlitestore: creating schema: %w", err)
+	}
+	return &Store{db: db, q: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks that the database can still be used.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	switch {
+	case version == schemaVersion:
+		return nil
+	case version > schemaVersion:
+		return fmt.Errorf("database schema version %d is newer than this server's %d", version, schemaVersion)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if version == 0 {
+		if _, err := tx.ExecContext(ctx, schema); err != nil {
+			return err
+		}
+	} else {
+		for _, upgrade := range upgrades[version-1:] {
+			if _, err := tx.ExecContext(ctx, upgrade); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execTx runs fn inside a transaction, committing when it returns nil. When s
+// already runs inside a transaction fn joins it.
+func (s *Store) execTx(ctx context.Context, fn func(*Store) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return mapError(err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Store{q: tx}); err != nil {
+		return err
+	}
+	return mapError(tx.Commit())
+}
+
+// mapError translates the errors of database/sql and SQLite to the ones pgx
+// returns for the same failures.
+func mapError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return pgx.ErrNoRows
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return &pgconn.PgError{Code: "23505", Message: sqliteErr.Error()}
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return &pgconn.PgError{Code: "23503", Message: sqliteErr.Error()}
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		return &pgconn.PgError{Code: "23502", Message: sqliteErr.Error()}
+	case sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return &pgconn.PgError{Code: "23514", Message: sqliteErr.Error()}
+	}
+	return err
+}
+
+func (s *Store) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := s.q.ExecContext(ctx, query, args...)
+	return res, mapError(err)
+}
+
+func (s *Store) queryRow(ctx context.Context, query string, args ...any) row {
+	return row{s.q.QueryRowContext(ctx, query, args...)}
+}
+
+type row struct {
+	*sql.Row
+}
+
+func (r row) Scan(dest ...any) error {
+	return mapError(r.Row.Scan(dest...))
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// queryAll runs query and scans every row it returns with scan.
+func queryAll[T any](ctx context.Context, s *Store, scan func(scanner) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var items []T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, mapError(err)
+		}
+		items = append(items, item)
+	}
+	return items, mapError(rows.Err())
+}
+
+func scanID(sc scanner) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := sc.Scan(&id)
+	return id, err
+}
+
+// idList encodes ids for json_each, which stands in for Postgres arrays.
+func idList(ids []uuid.UUID) string {
+	if ids == nil {
+		ids = []uuid.UUID{}
+	}
+	b, err := json.Marshal(ids)
+	if err != nil {
+		panic("sqlitestore: encoding ids: " + err.Error())
+	}
+	return string(b)
+}
+
+func unixNano(t time.Time) int64 {
+	return t.UnixNano()
+}
+
+func nullUnixNano(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UnixNano()
+}
+
+// timestamp scans a time stored as unix nanoseconds.
+type timestamp struct {
+	t *time.Time
+}
+
+func (ts timestamp) Scan(src any) error {
+	n, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("sqlitestore: cannot scan %T into a timestamp", src)
+	}
+	*ts.t = time.Unix(0, n)
+	return nil
+}
+
+// nullTimestamp scans a nullable time stored as unix nanoseconds.
+type nullTimestamp struct {
+	t **time.Time
+}
+
+func (ts nullTimestamp) Scan(src any) error {
+	if src == nil {
+		*ts.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (timestamp{&t}).Scan(src); err != nil {
+		return err
+	}
+	*ts.t = &t
+	return nil
+}
+
+// similarity is pg_trgm's similarity(a, b).
+func similarity(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	a, aok := args[0].(string)
+	b, bok := args[1].(string)
+	if !aok || !bok {
+		return nil, nil
+	}
+	return float64(textsearch.Similarity(a, b)), nil
+}
+
+// searchRank ranks message against query, written in web search syntax, or
+// returns NULL when it doesn't match. It stands in for the tsvector match and
+// ts_rank of Postgres' full-text search.
+func searchRank(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	message, mok := args[0].(string)
+	query, qok := args[1].(string)
+	if !mok || !qok {
+		return nil, nil
+	}
+	rank, ok := textsearch.ParseQuery(query).Match(message)
+	if !ok {
+		return nil, nil
+	}
+	return float64(rank), nil
+}
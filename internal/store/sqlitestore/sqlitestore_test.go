@@ -0,0 +1,94 @@
+package sqlitestore_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/internal/store/sqlitestore"
+	"github.com/lohanguedes/AMA-Backend/internal/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) api.Store {
+		s, err := sqlitestore.Open(context.Background(), filepath.Join(t.TempDir(), "wsrs.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+// TestUpgradeRoomCodes checks that upgrading a database created before room
+// codes were required gives its rooms unique codes and refuses rooms without.
+func TestUpgradeRoomCodes(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "wsrs.db")
+
+	// Only the rooms table matters to the upgrade from version 13.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE TABLE rooms ("id" TEXT PRIMARY KEY NOT NULL, "code" TEXT);
+CREATE UNIQUE INDEX rooms_code_idx ON rooms (code);
+INSERT INTO rooms (id, code) VALUES ('a', NULL), ('b', NULL), ('c', NULL), ('d', 'ABCDEF');
+PRAGMA user_version = 13;`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := sqlitestore.Open(ctx, path)
+	if err != nil {
+		t.Fatalf("upgrading: %v", err)
+	}
+	s.Close()
+
+	db, err = sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT id, code FROM rooms ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	valid := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{6}$`)
+	seen := map[string]bool{}
+	for rows.Next() {
+		var id string
+		var code *string
+		if err := rows.Scan(&id, &code); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case code == nil:
+			t.Errorf("room %s has no code", id)
+		case !valid.MatchString(*code):
+			t.Errorf("room %s has code %q", id, *code)
+		case seen[*code]:
+			t.Errorf("room %s has code %q of another room", id, *code)
+		default:
+			seen[*code] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !seen["ABCDEF"] {
+		t.Error("the code of room d was replaced")
+	}
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO rooms (id) VALUES ('e')"); err == nil {
+		t.Error("inserting a room without a code succeeded")
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE rooms SET code = NULL WHERE id = 'a'"); err == nil {
+		t.Error("clearing the code of a room succeeded")
+	}
+}
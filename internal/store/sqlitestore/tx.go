@@ -0,0 +1,213 @@
+package sqlitestore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// InsertMessageWithinCapacity is pgstore's InsertMessageWithinCapacity: the
+// transaction holds the database's write lock, so concurrent posts can't push
+// the room over its limit or a participant over the question quota.
+func (s *Store) InsertMessageWithinCapacity(ctx context.Context, arg pgstore.InsertMessageWithinCapacityParams) (uuid.UUID, uuid.UUID, error) {
+	var id, pruned uuid.UUID
+	err := s.execTx(ctx, func(s *Store) error {
+		room, err := s.GetRoomForUpdate(ctx, arg.RoomID)
+		if err != nil {
+			return err
+		}
+		if room.ClosedAt != nil {
+			return pgstore.ErrRoomClosed
+		}
+
+		if room.MaxQuestionsPerParticipant > 0 && arg.Participant != "" {
+			asked, err := s.IncrementParticipantQuestions(ctx, pgstore.IncrementParticipantQuestionsParams{
+				RoomID:      arg.RoomID,
+				Participant: arg.Participant,
+			})
+			if err != nil {
+				return err
+			}
+			if asked > room.MaxQuestionsPerParticipant {
+				return pgstore.ErrQuestionQuotaReached
+			}
+		}
+
+		if room.MaxMessages > 0 {
+			count, err := s.CountRoomMessages(ctx, arg.RoomID)
+			if err != nil {
+				return err
+			}
+
+			if count >= int64(room.MaxMessages) {
+				if !room.Prune {
+					return pgstore.ErrRoomAtCapacity
+				}
+				pruned, err = s.DeleteOldestPrunableMessage(ctx, arg.RoomID)
+				if err != nil {
+					if errors.Is(err, pgx.ErrNoRows) {
+						return pgstore.ErrRoomAtCapacity
+					}
+					return err
+				}
+			}
+		}
+
+		id, err = s.InsertMessage(ctx, arg.InsertMessageParams)
+		return err
+	})
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return id, pruned, nil
+}
+
+// DeleteRoomWithMessages deletes a room together with all of its messages.
+func (s *Store) DeleteRoomWithMessages(ctx context.Context, id uuid.UUID) error {
+	return s.execTx(ctx, func(s *Store) error {
+		if err := s.DeleteRoomMessages(ctx, id); err != nil {
+			return err
+		}
+		return s.DeleteRoom(ctx, id)
+	})
+}
+
+// ApplyReactionBatch is pgstore's ApplyReactionBatch.
+func (s *Store) ApplyReactionBatch(ctx context.Context, arg pgstore.ApplyReactionBatchParams) ([]pgstore.GetReactionCountsRow, error) {
+	ids := make([]uuid.UUID, 0, len(arg.Add)+len(arg.Remove))
+	ids = append(append(ids, arg.Add...), arg.Remove...)
+
+	var counts []pgstore.GetReactionCountsRow
+	err := s.execTx(ctx, func(s *Store) error {
+		found, err := s.GetRoomMessageIDs(ctx, pgstore.GetRoomMessageIDsParams{RoomID: arg.RoomID, Ids: ids})
+		if err != nil {
+			return err
+		}
+		known := make(map[uuid.UUID]struct{}, len(found))
+		for _, id := range found {
+			known[id] = struct{}{}
+		}
+		var unknown []uuid.UUID
+		for _, id := range ids {
+			if _, ok := known[id]; !ok {
+				unknown = append(unknown, id)
+			}
+		}
+		if len(unknown) > 0 {
+			return &pgstore.UnknownMessagesError{IDs: unknown}
+		}
+
+		if len(arg.Add) > 0 {
+			added, err := s.InsertClientReactions(ctx, pgstore.InsertClientReactionsParams{MessageIds: arg.Add, ClientID: arg.ClientID})
+			if err != nil {
+				return err
+			}
+			if err := s.IncrementReactionCounts(ctx, added); err != nil {
+				return err
+			}
+		}
+		if len(arg.Remove) > 0 {
+			removed, err := s.DeleteClientReactions(ctx, pgstore.DeleteClientReactionsParams{ClientID: arg.ClientID, MessageIds: arg.Remove})
+			if err != nil {
+				return err
+			}
+			if err := s.DecrementReactionCounts(ctx, removed); err != nil {
+				return err
+			}
+		}
+
+		counts, err = s.GetReactionCounts(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// InsertRoomWithWebhooks inserts a room together with its webhooks.
+func (s *Store) InsertRoomWithWebhooks(ctx context.Context, room pgstore.InsertRoomParams, webhooks []pgstore.InsertWebhookParams) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := s.execTx(ctx, func(s *Store) error {
+		var err error
+		id, err = s.InsertRoom(ctx, room)
+		if err != nil {
+			return err
+		}
+		for _, webhook := range webhooks {
+			webhook.RoomID = id
+			if err := s.InsertWebhook(ctx, webhook); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// UpdateRoomWebhooks is pgstore's UpdateRoomWebhooks, where the transaction
+// holding the write lock keeps concurrent updates from exceeding Max.
+func (s *Store) UpdateRoomWebhooks(ctx context.Context, arg pgstore.UpdateRoomWebhooksParams) ([]pgstore.Webhook, error) {
+	var hooks []pgstore.Webhook
+	err := s.execTx(ctx, func(s *Store) error {
+		if _, err := s.GetRoomForUpdate(ctx, arg.RoomID); err != nil {
+			return err
+		}
+		for _, id := range arg.Remove {
+			deleted, err := s.DeleteRoomWebhook(ctx, pgstore.DeleteRoomWebhookParams{ID: id, RoomID: arg.RoomID})
+			if err != nil {
+				return err
+			}
+			if deleted == 0 {
+				return pgx.ErrNoRows
+			}
+		}
+		for _, webhook := range arg.Add {
+			webhook.RoomID = arg.RoomID
+			if err := s.InsertWebhook(ctx, webhook); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		hooks, err = s.GetRoomWebhooks(ctx, arg.RoomID)
+		if err != nil {
+			return err
+		}
+		if len(hooks) > arg.Max {
+			return pgstore.ErrTooManyWebhooks
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// InsertPollWithOptions inserts a poll together with its options, numbered
+// from 0 in order.
+func (s *Store) InsertPollWithOptions(ctx context.Context, poll pgstore.InsertPollParams, options []string) error {
+	return s.execTx(ctx, func(s *Store) error {
+		if err := s.InsertPoll(ctx, poll); err != nil {
+			return err
+		}
+		for i, label := range options {
+			err := s.InsertPollOption(ctx, pgstore.InsertPollOptionParams{
+				PollID:   poll.ID,
+				Position: int32(i),
+				Label:    label,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,551 @@
+// Package storetest is the conformance suite of the stores the api package
+// runs on. Every store runs it from its own tests, so memstore and sqlitestore
+// keep behaving like pgstore where the handlers rely on it: the errors they
+// return, the order of their listings and the limits they enforce.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lohanguedes/AMA-Backend/internal/api"
+	"github.com/lohanguedes/AMA-Backend/internal/store/pgstore"
+)
+
+// start is the creation time of the first room and message of every test.
+// It is rounded to the microseconds Postgres keeps.
+var start = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+// Run runs the suite against the stores returned by open, which returns an
+// empty store every time it is called.
+func Run(t *testing.T, open func(t *testing.T) api.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s api.Store)
+	}{
+		{"MissingRows", testMissingRows},
+		{"UniqueViolations", testUniqueViolations},
+		{"ForeignKeyViolations", testForeignKeyViolations},
+		{"MessagesPage", testMessagesPage},
+		{"Capacity", testCapacity},
+		{"CapacityPrune", testCapacityPrune},
+		{"CapacityDeleted", testCapacityDeleted},
+		{"CapacityPending", testCapacityPending},
+		{"QuestionQuota", testQuestionQuota},
+		{"ClosedRoom", testClosedRoom},
+		{"DuplicateFlags", testDuplicateFlags},
+		{"ReactionBatch", testReactionBatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+// id returns the uuid ending in n, so ids sort in the order of n.
+func id(n byte) uuid.UUID {
+	var id uuid.UUID
+	id[15] = n
+	return id
+}
+
+func insertRoom(t *testing.T, s api.Store, arg pgstore.InsertRoomParams) uuid.UUID {
+	t.Helper()
+	if arg.Theme == "" {
+		arg.Theme = "room"
+	}
+	if arg.CreatedAt.IsZero() {
+		arg.CreatedAt = start
+	}
+	if arg.Code == "" {
+		// Codes are unique: each room gets one made of its id.
+		arg.Code = fmt.Sprintf("R%05d", arg.ID[15])
+	}
+	id, err := s.InsertRoom(context.Background(), arg)
+	if err != nil {
+		t.Fatalf("inserting room: %v", err)
+	}
+	return id
+}
+
+func insertMessage(t *testing.T, s api.Store, arg pgstore.InsertMessageParams) uuid.UUID {
+	t.Helper()
+	if arg.Message == "" {
+		arg.Message = "question " + arg.ID.String()
+	}
+	if arg.CreatedAt.IsZero() {
+		arg.CreatedAt = start
+	}
+	id, err := s.InsertMessage(context.Background(), arg)
+	if err != nil {
+		t.Fatalf("inserting message: %v", err)
+	}
+	return id
+}
+
+// sqlState returns the SQLSTATE of err, or "" when it isn't a *pgconn.PgError.
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// testMissingRows checks that reading or changing rows that don't exist
+// returns pgx.ErrNoRows, which the api package reports as not found.
+func testMissingRows(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1)})
+	message := insertMessage(t, s, pgstore.InsertMessageParams{ID: id(2), RoomID: room})
+	code := "ZZZZZZ"
+	stale := int64(7)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetRoom", func() error {
+			_, err := s.GetRoom(ctx, id(99))
+			return err
+		}},
+		{"GetRoomIDByCode", func() error {
+			_, err := s.GetRoomIDByCode(ctx, code)
+			return err
+		}},
+		{"GetMessage", func() error {
+			_, err := s.GetMessage(ctx, id(99))
+			return err
+		}},
+		{"GetPoll", func() error {
+			_, err := s.GetPoll(ctx, id(99))
+			return err
+		}},
+		{"GetMessageReply", func() error {
+			_, err := s.GetMessageReply(ctx, id(99))
+			return err
+		}},
+		{"GetAnnouncement", func() error {
+			_, err := s.GetAnnouncement(ctx, id(99))
+			return err
+		}},
+		{"CloseRoom", func() error {
+			at := start
+			_, err := s.CloseRoom(ctx, pgstore.CloseRoomParams{ID: id(99), ClosedAt: &at})
+			return err
+		}},
+		{"SoftDeleteMessage", func() error {
+			at := start
+			_, err := s.SoftDeleteMessage(ctx, pgstore.SoftDeleteMessageParams{ID: id(99), DeletedAt: &at})
+			return err
+		}},
+		{"MarkMessageAsAnsweredStaleVersion", func() error {
+			_, err := s.MarkMessageAsAnswered(ctx, pgstore.MarkMessageAsAnsweredParams{ID: message, ExpectedVersion: &stale})
+			return err
+		}},
+		{"SoftDeleteMessageStaleVersion", func() error {
+			at := start
+			_, err := s.SoftDeleteMessage(ctx, pgstore.SoftDeleteMessageParams{ID: message, DeletedAt: &at, ExpectedVersion: &stale})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, pgx.ErrNoRows) {
+				t.Errorf("got error %v, want pgx.ErrNoRows", err)
+			}
+		})
+	}
+}
+
+// testUniqueViolations checks that duplicate keys are reported as unique
+// violations (23505), which the api package reports as conflicts.
+func testUniqueViolations(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	code := "ABCDEF"
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1), Code: code})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(2), RoomID: room})
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"RoomID", func() error {
+			_, err := s.InsertRoom(ctx, pgstore.InsertRoomParams{ID: room, Theme: "again", CreatedAt: start})
+			return err
+		}},
+		{"RoomCode", func() error {
+			_, err := s.InsertRoom(ctx, pgstore.InsertRoomParams{ID: id(3), Theme: "again", CreatedAt: start, Code: code})
+			return err
+		}},
+		{"MessageID", func() error {
+			_, err := s.InsertMessage(ctx, pgstore.InsertMessageParams{ID: id(2), RoomID: room, Message: "again", CreatedAt: start})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); sqlState(err) != "23505" {
+				t.Errorf("got error %v, want a unique violation", err)
+			}
+		})
+	}
+}
+
+// testForeignKeyViolations checks that rows referring to missing ones are
+// refused with a foreign key violation (23503).
+func testForeignKeyViolations(t *testing.T, s api.Store) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"MessageOfMissingRoom", func() error {
+			_, err := s.InsertMessage(ctx, pgstore.InsertMessageParams{ID: id(1), RoomID: id(99), Message: "question", CreatedAt: start})
+			return err
+		}},
+		{"FlagOfMissingMessage", func() error {
+			_, err := s.InsertMessageFlag(ctx, pgstore.InsertMessageFlagParams{MessageID: id(99), ClientID: "client", CreatedAt: start})
+			return err
+		}},
+		{"AnnouncementOfMissingRoom", func() error {
+			return s.InsertAnnouncement(ctx, pgstore.InsertAnnouncementParams{ID: id(1), RoomID: id(99), Author: "host", Body: "hello", CreatedAt: start})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); sqlState(err) != "23503" {
+				t.Errorf("got error %v, want a foreign key violation", err)
+			}
+		})
+	}
+}
+
+// testMessagesPage checks that pages of messages are ordered by creation time
+// then id, start after the cursor, and leave out pending and, unless asked
+// for, deleted messages.
+func testMessagesPage(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1)})
+	other := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(2)})
+
+	// Inserted out of order, with 12 and 13 created at the same time.
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(13), RoomID: room, CreatedAt: start.Add(time.Second)})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(14), RoomID: room, CreatedAt: start.Add(2 * time.Second)})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(12), RoomID: room, CreatedAt: start.Add(time.Second)})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(11), RoomID: room, CreatedAt: start})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(15), RoomID: room, CreatedAt: start.Add(3 * time.Second), Pending: true})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(16), RoomID: room, CreatedAt: start.Add(4 * time.Second)})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(21), RoomID: other, CreatedAt: start})
+	deletedAt := start.Add(time.Minute)
+	if _, err := s.SoftDeleteMessage(ctx, pgstore.SoftDeleteMessageParams{ID: id(14), DeletedAt: &deletedAt}); err != nil {
+		t.Fatal(err)
+	}
+
+	page := func(after pgstore.Message, includeDeleted bool, max int32) []uuid.UUID {
+		t.Helper()
+		messages, err := s.GetRoomMessagesPage(ctx, pgstore.GetRoomMessagesPageParams{
+			RoomID:         room,
+			AfterCreatedAt: after.CreatedAt,
+			AfterID:        after.ID,
+			IncludeDeleted: includeDeleted,
+			MaxResults:     max,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]uuid.UUID, 0, len(messages))
+		for _, m := range messages {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name           string
+		after          pgstore.Message
+		includeDeleted bool
+		max            int32
+		want           []uuid.UUID
+	}{
+		{"FirstPage", pgstore.Message{}, false, 2, []uuid.UUID{id(11), id(12)}},
+		{"AfterTie", pgstore.Message{ID: id(12), CreatedAt: start.Add(time.Second)}, false, 2, []uuid.UUID{id(13), id(16)}},
+		{"LastPage", pgstore.Message{ID: id(13), CreatedAt: start.Add(time.Second)}, false, 10, []uuid.UUID{id(16)}},
+		{"IncludeDeleted", pgstore.Message{ID: id(13), CreatedAt: start.Add(time.Second)}, true, 10, []uuid.UUID{id(14), id(16)}},
+		{"PastTheEnd", pgstore.Message{ID: id(16), CreatedAt: start.Add(4 * time.Second)}, true, 10, []uuid.UUID{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := page(tt.after, tt.includeDeleted, tt.max)
+			if !equalIDs(got, tt.want) {
+				t.Errorf("got page %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func insertWithinCapacity(s api.Store, roomID uuid.UUID, n byte, participant string) (uuid.UUID, uuid.UUID, error) {
+	return s.InsertMessageWithinCapacity(context.Background(), pgstore.InsertMessageWithinCapacityParams{
+		InsertMessageParams: pgstore.InsertMessageParams{
+			ID:        id(n),
+			RoomID:    roomID,
+			Message:   "question",
+			CreatedAt: start.Add(time.Duration(n) * time.Second),
+		},
+		Participant: participant,
+	})
+}
+
+// testCapacity checks that a full room without pruning refuses messages.
+func testCapacity(t *testing.T, s api.Store) {
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1), MaxMessages: 2})
+
+	for n := byte(10); n < 12; n++ {
+		if _, pruned, err := insertWithinCapacity(s, room, n, ""); err != nil || pruned != uuid.Nil {
+			t.Fatalf("inserting message %d: pruned %v, error %v", n, pruned, err)
+		}
+	}
+	if _, _, err := insertWithinCapacity(s, room, 12, ""); !errors.Is(err, pgstore.ErrRoomAtCapacity) {
+		t.Errorf("got error %v, want ErrRoomAtCapacity", err)
+	}
+	if _, err := s.GetMessage(context.Background(), id(12)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("refused message was inserted: %v", err)
+	}
+}
+
+// testCapacityPrune checks that a full room with pruning deletes its oldest
+// unanswered message without reactions to make space, and refuses messages
+// when there is none.
+func testCapacityPrune(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1), MaxMessages: 3, Prune: true})
+
+	for n := byte(10); n < 13; n++ {
+		if _, _, err := insertWithinCapacity(s, room, n, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 10 is answered and 11 has a reaction, so 12 is the one to prune.
+	if _, err := s.MarkMessageAsAnswered(ctx, pgstore.MarkMessageAsAnsweredParams{ID: id(10)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReactToMessage(ctx, id(11)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, pruned, err := insertWithinCapacity(s, room, 13, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != id(12) {
+		t.Errorf("pruned %v, want %v", pruned, id(12))
+	}
+	if count, err := s.CountRoomMessages(ctx, room); err != nil || count != 3 {
+		t.Errorf("room has %d messages (error %v), want 3", count, err)
+	}
+
+	// 13 is the only prunable one left; once answered there is none.
+	if _, err := s.MarkMessageAsAnswered(ctx, pgstore.MarkMessageAsAnsweredParams{ID: id(13)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := insertWithinCapacity(s, room, 14, ""); !errors.Is(err, pgstore.ErrRoomAtCapacity) {
+		t.Errorf("got error %v, want ErrRoomAtCapacity", err)
+	}
+}
+
+// testCapacityDeleted checks that deleted messages don't take room capacity
+// and are never pruned.
+func testCapacityDeleted(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1), MaxMessages: 2, Prune: true})
+	deletedAt := start.Add(time.Minute)
+	for n := byte(10); n < 12; n++ {
+		if _, _, err := insertWithinCapacity(s, room, n, ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.SoftDeleteMessage(ctx, pgstore.SoftDeleteMessageParams{ID: id(n), DeletedAt: &deletedAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n := byte(12); n < 14; n++ {
+		if _, pruned, err := insertWithinCapacity(s, room, n, ""); err != nil || pruned != uuid.Nil {
+			t.Fatalf("inserting message %d: pruned %v, error %v", n, pruned, err)
+		}
+	}
+	if count, err := s.CountRoomMessages(ctx, room); err != nil || count != 2 {
+		t.Errorf("room has %d messages (error %v), want 2", count, err)
+	}
+	// The room is full: the oldest live message is pruned, not a deleted one.
+	if _, pruned, err := insertWithinCapacity(s, room, 14, ""); err != nil || pruned != id(12) {
+		t.Errorf("pruned %v (error %v), want %v", pruned, err, id(12))
+	}
+	if m, err := s.GetMessage(ctx, id(10)); err != nil || m.DeletedAt == nil {
+		t.Errorf("deleted message: got %+v (error %v), want it kept deleted", m, err)
+	}
+}
+
+// testCapacityPending checks that messages waiting for approval take room
+// capacity but are never pruned.
+func testCapacityPending(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1), MaxMessages: 2, Prune: true})
+	insertMessage(t, s, pgstore.InsertMessageParams{ID: id(10), RoomID: room, CreatedAt: start, Pending: true})
+	if _, _, err := insertWithinCapacity(s, room, 11, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, pruned, err := insertWithinCapacity(s, room, 12, ""); err != nil || pruned != id(11) {
+		t.Fatalf("pruned %v (error %v), want %v", pruned, err, id(11))
+	}
+	if _, err := s.MarkMessageAsAnswered(ctx, pgstore.MarkMessageAsAnsweredParams{ID: id(12)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := insertWithinCapacity(s, room, 13, ""); !errors.Is(err, pgstore.ErrRoomAtCapacity) {
+		t.Errorf("got error %v, want ErrRoomAtCapacity with only a pending message to prune", err)
+	}
+	if _, err := s.GetMessage(ctx, id(10)); err != nil {
+		t.Errorf("pending message: %v", err)
+	}
+}
+
+// testQuestionQuota checks that participants can't ask more questions than
+// the room allows, and that messages without a participant aren't counted.
+func testQuestionQuota(t *testing.T, s api.Store) {
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1), MaxQuestionsPerParticipant: 2})
+
+	for n := byte(10); n < 12; n++ {
+		if _, _, err := insertWithinCapacity(s, room, n, "client:a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := insertWithinCapacity(s, room, 12, "client:a"); !errors.Is(err, pgstore.ErrQuestionQuotaReached) {
+		t.Errorf("got error %v, want ErrQuestionQuotaReached", err)
+	}
+	if _, _, err := insertWithinCapacity(s, room, 13, "client:b"); err != nil {
+		t.Errorf("other participant: %v", err)
+	}
+	if _, _, err := insertWithinCapacity(s, room, 14, ""); err != nil {
+		t.Errorf("no participant: %v", err)
+	}
+}
+
+// testClosedRoom checks that closed rooms take no more messages.
+func testClosedRoom(t *testing.T, s api.Store) {
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1)})
+	closedAt := start.Add(time.Minute)
+	closed, err := s.CloseRoom(context.Background(), pgstore.CloseRoomParams{ID: room, ClosedAt: &closedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.ClosedAt == nil || !closed.ClosedAt.Equal(closedAt) {
+		t.Errorf("got closed_at %v, want %v", closed.ClosedAt, closedAt)
+	}
+	if _, _, err := insertWithinCapacity(s, room, 10, ""); !errors.Is(err, pgstore.ErrRoomClosed) {
+		t.Errorf("got error %v, want ErrRoomClosed", err)
+	}
+}
+
+// testDuplicateFlags checks that a client flagging a message twice is only
+// counted once.
+func testDuplicateFlags(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1)})
+	message := insertMessage(t, s, pgstore.InsertMessageParams{ID: id(2), RoomID: room})
+
+	for i, want := range []int64{1, 0} {
+		inserted, err := s.InsertMessageFlag(ctx, pgstore.InsertMessageFlagParams{MessageID: message, ClientID: "client", CreatedAt: start})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inserted != want {
+			t.Errorf("flag %d: inserted %d rows, want %d", i+1, inserted, want)
+		}
+	}
+	if count, err := s.CountMessageFlags(ctx, message); err != nil || count != 1 {
+		t.Errorf("message has %d flags (error %v), want 1", count, err)
+	}
+}
+
+// testReactionBatch checks that batches are applied all at once or not at
+// all, that a client reacts to a message at most once, and that messages
+// waiting for approval or deleted can't be reacted to.
+func testReactionBatch(t *testing.T, s api.Store) {
+	ctx := context.Background()
+	room := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(1)})
+	other := insertRoom(t, s, pgstore.InsertRoomParams{ID: id(2)})
+	first := insertMessage(t, s, pgstore.InsertMessageParams{ID: id(10), RoomID: room})
+	second := insertMessage(t, s, pgstore.InsertMessageParams{ID: id(11), RoomID: room})
+	elsewhere := insertMessage(t, s, pgstore.InsertMessageParams{ID: id(20), RoomID: other})
+	pending := insertMessage(t, s, pgstore.InsertMessageParams{ID: id(12), RoomID: room, Pending: true})
+	deleted := insertMessage(t, s, pgstore.InsertMessageParams{ID: id(13), RoomID: room})
+	if _, err := s.SoftDeleteMessage(ctx, pgstore.SoftDeleteMessageParams{ID: deleted, DeletedAt: &start}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.ApplyReactionBatch(ctx, pgstore.ApplyReactionBatchParams{
+		RoomID:   room,
+		ClientID: "client",
+		Add:      []uuid.UUID{first, elsewhere},
+	})
+	var unknown *pgstore.UnknownMessagesError
+	if !errors.As(err, &unknown) || !equalIDs(unknown.IDs, []uuid.UUID{elsewhere}) {
+		t.Fatalf("got error %v, want the unknown id %v", err, elsewhere)
+	}
+	if m, err := s.GetMessage(ctx, first); err != nil || m.ReactionCount != 0 {
+		t.Errorf("failed batch was applied: reaction_count %d, error %v", m.ReactionCount, err)
+	}
+
+	_, err = s.ApplyReactionBatch(ctx, pgstore.ApplyReactionBatchParams{
+		RoomID:   room,
+		ClientID: "client",
+		Add:      []uuid.UUID{pending, deleted},
+	})
+	if !errors.As(err, &unknown) || !equalIDs(unknown.IDs, []uuid.UUID{pending, deleted}) {
+		t.Fatalf("got error %v, want the pending and deleted ids %v and %v unknown", err, pending, deleted)
+	}
+
+	for range 2 {
+		if _, err := s.ApplyReactionBatch(ctx, pgstore.ApplyReactionBatchParams{
+			RoomID:   room,
+			ClientID: "client",
+			Add:      []uuid.UUID{first, second},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts, err := s.ApplyReactionBatch(ctx, pgstore.ApplyReactionBatchParams{
+		RoomID:   room,
+		ClientID: "client",
+		Remove:   []uuid.UUID{second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts[0].ID != second || counts[0].ReactionCount != 0 {
+		t.Errorf("got counts %+v, want %v at 0", counts, second)
+	}
+	if m, err := s.GetMessage(ctx, first); err != nil || m.ReactionCount != 1 {
+		t.Errorf("got reaction_count %d (error %v), want 1", m.ReactionCount, err)
+	}
+}
@@ -0,0 +1,157 @@
+// Package textsearch approximates the Postgres text matching the stores
+// without Postgres rely on: pg_trgm similarity and websearch_to_tsquery
+// full-text search.
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// words splits s into lower-cased runs of letters and digits.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Trigrams is a set of trigrams, as returned by ExtractTrigrams.
+type Trigrams map[string]struct{}
+
+// ExtractTrigrams returns the set of trigrams of s the way pg_trgm extracts
+// them: every word is padded with two spaces in front and one behind.
+func ExtractTrigrams(s string) Trigrams {
+	set := make(Trigrams)
+	for _, word := range words(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// TrigramSimilarity is pg_trgm's similarity(): the share of trigrams the two
+// sets have in common.
+func TrigramSimilarity(a, b Trigrams) float32 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			shared++
+		}
+	}
+	return float32(shared) / float32(len(a)+len(b)-shared)
+}
+
+// Similarity is TrigramSimilarity of the trigrams of a and b.
+func Similarity(a, b string) float32 {
+	return TrigramSimilarity(ExtractTrigrams(a), ExtractTrigrams(b))
+}
+
+// Query approximates websearch_to_tsquery: a message matches when it
+// contains every term of any one alternative ("or") and none of the excluded
+// ("-word") terms. Quoted phrases must appear as consecutive words.
+type Query struct {
+	alternatives [][][]string
+	excluded     [][]string
+}
+
+// ParseQuery parses q written in web search syntax.
+func ParseQuery(q string) Query {
+	var (
+		query   Query
+		current [][]string
+	)
+	for _, token := range tokenizeSearch(q) {
+		switch {
+		case !token.quoted && strings.EqualFold(token.text, "or"):
+			if len(current) > 0 {
+				query.alternatives = append(query.alternatives, current)
+				current = nil
+			}
+		case !token.quoted && strings.HasPrefix(token.text, "-"):
+			if phrase := words(token.text); len(phrase) > 0 {
+				query.excluded = append(query.excluded, phrase)
+			}
+		default:
+			if phrase := words(token.text); len(phrase) > 0 {
+				current = append(current, phrase)
+			}
+		}
+	}
+	if len(current) > 0 {
+		query.alternatives = append(query.alternatives, current)
+	}
+	return query
+}
+
+type searchToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeSearch(q string) []searchToken {
+	var tokens []searchToken
+	for i, part := range strings.Split(q, `"`) {
+		// Odd parts sit between a pair of quotes.
+		if i%2 == 1 {
+			tokens = append(tokens, searchToken{text: part, quoted: true})
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			tokens = append(tokens, searchToken{text: field})
+		}
+	}
+	return tokens
+}
+
+// Match reports whether message matches the query and ranks it by how often
+// the matched terms occur relative to its length, like ts_rank.
+func (q Query) Match(message string) (float32, bool) {
+	text := words(message)
+	for _, phrase := range q.excluded {
+		if countPhrase(text, phrase) > 0 {
+			return 0, false
+		}
+	}
+
+	var best float32
+	matched := false
+	for _, terms := range q.alternatives {
+		hits := 0
+		for _, phrase := range terms {
+			n := countPhrase(text, phrase)
+			if n == 0 {
+				hits = 0
+				break
+			}
+			hits += n
+		}
+		if hits == 0 {
+			continue
+		}
+		matched = true
+		best = max(best, float32(hits)/float32(len(text)))
+	}
+	return best, matched
+}
+
+func countPhrase(text, phrase []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(text); i++ {
+		found := true
+		for j, word := range phrase {
+			if text[i+j] != word {
+				found = false
+				break
+			}
+		}
+		if found {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,62 @@
+package textsearch
+
+import "testing"
+
+func TestQueryMatch(t *testing.T) {
+	tests := []struct {
+		query   string
+		message string
+		match   bool
+	}{
+		{"deploy", "How do you deploy on Fridays?", true},
+		{"DEPLOY", "how do you deploy", true},
+		{"deploy friday", "How do you deploy on Fridays?", false},
+		{"deploy fridays", "How do you deploy on Fridays?", true},
+		{"deploy rollback", "How do you deploy?", false},
+		{`"you deploy"`, "How do you deploy?", true},
+		{`"deploy you"`, "How do you deploy?", false},
+		{"rollback or deploy", "How do you deploy?", true},
+		{"rollback or canary", "How do you deploy?", false},
+		{"deploy -friday", "Do you deploy on friday?", false},
+		{"deploy -friday", "Do you deploy on monday?", true},
+		{"-friday", "Do you deploy?", false},
+		{"   ", "Do you deploy?", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query+"/"+tt.message, func(t *testing.T) {
+			if _, match := ParseQuery(tt.query).Match(tt.message); match != tt.match {
+				t.Errorf("got match %v, want %v", match, tt.match)
+			}
+		})
+	}
+}
+
+func TestQueryRank(t *testing.T) {
+	q := ParseQuery("deploy")
+	dense, _ := q.Match("deploy deploy now")
+	sparse, _ := q.Match("when do we deploy to production")
+	if dense <= sparse {
+		t.Errorf("got rank %v for the denser match and %v for the sparser one, want it higher", dense, sparse)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		min, max float32
+	}{
+		{"How do we deploy?", "how do we deploy", 1, 1},
+		{"How do we deploy?", "How do we deploy on Fridays?", 0.5, 0.99},
+		{"deploy", "kubernetes", 0, 0},
+		{"", "deploy", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %v, want between %v and %v", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+	// pg_trgm's similarity("word", "words") is 4/7.
+	if got := Similarity("word", "words"); got != float32(4)/7 {
+		t.Errorf("Similarity(word, words) = %v, want 4/7", got)
+	}
+}
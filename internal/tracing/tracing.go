@@ -0,0 +1,93 @@
+// Package tracing sets up OpenTelemetry tracing for the server and traces
+// the queries pgx runs against Postgres.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the service the spans are reported under, unless
+// OTEL_SERVICE_NAME says otherwise.
+const ServiceName = "wsrs"
+
+const instrumentation = "github.com/lohanguedes/AMA-Backend/internal/tracing"
+
+// Setup exports the spans of the process over OTLP/HTTP and propagates W3C
+// trace context. The exporter is configured with the standard
+// OTEL_EXPORTER_OTLP_* variables. The returned function flushes the pending
+// spans and must be called before exiting.
+func Setup(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(
+		resource.NewSchemaless(semconv.ServiceName(ServiceName)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return provider.Shutdown, nil
+}
+
+// QueryTracer is a pgx.QueryTracer recording a span for every query. Spans
+// are named after the sqlc query, read from the "-- name:" comment sqlc
+// starts its queries with.
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = otel.Tracer(instrumentation).Start(ctx, queryName(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.String("db.statement", data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// queryName returns the name sqlc gave sql, or "query" for the statements
+// written by hand.
+func queryName(sql string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(sql), "\n")
+	rest, ok := strings.CutPrefix(line, "-- name: ")
+	if !ok {
+		return "query"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return "pgstore." + name
+}
@@ -0,0 +1,392 @@
+// Package events defines the wire format of the events broadcast to room
+// subscribers, so Go clients can decode them without copying the types.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event kinds, sent as the "kind" field of every event.
+const (
+	KindMessageCreated        = "message_created"
+	KindMessageEdited         = "message_edited"
+	KindMessageDeleted        = "message_deleted"
+	KindMessageAnswered       = "message_answered"
+	KindMessageRestored       = "message_restored"
+	KindMessageLanguage       = "message_language"
+	KindSlowConsumerWarning   = "slow_consumer_warning"
+	KindRoomExpired           = "room_expired"
+	KindRoomClosed            = "room_closed"
+	KindRoomDeleted           = "room_deleted"
+	KindRoomUpdated           = "room_updated"
+	KindReactionCountsUpdated = "reaction_counts_updated"
+	KindMessageFlagThreshold  = "message_flag_threshold"
+	KindMessagePending        = "message_pending"
+	KindReplyCreated          = "reply_created"
+	KindPollCreated           = "poll_created"
+	KindPollVote              = "poll_vote"
+	KindPollClosed            = "poll_closed"
+	KindAnnouncementCreated   = "announcement_created"
+	KindViewerCount           = "viewer_count"
+	KindComposing             = "composing"
+	KindRoomJoined            = "room_joined"
+	KindRoomLeft              = "room_left"
+	KindSubscriptionError     = "subscription_error"
+	KindCommandResult         = "command_result"
+)
+
+// Event kinds the server no longer sends. They are kept so clients of older
+// servers can still decode them.
+const (
+	// KindMessageReactionIncreased used to carry the new count of a message
+	// every time it was reacted to.
+	//
+	// Deprecated: counts are sent in KindReactionCountsUpdated, coalesced.
+	KindMessageReactionIncreased = "message_reaction_increased"
+	// KindMessageReactionDecreased used to carry the new count of a message
+	// every time a reaction to it was taken back.
+	//
+	// Deprecated: counts are sent in KindReactionCountsUpdated, coalesced.
+	KindMessageReactionDecreased = "message_reaction_decreased"
+	// KindMessageEmojiReaction used to carry the new count of one kind of
+	// emoji reaction to a message.
+	//
+	// Deprecated: emoji counts are sent in KindReactionCountsUpdated,
+	// coalesced.
+	KindMessageEmojiReaction = "message_emoji_reaction"
+	// KindReactionsBatchUpdated used to carry the counts changed by a batch of
+	// reactions.
+	//
+	// Deprecated: the counts of batches are sent in KindReactionCountsUpdated
+	// like those of every other reaction change.
+	KindReactionsBatchUpdated = "reactions_batch_updated"
+)
+
+// Scopes of subscriptions and events. Events in the moderator scope are only
+// sent to subscriptions opened with the moderator scope.
+const (
+	ScopePublic    = ""
+	ScopeModerator = "moderator"
+)
+
+// Event is the envelope of everything sent to room subscribers. Value holds
+// one of the value types below, matching Kind.
+//
+// Seq numbers the public events of a room: it increases by exactly one with
+// every such event, so a client that sees a gap missed events and should
+// reload the room. Events addressed to a single connection or only to
+// moderators carry no Seq, and neither do viewer_count and composing, which
+// only matter until the next one.
+//
+// RoomID is only sent on connections subscribed to several rooms at once,
+// where it tells which room an event belongs to.
+type Event struct {
+	Kind   string `json:"kind"`
+	Seq    uint64 `json:"seq,omitempty"`
+	Value  any    `json:"value"`
+	RoomID string `json:"-"`
+	Scope  string `json:"-"`
+}
+
+// MessageCreated is also the value of message_pending, which tells moderators
+// about a message waiting for their approval before it is created for
+// everyone else.
+type MessageCreated struct {
+	ID         string `json:"id,omitempty"`
+	Message    string `json:"message,omitempty"`
+	AuthorName string `json:"author_name,omitempty"`
+	Language   string `json:"language,omitempty"`
+	Version    int64  `json:"version,omitempty"`
+}
+
+type MessageEdited struct {
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message,omitempty"`
+	Version int64  `json:"version,omitempty"`
+}
+
+type MessageDeleted struct {
+	ID string `json:"id,omitempty"`
+}
+
+// MessageReaction carries the new reaction count of a message, as answered to
+// reactions. It was also the value of the deprecated
+// message_reaction_increased and message_reaction_decreased events.
+type MessageReaction struct {
+	ID      string `json:"id,omitempty"`
+	Count   int64  `json:"count"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// MessageEmojiReaction carries the new count of one kind of emoji reaction
+// to a message. Emoji reactions are apart from the upvotes counted by
+// MessageReaction, which alone rank messages.
+//
+// Deprecated: the server sends the counts in ReactionCountsUpdated instead.
+type MessageEmojiReaction struct {
+	ID    string `json:"id,omitempty"`
+	Kind  string `json:"kind"`
+	Count int64  `json:"count"`
+}
+
+type MessageAnswered struct {
+	ID      string `json:"id,omitempty"`
+	Answer  string `json:"answer,omitempty"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// MessageLanguage is sent when a host corrects the language a message was
+// tagged with.
+type MessageLanguage struct {
+	ID       string `json:"id,omitempty"`
+	Language string `json:"language"`
+	Version  int64  `json:"version,omitempty"`
+}
+
+// ReplyCreated is sent when a host replies to a message. A message can have
+// several replies, in the order they were created.
+type ReplyCreated struct {
+	ID        string `json:"id,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	Author    string `json:"author,omitempty"`
+	Body      string `json:"body,omitempty"`
+}
+
+// PollCreated is sent when a host opens a poll. Votes pick an option by its
+// index in Options.
+type PollCreated struct {
+	ID       string   `json:"id,omitempty"`
+	Question string   `json:"question,omitempty"`
+	Options  []string `json:"options"`
+}
+
+// PollResults is the value of poll_vote and poll_closed and carries the vote
+// count of every option of the poll, in the order of its options.
+type PollResults struct {
+	ID         string  `json:"id,omitempty"`
+	Votes      []int64 `json:"votes"`
+	TotalVotes int64   `json:"total_votes"`
+}
+
+// AnnouncementCreated is sent when a host announces something to the room,
+// like a break, apart from its messages.
+type AnnouncementCreated struct {
+	ID     string `json:"id,omitempty"`
+	Author string `json:"author,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// ViewerCount tells the subscribers of a room how many they are. It is sent
+// every few seconds while the count changes.
+type ViewerCount struct {
+	Count int `json:"count"`
+}
+
+// Composing tells the subscribers of a room how many clients are composing a
+// question, including the client itself when it is one of them. It is sent at
+// most once a second while the count changes.
+type Composing struct {
+	Count int `json:"count"`
+}
+
+// MessageRestored is sent when a host undoes the deletion of a message, with
+// everything clients need to show it again.
+type MessageRestored struct {
+	ID            string `json:"id,omitempty"`
+	Message       string `json:"message,omitempty"`
+	AuthorName    string `json:"author_name,omitempty"`
+	ReactionCount int64  `json:"reaction_count"`
+	Answered      bool   `json:"answered"`
+	Answer        string `json:"answer,omitempty"`
+	Version       int64  `json:"version,omitempty"`
+}
+
+// ReactionsBatchUpdated carries the new reaction counts of every message
+// touched by a batch of reaction changes.
+//
+// Deprecated: the server sends ReactionCountsUpdated instead.
+type ReactionsBatchUpdated struct {
+	Messages []MessageReaction `json:"messages"`
+}
+
+// ReactionCountsUpdated carries the current reaction count of every message
+// whose reactions changed since the previous one, by message id. Reaction
+// changes, those of reaction batches included, are coalesced into one such
+// event per room every few hundred milliseconds. Emoji holds the emoji reaction counts of every message whose
+// emoji reactions changed, by message id and kind.
+type ReactionCountsUpdated struct {
+	Counts map[string]int64            `json:"counts"`
+	Emoji  map[string]map[string]int64 `json:"emoji,omitempty"`
+}
+
+// MessageFlagThreshold is sent to moderators when a message was flagged by
+// enough clients to need their attention.
+type MessageFlagThreshold struct {
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message,omitempty"`
+	Flags   int64  `json:"flags"`
+}
+
+// SlowConsumerWarning is sent to a client whose send queue is filling up
+// faster than it reads; it gets disconnected once the queue overflows.
+type SlowConsumerWarning struct {
+	QueuedEvents  int `json:"queued_events"`
+	QueueCapacity int `json:"queue_capacity"`
+}
+
+// RoomExpired is the last event sent on a room's subscriptions before the
+// expired room is deleted and they are closed.
+type RoomExpired struct {
+	ID string `json:"id,omitempty"`
+}
+
+// RoomClosed is sent when a host closes the room. Its questions and reactions
+// can't change anymore, but the room and its subscriptions stay open.
+type RoomClosed struct {
+	ID string `json:"id,omitempty"`
+}
+
+// RoomDeleted is the last event sent on a room's subscriptions when a host
+// deletes the room, after which they are closed.
+type RoomDeleted struct {
+	ID string `json:"id,omitempty"`
+}
+
+// RoomUpdated is sent when a host changes the theme or settings of the room.
+// It carries all of them, changed or not.
+type RoomUpdated struct {
+	ID                         string     `json:"id,omitempty"`
+	Theme                      string     `json:"theme"`
+	MaxMessages                int32      `json:"max_messages"`
+	Prune                      bool       `json:"prune"`
+	RequireName                bool       `json:"require_name"`
+	ExpiresAt                  *time.Time `json:"expires_at"`
+	DuplicateThreshold         float32    `json:"duplicate_threshold"`
+	MaxQuestionsPerParticipant int32      `json:"max_questions_per_participant"`
+	RequireApproval            bool       `json:"require_approval"`
+	ClosesAt                   *time.Time `json:"closes_at"`
+	Description                string     `json:"description"`
+	HostName                   string     `json:"host_name"`
+	StartsAt                   *time.Time `json:"starts_at"`
+}
+
+// RoomJoined acknowledges a join control frame on a multi-room connection.
+// Seq is the room's current sequence number, events of the room that follow
+// continue from it.
+type RoomJoined struct {
+	RoomID string `json:"room_id"`
+	Seq    uint64 `json:"seq"`
+}
+
+// RoomLeft acknowledges a leave control frame on a multi-room connection.
+type RoomLeft struct {
+	RoomID string `json:"room_id"`
+}
+
+// SubscriptionError reports a control frame of a multi-room connection that
+// could not be applied. The connection stays open.
+type SubscriptionError struct {
+	Action  string `json:"action,omitempty"`
+	RoomID  string `json:"room_id,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// CommandResult answers a command sent over a room's websocket. Status and
+// Body are the status and response body of the REST call the command stands
+// for, so clients handle both the same way. Bodies that aren't JSON, like
+// plain text errors, are sent as a JSON string.
+type CommandResult struct {
+	ID      string          `json:"id,omitempty"`
+	Command string          `json:"command"`
+	Status  int             `json:"status"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// UnmarshalEvent decodes an event, setting Value to the concrete value type
+// of its kind.
+func UnmarshalEvent(data []byte) (Event, error) {
+	var raw struct {
+		Kind   string          `json:"kind"`
+		RoomID string          `json:"room_id"`
+		Seq    uint64          `json:"seq"`
+		Value  json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Event{}, err
+	}
+
+	var (
+		value any
+		err   error
+	)
+	switch raw.Kind {
+	case KindMessageCreated, KindMessagePending:
+		value, err = decodeValue[MessageCreated](raw.Value)
+	case KindMessageEdited:
+		value, err = decodeValue[MessageEdited](raw.Value)
+	case KindMessageDeleted:
+		value, err = decodeValue[MessageDeleted](raw.Value)
+	case KindMessageReactionIncreased, KindMessageReactionDecreased:
+		value, err = decodeValue[MessageReaction](raw.Value)
+	case KindMessageEmojiReaction:
+		value, err = decodeValue[MessageEmojiReaction](raw.Value)
+	case KindMessageAnswered:
+		value, err = decodeValue[MessageAnswered](raw.Value)
+	case KindMessageRestored:
+		value, err = decodeValue[MessageRestored](raw.Value)
+	case KindMessageLanguage:
+		value, err = decodeValue[MessageLanguage](raw.Value)
+	case KindReplyCreated:
+		value, err = decodeValue[ReplyCreated](raw.Value)
+	case KindPollCreated:
+		value, err = decodeValue[PollCreated](raw.Value)
+	case KindPollVote, KindPollClosed:
+		value, err = decodeValue[PollResults](raw.Value)
+	case KindAnnouncementCreated:
+		value, err = decodeValue[AnnouncementCreated](raw.Value)
+	case KindViewerCount:
+		value, err = decodeValue[ViewerCount](raw.Value)
+	case KindComposing:
+		value, err = decodeValue[Composing](raw.Value)
+	case KindReactionsBatchUpdated:
+		value, err = decodeValue[ReactionsBatchUpdated](raw.Value)
+	case KindReactionCountsUpdated:
+		value, err = decodeValue[ReactionCountsUpdated](raw.Value)
+	case KindMessageFlagThreshold:
+		value, err = decodeValue[MessageFlagThreshold](raw.Value)
+	case KindSlowConsumerWarning:
+		value, err = decodeValue[SlowConsumerWarning](raw.Value)
+	case KindRoomExpired:
+		value, err = decodeValue[RoomExpired](raw.Value)
+	case KindRoomClosed:
+		value, err = decodeValue[RoomClosed](raw.Value)
+	case KindRoomDeleted:
+		value, err = decodeValue[RoomDeleted](raw.Value)
+	case KindRoomUpdated:
+		value, err = decodeValue[RoomUpdated](raw.Value)
+	case KindRoomJoined:
+		value, err = decodeValue[RoomJoined](raw.Value)
+	case KindRoomLeft:
+		value, err = decodeValue[RoomLeft](raw.Value)
+	case KindSubscriptionError:
+		value, err = decodeValue[SubscriptionError](raw.Value)
+	case KindCommandResult:
+		value, err = decodeValue[CommandResult](raw.Value)
+	default:
+		return Event{}, fmt.Errorf("events: unknown event kind %q", raw.Kind)
+	}
+	if err != nil {
+		return Event{}, fmt.Errorf("events: decoding %s: %w", raw.Kind, err)
+	}
+
+	return Event{Kind: raw.Kind, Seq: raw.Seq, Value: value, RoomID: raw.RoomID}, nil
+}
+
+func decodeValue[T any](data json.RawMessage) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
@@ -0,0 +1,115 @@
+package events_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lohanguedes/AMA-Backend/pkg/events"
+)
+
+func TestUnmarshalEventRoundTrip(t *testing.T) {
+	at := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tests := []events.Event{
+		{Kind: events.KindMessageCreated, Seq: 1, Value: events.MessageCreated{ID: "m1", Message: "question", AuthorName: "Ada", Language: "en", Version: 1}},
+		{Kind: events.KindMessagePending, Value: events.MessageCreated{ID: "m1", Message: "question"}},
+		{Kind: events.KindMessageEdited, Seq: 2, Value: events.MessageEdited{ID: "m1", Message: "edited", Version: 2}},
+		{Kind: events.KindMessageDeleted, Seq: 3, Value: events.MessageDeleted{ID: "m1"}},
+		{Kind: events.KindMessageReactionIncreased, Seq: 4, Value: events.MessageReaction{ID: "m1", Count: 1, Version: 2}},
+		{Kind: events.KindMessageReactionDecreased, Seq: 5, Value: events.MessageReaction{ID: "m1", Count: 0, Version: 3}},
+		{Kind: events.KindMessageEmojiReaction, Seq: 6, Value: events.MessageEmojiReaction{ID: "m1", Kind: "heart", Count: 2}},
+		{Kind: events.KindMessageAnswered, Seq: 7, Value: events.MessageAnswered{ID: "m1", Answer: "yes", Version: 4}},
+		{Kind: events.KindMessageRestored, Seq: 8, Value: events.MessageRestored{ID: "m1", Message: "question", ReactionCount: 3, Answered: true, Answer: "yes", Version: 5}},
+		{Kind: events.KindMessageLanguage, Seq: 8, Value: events.MessageLanguage{ID: "m1", Language: "pt", Version: 6}},
+		{Kind: events.KindReplyCreated, Seq: 9, Value: events.ReplyCreated{ID: "r1", MessageID: "m1", Author: "host", Body: "reply"}},
+		{Kind: events.KindPollCreated, Seq: 10, Value: events.PollCreated{ID: "p1", Question: "which?", Options: []string{"a", "b"}}},
+		{Kind: events.KindPollVote, Seq: 11, Value: events.PollResults{ID: "p1", Votes: []int64{1, 0}, TotalVotes: 1}},
+		{Kind: events.KindPollClosed, Seq: 12, Value: events.PollResults{ID: "p1", Votes: []int64{1, 2}, TotalVotes: 3}},
+		{Kind: events.KindAnnouncementCreated, Seq: 13, Value: events.AnnouncementCreated{ID: "a1", Author: "host", Body: "break"}},
+		{Kind: events.KindViewerCount, Value: events.ViewerCount{Count: 12}},
+		{Kind: events.KindComposing, Value: events.Composing{Count: 2}},
+		{Kind: events.KindReactionsBatchUpdated, Seq: 14, Value: events.ReactionsBatchUpdated{Messages: []events.MessageReaction{{ID: "m1", Count: 2}}}},
+		{Kind: events.KindReactionCountsUpdated, Seq: 15, Value: events.ReactionCountsUpdated{
+			Counts: map[string]int64{"m1": 2, "m2": 0},
+			Emoji:  map[string]map[string]int64{"m1": {"heart": 1}},
+		}},
+		{Kind: events.KindMessageFlagThreshold, Value: events.MessageFlagThreshold{ID: "m1", Message: "question", Flags: 3}},
+		{Kind: events.KindSlowConsumerWarning, Value: events.SlowConsumerWarning{QueuedEvents: 49, QueueCapacity: 64}},
+		{Kind: events.KindRoomExpired, Seq: 16, Value: events.RoomExpired{ID: "room"}},
+		{Kind: events.KindRoomClosed, Seq: 17, Value: events.RoomClosed{ID: "room"}},
+		{Kind: events.KindRoomDeleted, Seq: 18, Value: events.RoomDeleted{ID: "room"}},
+		{Kind: events.KindRoomUpdated, Seq: 19, Value: events.RoomUpdated{ID: "room", Theme: "theme", MaxMessages: 10, Prune: true, ExpiresAt: &at, ClosesAt: &at, StartsAt: &at, Description: "about", HostName: "host"}},
+		{Kind: events.KindRoomJoined, Value: events.RoomJoined{RoomID: "room", Seq: 19}},
+		{Kind: events.KindRoomLeft, Value: events.RoomLeft{RoomID: "room"}},
+		{Kind: events.KindSubscriptionError, Value: events.SubscriptionError{Action: "join", RoomID: "room", Code: "room_not_found", Message: "room not found"}},
+		{Kind: events.KindCommandResult, Value: events.CommandResult{ID: "c1", Command: "react", Status: 200, Body: json.RawMessage(`{"id":"m1"}`)}},
+	}
+	for _, want := range tests {
+		t.Run(want.Kind, func(t *testing.T) {
+			data, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := events.UnmarshalEvent(data)
+			if err != nil {
+				t.Fatalf("decoding %s: %v", data, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestEventWireFormat(t *testing.T) {
+	// RoomID and Scope are never marshalled: the room is only named on
+	// multi-room connections, by the server.
+	data, err := json.Marshal(events.Event{
+		Kind:   events.KindMessageDeleted,
+		Seq:    3,
+		RoomID: "room",
+		Scope:  events.ScopeModerator,
+		Value:  events.MessageDeleted{ID: "m1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"kind":"message_deleted","seq":3,"value":{"id":"m1"}}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestUnmarshalEventRoomID(t *testing.T) {
+	got, err := events.UnmarshalEvent([]byte(`{"kind":"room_closed","room_id":"room","seq":4,"value":{"id":"room"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RoomID != "room" || got.Seq != 4 {
+		t.Errorf("got room_id %q and seq %d, want room and 4", got.RoomID, got.Seq)
+	}
+}
+
+func TestUnmarshalEventErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"NotJSON", `{`, ""},
+		{"UnknownKind", `{"kind":"message_teleported","value":{}}`, `unknown event kind "message_teleported"`},
+		{"MismatchedValue", `{"kind":"viewer_count","value":{"count":"many"}}`, "decoding viewer_count"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := events.UnmarshalEvent([]byte(tt.data))
+			if err == nil {
+				t.Fatal("decoded an invalid event")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}